@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/ZZTmercari/spannerclosecheck/pkg/analyzer"
+)
+
+const configUsage = `usage: spannerclosecheck config init [-force] [file]
+       spannerclosecheck config schema [file]
+
+init writes a configuration file listing every option with its default, to
+` + analyzer.DefaultConfigFile + ` unless file is given. Use - for the standard output.
+
+schema writes the JSON Schema of configuration files, for editors to validate
+them, to the standard output unless file is given.
+`
+
+// configCommand runs the config subcommand with args, and returns the exit
+// code of the process
+func configCommand(args []string) int {
+	if len(args) == 0 || args[0] != "init" && args[0] != "schema" {
+		fmt.Fprint(os.Stderr, configUsage)
+		return 2
+	}
+	if args[0] == "schema" {
+		return schemaCommand(args[1:])
+	}
+
+	force := false
+	path := analyzer.DefaultConfigFile
+	for _, arg := range args[1:] {
+		switch {
+		case arg == "-force" || arg == "--force":
+			force = true
+		case arg == "-h" || arg == "-help" || arg == "--help":
+			fmt.Fprint(os.Stdout, configUsage)
+			return 0
+		case len(arg) > 1 && arg[0] == '-':
+			fmt.Fprintf(os.Stderr, "config init: unknown flag %s\n%s", arg, configUsage)
+			return 2
+		default:
+			path = arg
+		}
+	}
+
+	if err := configInit(path, force); err != nil {
+		fmt.Fprintf(os.Stderr, "config init: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// configInit writes the default configuration to path, unless it exists and
+// force is not set
+func configInit(path string, force bool) error {
+	var b bytes.Buffer
+	if err := analyzer.WriteConfig(&b, &analyzer.Analyzer.Flags); err != nil {
+		return err
+	}
+	if path == "-" {
+		_, err := os.Stdout.Write(b.Bytes())
+		return err
+	}
+
+	if !force {
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("%s already exists, use -force to overwrite it", path)
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	if err := os.WriteFile(path, b.Bytes(), 0o644); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "wrote %s\n", path)
+	return nil
+}
+
+// schemaCommand runs the config schema subcommand with args, and returns the
+// exit code of the process
+func schemaCommand(args []string) int {
+	path := "-"
+	for _, arg := range args {
+		switch {
+		case arg == "-h" || arg == "-help" || arg == "--help":
+			fmt.Fprint(os.Stdout, configUsage)
+			return 0
+		case len(arg) > 1 && arg[0] == '-':
+			fmt.Fprintf(os.Stderr, "config schema: unknown flag %s\n%s", arg, configUsage)
+			return 2
+		default:
+			path = arg
+		}
+	}
+
+	var b bytes.Buffer
+	if err := analyzer.WriteConfigSchema(&b, &analyzer.Analyzer.Flags); err != nil {
+		fmt.Fprintf(os.Stderr, "config schema: %v\n", err)
+		return 1
+	}
+	if path == "-" {
+		os.Stdout.Write(b.Bytes())
+		return 0
+	}
+	if err := os.WriteFile(path, b.Bytes(), 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "config schema: %v\n", err)
+		return 1
+	}
+	return 0
+}
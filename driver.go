@@ -0,0 +1,370 @@
+package main
+
+import (
+	"cmp"
+	"flag"
+	"fmt"
+	"go/token"
+	"go/types"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ZZTmercari/spannerclosecheck/pkg/analyzer"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/checker"
+	"golang.org/x/tools/go/packages"
+)
+
+// Output formats of the -format flag
+const (
+	formatText  = "text"
+	formatSARIF = "sarif"
+	formatJSON  = "json"
+	formatVet   = "vet-json"
+)
+
+// formats are the values of the -format flag
+var formats = []string{formatText, formatJSON, formatSARIF, formatVet}
+
+// Flags of singlechecker runDriver does not implement, which it rejects
+var (
+	unsupportedBoolFlags = []string{"fix", "diff", "json", "flags", "V"}
+	unsupportedFlags     = []string{"c", "debug", "cpuprofile", "memprofile", "trace"}
+)
+
+// Deprecated flags singlechecker accepts without effect, and runDriver too
+var (
+	legacyBoolFlags = []string{"source", "v", "all"}
+	legacyFlags     = []string{"tags"}
+)
+
+// driverOptions are the options of runDriver
+type driverOptions struct {
+	quiet     bool
+	format    string
+	maxIssues int
+	tests     bool
+}
+
+// driverFlagSet returns the flags of runDriver setting opts, along with
+// those of a and of singlechecker, so that driverArgs and runDriver read the
+// command line as singlechecker does
+func driverFlagSet(a *analysis.Analyzer, opts *driverOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet(a.Name, flag.ContinueOnError)
+	fs.BoolVar(&opts.quiet, "quiet", false, "print diagnostics to the standard output as file:line: message only")
+	fs.StringVar(&opts.format, "format", formatText, "output format: "+strings.Join(formats, " or "))
+	fs.IntVar(&opts.maxIssues, "max-issues", 0, "maximum number of issues reported, dropping the rest (0 means no limit)")
+	fs.BoolVar(&opts.tests, "test", true, "indicates whether test files should be analyzed, too")
+	for _, name := range unsupportedBoolFlags {
+		fs.Bool(name, false, "not supported with -quiet or -format")
+	}
+	for _, name := range unsupportedFlags {
+		fs.String(name, "", "not supported with -quiet or -format")
+	}
+	for _, name := range legacyBoolFlags {
+		fs.Bool(name, false, "no effect (deprecated)")
+	}
+	for _, name := range legacyFlags {
+		fs.String(name, "", "no effect (deprecated)")
+	}
+	a.Flags.VisitAll(func(f *flag.Flag) {
+		fs.Var(f.Value, f.Name, f.Usage)
+	})
+	return fs
+}
+
+// driverArgs reports whether args select an output singlechecker does not
+// print, with -quiet, -max-issues or a -format other than text, or
+// -whole-program, which needs the packages runDriver loads. Otherwise it
+// returns args without -quiet, -max-issues and -format for singlechecker,
+// which does not define them.
+func driverArgs(a *analysis.Analyzer, args []string) (rest []string, driver bool) {
+	fs := driverFlagSet(a, &driverOptions{})
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" || len(arg) < 2 || arg[0] != '-' {
+			return append(rest, args[i:]...), driver
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		// Flags other than booleans may take their value from the next
+		// argument
+		next := !hasValue && !isBoolFlag(fs.Lookup(name)) && i+1 < len(args)
+		switch name {
+		case "quiet":
+			if quiet, err := strconv.ParseBool(cmp.Or(value, "true")); err != nil || quiet {
+				driver = true
+			}
+		case "format":
+			if next {
+				i++
+				value = args[i]
+			}
+			if value != formatText {
+				driver = true
+			}
+		case "whole-program":
+			if whole, err := strconv.ParseBool(cmp.Or(value, "true")); err != nil || whole {
+				driver = true
+			}
+			rest = append(rest, arg)
+		case "max-issues":
+			if next {
+				i++
+				value = args[i]
+			}
+			if n, err := strconv.Atoi(value); err != nil || n != 0 {
+				driver = true
+			}
+		default:
+			rest = append(rest, arg)
+			if next {
+				i++
+				rest = append(rest, args[i])
+			}
+		}
+	}
+	return rest, driver
+}
+
+// isBoolFlag reports whether f is a boolean flag, set without a value
+func isBoolFlag(f *flag.Flag) bool {
+	if f == nil {
+		return false
+	}
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// runDriver analyzes the packages of args with a, like singlechecker, and
+// returns the exit code of the process: 1 if the analysis failed, 3 if it
+// reported diagnostics in text. The diagnostics past -max-issues, once
+// sorted, are dropped, or listed as suppressed in JSON.
+func runDriver(a *analysis.Analyzer, args []string) int {
+	var opts driverOptions
+	fs := driverFlagSet(a, &opts)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if !slices.Contains(formats, opts.format) {
+		fmt.Fprintf(os.Stderr, "invalid -format %q, want %s\n", opts.format, strings.Join(formats, " or "))
+		return 2
+	}
+	if opts.quiet && opts.format != formatText {
+		fmt.Fprintf(os.Stderr, "-quiet prints text, it cannot be used with -format %s\n", opts.format)
+		return 2
+	}
+	if opts.maxIssues < 0 {
+		fmt.Fprintf(os.Stderr, "invalid -max-issues %d, want 0 or more\n", opts.maxIssues)
+		return 2
+	}
+	if opts.maxIssues > 0 && opts.format == formatVet {
+		// go vet -json lists the diagnostics of each package in turn
+		fmt.Fprintf(os.Stderr, "-max-issues is not supported with -format %s\n", opts.format)
+		return 2
+	}
+	selected := "-format " + opts.format
+	switch {
+	case opts.quiet:
+		selected = "-quiet"
+	case opts.maxIssues > 0 && opts.format == formatText:
+		selected = "-max-issues"
+	case opts.format == formatText:
+		selected = "-whole-program"
+	}
+	unsupported := false
+	fs.Visit(func(f *flag.Flag) {
+		if slices.Contains(unsupportedBoolFlags, f.Name) || slices.Contains(unsupportedFlags, f.Name) {
+			fmt.Fprintf(os.Stderr, "-%s is not supported with %s\n", f.Name, selected)
+			unsupported = true
+		}
+	})
+	if unsupported {
+		return 2
+	}
+	severities := make(map[severityKey]analyzer.Severity)
+	if opts.format == formatJSON || opts.format == formatSARIF {
+		// Structured output carries warnings with their severity, while
+		// go vet -json leaves them out
+		analyzer.WarningOutput = nil
+		analyzer.SeverityOutput = func(pass *analysis.Pass, d analysis.Diagnostic, s analyzer.Severity) {
+			if pass.Analyzer == a {
+				severities[severityKey{pass.Fset.Position(d.Pos), d.Category}] = s
+			}
+		}
+	}
+
+	cfg := &packages.Config{Mode: packages.LoadAllSyntax | packages.NeedModule, Tests: opts.tests}
+	pkgs, err := packages.Load(cfg, fs.Args()...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	// Whole-program mode builds the SSA of the dependencies loaded here
+	analyzer.ProgramPackages = pkgs
+	exitcode := 0
+	if packages.PrintErrors(pkgs) > 0 {
+		exitcode = 1
+	}
+	var suppressed []diagnostic
+	if opts.format == formatJSON {
+		roots := make(map[*types.Package]bool)
+		for _, pkg := range pkgs {
+			roots[pkg.Types] = true
+		}
+		analyzer.SuppressedOutput = func(pass *analysis.Pass, d analysis.Diagnostic, s analyzer.Suppression) {
+			if pass.Analyzer == a && roots[pass.Pkg] {
+				suppressed = append(suppressed, diagnostic{d, a.Name, pass.Pkg.Path(), pass.Fset, pass.Fset.Position(d.Pos), analyzer.SeverityError, &s})
+			}
+		}
+	}
+	graph, err := checker.Analyze([]*analysis.Analyzer{a}, pkgs, nil)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if opts.format == formatVet {
+		// go vet -json reports failed analyses in the output, which the go
+		// command prints to the standard error
+		if err := writeVet(os.Stderr, graph); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return exitcode
+	}
+	diags, failed := rootDiagnostics(graph, severities, suppressed)
+	if failed {
+		exitcode = 1
+	}
+	diags = limitDiagnostics(diags, opts.maxIssues, opts.format == formatJSON)
+	if opts.format != formatText {
+		// Like -json, structured output does not fail the run on findings
+		write := writeSARIF
+		if opts.format == formatJSON {
+			write = writeJSON
+		}
+		if err := write(os.Stdout, diags); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return exitcode
+	}
+	for _, d := range diags {
+		printDiagnostic(d, opts.quiet)
+	}
+	if exitcode == 0 && len(diags) > 0 {
+		exitcode = 3
+	}
+	return exitcode
+}
+
+// diagnostic is a diagnostic of a root package
+type diagnostic struct {
+	analysis.Diagnostic
+	analyzer string
+	pkg      string
+	fset     *token.FileSet
+	posn     token.Position
+	severity analyzer.Severity
+	// suppression is set for the diagnostics the options drop
+	suppression *analyzer.Suppression
+}
+
+// severityKey identifies the diagnostics of a category at a position, whose
+// severity analyzer.SeverityOutput passes
+type severityKey struct {
+	posn     token.Position
+	category string
+}
+
+// rootDiagnostics returns the diagnostics of the root packages of graph,
+// with their severity in severities or else SeverityError, along with those
+// suppressed, sorted by position and analyzer, and prints the errors of the
+// actions
+func rootDiagnostics(graph *checker.Graph, severities map[severityKey]analyzer.Severity, suppressed []diagnostic) (diags []diagnostic, failed bool) {
+	// Files of a package and of its test variant are analyzed twice
+	type key struct {
+		posn    token.Position
+		message string
+	}
+	seen := make(map[key]bool)
+	for act := range graph.All() {
+		if act.Err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", act.Analyzer.Name, act.Err)
+			failed = true
+			continue
+		}
+		if !act.IsRoot {
+			continue
+		}
+		for _, d := range act.Diagnostics {
+			posn := act.Package.Fset.Position(d.Pos)
+			if k := (key{posn, d.Message}); !seen[k] {
+				seen[k] = true
+				severity := cmp.Or(severities[severityKey{posn, d.Category}], analyzer.SeverityError)
+				diags = append(diags, diagnostic{d, act.Analyzer.Name, act.Package.PkgPath, act.Package.Fset, posn, severity, nil})
+			}
+		}
+	}
+	for _, d := range suppressed {
+		if k := (key{d.posn, d.Message}); !seen[k] {
+			seen[k] = true
+			diags = append(diags, d)
+		}
+	}
+	slices.SortStableFunc(diags, func(a, b diagnostic) int {
+		return cmp.Or(
+			strings.Compare(a.posn.Filename, b.posn.Filename),
+			cmp.Compare(a.posn.Line, b.posn.Line),
+			cmp.Compare(a.posn.Column, b.posn.Column),
+			strings.Compare(a.analyzer, b.analyzer),
+			strings.Compare(a.Category, b.Category),
+			strings.Compare(a.Message, b.Message),
+		)
+	})
+	return diags, failed
+}
+
+// limitDiagnostics returns diags, as sorted by rootDiagnostics, with the
+// issues past the first max dropped, or marked as suppressed if keep is set,
+// and notes dropped issues on the standard error. Zero means no limit.
+func limitDiagnostics(diags []diagnostic, max int, keep bool) []diagnostic {
+	if max <= 0 {
+		return diags
+	}
+	limited := diags[:0]
+	issues := 0
+	for _, d := range diags {
+		if d.suppression == nil {
+			if issues++; issues > max {
+				if !keep {
+					continue
+				}
+				d.suppression = &analyzer.Suppression{
+					Kind:          analyzer.SuppressionMaxIssues,
+					Justification: fmt.Sprintf("the run already reported -max-issues %d issues", max),
+				}
+			}
+		}
+		limited = append(limited, d)
+	}
+	if issues > max && !keep {
+		fmt.Fprintf(os.Stderr, "spannerclosecheck: more than %d issues, the rest are not reported (-max-issues)\n", max)
+	}
+	return limited
+}
+
+// printDiagnostic prints d as singlechecker does, or as file:line: message
+// to the standard output if quiet is set
+func printDiagnostic(d diagnostic, quiet bool) {
+	if quiet {
+		fmt.Fprintf(os.Stdout, "%s:%d: %s\n", d.posn.Filename, d.posn.Line, d.Message)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "%s: %s\n", d.posn, d.Message)
+	for _, r := range d.Related {
+		fmt.Fprintf(os.Stderr, "%s: \t%s\n", d.fset.Position(r.Pos), r.Message)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"go/token"
+	"io"
+
+	"github.com/ZZTmercari/spannerclosecheck/pkg/analyzer"
+)
+
+// jsonDiagnostic is a diagnostic of -format json. Suggested fixes are
+// written like those of -json, as byte offsets into the Go files.
+type jsonDiagnostic struct {
+	Analyzer       string           `json:"analyzer"`
+	Check          string           `json:"check"`
+	Severity       string           `json:"severity"`
+	Confidence     string           `json:"confidence"`
+	Package        string           `json:"package"`
+	File           string           `json:"file"`
+	Line           int              `json:"line"`
+	Column         int              `json:"column"`
+	EndLine        int              `json:"end_line,omitempty"`
+	EndColumn      int              `json:"end_column,omitempty"`
+	Message        string           `json:"message"`
+	Related        []jsonRelated    `json:"related,omitempty"`
+	SuggestedFixes []jsonFix        `json:"suggested_fixes,omitempty"`
+	Suppressed     bool             `json:"suppressed"`
+	Suppression    *jsonSuppression `json:"suppression,omitempty"`
+}
+
+type jsonRelated struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
+}
+
+type jsonFix struct {
+	Message string     `json:"message"`
+	Edits   []jsonEdit `json:"edits"`
+}
+
+type jsonEdit struct {
+	Filename string `json:"filename"`
+	Start    int    `json:"start"`
+	End      int    `json:"end"`
+	New      string `json:"new"`
+}
+
+type jsonSuppression struct {
+	Kind          string `json:"kind"`
+	Justification string `json:"justification"`
+}
+
+// writeJSON writes diags to w as a JSON array
+func writeJSON(w io.Writer, diags []diagnostic) error {
+	out := []jsonDiagnostic{}
+	for _, d := range diags {
+		jd := jsonDiagnostic{
+			Analyzer:   d.analyzer,
+			Check:      d.Category,
+			Severity:   string(d.severity),
+			Confidence: analyzer.ConfidenceOf(d.Diagnostic).String(),
+			Package:    d.pkg,
+			File:       d.posn.Filename,
+			Line:       d.posn.Line,
+			Column:     d.posn.Column,
+			Message:    d.Message,
+			Suppressed: d.suppression != nil,
+		}
+		if end := d.fset.Position(d.End); end.IsValid() && end.Filename == d.posn.Filename {
+			jd.EndLine, jd.EndColumn = end.Line, end.Column
+		}
+		for _, r := range d.Related {
+			posn := d.fset.Position(r.Pos)
+			jd.Related = append(jd.Related, jsonRelated{File: posn.Filename, Line: posn.Line, Column: posn.Column, Message: r.Message})
+		}
+		for _, fix := range d.SuggestedFixes {
+			jf := jsonFix{Message: fix.Message, Edits: []jsonEdit{}}
+			for _, edit := range fix.TextEdits {
+				jf.Edits = append(jf.Edits, jsonTextEdit(d.fset, edit.Pos, edit.End, string(edit.NewText)))
+			}
+			jd.SuggestedFixes = append(jd.SuggestedFixes, jf)
+		}
+		if s := d.suppression; s != nil {
+			jd.Suppression = &jsonSuppression{Kind: s.Kind, Justification: s.Justification}
+		}
+		out = append(out, jd)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// jsonTextEdit returns the edit replacing pos to end with text, in the Go
+// file rather than in the file of //line directives
+func jsonTextEdit(fset *token.FileSet, pos, end token.Pos, text string) jsonEdit {
+	start := fset.PositionFor(pos, false)
+	stop := fset.PositionFor(end, false)
+	if !stop.IsValid() {
+		stop = start
+	}
+	return jsonEdit{Filename: start.Filename, Start: start.Offset, End: stop.Offset, New: text}
+}
@@ -3,12 +3,17 @@ package main
 import (
 	"fmt"
 	"os"
+	"runtime/debug"
 
 	"github.com/ZZTmercari/spannerclosecheck/pkg/analyzer"
 	"golang.org/x/tools/go/analysis/singlechecker"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		os.Exit(configCommand(os.Args[2:]))
+	}
+
 	// Check for version flag before singlechecker takes over
 	for _, arg := range os.Args {
 		if arg == "-version" || arg == "--version" {
@@ -19,5 +24,13 @@ func main() {
 		}
 	}
 
+	// Warnings are printed without failing the run
+	analyzer.WarningOutput = os.Stderr
+	analyzer.SetMemoryLimit = debug.SetMemoryLimit
+	args, driver := driverArgs(analyzer.Analyzer, os.Args[1:])
+	if driver {
+		os.Exit(runDriver(analyzer.Analyzer, os.Args[1:]))
+	}
+	os.Args = append(os.Args[:1], args...)
 	singlechecker.Main(analyzer.Analyzer)
 }
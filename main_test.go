@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ZZTmercari/spannerclosecheck/pkg/analyzer"
+)
+
+var update = flag.Bool("update", false, "update the golden files of testdata")
+
+// command is the spannerclosecheck command built by TestMain
+var command string
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	dir, err := os.MkdirTemp("", "spannerclosecheck")
+	if err != nil {
+		panic(err)
+	}
+	command = filepath.Join(dir, "spannerclosecheck")
+	if out, err := exec.Command("go", "build", "-o", command, ".").CombinedOutput(); err != nil {
+		os.Stderr.Write(out)
+		os.RemoveAll(dir)
+		os.Exit(1)
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// testdataDir returns the absolute path of testdata
+func testdataDir(t *testing.T) string {
+	t.Helper()
+	dir, err := filepath.Abs("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// runCommand runs the command with args in dir, on the packages of testdata
+// and the Spanner stubs of the analyzer tests, and returns its standard
+// output and error, with the path of testdata replaced by "testdata", and
+// its exit code
+func runCommand(t *testing.T, dir string, args ...string) (stdout, stderr string, code int) {
+	t.Helper()
+	testdata := testdataDir(t)
+	stubs, err := filepath.Abs(filepath.Join("pkg", "analyzer", "testdata"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var outBuf, errBuf bytes.Buffer
+	cmd := exec.Command(command, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GO111MODULE=off", "GOPATH="+testdata+string(filepath.ListSeparator)+stubs, "GOFLAGS=")
+	cmd.Stdout, cmd.Stderr = &outBuf, &errBuf
+	if err := cmd.Run(); err != nil {
+		var exit *exec.ExitError
+		if !errors.As(err, &exit) {
+			t.Fatal(err)
+		}
+		code = exit.ExitCode()
+	}
+	normalize := func(b *bytes.Buffer) string {
+		return strings.ReplaceAll(b.String(), testdata, "testdata")
+	}
+	return normalize(&outBuf), normalize(&errBuf), code
+}
+
+// checkGolden compares got with the golden file testdata/name, or updates
+// it with -update
+func checkGolden(t *testing.T, name, got string) {
+	t.Helper()
+	golden := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("output does not match %s, run go test -update\ngot:\n%s\nwant:\n%s", golden, got, want)
+	}
+}
+
+func TestQuiet(t *testing.T) {
+	stdout, stderr, code := runCommand(t, ".", "-quiet", "output", "clean")
+	if code != 3 {
+		t.Errorf("got exit code %d, want 3\n%s", code, stderr)
+	}
+	if stderr != "" {
+		t.Errorf("got standard error %q, want none", stderr)
+	}
+	checkGolden(t, "quiet.golden", stdout)
+}
+
+func TestExitCodes(t *testing.T) {
+	for _, test := range []struct {
+		args   []string
+		code   int
+		stderr string
+	}{
+		{[]string{"-quiet", "clean"}, 0, ""},
+		{[]string{"-quiet", "output"}, 3, ""},
+		{[]string{"-quiet", "broken"}, 1, "analysis skipped due to errors in package"},
+		{[]string{"-quiet", "-fix", "output"}, 2, "-fix is not supported with -quiet\n"},
+		{[]string{"-format", "json", "-c", "1", "output"}, 2, "-c is not supported with -format json\n"},
+		{[]string{"-format", "xml", "output"}, 2, `invalid -format "xml"`},
+		{[]string{"-quiet", "-format", "sarif", "output"}, 2, "-quiet prints text, it cannot be used with -format sarif\n"},
+		{[]string{"-max-issues", "1", "-fix", "output"}, 2, "-fix is not supported with -max-issues\n"},
+		{[]string{"-max-issues", "1", "-format", "vet-json", "output"}, 2, "-max-issues is not supported with -format vet-json\n"},
+		{[]string{"-whole-program", "-fix", "output"}, 2, "-fix is not supported with -whole-program\n"},
+		{[]string{"-whole-program", "output"}, 3, "output.go:10:35: ReadOnlyTransaction.Close() must be deferred\n"},
+		// singlechecker
+		{[]string{"-format", "text", "output"}, 3, "output.go:10:35: ReadOnlyTransaction.Close() must be deferred\n"},
+		{[]string{"-format=text", "-quiet=false", "clean"}, 0, ""},
+	} {
+		t.Run(strings.Join(test.args, " "), func(t *testing.T) {
+			_, stderr, code := runCommand(t, ".", test.args...)
+			if code != test.code {
+				t.Errorf("got exit code %d, want %d\n%s", code, test.code, stderr)
+			}
+			if !strings.Contains(stderr, test.stderr) || test.stderr == "" && stderr != "" {
+				t.Errorf("got standard error %q, want %q", stderr, test.stderr)
+			}
+		})
+	}
+}
+
+func TestMaxIssues(t *testing.T) {
+	// Issues are kept in the order of their positions, whatever order the
+	// packages are analyzed in
+	stdout, stderr, code := runCommand(t, ".", "-quiet", "-max-issues", "3", "report", "output")
+	if code != 3 {
+		t.Errorf("got exit code %d, want 3\n%s", code, stderr)
+	}
+	want := "testdata/src/output/output.go:10: ReadOnlyTransaction.Close() must be deferred\n" +
+		"testdata/src/output/output.go:19: RowIterator.Stop() must be deferred\n" +
+		"testdata/src/report/report.go:10: ReadOnlyTransaction.Close() must be deferred\n"
+	if stdout != want {
+		t.Errorf("got issues\n%s\nwant\n%s", stdout, want)
+	}
+	if strings.Count(stderr, "more than 3 issues, the rest are not reported (-max-issues)\n") != 1 {
+		t.Errorf("got standard error %q, want one note of the dropped issues", stderr)
+	}
+
+	// JSON lists them as suppressed
+	stdout, stderr, _ = runCommand(t, ".", "-format", "json", "-max-issues", "1", "output")
+	if strings.Count(stdout, `"kind": "max-issues"`) != 1 || stderr != "" {
+		t.Errorf("got output\n%s%s\nwant one issue suppressed by -max-issues", stdout, stderr)
+	}
+}
+
+func TestFormatTextFix(t *testing.T) {
+	stdout, stderr, code := runCommand(t, ".", "-format", "text", "-fix", "-diff", "output")
+	if code != 0 {
+		t.Errorf("got exit code %d, want 0\n%s", code, stderr)
+	}
+	if !strings.Contains(stdout, "+\tdefer txn.Close()\n") {
+		t.Errorf("-fix -diff does not defer txn.Close():\n%s", stdout)
+	}
+}
+
+func TestDriverArgs(t *testing.T) {
+	for _, test := range []struct {
+		args   []string
+		rest   []string
+		driver bool
+	}{
+		{[]string{"./..."}, []string{"./..."}, false},
+		{[]string{"-quiet", "./..."}, nil, true},
+		{[]string{"--quiet=true", "./..."}, nil, true},
+		{[]string{"-quiet=false", "-fix", "./..."}, []string{"-fix", "./..."}, false},
+		{[]string{"-format", "json", "./..."}, nil, true},
+		{[]string{"-format=sarif", "./..."}, nil, true},
+		{[]string{"-format", "text", "-fix", "./..."}, []string{"-fix", "./..."}, false},
+		{[]string{"-format=text", "-c", "2", "./..."}, []string{"-c", "2", "./..."}, false},
+		{[]string{"-max-issues", "10", "./..."}, nil, true},
+		{[]string{"-max-issues=0", "-fix", "./..."}, []string{"-fix", "./..."}, false},
+		{[]string{"-whole-program", "./..."}, nil, true},
+		{[]string{"-whole-program=false", "./..."}, []string{"-whole-program=false", "./..."}, false},
+		{[]string{"-exclude", "-quiet", "./..."}, []string{"-exclude", "-quiet", "./..."}, false},
+		{[]string{"./...", "-quiet"}, []string{"./...", "-quiet"}, false},
+		{[]string{"--", "-quiet"}, []string{"--", "-quiet"}, false},
+	} {
+		rest, driver := driverArgs(analyzer.Analyzer, test.args)
+		if driver != test.driver || !driver && !slices.Equal(rest, test.rest) {
+			t.Errorf("driverArgs(%q) = %q, %t, want %q, %t", test.args, rest, driver, test.rest, test.driver)
+		}
+	}
+}
+
+func TestSARIF(t *testing.T) {
+	// Files below the working directory are relative to %SRCROOT%
+	dir := filepath.Join(testdataDir(t), "src")
+	stdout, stderr, code := runCommand(t, dir, "-format", "sarif", "-severity", "not-deferred=warning", "report")
+	if code != 0 {
+		t.Errorf("got exit code %d, want 0\n%s", code, stderr)
+	}
+	if stderr != "" {
+		t.Errorf("got standard error %q, want none", stderr)
+	}
+	checkGolden(t, "sarif.golden", stdout)
+}
+
+func TestJSON(t *testing.T) {
+	// A finding reported, one silenced by a nolint comment and one in a file
+	// matching -exclude
+	stdout, stderr, code := runCommand(t, ".", "-format", "json", "-exclude", "excluded.go", "suppressed")
+	if code != 0 {
+		t.Errorf("got exit code %d, want 0\n%s", code, stderr)
+	}
+	if stderr != "" {
+		t.Errorf("got standard error %q, want none", stderr)
+	}
+	checkGolden(t, "json.golden", stdout)
+}
+
+func TestVetJSON(t *testing.T) {
+	// broken does not type check, so its analysis fails
+	stdout, stderr, code := runCommand(t, ".", "-format", "vet-json", "report", "broken", "clean")
+	if code != 1 {
+		t.Errorf("got exit code %d, want 1", code)
+	}
+	if stdout != "" {
+		t.Errorf("got standard output %q, want none", stdout)
+	}
+	// Like go vet -json, the stream goes to the standard error, after the
+	// errors of the packages and the warnings of the analysis
+	checkGolden(t, "vet.golden", stderr)
+}
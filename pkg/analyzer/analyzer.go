@@ -1,8 +1,13 @@
 package analyzer
 
 import (
+	"fmt"
+	"sync"
+	"text/template"
+
 	"golang.org/x/tools/go/analysis"
 	"golang.org/x/tools/go/analysis/passes/buildssa"
+	"golang.org/x/tools/go/ssa"
 )
 
 const Doc = `check for unclosed Spanner transactions and statements
@@ -12,30 +17,181 @@ Spanner resources should be closed to prevent memory leaks and connection issues
 
 // Constants
 const (
-	methodNameClose  = "Close"
-	methodNameStop   = "Stop"
-	methodNameSingle = "Single"
+	methodNameClose   = "Close"
+	methodNameStop    = "Stop"
+	methodNameSingle  = "Single"
+	methodNameDo      = "Do"
+	funcNameSelectAll = "SelectAll"
 
 	typeNameReadOnlyTransaction      = "ReadOnlyTransaction"
 	typeNameBatchReadOnlyTransaction = "BatchReadOnlyTransaction"
+	typeNameReadWriteTransaction     = "ReadWriteTransaction"
 	typeNameRowIterator              = "RowIterator"
+	typeNameClient                   = "Client"
+	typeNameSessionPoolConfig        = "SessionPoolConfig"
 
-	pathGoogleSpanner = "cloud.google.com/go/spanner"
+	pathGoogleSpanner      = "cloud.google.com/go/spanner"
+	pathGoogleSpannerAPIv1 = "cloud.google.com/go/spanner/apiv1"
+	pathGoogleAPIIterator  = "google.golang.org/api/iterator"
 
 	nolintSpanner = "nolint:spannerclosecheck"
 	nolintAll     = "nolint:all"
 	nolintPrefix  = "nolint"
 )
 
-// Analyzer is the main analyzer for spannerclosecheck
-// TODO: Flag for Lenient Mode (skip some checks or skip some files)
-var Analyzer = &analysis.Analyzer{
-	Name:     "spannerclosecheck",
-	Doc:      Doc,
-	Run:      run,
-	Requires: []*analysis.Analyzer{buildssa.Analyzer},
+// defaultOptions configure Analyzer and the group analyzers, so that their
+// flags set the same options
+var defaultOptions = &Options{Config: DefaultConfigFile}
+
+// Analyzer is the main analyzer for spannerclosecheck, running every group
+// of checks
+var Analyzer = NewAnalyzer(defaultOptions)
+
+// Analyzers running a single group of checks, for drivers enabling them
+// independently. Running one of them along with Analyzer reports the
+// findings of its group twice.
+var (
+	Close         = NewGroupAnalyzer(defaultOptions, GroupClose)
+	UseAfterClose = NewGroupAnalyzer(defaultOptions, GroupUseAfterClose)
+	Ownership     = NewGroupAnalyzer(defaultOptions, GroupOwnership)
+	Style         = NewGroupAnalyzer(defaultOptions, GroupStyle)
+)
+
+// Group is a group of checks that can be run on its own, see
+// NewGroupAnalyzer. Opt-in checks of a group still need their option.
+type Group string
+
+const (
+	// GroupClose reports resources that are not closed, or not closed with
+	// defer: leaks, discarded and returned resources, deferred closes that
+	// never run, and client construction
+	GroupClose Group = "close"
+	// GroupUseAfterClose reports resources used or closed again after they
+	// are closed, including closes racing with the goroutines using them
+	GroupUseAfterClose Group = "useafterclose"
+	// GroupOwnership reports closes by code that does not own the resource:
+	// borrowed clients, ReadWriteTransactions outliving their callback and
+	// closes in another goroutine
+	GroupOwnership Group = "ownership"
+	// GroupStyle reports redundant or suspicious code that does not leak:
+	// redundant and duplicated closes, Client.Single() suggestions, session
+	// pool configuration and discarded close errors
+	GroupStyle Group = "style"
+)
+
+// allGroups are the groups run by NewAnalyzer
+var allGroups = []Group{GroupClose, GroupUseAfterClose, GroupOwnership, GroupStyle}
+
+// NewAnalyzer returns an analyzer running every group of checks and the
+// checkers added with Register, configured by opts. Options are also exposed
+// as flags on the returned analyzer.
+func NewAnalyzer(opts *Options) *analysis.Analyzer {
+	a := newAnalyzer("spannerclosecheck", Doc, opts, allGroups, true)
+	bindRegisteredFlags(&a.Flags)
+	return a
 }
 
-func run(pass *analysis.Pass) (interface{}, error) {
-	return deferOnlyAnalyzer(pass)
+// NewGroupAnalyzer returns an analyzer running only the checks of group,
+// configured by opts, named after the group, as in spannerclosecheck_close.
+// Options are also exposed as flags on the returned analyzer.
+func NewGroupAnalyzer(opts *Options, group Group) *analysis.Analyzer {
+	name := "spannerclosecheck_" + string(group)
+	return newAnalyzer(name, fmt.Sprintf("%s\n\nRuns the %s checks of spannerclosecheck only.", Doc, group), opts, []Group{group}, false)
+}
+
+func newAnalyzer(name, doc string, opts *Options, groups []Group, registered bool) *analysis.Analyzer {
+	returns := sharedReturnsAnalyzer(opts)
+	a := &analysis.Analyzer{
+		Name:       name,
+		Doc:        doc,
+		Requires:   []*analysis.Analyzer{buildssa.Analyzer, directiveAnalyzer, closerAnalyzer, returns},
+		ResultType: resultType,
+	}
+	b := budgetOf(opts)
+	a.Run = func(pass *analysis.Pass) (interface{}, error) {
+		run, err := compileOptions(opts)
+		if err != nil {
+			return nil, err
+		}
+		if run.excludes.excludesPackage(pass) {
+			return &Result{Funcs: make(map[*ssa.Function][]*Acquisition)}, nil
+		}
+		release := b.acquire(opts)
+		defer release()
+		defer run.generated.register(pass)()
+		report := reportSeverities(pass, pass.Report, opts, run)
+		report = reportIncluded(pass, reportConfident(pass, report, opts.MinConfidence.orDefault(ConfidenceLow)), run.excludes)
+		pass.Report = reportNolint(pass, report)
+		defer reportSorted(pass)()
+		return deferOnlyAnalyzer(pass, opts, returns, groups, registered)
+	}
+	opts.bindFlags(&a.Flags)
+	bindConfig(&a.Flags, opts)
+	return a
+}
+
+// runOptions are the compiled options of a run of an analyzer
+type runOptions struct {
+	excludes     *exclusions
+	generated    *generatedFiles
+	tmpl         *template.Template
+	translations []translation
+}
+
+// format wraps report to translate the messages of the diagnostics, then to
+// format them with the message template
+func (run *runOptions) format(report func(analysis.Diagnostic)) func(analysis.Diagnostic) {
+	return reportTranslated(reportTemplated(report, run.tmpl), run.translations)
+}
+
+// compileOptions applies the configuration file to opts, once flags have
+// been parsed, and compiles them. The analyzers sharing opts call it first
+// thing in their run, so that they see the same options.
+func compileOptions(opts *Options) (*runOptions, error) {
+	if err := applyConfig(opts); err != nil {
+		return nil, err
+	}
+	if err := checkMemoryLimit(opts); err != nil {
+		return nil, err
+	}
+	if err := checkWholeProgram(opts); err != nil {
+		return nil, err
+	}
+	registerSpannerPaths(opts)
+	run := &runOptions{}
+	var err error
+	if run.excludes, err = compileExclusions(opts); err != nil {
+		return nil, err
+	}
+	if run.generated, err = compileGenerated(opts); err != nil {
+		return nil, err
+	}
+	if run.tmpl, err = compileMessageTemplate(opts); err != nil {
+		return nil, err
+	}
+	if run.translations, err = messageCatalog(opts); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// returnsAnalyzers are the returns analyzers of the options analyzers were
+// created with. Drivers reject two analyzers registering the same fact type,
+// so analyzers sharing options share their returns analyzer.
+var returnsAnalyzers = struct {
+	sync.Mutex
+	m map[*Options]*analysis.Analyzer
+}{m: make(map[*Options]*analysis.Analyzer)}
+
+// sharedReturnsAnalyzer returns the returns analyzer of opts, creating it on
+// first use
+func sharedReturnsAnalyzer(opts *Options) *analysis.Analyzer {
+	returnsAnalyzers.Lock()
+	defer returnsAnalyzers.Unlock()
+	if a, ok := returnsAnalyzers.m[opts]; ok {
+		return a
+	}
+	a := newReturnsAnalyzer(opts)
+	returnsAnalyzers.m[opts] = a
+	return a
 }
@@ -1,13 +1,1477 @@
 package analyzer_test
 
 import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/token"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/ZZTmercari/spannerclosecheck/pkg/analyzer"
+	"golang.org/x/tools/go/analysis"
 	"golang.org/x/tools/go/analysis/analysistest"
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/go/ssa"
 )
 
 func Test(t *testing.T) {
 	testdata := analysistest.TestData()
-	analysistest.Run(t, testdata, analyzer.Analyzer, "a")
+	analysistest.Run(t, testdata, analyzer.Analyzer, "a", "gapic", "versions", "vendored", "adapter/...", "testifysuite")
+}
+
+func TestGroupAnalyzers(t *testing.T) {
+	testdata := analysistest.TestData()
+	for pkg, a := range map[string]*analysis.Analyzer{
+		"groups/close":         analyzer.Close,
+		"groups/useafterclose": analyzer.UseAfterClose,
+		"groups/ownership":     analyzer.Ownership,
+		"groups/style":         analyzer.Style,
+	} {
+		analysistest.Run(t, testdata, a, pkg)
+	}
+
+	// Drivers such as multichecker validate the analyzers they run together
+	if err := analysis.Validate([]*analysis.Analyzer{analyzer.Close, analyzer.UseAfterClose, analyzer.Ownership, analyzer.Style}); err != nil {
+		t.Fatal(err)
+	}
+	opts := &analyzer.Options{}
+	style, closeGroup := analyzer.NewGroupAnalyzer(opts, analyzer.GroupStyle), analyzer.NewGroupAnalyzer(opts, analyzer.GroupClose)
+	if err := analysis.Validate([]*analysis.Analyzer{style, closeGroup}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := style.Name, "spannerclosecheck_style"; got != want {
+		t.Errorf("got name %q, want %q", got, want)
+	}
+}
+
+func TestDiscardCategory(t *testing.T) {
+	testdata := analysistest.TestData()
+	for _, result := range analysistest.Run(t, testdata, analyzer.Analyzer, "a") {
+		for _, d := range result.Diagnostics {
+			discarded := strings.Contains(d.Message, "acquired and discarded") || strings.Contains(d.Message, "acquired and dropped")
+			if got := d.Category == "discarded"; got != discarded {
+				t.Errorf("%s: got category %q", d.Message, d.Category)
+			}
+		}
+	}
+}
+
+func TestCategories(t *testing.T) {
+	testdata := analysistest.TestData()
+	seen := make(map[string]bool)
+	for _, result := range analysistest.Run(t, testdata, analyzer.Analyzer, "a") {
+		for _, d := range result.Diagnostics {
+			if d.Category == "" {
+				t.Errorf("%s: no category", d.Message)
+			}
+			seen[d.Category] = true
+		}
+	}
+	for _, category := range []string{"unclosed", "not-deferred", "discarded", "double-close", "use-after-close", "retained-transaction"} {
+		if !seen[category] {
+			t.Errorf("no diagnostic of category %q", category)
+		}
+	}
+
+	described := make(map[string]bool)
+	for _, c := range analyzer.Categories() {
+		if c.Description == "" {
+			t.Errorf("category %q has no description", c.Name)
+		}
+		if want := analyzer.ConfidenceOf(analysis.Diagnostic{Category: c.Name}); c.Confidence != want {
+			t.Errorf("category %q: got confidence %s, want %s", c.Name, c.Confidence, want)
+		}
+		described[c.Name] = true
+	}
+	for category := range seen {
+		if !described[category] {
+			t.Errorf("category %q is missing from Categories", category)
+		}
+	}
+}
+
+func TestSuggestSingle(t *testing.T) {
+	testdata := analysistest.TestData()
+	a := analyzer.NewAnalyzer(&analyzer.Options{SuggestSingle: true})
+	analysistest.RunWithSuggestedFixes(t, testdata, a, "single")
+}
+
+func TestSuggestSingleLenient(t *testing.T) {
+	testdata := analysistest.TestData()
+	a := analyzer.NewAnalyzer(&analyzer.Options{SuggestSingle: true, Lenient: true})
+	analysistest.RunWithSuggestedFixes(t, testdata, a, "single/lenient")
+}
+
+func TestCustomResources(t *testing.T) {
+	testdata := analysistest.TestData()
+	a := analyzer.NewAnalyzer(&analyzer.Options{})
+	for _, spec := range []string{
+		"example.com/ourdb.Txn:Release:acquire=Begin:exempt=OneShotTxn",
+		"example.com/ourdb.Iter:Close",
+	} {
+		if err := a.Flags.Set("resource", spec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	analysistest.Run(t, testdata, a, "custom")
+}
+
+func TestCloseMethods(t *testing.T) {
+	testdata := analysistest.TestData()
+	a := analyzer.NewAnalyzer(&analyzer.Options{})
+	if err := a.Flags.Set("resource", "closemethods.Conn:CloseWithContext:close=Release:acquire=Dial"); err != nil {
+		t.Fatal(err)
+	}
+	analysistest.Run(t, testdata, a, "closemethods")
+}
+
+func TestCustomResourcesInvalid(t *testing.T) {
+	for _, spec := range []string{
+		"example.com/ourdb.Txn",
+		"example.com/ourdb:Close",
+		"example.com/ourdb.Txn:",
+		"example.com/ourdb.Txn:Close:unknown=X",
+	} {
+		a := analyzer.NewAnalyzer(&analyzer.Options{})
+		if err := a.Flags.Set("resource", spec); err == nil {
+			t.Errorf("resource %q: expected error", spec)
+		}
+	}
+}
+
+// TestFactsAcrossUnits checks that the facts of the analyzers pass between
+// separate compilation units: go vet runs the command on each package with
+// the facts unitchecker serialized for its dependencies, which must report
+// what the analysis of the packages together reports. The findings of the
+// packages depend on the facts of the packages they import: functions
+// returning resources through an interface, functions closing their
+// parameters, and fields owning their resources.
+func TestFactsAcrossUnits(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds the command and runs go vet")
+	}
+	gocmd, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+	vettool := filepath.Join(t.TempDir(), "spannerclosecheck")
+	if out, err := exec.Command(gocmd, "build", "-o", vettool, "github.com/ZZTmercari/spannerclosecheck").CombinedOutput(); err != nil {
+		t.Fatalf("go build: %v\n%s", err, out)
+	}
+
+	testdata := analysistest.TestData()
+	pkgs := []string{"returns", "closer", "ownership"}
+	want := make(map[string]bool)
+	for _, result := range analysistest.Run(t, testdata, analyzer.Analyzer, pkgs...) {
+		for _, d := range result.Diagnostics {
+			want[fmt.Sprintf("%s: %s", result.Pass.Fset.Position(d.Pos), d.Message)] = true
+		}
+	}
+
+	cmd := exec.Command(gocmd, append([]string{"vet", "-vettool=" + vettool, "-json"}, pkgs...)...)
+	cmd.Env = append(os.Environ(), "GO111MODULE=off", "GOPATH="+testdata, "GOFLAGS=")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("go vet: %v\n%s", err, out)
+	}
+	got := make(map[string]bool)
+	for _, tree := range decodeVetJSON(t, out) {
+		for _, analyzers := range tree {
+			for _, d := range analyzers["spannerclosecheck"] {
+				got[d.Posn+": "+d.Message] = true
+			}
+		}
+	}
+
+	// returnsResourceFact: store.Query returns its iterator as store.Rows
+	sentinel := filepath.Join(testdata, "src", "returns", "returns_test.go") + ":24:21: RowIterator.Stop() must be deferred"
+	if !got[sentinel] {
+		t.Errorf("go vet did not report %s", sentinel)
+	}
+	for _, d := range slices.Sorted(maps.Keys(want)) {
+		if !got[d] {
+			t.Errorf("go vet did not report %s", d)
+		}
+	}
+	for _, d := range slices.Sorted(maps.Keys(got)) {
+		if !want[d] {
+			t.Errorf("go vet reported %s", d)
+		}
+	}
+}
+
+// vetDiagnostic is a diagnostic of go vet -json
+type vetDiagnostic struct {
+	Posn    string `json:"posn"`
+	Message string `json:"message"`
+}
+
+// decodeVetJSON returns the results of go vet -json output, by package and
+// analyzer, skipping its "#" lines
+func decodeVetJSON(t *testing.T, out []byte) []map[string]map[string][]vetDiagnostic {
+	t.Helper()
+	var stream bytes.Buffer
+	for _, line := range strings.SplitAfter(string(out), "\n") {
+		if !strings.HasPrefix(line, "#") {
+			stream.WriteString(line)
+		}
+	}
+	var trees []map[string]map[string][]vetDiagnostic
+	for dec := json.NewDecoder(&stream); dec.More(); {
+		var tree map[string]map[string][]vetDiagnostic
+		if err := dec.Decode(&tree); err != nil {
+			t.Fatalf("go vet -json: %v\n%s", err, out)
+		}
+		trees = append(trees, tree)
+	}
+	return trees
+}
+
+func TestCheckConcurrency(t *testing.T) {
+	testdata := analysistest.TestData()
+	concurrency.start()
+	defer concurrency.stop()
+
+	a := analyzer.NewAnalyzer(&analyzer.Options{CheckConcurrency: 2})
+	analysistest.Run(t, testdata, a, "a", "gapic", "returns/...", "closer/...", "ownership/...", "deferloop", "loopvar", "sorted")
+	if got := concurrency.stop(); got < 1 || got > 2 {
+		t.Errorf("checks ran on %d packages at once, want at most 2", got)
+	}
+}
+
+func TestSortedDiagnostics(t *testing.T) {
+	testdata := analysistest.TestData()
+	a := analyzer.NewAnalyzer(&analyzer.Options{})
+	for _, result := range analysistest.Run(t, testdata, a, "sorted") {
+		var prev token.Position
+		for _, d := range result.Diagnostics {
+			posn := result.Pass.Fset.Position(d.Pos)
+			if posn.Filename < prev.Filename || posn.Filename == prev.Filename && (posn.Line < prev.Line || posn.Line == prev.Line && posn.Column < prev.Column) {
+				t.Errorf("%s: reported after %s", posn, prev)
+			}
+			prev = posn
+		}
+	}
+}
+
+// severityDiagnostic is a diagnostic with its severity
+type severityDiagnostic struct {
+	analysis.Diagnostic
+	severity analyzer.Severity
+}
+
+// diagnosticSeverities runs a on pkgs and returns the diagnostics it
+// reports, with their severity as passed to SeverityOutput or else
+// SeverityError
+func diagnosticSeverities(t *testing.T, a *analysis.Analyzer, pkgs ...string) []severityDiagnostic {
+	t.Helper()
+	type key struct {
+		pos      token.Pos
+		category string
+	}
+	seen := make(map[key]analyzer.Severity)
+	analyzer.SeverityOutput = func(pass *analysis.Pass, d analysis.Diagnostic, s analyzer.Severity) {
+		seen[key{d.Pos, d.Category}] = s
+	}
+	defer func() { analyzer.SeverityOutput = nil }()
+	var diags []severityDiagnostic
+	for _, result := range analysistest.Run(t, analysistest.TestData(), a, pkgs...) {
+		for _, d := range result.Diagnostics {
+			severity, ok := seen[key{d.Pos, d.Category}]
+			if !ok {
+				severity = analyzer.SeverityError
+			}
+			diags = append(diags, severityDiagnostic{d, severity})
+		}
+	}
+	return diags
+}
+
+func TestSeverityOutput(t *testing.T) {
+	a := analyzer.NewAnalyzer(&analyzer.Options{Severities: map[string]analyzer.Severity{
+		"not-deferred": analyzer.SeverityWarning,
+		"RowIterator":  analyzer.SeverityWarning,
+		"discarded":    analyzer.SeverityError,
+	}})
+	got := make(map[string]analyzer.Severity)
+	for _, d := range diagnosticSeverities(t, a, "severity") {
+		if strings.HasPrefix(d.Message, "warning") {
+			t.Errorf("%s: the message carries the severity", d.Message)
+		}
+		got[d.Category] = d.severity
+	}
+	want := map[string]analyzer.Severity{
+		"not-deferred": analyzer.SeverityWarning,
+		"unclosed":     analyzer.SeverityWarning,
+		"discarded":    analyzer.SeverityError,
+	}
+	if !maps.Equal(got, want) {
+		t.Errorf("got severities %v, want %v", got, want)
+	}
+}
+
+func TestMemoryLimit(t *testing.T) {
+	testdata := analysistest.TestData()
+	before := debug.SetMemoryLimit(-1)
+
+	// Without SetMemoryLimit, the limit of the host process is left alone
+	// and the option rejected
+	a := analyzer.NewAnalyzer(&analyzer.Options{MemoryLimit: 1 << 40})
+	for _, result := range analysistest.Run(discardErrors{}, testdata, a, "sorted") {
+		if result.Err == nil || !strings.Contains(result.Err.Error(), "memory-limit") {
+			t.Errorf("got error %v, want a memory-limit error", result.Err)
+		}
+	}
+	if got := debug.SetMemoryLimit(-1); got != before {
+		t.Errorf("memory limit changed from %d to %d", before, got)
+	}
+
+	var limits []int64
+	analyzer.SetMemoryLimit = func(limit int64) int64 {
+		limits = append(limits, limit)
+		return 0
+	}
+	defer func() { analyzer.SetMemoryLimit = nil }()
+	a = analyzer.NewAnalyzer(&analyzer.Options{MemoryLimit: 1 << 40})
+	analysistest.Run(t, testdata, a, "sorted")
+	if !slices.Equal(limits, []int64{1 << 40}) {
+		t.Errorf("got limits %v, want the limit of the options once", limits)
+	}
+}
+
+func TestMemoryLimitFlag(t *testing.T) {
+	for value, want := range map[string]string{
+		"0":       "0",
+		"1024":    "1KiB",
+		"512MiB":  "512MiB",
+		"6GiB":    "6GiB",
+		"1536MiB": "1536MiB",
+		"12345B":  "12345B",
+		"-1GiB":   "",
+		"8GB":     "",
+		"lots":    "",
+		"1.5GiB":  "",
+	} {
+		a := analyzer.NewAnalyzer(&analyzer.Options{})
+		err := a.Flags.Set("memory-limit", value)
+		if want == "" {
+			if err == nil {
+				t.Errorf("memory-limit %q: expected error", value)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("memory-limit %q: %v", value, err)
+			continue
+		}
+		if got := a.Flags.Lookup("memory-limit").Value.String(); got != want {
+			t.Errorf("memory-limit %q: got %q, want %q", value, got, want)
+		}
+	}
+}
+
+func TestSuggestedFixes(t *testing.T) {
+	testdata := analysistest.TestData()
+	analysistest.RunWithSuggestedFixes(t, testdata, analyzer.Analyzer, "fixes")
+}
+
+func TestSingleClose(t *testing.T) {
+	testdata := analysistest.TestData()
+	analysistest.RunWithSuggestedFixes(t, testdata, analyzer.Analyzer, "singleclose")
+
+	for _, test := range []struct {
+		opts analyzer.Options
+		want analyzer.Severity
+	}{
+		{analyzer.Options{}, analyzer.SeverityInfo},
+		{analyzer.Options{SingleClose: analyzer.SeverityWarning}, analyzer.SeverityWarning},
+		{analyzer.Options{Severities: map[string]analyzer.Severity{"single-close": analyzer.SeverityError}}, analyzer.SeverityError},
+	} {
+		a := analyzer.NewAnalyzer(&test.opts)
+		for _, d := range diagnosticSeverities(t, a, "singleclose") {
+			if d.Category != "single-close" {
+				t.Errorf("%s: got category %q, want single-close", d.Message, d.Category)
+			}
+			if d.severity != test.want {
+				t.Errorf("%s: got severity %s, want %s", d.Message, d.severity, test.want)
+			}
+		}
+	}
+}
+
+func TestDoubleCloseSeverity(t *testing.T) {
+	for _, test := range []struct {
+		opts analyzer.Options
+		want analyzer.Severity
+	}{
+		{analyzer.Options{}, analyzer.SeverityWarning},
+		{analyzer.Options{Severities: map[string]analyzer.Severity{"double-close": analyzer.SeverityError}}, analyzer.SeverityError},
+	} {
+		diags := diagnosticSeverities(t, analyzer.NewAnalyzer(&test.opts), "doubleclose")
+		if len(diags) != 2 {
+			t.Errorf("got %d diagnostics, want the 2 double closes", len(diags))
+		}
+		for _, d := range diags {
+			if d.severity != test.want {
+				t.Errorf("%s: got severity %s, want %s", d.Message, d.severity, test.want)
+			}
+		}
+	}
+}
+
+func TestSingleCloseWarningOutput(t *testing.T) {
+	testdata := analysistest.TestData()
+	var b bytes.Buffer
+	analyzer.WarningOutput = &b
+	defer func() { analyzer.WarningOutput = nil }()
+
+	// Informational reports are printed without failing the run
+	a := analyzer.NewAnalyzer(&analyzer.Options{})
+	for _, result := range analysistest.Run(discardErrors{}, testdata, a, "singleclose") {
+		for _, d := range result.Diagnostics {
+			t.Errorf("unexpected diagnostic: %s", d.Message)
+		}
+	}
+	if got := b.String(); strings.Count(got, ": info: ReadOnlyTransaction.Close() is redundant") != 2 {
+		t.Errorf("got output\n%s\nwant the two redundant closes", got)
+	}
+}
+
+func TestSingleCloseSeverityInvalid(t *testing.T) {
+	a := analyzer.NewAnalyzer(&analyzer.Options{})
+	if err := a.Flags.Set("single-close", "error"); err == nil {
+		t.Error("single-close \"error\": expected error")
+	}
+}
+
+func TestDeferInLoop(t *testing.T) {
+	testdata := analysistest.TestData()
+	analysistest.RunWithSuggestedFixes(t, testdata, analyzer.Analyzer, "deferloop")
+}
+
+func TestDeferBeforeUse(t *testing.T) {
+	testdata := analysistest.TestData()
+	a := analyzer.NewAnalyzer(&analyzer.Options{DeferBeforeUse: true})
+	analysistest.RunWithSuggestedFixes(t, testdata, a, "deferorder")
+}
+
+func TestDeferWithin(t *testing.T) {
+	testdata := analysistest.TestData()
+	a := analyzer.NewAnalyzer(&analyzer.Options{DeferWithin: 2})
+	analysistest.RunWithSuggestedFixes(t, testdata, a, "deferwithin")
+}
+
+func TestExemptFuncs(t *testing.T) {
+	testdata := analysistest.TestData()
+	a := analyzer.NewAnalyzer(&analyzer.Options{})
+	if err := a.Flags.Set("exempt-func", "main,TestMain,exemptfunc.startDaemon"); err != nil {
+		t.Fatal(err)
+	}
+	analysistest.Run(t, testdata, a, "exemptfunc")
+}
+
+func TestExemptConstructors(t *testing.T) {
+	testdata := analysistest.TestData()
+	a := analyzer.NewAnalyzer(&analyzer.Options{})
+	if err := a.Flags.Set("exempt-constructor", "OneShotTxn,exempt.newOneShot,ReadOnlyTransaction=CachedTxn,ReadOnlyTransaction=exempt.cachedQuery"); err != nil {
+		t.Fatal(err)
+	}
+	analysistest.Run(t, testdata, a, "exempt")
+}
+
+func TestLifecycleHooks(t *testing.T) {
+	testdata := analysistest.TestData()
+	a := analyzer.NewAnalyzer(&analyzer.Options{})
+	if err := a.Flags.Set("lifecycle-hook", "(*lifecycle.shutdown).Register,lifecycle.onExit"); err != nil {
+		t.Fatal(err)
+	}
+	analysistest.Run(t, testdata, a, "lifecycle")
+}
+
+func TestAcquireFuncs(t *testing.T) {
+	testdata := analysistest.TestData()
+	a := analyzer.NewAnalyzer(&analyzer.Options{})
+	for name, value := range map[string]string{
+		"resource":     "example.com/ourdb.Txn:Release:acquire=Begin",
+		"acquire-func": "(*acquire/repo.Repo).NewReadTxn,(*acquire/repo.Repo).NewTxn,newLocalTxn",
+	} {
+		if err := a.Flags.Set(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	analysistest.Run(t, testdata, a, "acquire/...")
+}
+
+func TestCloseHelpers(t *testing.T) {
+	testdata := analysistest.TestData()
+	a := analyzer.NewAnalyzer(&analyzer.Options{})
+	if err := a.Flags.Set("close-helper", "closehelper/cleanup.CloseAll,closehelper/cleanup.Run"); err != nil {
+		t.Fatal(err)
+	}
+	analysistest.Run(t, testdata, a, "closehelper")
+}
+
+func TestConsumingFuncs(t *testing.T) {
+	testdata := analysistest.TestData()
+	a := analyzer.NewAnalyzer(&analyzer.Options{})
+	if err := a.Flags.Set("consuming-func", "consume/scan.All,(*consume/scan.Scanner).Collect"); err != nil {
+		t.Fatal(err)
+	}
+	analysistest.Run(t, testdata, a, "consume")
+}
+
+func TestCollectors(t *testing.T) {
+	testdata := analysistest.TestData()
+	a := analyzer.NewAnalyzer(&analyzer.Options{})
+	for _, spec := range []string{"collector/closers.Closers:Add:CloseAll", "collector/closers.Group:Track:Release"} {
+		if err := a.Flags.Set("collector", spec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	analysistest.Run(t, testdata, a, "collector")
+}
+
+func TestClosers(t *testing.T) {
+	testdata := analysistest.TestData()
+	analysistest.Run(t, testdata, analyzer.Analyzer, "closer")
+}
+
+func TestReturnedResources(t *testing.T) {
+	testdata := analysistest.TestData()
+	analysistest.Run(t, testdata, analyzer.Analyzer, "returns/...")
+}
+
+func TestOwnershipDirectives(t *testing.T) {
+	testdata := analysistest.TestData()
+	analysistest.Run(t, testdata, analyzer.Analyzer, "ownership")
+}
+
+func TestLoopVarCaptures(t *testing.T) {
+	testdata := analysistest.TestData()
+	analysistest.Run(t, testdata, analyzer.Analyzer, "loopvar")
+}
+
+func TestBorrowedClients(t *testing.T) {
+	testdata := analysistest.TestData()
+	analysistest.Run(t, testdata, analyzer.Analyzer, "borrowed")
+}
+
+func TestClientPerRequest(t *testing.T) {
+	testdata := analysistest.TestData()
+	a := analyzer.NewAnalyzer(&analyzer.Options{ClientPerRequest: true})
+	analysistest.Run(t, testdata, a, "perrequest")
+}
+
+func TestPackageClients(t *testing.T) {
+	testdata := analysistest.TestData()
+	a := analyzer.NewAnalyzer(&analyzer.Options{PackageClients: true})
+	analysistest.Run(t, testdata, a, "pkgclient/...")
+}
+
+func TestClientInLoop(t *testing.T) {
+	testdata := analysistest.TestData()
+	a := analyzer.NewAnalyzer(&analyzer.Options{ClientInLoop: true})
+	analysistest.Run(t, testdata, a, "clientloop")
+}
+
+func TestStreamCancel(t *testing.T) {
+	testdata := analysistest.TestData()
+	a := analyzer.NewAnalyzer(&analyzer.Options{StreamCancel: true})
+	analysistest.Run(t, testdata, a, "streamcancel")
+}
+
+func TestOwnerGoroutine(t *testing.T) {
+	testdata := analysistest.TestData()
+	a := analyzer.NewAnalyzer(&analyzer.Options{OwnerGoroutine: true})
+	analysistest.Run(t, testdata, a, "owner")
+}
+
+func TestCloseErrors(t *testing.T) {
+	testdata := analysistest.TestData()
+	a := analyzer.NewAnalyzer(&analyzer.Options{CloseErrors: true})
+	for _, spec := range []string{"example.com/ourdb.Txn:Release:acquire=Begin", "example.com/ourdb.Iter:Close"} {
+		if err := a.Flags.Set("resource", spec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	analysistest.RunWithSuggestedFixes(t, testdata, a, "closeerr")
+}
+
+func TestSessionPool(t *testing.T) {
+	testdata := analysistest.TestData()
+	a := analyzer.NewAnalyzer(&analyzer.Options{SessionPool: true})
+	analysistest.Run(t, testdata, a, "sessionpool")
+}
+
+func TestDuplicateDefers(t *testing.T) {
+	testdata := analysistest.TestData()
+	a := analyzer.NewAnalyzer(&analyzer.Options{DuplicateDefers: true})
+	analysistest.RunWithSuggestedFixes(t, testdata, a, "duplicatedefer")
+}
+
+func TestWholeProgram(t *testing.T) {
+	testdata := analysistest.TestData()
+	// The dependencies are loaded from the GOPATH of the test data
+	t.Setenv("GOPATH", testdata)
+	t.Setenv("GO111MODULE", "off")
+	t.Setenv("GOFLAGS", "")
+	t.Setenv("GOPROXY", "off")
+	a := analyzer.NewAnalyzer(&analyzer.Options{WholeProgram: true})
+	if err := a.Flags.Set("resource", "example.com/ourdb.Txn:Release:acquire=Begin"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Drivers not loading packages themselves do not support it
+	for _, result := range analysistest.Run(discardErrors{}, testdata, a, "wholeprogram") {
+		if result.Err == nil || !strings.Contains(result.Err.Error(), "whole-program") {
+			t.Errorf("got error %v, want a whole-program error", result.Err)
+		}
+	}
+
+	// Like the spannerclosecheck command
+	pkgs, err := packages.Load(&packages.Config{Mode: packages.LoadAllSyntax, Tests: true}, "wholeprogram")
+	if err != nil {
+		t.Fatal(err)
+	}
+	analyzer.ProgramPackages = pkgs
+	defer func() { analyzer.ProgramPackages = nil }()
+	analysistest.Run(t, testdata, a, "wholeprogram")
+}
+
+// discardErrors is an analysistest.Testing ignoring the expectations of the
+// test data
+type discardErrors struct{}
+
+func (discardErrors) Errorf(string, ...interface{}) {}
+
+func TestSpannerPaths(t *testing.T) {
+	testdata := analysistest.TestData()
+	a := analyzer.NewAnalyzer(&analyzer.Options{})
+	if err := a.Flags.Set("spanner-path", "go.corp.example/third_party/spanner"); err != nil {
+		t.Fatal(err)
+	}
+	analysistest.Run(t, testdata, a, "mirror")
+}
+
+func TestWithoutWholeProgram(t *testing.T) {
+	testdata := analysistest.TestData()
+	a := analyzer.NewAnalyzer(&analyzer.Options{})
+	if err := a.Flags.Set("resource", "example.com/ourdb.Txn:Release:acquire=Begin"); err != nil {
+		t.Fatal(err)
+	}
+	var lines []int
+	for _, result := range analysistest.Run(discardErrors{}, testdata, a, "wholeprogram") {
+		for _, d := range result.Diagnostics {
+			lines = append(lines, result.Pass.Fset.Position(d.Pos).Line)
+		}
+	}
+	// Without the bodies of lib, Open is not an acquisition, and Drain does
+	// not defer closing its parameter
+	if want := []int{24, 29}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("got diagnostics at lines %v, want %v", lines, want)
+	}
+}
+
+func TestMinConfidence(t *testing.T) {
+	testdata := analysistest.TestData()
+	analysistest.Run(t, testdata, analyzer.Analyzer, "confidence")
+	a := analyzer.NewAnalyzer(&analyzer.Options{MinConfidence: analyzer.ConfidenceMedium})
+	analysistest.Run(t, testdata, a, "confidence/medium")
+}
+
+func TestMinConfidenceInvalid(t *testing.T) {
+	a := analyzer.NewAnalyzer(&analyzer.Options{})
+	if err := a.Flags.Set("min-confidence", "certain"); err == nil {
+		t.Error("got no error for an invalid confidence")
+	}
+}
+
+// writeConfig writes a configuration file to dir and returns its path
+func writeConfig(t *testing.T, dir, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, analyzer.DefaultConfigFile)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+const testConfig = `# Options of the config test package
+duplicate-defers: true
+resource:
+  - "example.com/ourdb.Txn:Release:acquire=Begin"  # in-house transactions
+`
+
+func TestConfig(t *testing.T) {
+	testdata := analysistest.TestData()
+	path := writeConfig(t, t.TempDir(), testConfig)
+	a := analyzer.NewAnalyzer(&analyzer.Options{Config: path})
+	analysistest.Run(t, testdata, a, "config")
+}
+
+func TestConfigFacts(t *testing.T) {
+	testdata := analysistest.TestData()
+	path := writeConfig(t, t.TempDir(), testConfig)
+	a := analyzer.NewAnalyzer(&analyzer.Options{Config: path})
+	analysistest.Run(t, testdata, a, "configfacts")
+}
+
+func TestConfigDiscovery(t *testing.T) {
+	testdata := analysistest.TestData()
+	dir := t.TempDir()
+	writeConfig(t, dir, testConfig)
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(sub)
+	a := analyzer.NewAnalyzer(&analyzer.Options{Config: analyzer.DefaultConfigFile})
+	analysistest.Run(t, testdata, a, "config")
+}
+
+func TestConfigFlagPrecedence(t *testing.T) {
+	testdata := analysistest.TestData()
+	path := writeConfig(t, t.TempDir(), testConfig)
+	a := analyzer.NewAnalyzer(&analyzer.Options{Config: path})
+	if err := a.Flags.Set("duplicate-defers", "false"); err != nil {
+		t.Fatal(err)
+	}
+	for _, result := range analysistest.Run(discardErrors{}, testdata, a, "config") {
+		for _, d := range result.Diagnostics {
+			if strings.Contains(d.Message, "duplicates the defer") {
+				t.Errorf("got %q, want -duplicate-defers=false to override the configuration file", d.Message)
+			}
+		}
+	}
+}
+
+// TestConfigInvalid checks that an invalid configuration fails the run; the
+// errors of configuration files are tested with applyConfig
+func TestConfigInvalid(t *testing.T) {
+	testdata := analysistest.TestData()
+	path := writeConfig(t, t.TempDir(), "strict: true\n")
+	a := analyzer.NewAnalyzer(&analyzer.Options{Config: path})
+	for _, result := range analysistest.Run(discardErrors{}, testdata, a, "config") {
+		if want := `:1: unknown option "strict"`; result.Err == nil || !strings.Contains(result.Err.Error(), want) {
+			t.Errorf("got error %v, want %q", result.Err, want)
+		}
+	}
+}
+
+func TestWriteConfig(t *testing.T) {
+	var b bytes.Buffer
+	if err := analyzer.WriteConfig(&b, &analyzer.NewAnalyzer(&analyzer.Options{}).Flags); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"\nsingle-close: info\n", "\nmin-confidence: low\n", "\ninclude-generated: false\n", "\n# resource: []\n", "\n# lang: \"\"\n"} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("configuration has no %q:\n%s", want, b.String())
+		}
+	}
+	// -skip-generated would override -include-generated
+	if strings.Contains(b.String(), "skip-generated:") {
+		t.Errorf("configuration sets both -include-generated and -skip-generated:\n%s", b.String())
+	}
+
+	// The defaults change nothing
+	testdata := analysistest.TestData()
+	path := writeConfig(t, t.TempDir(), b.String())
+	a := analyzer.NewAnalyzer(&analyzer.Options{Config: path})
+	for _, result := range analysistest.Run(discardErrors{}, testdata, a, "config") {
+		if result.Err != nil {
+			t.Fatal(result.Err)
+		}
+		if len(result.Diagnostics) != 0 {
+			t.Errorf("got %d diagnostics, want none without the options of the config test", len(result.Diagnostics))
+		}
+	}
+}
+
+func TestWriteConfigUncommented(t *testing.T) {
+	fs := &analyzer.NewAnalyzer(&analyzer.Options{}).Flags
+	var b bytes.Buffer
+	if err := analyzer.WriteConfig(&b, fs); err != nil {
+		t.Fatal(err)
+	}
+	// Every option left out of the configuration loads back once uncommented
+	setting := regexp.MustCompile(`^# ([a-z-]+): `)
+	lines := strings.Split(b.String(), "\n")
+	uncommented := 0
+	for i, line := range lines {
+		if m := setting.FindStringSubmatch(line); m != nil && fs.Lookup(m[1]) != nil {
+			lines[i] = strings.TrimPrefix(line, "# ")
+			uncommented++
+		}
+	}
+	if uncommented == 0 {
+		t.Fatalf("configuration has no commented option:\n%s", b.String())
+	}
+	path := writeConfig(t, t.TempDir(), strings.Join(lines, "\n"))
+	a := analyzer.NewAnalyzer(&analyzer.Options{Config: path})
+	for _, result := range analysistest.Run(discardErrors{}, analysistest.TestData(), a, "config") {
+		if result.Err != nil {
+			t.Fatalf("uncommented configuration: %v\n%s", result.Err, strings.Join(lines, "\n"))
+		}
+		if len(result.Diagnostics) != 0 {
+			t.Errorf("got %d diagnostics, want none without the options of the config test", len(result.Diagnostics))
+		}
+	}
+}
+
+func TestWriteConfigSchema(t *testing.T) {
+	fs := &analyzer.NewAnalyzer(&analyzer.Options{}).Flags
+	var b bytes.Buffer
+	if err := analyzer.WriteConfigSchema(&b, fs); err != nil {
+		t.Fatal(err)
+	}
+	var schema struct {
+		Type                 string
+		AdditionalProperties bool
+		Properties           map[string]struct {
+			Type  string
+			Enum  []string
+			AnyOf []map[string]any
+		}
+	}
+	if err := json.Unmarshal(b.Bytes(), &schema); err != nil {
+		t.Fatalf("invalid schema: %v\n%s", err, b.String())
+	}
+	if schema.Type != "object" || schema.AdditionalProperties {
+		t.Errorf("got schema of type %q with additional properties %v, want an object rejecting unknown keys", schema.Type, schema.AdditionalProperties)
+	}
+	for name, want := range map[string]string{
+		"lenient":      "boolean",
+		"skip-tests":   "boolean",
+		"defer-within": "integer",
+		"single-close": "string",
+		"lang":         "string",
+	} {
+		if got := schema.Properties[name].Type; got != want {
+			t.Errorf("%s: got type %q, want %q", name, got, want)
+		}
+	}
+	if got := schema.Properties["min-confidence"].Enum; !slices.Equal(got, []string{"low", "medium", "high"}) {
+		t.Errorf("min-confidence: got values %q, want low, medium and high", got)
+	}
+	if len(schema.Properties["resource"].AnyOf) != 2 {
+		t.Errorf("resource: got %v, want a value or a list", schema.Properties["resource"].AnyOf)
+	}
+	fs.VisitAll(func(f *flag.Flag) {
+		if _, ok := schema.Properties[f.Name]; !ok && f.Name != "config" {
+			t.Errorf("schema has no option %s", f.Name)
+		}
+	})
+}
+
+func TestEnv(t *testing.T) {
+	testdata := analysistest.TestData()
+	t.Setenv("SPANNERCLOSECHECK_DUPLICATE_DEFERS", "true")
+	t.Setenv("SPANNERCLOSECHECK_RESOURCE", "example.com/ourdb.Txn:Release:acquire=Begin")
+	a := analyzer.NewAnalyzer(&analyzer.Options{})
+	analysistest.Run(t, testdata, a, "config")
+}
+
+// TestEnvInvalid checks that an invalid variable fails the run; the errors
+// and the precedence of variables are tested with applyConfig
+func TestEnvInvalid(t *testing.T) {
+	testdata := analysistest.TestData()
+	t.Setenv("SPANNERCLOSECHECK_STRICT", "loud")
+	a := analyzer.NewAnalyzer(&analyzer.Options{})
+	for _, result := range analysistest.Run(discardErrors{}, testdata, a, "config") {
+		if want := `SPANNERCLOSECHECK_STRICT: unknown option "strict"`; result.Err == nil || !strings.Contains(result.Err.Error(), want) {
+			t.Errorf("got error %v, want %q", result.Err, want)
+		}
+	}
+}
+
+func TestExclude(t *testing.T) {
+	testdata := analysistest.TestData()
+	a := analyzer.NewAnalyzer(&analyzer.Options{})
+	if err := a.Flags.Set("exclude", "exclude/*_migration.go,re:experimental,third_party"); err != nil {
+		t.Fatal(err)
+	}
+	analysistest.Run(t, testdata, a, "exclude", "exclude/third_party/vendorlib")
+}
+
+func TestExcludeInvalid(t *testing.T) {
+	testdata := analysistest.TestData()
+	for want, opts := range map[string]*analyzer.Options{
+		`exclude "re:third_(party"`: {Exclude: []string{"re:third_(party"}},
+		`exclude-func "mock("`:      {ExcludeFuncs: []string{"mock("}},
+	} {
+		a := analyzer.NewAnalyzer(opts)
+		for _, result := range analysistest.Run(discardErrors{}, testdata, a, "exclude") {
+			if result.Err == nil || !strings.Contains(result.Err.Error(), want) {
+				t.Errorf("got error %v, want %q", result.Err, want)
+			}
+		}
+	}
+}
+
+func TestSuppressedOutput(t *testing.T) {
+	testdata := analysistest.TestData()
+	suppressed := make(map[string][]string)
+	analyzer.SuppressedOutput = func(pass *analysis.Pass, d analysis.Diagnostic, s analyzer.Suppression) {
+		posn := pass.Fset.Position(d.Pos)
+		suppressed[s.Kind] = append(suppressed[s.Kind], fmt.Sprintf("%s: %s", filepath.Base(posn.Filename), s.Justification))
+	}
+	defer func() { analyzer.SuppressedOutput = nil }()
+
+	a := analyzer.NewAnalyzer(&analyzer.Options{
+		Exclude:       []string{"exclude/*_migration.go"},
+		ExcludeFuncs:  []string{`.*\.mock.*`},
+		MinConfidence: analyzer.ConfidenceHigh,
+	})
+	analysistest.Run(discardErrors{}, testdata, a, "exclude", "excludefunc", "confidence", "singleclose")
+	for kind, want := range map[string]string{
+		analyzer.SuppressionNolint:        "singleclose_test.go: a nolint comment silences it",
+		analyzer.SuppressionExclude:       "v2_migration.go: the file or its package matches -exclude",
+		analyzer.SuppressionExcludeFunc:   "the function matches -exclude-func",
+		analyzer.SuppressionMinConfidence: "below -min-confidence high",
+	} {
+		if !slices.ContainsFunc(suppressed[kind], func(s string) bool { return strings.Contains(s, want) }) {
+			t.Errorf("got %s suppressions %q, want %q", kind, suppressed[kind], want)
+		}
+	}
+}
+
+func TestLineDirectives(t *testing.T) {
+	testdata := analysistest.TestData()
+	a := analyzer.NewAnalyzer(&analyzer.Options{})
+	for name, value := range map[string]string{
+		"exclude":    "excluded.go",
+		"skip-tests": "true",
+	} {
+		if err := a.Flags.Set(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var got []string
+	for _, result := range analysistest.Run(t, testdata, a, "linedirective") {
+		for _, d := range result.Diagnostics {
+			posn := result.Pass.Fset.Position(d.Pos)
+			got = append(got, fmt.Sprintf("%s:%d: %s", filepath.Base(posn.Filename), posn.Line, d.Message))
+		}
+	}
+	// Reports are positioned, and refer to lines, in the templates
+	want := []string{
+		"query.tmpl:32: deferred RowIterator.Stop() closes the resource again after Stop() at line 33",
+		"store.tmpl:11: ReadOnlyTransaction.Close() must be deferred",
+	}
+	// The package and its test variant report the files of the package
+	slices.Sort(got)
+	if got = slices.Compact(got); !slices.Equal(got, want) {
+		t.Errorf("got diagnostics\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestExcludeFunc(t *testing.T) {
+	testdata := analysistest.TestData()
+	a := analyzer.NewAnalyzer(&analyzer.Options{})
+	for _, pattern := range []string{`.*\.mock.*`, `.*Fixture.*`} {
+		if err := a.Flags.Set("exclude-func", pattern); err != nil {
+			t.Fatal(err)
+		}
+	}
+	analysistest.Run(t, testdata, a, "excludefunc")
+}
+
+func TestDisabledResources(t *testing.T) {
+	testdata := analysistest.TestData()
+	a := analyzer.NewAnalyzer(&analyzer.Options{})
+	for name, value := range map[string]string{
+		"resource":         "example.com/ourdb.Txn:Release:acquire=Begin",
+		"disable-resource": "RowIterator,example.com/ourdb.Txn",
+	} {
+		if err := a.Flags.Set(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	analysistest.Run(t, testdata, a, "disabled")
+}
+
+func TestSeverities(t *testing.T) {
+	testdata := analysistest.TestData()
+	a := analyzer.NewAnalyzer(&analyzer.Options{})
+	if err := a.Flags.Set("severity", "not-deferred=warning,RowIterator=warning,discarded=error"); err != nil {
+		t.Fatal(err)
+	}
+	analysistest.Run(t, testdata, a, "severity")
+
+	if err := a.Flags.Set("severity", "not-deferred=fatal"); err == nil {
+		t.Error("got no error for an invalid severity")
+	}
+}
+
+func TestMessageTemplate(t *testing.T) {
+	testdata := analysistest.TestData()
+	a := analyzer.NewAnalyzer(&analyzer.Options{})
+	for name, value := range map[string]string{
+		"message-template": "{{.Message}} (see RUNBOOK-12#{{.Category}})",
+		"severity":         "not-deferred=warning",
+	} {
+		if err := a.Flags.Set(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	analysistest.Run(t, testdata, a, "messagetemplate")
+
+	for _, tmpl := range []string{"{{.Message", "{{.Runbook}}"} {
+		a := analyzer.NewAnalyzer(&analyzer.Options{MessageTemplate: tmpl})
+		for _, result := range analysistest.Run(discardErrors{}, testdata, a, "messagetemplate") {
+			if result.Err == nil || !strings.Contains(result.Err.Error(), "message-template") {
+				t.Errorf("template %q: got error %v, want a message-template error", tmpl, result.Err)
+			}
+		}
+	}
+}
+
+func TestLang(t *testing.T) {
+	testdata := analysistest.TestData()
+	a := analyzer.NewAnalyzer(&analyzer.Options{})
+	if err := a.Flags.Set("lang", "ja"); err != nil {
+		t.Fatal(err)
+	}
+	var fixes []string
+	for _, result := range analysistest.Run(t, testdata, a, "lang") {
+		for _, d := range result.Diagnostics {
+			for _, fix := range d.SuggestedFixes {
+				fixes = append(fixes, fix.Message)
+			}
+		}
+	}
+	if want := "ReadOnlyTransaction.Close() を defer する"; !slices.Contains(fixes, want) {
+		t.Errorf("got suggested fixes %q, want %q", fixes, want)
+	}
+
+	a = analyzer.NewAnalyzer(&analyzer.Options{Lang: "xx"})
+	for _, result := range analysistest.Run(discardErrors{}, testdata, a, "lang") {
+		if result.Err == nil || !strings.Contains(result.Err.Error(), `lang "xx"`) {
+			t.Errorf("got error %v, want an unknown language error", result.Err)
+		}
+	}
+}
+
+func TestRegisterMessages(t *testing.T) {
+	analyzer.RegisterMessages("en-x-shout", map[string]string{
+		"%s.%s() must be deferred":  "DEFER %[1]s.%[2]s()!",
+		"%s acquired and discarded": "%[1]s DISCARDED",
+	})
+	testdata := analysistest.TestData()
+	a := analyzer.NewAnalyzer(&analyzer.Options{Lang: "en-x-shout"})
+	var messages []string
+	for _, result := range analysistest.Run(discardErrors{}, testdata, a, "lang") {
+		for _, d := range result.Diagnostics {
+			messages = append(messages, d.Message)
+		}
+	}
+	for _, want := range []string{
+		"DEFER ReadOnlyTransaction.Close()!",
+		"DEFER ReadOnlyTransaction.Close()! in the goroutine",
+		"RowIterator DISCARDED: the blank identifier drops the only reference, so Stop() can never be called",
+	} {
+		if !slices.Contains(messages, want) {
+			t.Errorf("got messages %q, want %q", messages, want)
+		}
+	}
+}
+
+func TestWarningOutput(t *testing.T) {
+	testdata := analysistest.TestData()
+	var b bytes.Buffer
+	analyzer.WarningOutput = &b
+	defer func() { analyzer.WarningOutput = nil }()
+
+	a := analyzer.NewAnalyzer(&analyzer.Options{Severities: map[string]analyzer.Severity{
+		"not-deferred": analyzer.SeverityWarning,
+		"RowIterator":  analyzer.SeverityWarning,
+		"discarded":    analyzer.SeverityError,
+	}})
+	var reported []string
+	for _, result := range analysistest.Run(discardErrors{}, testdata, a, "severity") {
+		for _, d := range result.Diagnostics {
+			reported = append(reported, d.Message)
+		}
+	}
+	if len(reported) != 1 || !strings.HasPrefix(reported[0], "RowIterator acquired and discarded") {
+		t.Errorf("got diagnostics %q, want the error only", reported)
+	}
+	if got := b.String(); strings.Count(got, ": warning: ") != 2 || !strings.Contains(got, "severity_test.go:13:") {
+		t.Errorf("got warnings\n%s\nwant the two warnings of severity_test.go", got)
+	}
+}
+
+func TestSeveritiesFormatted(t *testing.T) {
+	// Severities are set by the category and the resource type of the
+	// reports, not by their templated and translated message
+	newAnalyzer := func() *analysis.Analyzer {
+		a := analyzer.NewAnalyzer(&analyzer.Options{})
+		for name, value := range map[string]string{
+			"severity":         "not-deferred=warning,RowIterator=warning,discarded=error",
+			"message-template": "TEAM-123 {{.Category}}",
+			"lang":             "ja",
+		} {
+			if err := a.Flags.Set(name, value); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return a
+	}
+	testdata := analysistest.TestData()
+
+	var b bytes.Buffer
+	analyzer.WarningOutput = &b
+	var reported []string
+	for _, result := range analysistest.Run(discardErrors{}, testdata, newAnalyzer(), "severity") {
+		for _, d := range result.Diagnostics {
+			reported = append(reported, d.Message)
+		}
+	}
+	analyzer.WarningOutput = nil
+	if !slices.Equal(reported, []string{"TEAM-123 discarded"}) {
+		t.Errorf("got diagnostics %q, want the error only", reported)
+	}
+	for _, want := range []string{"severity_test.go:13:35: 警告: TEAM-123 not-deferred\n", "severity_test.go:18:31: 警告: TEAM-123 unclosed\n"} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("got warnings\n%s\nwant %q", &b, want)
+		}
+	}
+
+	severities := make(map[string]analyzer.Severity)
+	analyzer.SeverityOutput = func(pass *analysis.Pass, d analysis.Diagnostic, s analyzer.Severity) {
+		severities[d.Category] = s
+	}
+	defer func() { analyzer.SeverityOutput = nil }()
+	analysistest.Run(discardErrors{}, testdata, newAnalyzer(), "severity")
+	want := map[string]analyzer.Severity{"not-deferred": analyzer.SeverityWarning, "unclosed": analyzer.SeverityWarning}
+	if !maps.Equal(severities, want) {
+		t.Errorf("got severities %v, want %v", severities, want)
+	}
+}
+
+func TestSkipTests(t *testing.T) {
+	testdata := analysistest.TestData()
+	a := analyzer.NewAnalyzer(&analyzer.Options{})
+	if err := a.Flags.Set("skip-tests", "true"); err != nil {
+		t.Fatal(err)
+	}
+	analysistest.Run(t, testdata, a, "skiptests")
+}
+
+func TestTestsOnly(t *testing.T) {
+	testdata := analysistest.TestData()
+	a := analyzer.NewAnalyzer(&analyzer.Options{})
+	if err := a.Flags.Set("tests-only", "true"); err != nil {
+		t.Fatal(err)
+	}
+	analysistest.Run(t, testdata, a, "testsonly", "testsonly/prod")
+}
+
+func TestTestsOnlySkipTests(t *testing.T) {
+	testdata := analysistest.TestData()
+	a := analyzer.NewAnalyzer(&analyzer.Options{SkipTests: true, TestsOnly: true})
+	for _, result := range analysistest.Run(discardErrors{}, testdata, a, "testsonly") {
+		if result.Err == nil {
+			t.Error("got no error for -skip-tests with -tests-only")
+		}
+	}
+}
+
+func TestLenient(t *testing.T) {
+	testdata := analysistest.TestData()
+	a := analyzer.NewAnalyzer(&analyzer.Options{Lenient: true})
+	analysistest.Run(t, testdata, a, "lenient")
+}
+
+func TestFileConfig(t *testing.T) {
+	testdata := analysistest.TestData()
+	a := analyzer.NewAnalyzer(&analyzer.Options{})
+	analysistest.Run(t, testdata, a, "fileconfig")
+}
+
+func TestCheckGenerated(t *testing.T) {
+	testdata := analysistest.TestData()
+	for name, value := range map[string]string{"skip-generated": "false", "include-generated": "true"} {
+		t.Run(name, func(t *testing.T) {
+			a := analyzer.NewAnalyzer(&analyzer.Options{})
+			if err := a.Flags.Set(name, value); err != nil {
+				t.Fatal(err)
+			}
+			analysistest.Run(t, testdata, a, "generated")
+		})
+	}
+}
+
+func TestSkipGenerated(t *testing.T) {
+	testdata := analysistest.TestData()
+	a := analyzer.NewAnalyzer(&analyzer.Options{})
+	for _, result := range analysistest.Run(discardErrors{}, testdata, a, "generated") {
+		for _, d := range result.Diagnostics {
+			t.Errorf("%v: unexpected diagnostic in a generated file: %s", result.Pass.Fset.Position(d.Pos), d.Message)
+		}
+	}
+}
+
+func TestGeneratedComment(t *testing.T) {
+	testdata := analysistest.TestData()
+	a := analyzer.NewAnalyzer(&analyzer.Options{})
+	analysistest.Run(t, testdata, a, "gencode")
+}
+
+func TestGeneratedPatterns(t *testing.T) {
+	testdata := analysistest.TestData()
+	for _, tt := range []struct {
+		patterns []string
+		want     []string
+	}{
+		{[]string{"*.sql.go"}, []string{"dao_gen.go", "store.go"}},
+		{[]string{"re:\\.sql\\.go$", "store.go"}, []string{"dao_gen.go"}},
+		{[]string{}, []string{"dao_gen.go", "queries.sql.go", "store.go"}},
+	} {
+		a := analyzer.NewAnalyzer(&analyzer.Options{GeneratedPatterns: tt.patterns})
+		var got []string
+		for _, result := range analysistest.Run(discardErrors{}, testdata, a, "gencode") {
+			for _, d := range result.Diagnostics {
+				got = append(got, filepath.Base(result.Pass.Fset.Position(d.Pos).Filename))
+			}
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("GeneratedPatterns %q: got reports in %v, want %v", tt.patterns, got, tt.want)
+		}
+	}
+
+	a := analyzer.NewAnalyzer(&analyzer.Options{GeneratedPatterns: []string{"re:("}})
+	for _, result := range analysistest.Run(discardErrors{}, testdata, a, "gencode") {
+		if result.Err == nil {
+			t.Error("got no error for an invalid generated file pattern")
+		}
+	}
+}
+
+func TestGeneratedPatternFlag(t *testing.T) {
+	opts := &analyzer.Options{}
+	a := analyzer.NewAnalyzer(opts)
+	if got, want := a.Flags.Lookup("generated-pattern").DefValue, strings.Join(analyzer.DefaultGeneratedPatterns, ","); got != want {
+		t.Errorf("-generated-pattern defaults to %s, want %s", got, want)
+	}
+	if err := a.Flags.Set("generated-pattern", "*.sql.go"); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(opts.GeneratedPatterns, []string{"*.sql.go"}) {
+		t.Errorf("-generated-pattern=*.sql.go sets %q, want the defaults replaced", opts.GeneratedPatterns)
+	}
+	if err := a.Flags.Set("generated-pattern", "none"); err != nil {
+		t.Fatal(err)
+	}
+	if opts.GeneratedPatterns == nil || len(opts.GeneratedPatterns) != 0 {
+		t.Errorf("-generated-pattern=none sets %q, want no patterns", opts.GeneratedPatterns)
+	}
+}
+
+func TestFlags(t *testing.T) {
+	opts := &analyzer.Options{}
+	a := analyzer.NewAnalyzer(opts)
+	for _, name := range []string{
+		"suggest-single", "defer-before-use", "defer-within", "client-per-request", "client-in-loop", "package-clients",
+		"session-pool", "stream-cancel", "owner-goroutine", "close-errors", "duplicate-defers",
+		"whole-program", "single-close", "min-confidence", "severity", "include-generated", "skip-generated", "generated-pattern", "skip-tests", "tests-only", "lenient", "resource",
+		"disable-resource", "exempt-constructor", "exempt-func", "acquire-func", "lifecycle-hook", "close-helper", "consuming-func",
+		"collector", "exclude", "exclude-func", "config", "check-concurrency", "memory-limit",
+	} {
+		if a.Flags.Lookup(name) == nil {
+			t.Errorf("no -%s flag", name)
+		}
+	}
+
+	if got := a.Flags.Lookup("skip-generated").DefValue; got != "true" {
+		t.Errorf("-skip-generated defaults to %s, want true", got)
+	}
+	if err := a.Flags.Set("skip-generated", "false"); err != nil {
+		t.Fatal(err)
+	}
+	if !opts.CheckGenerated {
+		t.Error("-skip-generated=false does not set CheckGenerated")
+	}
+}
+
+// acquireChecker reports the resources acquired with the methods of its
+// -checker-methods flag, in the checker test package only
+type acquireChecker struct {
+	methods *string
+}
+
+func (c *acquireChecker) Name() string { return "acquire" }
+
+func (c *acquireChecker) Flags(fs *flag.FlagSet) {
+	fs.Var(stringFlag{c.methods}, "checker-methods", "comma-separated methods reported by the test checker")
+}
+
+func (c *acquireChecker) CheckFunc(pass *analysis.Pass, fn *ssa.Function, resources *analyzer.Resources) {
+	if pass.Pkg.Path() != "checker" || *c.methods == "" {
+		return
+	}
+	for _, block := range fn.Blocks {
+		for _, instr := range block.Instrs {
+			call, ok := instr.(*ssa.Call)
+			if !ok || call.Call.Method != nil {
+				continue
+			}
+			callee := call.Call.StaticCallee()
+			rt := resources.Lookup(call.Type())
+			if callee == nil || rt == nil || !strings.Contains(","+*c.methods+",", ","+callee.Name()+",") {
+				continue
+			}
+			pass.Reportf(call.Pos(), "%s acquired with %s", rt.Name, callee.Name())
+		}
+	}
+}
+
+// concurrencyChecker records how many packages registered checkers run on
+// at the same time, between start and stop. The functions of a package are
+// checked one after the other, so concurrent calls are of distinct packages.
+type concurrencyChecker struct {
+	sync.Mutex
+	measuring bool
+	running   int
+	max       int
+}
+
+func (c *concurrencyChecker) Name() string           { return "concurrency" }
+func (c *concurrencyChecker) Flags(fs *flag.FlagSet) {}
+
+func (c *concurrencyChecker) CheckFunc(*analysis.Pass, *ssa.Function, *analyzer.Resources) {
+	c.Lock()
+	if !c.measuring {
+		c.Unlock()
+		return
+	}
+	c.running++
+	c.max = max(c.max, c.running)
+	c.Unlock()
+
+	// Give other packages the time to be checked concurrently
+	time.Sleep(100 * time.Microsecond)
+	c.Lock()
+	c.running--
+	c.Unlock()
+}
+
+func (c *concurrencyChecker) start() {
+	c.Lock()
+	defer c.Unlock()
+	c.measuring, c.max = true, 0
+}
+
+// stop ends the measure and returns the most packages checked at once
+func (c *concurrencyChecker) stop() int {
+	c.Lock()
+	defer c.Unlock()
+	c.measuring = false
+	return c.max
+}
+
+// stringFlag is a flag.Value setting a string shared by every flag set it is
+// registered on
+type stringFlag struct {
+	s *string
+}
+
+func (f stringFlag) String() string {
+	if f.s == nil {
+		return ""
+	}
+	return *f.s
+}
+
+func (f stringFlag) Set(s string) error {
+	*f.s = s
+	return nil
+}
+
+var checkerMethods string
+
+// concurrency measures the packages checked at once for TestCheckConcurrency
+var concurrency = &concurrencyChecker{}
+
+func init() {
+	analyzer.Register(&acquireChecker{methods: &checkerMethods})
+	analyzer.Register(concurrency)
+}
+
+func TestRegisteredChecker(t *testing.T) {
+	testdata := analysistest.TestData()
+	a := analyzer.NewAnalyzer(&analyzer.Options{})
+	if err := a.Flags.Set("checker-methods", "Query"); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { checkerMethods = "" }()
+	analysistest.Run(t, testdata, a, "checker")
+}
+
+func TestRegisterDuplicate(t *testing.T) {
+	for _, name := range []string{"acquire", "unclosed"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Register(%q) did not panic", name)
+				}
+			}()
+			analyzer.Register(&namedChecker{name})
+		}()
+	}
+}
+
+// namedChecker is a checker reporting nothing
+type namedChecker struct {
+	name string
+}
+
+func (c *namedChecker) Name() string                                                 { return c.name }
+func (c *namedChecker) Flags(fs *flag.FlagSet)                                       {}
+func (c *namedChecker) CheckFunc(*analysis.Pass, *ssa.Function, *analyzer.Resources) {}
+
+// inventoryAnalyzer reports the resources of the result of the analyzer with
+// the lines of their close sites
+var inventoryAnalyzer = &analysis.Analyzer{
+	Name:     "inventory",
+	Doc:      "reports the resources tracked by spannerclosecheck",
+	Requires: []*analysis.Analyzer{analyzer.Analyzer},
+	Run: func(pass *analysis.Pass) (interface{}, error) {
+		res := pass.ResultOf[analyzer.Analyzer].(*analyzer.Result)
+		for _, acquired := range res.Funcs {
+			for _, acq := range acquired {
+				sites := []string{acq.Type.Name + " closed at"}
+				for _, c := range acq.Closes {
+					site := fmt.Sprint(pass.Fset.Position(c.Call.Pos()).Line)
+					if c.Deferred {
+						site = "deferred:" + site
+					}
+					sites = append(sites, site)
+				}
+				if acq.Returned {
+					sites = append(sites, "returned")
+				}
+				pass.Reportf(acq.Pos, "%s", strings.Join(sites, " "))
+			}
+		}
+		return nil, nil
+	},
+}
+
+func TestResult(t *testing.T) {
+	testdata := analysistest.TestData()
+	analysistest.Run(t, testdata, inventoryAnalyzer, "inventory")
 }
@@ -0,0 +1,90 @@
+package analyzer
+
+import (
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ssa"
+)
+
+// borrowedMessage reports a close of a client received as a parameter
+const borrowedMessage = "%s.%s() closes a client borrowed from the caller, leave closing it to its owner"
+
+// sharedClientTypes are the long-lived clients their owner shares with the
+// functions using them. A function receiving one as a parameter borrows it:
+// it need not close it, and must not, as other users may still need it.
+var sharedClientTypes = []ResourceType{
+	{Name: typeNameClient, CloseMethod: methodNameClose, PkgPath: pathGoogleSpanner},
+	{Name: typeNameClient, CloseMethod: methodNameClose, PkgPath: pathGoogleSpannerAPIv1},
+}
+
+// checkBorrowedCloses reports closes of clients fn borrows from its caller.
+// Parameters are borrowed, unless declared with directiveCloses; receivers
+// belong to the method, as in the Close method of a struct owning a client.
+func checkBorrowedCloses(pass *analysis.Pass, fn *ssa.Function, clientTypes map[*types.Named]*ResourceType) {
+	if len(clientTypes) == 0 || isGeneratedFile(pass, fn.Pos()) {
+		return
+	}
+
+	owned := make(map[int]bool)
+	if obj, ok := fn.Object().(*types.Func); ok {
+		closers := pass.ResultOf[closerAnalyzer].(map[*types.Func][]closedParam)
+		for _, closed := range closers[obj] {
+			if closed.Method == "" {
+				owned[closed.Index] = true
+			}
+		}
+	}
+
+	params := fn.Params
+	if fn.Signature.Recv() != nil && len(params) > 0 {
+		params = params[1:]
+	}
+	for i, param := range params {
+		named, ok := derefType(param.Type()).(*types.Named)
+		if !ok || owned[i] {
+			continue
+		}
+		if rt, ok := clientTypes[named]; ok {
+			reportBorrowedCloses(pass, param, rt, 0)
+		}
+	}
+}
+
+// reportBorrowedCloses reports the closes of the borrowed val, also from
+// closures capturing it
+func reportBorrowedCloses(pass *analysis.Pass, val ssa.Value, rt *ResourceType, depth int) {
+	if val.Referrers() == nil {
+		return
+	}
+
+	for _, ref := range *val.Referrers() {
+		switch ref := ref.(type) {
+		case ssa.CallInstruction:
+			if isCloseCall(ref.Common(), val, rt) {
+				reportf(pass, ref.Pos(), categoryBorrowed, borrowedMessage, rt.QualifiedName(), rt.CloseMethod)
+			}
+		case *ssa.UnOp:
+			// Captured variables are accessed through loads: *client
+			if ref.Op == token.MUL {
+				reportBorrowedCloses(pass, ref, rt, depth)
+			}
+		case *ssa.Store:
+			// Parameters captured by closures are spilled to an Alloc
+			if alloc, ok := ref.Addr.(*ssa.Alloc); ok && ref.Val == val {
+				reportBorrowedCloses(pass, alloc, rt, depth)
+			}
+		case *ssa.MakeClosure:
+			fn, ok := ref.Fn.(*ssa.Function)
+			if !ok || depth >= maxHelperDepth {
+				continue
+			}
+			for i, binding := range ref.Bindings {
+				if binding == val && i < len(fn.FreeVars) {
+					reportBorrowedCloses(pass, fn.FreeVars[i], rt, depth+1)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package analyzer
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// budget bounds how many packages the checks run on concurrently, for
+// Options.CheckConcurrency, and applies Options.MemoryLimit. Drivers such as
+// golangci-lint analyze many packages in parallel, and the checks of each
+// in-flight package hold their own state, such as the paths of its
+// functions. The SSA form the checks read is built and kept by the driver
+// beforehand, which the budget does not bound.
+type budget struct {
+	once sync.Once
+	sem  chan struct{}
+}
+
+// budgets are the budgets of the options analyzers were created with.
+// Analyzers sharing options, such as the analyzers of groups, share their
+// budget, so that the packages they check count together.
+var budgets = struct {
+	sync.Mutex
+	m map[*Options]*budget
+}{m: make(map[*Options]*budget)}
+
+// budgetOf returns the budget of opts, creating it on first use
+func budgetOf(opts *Options) *budget {
+	budgets.Lock()
+	defer budgets.Unlock()
+	b, ok := budgets.m[opts]
+	if !ok {
+		b = &budget{}
+		budgets.m[opts] = b
+	}
+	return b
+}
+
+// SetMemoryLimit, when set, applies Options.MemoryLimit on the first
+// analysis. The spannerclosecheck command sets it to
+// runtime/debug.SetMemoryLimit; other drivers leave it unset, so that the
+// analyzer does not change the limit of their process, and reject the
+// option.
+var SetMemoryLimit func(limit int64) int64
+
+// errMemoryLimit is the error of Options.MemoryLimit under drivers that do
+// not set SetMemoryLimit
+var errMemoryLimit = errors.New("memory-limit: only the spannerclosecheck command sets the memory limit of its process, set GOMEMLIMIT for other drivers")
+
+// checkMemoryLimit returns errMemoryLimit if opts sets a memory limit the
+// driver does not apply
+func checkMemoryLimit(opts *Options) error {
+	if opts.MemoryLimit > 0 && SetMemoryLimit == nil {
+		return errMemoryLimit
+	}
+	return nil
+}
+
+// acquire waits for a free package slot and returns a function releasing it.
+// The memory limit is applied on first use, once flags have been parsed.
+func (b *budget) acquire(opts *Options) func() {
+	b.once.Do(func() {
+		if opts.MemoryLimit > 0 && SetMemoryLimit != nil {
+			SetMemoryLimit(opts.MemoryLimit)
+		}
+		if opts.CheckConcurrency > 0 {
+			b.sem = make(chan struct{}, opts.CheckConcurrency)
+		}
+	})
+
+	if b.sem == nil {
+		return func() {}
+	}
+	b.sem <- struct{}{}
+	return func() { <-b.sem }
+}
+
+// byteSizeFlag is a flag holding a size in bytes, such as 512MiB or 6GiB
+type byteSizeFlag int64
+
+var byteSizeUnits = []struct {
+	suffix string
+	size   int64
+}{
+	{"TiB", 1 << 40},
+	{"GiB", 1 << 30},
+	{"MiB", 1 << 20},
+	{"KiB", 1 << 10},
+	{"B", 1},
+}
+
+func (f *byteSizeFlag) String() string {
+	if f == nil || *f == 0 {
+		return "0"
+	}
+	for _, unit := range byteSizeUnits {
+		if int64(*f)%unit.size == 0 {
+			return strconv.FormatInt(int64(*f)/unit.size, 10) + unit.suffix
+		}
+	}
+	return strconv.FormatInt(int64(*f), 10)
+}
+
+func (f *byteSizeFlag) Set(value string) error {
+	number, size := value, int64(1)
+	for _, unit := range byteSizeUnits {
+		if strings.HasSuffix(value, unit.suffix) {
+			number, size = strings.TrimSuffix(value, unit.suffix), unit.size
+			break
+		}
+	}
+	n, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || n < 0 {
+		return fmt.Errorf("invalid size %q: want bytes with an optional B, KiB, MiB, GiB or TiB suffix", value)
+	}
+	*f = byteSizeFlag(n * size)
+	return nil
+}
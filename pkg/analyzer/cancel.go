@@ -0,0 +1,115 @@
+package analyzer
+
+import (
+	"fmt"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ssa"
+)
+
+// streamCancelMessage reports a cancelable context used for a streaming read
+// whose cancel function is not deferred
+const streamCancelMessage = "cancel of %s() is not deferred: the context is used by the streaming read %s() at line %d, whose gRPC stream leaks if the function returns or panics before cancel() runs, defer cancel() right after creating the context"
+
+// cancelableContexts are the functions deriving a context with a cancel function
+var cancelableContexts = []string{
+	"context.WithCancel",
+	"context.WithCancelCause",
+	"context.WithTimeout",
+	"context.WithTimeoutCause",
+	"context.WithDeadline",
+	"context.WithDeadlineCause",
+}
+
+// streamingReads are the methods of Spanner transactions opening a gRPC stream
+// that lives as long as the context passed to them
+var streamingReads = map[string]bool{
+	"Query":            true,
+	"QueryWithStats":   true,
+	"QueryWithOptions": true,
+	"Read":             true,
+	"ReadWithOptions":  true,
+	"ReadUsingIndex":   true,
+	"Execute":          true,
+}
+
+// checkStreamCancels reports contexts derived with a cancel function in fn and
+// passed to a Spanner streaming read, whose cancel is not deferred:
+//
+//	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+//	iter := txn.Query(ctx, stmt)
+//	defer iter.Stop()
+//	...
+//	cancel() // skipped by early returns and panics
+//
+// A cancel function that escapes fn, as a result, an argument or in a
+// closure, is left to its new owner.
+func checkStreamCancels(pass *analysis.Pass, fn *ssa.Function) {
+	if fn == nil || isGeneratedFile(pass, fn.Pos()) {
+		return
+	}
+
+	for _, block := range fn.Blocks {
+		for _, instr := range block.Instrs {
+			call, ok := instr.(*ssa.Call)
+			if !ok || !callsOneOf(call.Common(), cancelableContexts) {
+				continue
+			}
+			ctx, cancel := resultExtract(call, 0), resultExtract(call, 1)
+			// A discarded cancel is reported by go vet's lostcancel
+			if ctx == nil || cancel == nil || len(*cancel.Referrers()) == 0 {
+				continue
+			}
+			read := streamingRead(ctx)
+			if read == nil || cancelDeferred(cancel) {
+				continue
+			}
+			pass.Report(analysis.Diagnostic{
+				Pos:      call.Pos(),
+				Category: categoryStreamCancel,
+				Message:  fmt.Sprintf(streamCancelMessage, "context."+call.Common().StaticCallee().Name(), constructorName(read), pass.Fset.Position(read.Pos()).Line),
+				Related: []analysis.RelatedInformation{{
+					Pos:     read.Pos(),
+					Message: "streaming read",
+				}},
+			})
+		}
+	}
+}
+
+// streamingRead returns the first Spanner streaming read ctx is passed to, or nil
+func streamingRead(ctx ssa.Value) *ssa.Call {
+	for _, ref := range *ctx.Referrers() {
+		call, ok := ref.(*ssa.Call)
+		if !ok || len(call.Call.Args) < 2 || call.Call.Args[1] != ctx {
+			continue
+		}
+		callee := call.Call.StaticCallee()
+		if callee == nil || callee.Pkg == nil || !streamingReads[callee.Name()] || callee.Signature.Recv() == nil {
+			continue
+		}
+		if matchesPkgPath(callee.Pkg.Pkg.Path(), pathGoogleSpanner) {
+			return call
+		}
+	}
+	return nil
+}
+
+// cancelDeferred checks if the cancel function is deferred, or escapes to
+// code that becomes responsible for it, such as a closure, which is either
+// deferred or runs elsewhere
+func cancelDeferred(cancel ssa.Value) bool {
+	for _, ref := range *cancel.Referrers() {
+		switch ref := ref.(type) {
+		case *ssa.Defer:
+			return true
+		case *ssa.Call:
+			if ref.Call.Value != cancel {
+				return true
+			}
+		case *ssa.MakeClosure, *ssa.Return, *ssa.Store, *ssa.Go, *ssa.MakeInterface, *ssa.Phi:
+			return true
+		}
+	}
+	return false
+}
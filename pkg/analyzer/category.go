@@ -0,0 +1,133 @@
+package analyzer
+
+import (
+	"cmp"
+	"fmt"
+	"go/token"
+	"maps"
+	"slices"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// Categories of the diagnostics, one per kind of finding, so that
+// golangci-lint exclusions, IDEs and -json consumers can filter them.
+const (
+	// categoryUnclosed is the category of resources never closed
+	categoryUnclosed = "unclosed"
+	// categoryNotDeferred is the category of resources closed without defer
+	categoryNotDeferred = "not-deferred"
+	// categoryGoroutineEscape is the category of resources escaping into
+	// goroutines that do not close them
+	categoryGoroutineEscape = "goroutine-escape"
+	// categoryDiscarded is the category of resources discarded with the blank
+	// identifier or dropped, telling them apart from resources closed wrongly
+	categoryDiscarded       = "discarded"
+	categoryReassigned      = "reassigned"
+	categoryCollection      = "collection"
+	categoryWrapperField    = "wrapper-field"
+	categoryCleanup         = "cleanup"
+	categoryConditional     = "conditional-defer"
+	categoryDeferInLoop     = "defer-in-loop"
+	categoryDeferOrder      = "defer-order"
+	categoryLoopVar         = "loopvar"
+	categoryExitAfterDefer  = "exit-after-defer"
+	categoryDoubleClose     = "double-close"
+	categoryDuplicateDefer  = "duplicate-defer"
+	categoryUseAfterClose   = "use-after-close"
+	categoryCloseInLoop     = "close-in-loop"
+	categoryCloseBeforeJoin = "close-before-join"
+	categoryForeignClose    = "foreign-goroutine"
+	categoryBorrowed        = "borrowed-close"
+	categoryRetainedTxn     = "retained-transaction"
+	categoryGapicStream     = "gapic-stream"
+	categoryStreamCancel    = "stream-cancel"
+	categoryClient          = "client-construction"
+	categoryPackageClient   = "package-client"
+	categorySessionPool     = "session-pool"
+	categoryCloseError      = "close-error"
+	categorySingleUse       = "single-use"
+	categorySingleClose     = "single-close"
+	categoryDirective       = "directive"
+)
+
+// categoryDescriptions describe the findings of each category
+var categoryDescriptions = map[string]string{
+	categoryUnclosed:        "Resource never closed",
+	categoryNotDeferred:     "Resource closed without defer",
+	categoryGoroutineEscape: "Resource escaping into a goroutine that does not close it",
+	categoryDiscarded:       "Resource discarded with the blank identifier or dropped",
+	categoryReassigned:      "Variable reassigned before its resource is closed",
+	categoryCollection:      "Resource stored into a slice or map that is not closed",
+	categoryWrapperField:    "Wrapper field not closed by the wrapper",
+	categoryCleanup:         "Cleanup function not deferred",
+	categoryConditional:     "Close deferred on some paths only",
+	categoryDeferInLoop:     "Close deferred inside a loop",
+	categoryDeferOrder:      "Close deferred before the error check or after the first use",
+	categoryLoopVar:         "Loop variable captured by a deferred close",
+	categoryExitAfterDefer:  "Deferred close skipped by os.Exit",
+	categoryDoubleClose:     "Resource closed twice",
+	categoryDuplicateDefer:  "Close deferred twice",
+	categoryUseAfterClose:   "Resource used after its close",
+	categoryCloseInLoop:     "Resource closed inside the loop reading it",
+	categoryCloseBeforeJoin: "Resource closed before goroutines using it finish",
+	categoryForeignClose:    "Resource closed in another goroutine than the acquiring one",
+	categoryBorrowed:        "Borrowed client closed",
+	categoryRetainedTxn:     "ReadWriteTransaction outliving its callback",
+	categoryGapicStream:     "GAPIC stream not drained or cancelled",
+	categoryStreamCancel:    "Context of a streaming read not cancelled",
+	categoryClient:          "Client created per request, per event or in a loop",
+	categoryPackageClient:   "Package-level client never closed",
+	categorySessionPool:     "Suspicious SessionPoolConfig value",
+	categoryCloseError:      "Error of a deferred close discarded",
+	categorySingleUse:       "Transaction used for a single statement",
+	categorySingleClose:     "Redundant close of a Client.Single() transaction",
+	categoryDirective:       "Invalid directive",
+}
+
+// categorySeverities are the default severities of the categories not
+// reported as errors. Closing twice is a no-op, so double closes, which
+// include the idiom of a deferred close along with an early one, are
+// warnings.
+var categorySeverities = map[string]Severity{
+	categoryDoubleClose: SeverityWarning,
+	categorySingleClose: SeverityInfo,
+}
+
+// Category is a category of the diagnostics of the built-in checks, for
+// output formats describing the checks, such as SARIF rules
+type Category struct {
+	// Name is the category of the diagnostics, such as not-deferred
+	Name string
+	// Description describes the finding, as in "Resource never closed"
+	Description string
+	// Confidence is the confidence of the diagnostics
+	Confidence Confidence
+	// Severity is the severity of the diagnostics without Options.Severities
+	// and Options.SingleClose
+	Severity Severity
+}
+
+// Categories returns the categories of the diagnostics of the built-in
+// checks, sorted by name. Registered checkers may report other categories.
+func Categories() []Category {
+	var categories []Category
+	for _, name := range slices.Sorted(maps.Keys(categoryDescriptions)) {
+		categories = append(categories, Category{
+			Name:        name,
+			Description: categoryDescriptions[name],
+			Confidence:  ConfidenceOf(analysis.Diagnostic{Category: name}),
+			Severity:    cmp.Or(categorySeverities[name], SeverityError),
+		})
+	}
+	return categories
+}
+
+// reportf reports a diagnostic of category at pos
+func reportf(pass *analysis.Pass, pos token.Pos, category, format string, args ...interface{}) {
+	pass.Report(analysis.Diagnostic{
+		Pos:      pos,
+		Category: category,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
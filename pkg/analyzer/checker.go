@@ -0,0 +1,198 @@
+package analyzer
+
+import (
+	"flag"
+	"fmt"
+	"go/types"
+	"slices"
+	"sync"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ssa"
+)
+
+// Checker is a check run on every source function of the analyzed packages.
+// The built-in checks are checkers too. Third parties add their own with
+// Register.
+type Checker interface {
+	// Name identifies the checker, it must be unique
+	Name() string
+	// Flags registers the options of the checker on fs, the flags of an
+	// analyzer running it
+	Flags(fs *flag.FlagSet)
+	// CheckFunc reports the findings of the checker in fn
+	CheckFunc(pass *analysis.Pass, fn *ssa.Function, resources *Resources)
+}
+
+// Resources are the resource types known to the package being analyzed
+type Resources struct {
+	// Types are the resources that must be closed: the Spanner resources and
+	// the custom resources of Options.Resources and resource directives
+	Types map[*types.Named]*ResourceType
+	// Clients are the Spanner client types
+	Clients map[*types.Named]*ResourceType
+
+	opts    *Options
+	returns resourceReturns
+}
+
+// Lookup returns the resource type of t, a resource, a pointer to one or a
+// struct embedding one, or nil
+func (r *Resources) Lookup(t types.Type) *ResourceType {
+	return getSpannerType(t, r.Types)
+}
+
+// builtinChecker is a built-in check of a Group
+type builtinChecker struct {
+	name  string
+	group Group
+	// enabled reports if the options enable the check, nil for checks that
+	// always run
+	enabled func(opts *Options) bool
+	check   func(pass *analysis.Pass, fn *ssa.Function, res *Resources)
+}
+
+func (c *builtinChecker) Name() string { return c.name }
+
+// Flags registers nothing: the options of built-in checks are bound by
+// Options.bindFlags
+func (c *builtinChecker) Flags(fs *flag.FlagSet) {}
+
+func (c *builtinChecker) CheckFunc(pass *analysis.Pass, fn *ssa.Function, res *Resources) {
+	if c.enabled == nil || c.enabled(res.opts) {
+		c.check(pass, fn, res)
+	}
+}
+
+// builtinCheckers are the built-in checks, in the order they run
+var builtinCheckers = []*builtinChecker{
+	{name: "unclosed", group: GroupClose, check: func(pass *analysis.Pass, fn *ssa.Function, res *Resources) {
+		checkFunc(pass, fn, res.Types, res.returns, res.opts)
+	}},
+	{name: "returned", group: GroupClose, check: func(pass *analysis.Pass, fn *ssa.Function, res *Resources) {
+		checkReturnedResources(pass, fn, res.Types, res.returns, res.opts)
+	}},
+	{name: "borrowed", group: GroupOwnership, check: func(pass *analysis.Pass, fn *ssa.Function, res *Resources) {
+		checkBorrowedCloses(pass, fn, res.Clients)
+	}},
+	{name: "loopvar", group: GroupClose, check: func(pass *analysis.Pass, fn *ssa.Function, res *Resources) {
+		checkLoopVarCaptures(pass, fn, res.Types)
+	}},
+	{name: "useafterclose", group: GroupUseAfterClose, check: func(pass *analysis.Pass, fn *ssa.Function, res *Resources) {
+		checkUseAfterClose(pass, fn, res.Types)
+	}},
+	{name: "closeinnextloop", group: GroupUseAfterClose, check: func(pass *analysis.Pass, fn *ssa.Function, res *Resources) {
+		checkCloseInNextLoop(pass, fn, res.Types)
+	}},
+	{name: "doubleclose", group: GroupUseAfterClose, check: func(pass *analysis.Pass, fn *ssa.Function, res *Resources) {
+		checkDoubleClose(pass, fn, res.Types)
+	}},
+	{name: "closebeforejoin", group: GroupUseAfterClose, check: func(pass *analysis.Pass, fn *ssa.Function, res *Resources) {
+		checkCloseBeforeJoin(pass, fn, res.Types)
+	}},
+	{name: "exitafterdefer", group: GroupClose, check: func(pass *analysis.Pass, fn *ssa.Function, res *Resources) {
+		if !isExemptFunc(fn, res.opts) {
+			checkExitAfterDefer(pass, fn, res.Types, res.Clients)
+		}
+	}},
+	{name: "retainedtxn", group: GroupOwnership, check: func(pass *analysis.Pass, fn *ssa.Function, res *Resources) {
+		checkRetainedReadWriteTxns(pass, fn)
+	}},
+	{name: "gapicstream", group: GroupClose, check: func(pass *analysis.Pass, fn *ssa.Function, res *Resources) {
+		checkGapicStreams(pass, fn)
+	}},
+	{name: "singleclose", group: GroupStyle, enabled: func(opts *Options) bool {
+		return opts.SingleClose.orDefault(SeverityInfo) != SeverityOff
+	}, check: func(pass *analysis.Pass, fn *ssa.Function, res *Resources) {
+		checkSingleClose(pass, fn, res.Types, res.opts)
+	}},
+	{name: "suggestsingle", group: GroupStyle, enabled: func(opts *Options) bool {
+		return opts.SuggestSingle
+	}, check: func(pass *analysis.Pass, fn *ssa.Function, res *Resources) {
+		checkSingleUse(pass, fn, res.Types)
+	}},
+	{name: "clientconstruction", group: GroupClose, enabled: func(opts *Options) bool {
+		return opts.ClientPerRequest || opts.ClientInLoop
+	}, check: func(pass *analysis.Pass, fn *ssa.Function, res *Resources) {
+		checkClientConstruction(pass, fn, res.opts)
+	}},
+	{name: "streamcancel", group: GroupClose, enabled: func(opts *Options) bool {
+		return opts.StreamCancel
+	}, check: func(pass *analysis.Pass, fn *ssa.Function, res *Resources) {
+		checkStreamCancels(pass, fn)
+	}},
+	{name: "ownergoroutine", group: GroupOwnership, enabled: func(opts *Options) bool {
+		return opts.OwnerGoroutine
+	}, check: func(pass *analysis.Pass, fn *ssa.Function, res *Resources) {
+		checkForeignCloses(pass, fn, res.Types)
+	}},
+	{name: "closeerrors", group: GroupStyle, enabled: func(opts *Options) bool {
+		return opts.CloseErrors
+	}, check: func(pass *analysis.Pass, fn *ssa.Function, res *Resources) {
+		checkCloseErrors(pass, fn, res.Types)
+	}},
+	{name: "duplicatedefers", group: GroupStyle, enabled: func(opts *Options) bool {
+		return opts.DuplicateDefers
+	}, check: func(pass *analysis.Pass, fn *ssa.Function, res *Resources) {
+		checkDuplicateDefers(pass, fn, res.Types)
+	}},
+}
+
+// registry holds the checkers added with Register, and the flags of the
+// analyzers running them
+var registry struct {
+	sync.Mutex
+	checkers []Checker
+	flags    []*flag.FlagSet
+}
+
+// Register adds c to the checkers run by the analyzers of NewAnalyzer,
+// including Analyzer, and registers its flags on them. Group analyzers run
+// the built-in checks of their group only. Register panics if a checker of
+// the same name is registered already; call it from an init function.
+func Register(c Checker) {
+	registry.Lock()
+	defer registry.Unlock()
+	for _, b := range builtinCheckers {
+		if b.name == c.Name() {
+			panic(fmt.Sprintf("spannerclosecheck: checker %q is built in", c.Name()))
+		}
+	}
+	for _, r := range registry.checkers {
+		if r.Name() == c.Name() {
+			panic(fmt.Sprintf("spannerclosecheck: checker %q registered twice", c.Name()))
+		}
+	}
+	registry.checkers = append(registry.checkers, c)
+	for _, fs := range registry.flags {
+		c.Flags(fs)
+	}
+}
+
+// bindRegisteredFlags registers the flags of the registered checkers on fs,
+// and of those registered later
+func bindRegisteredFlags(fs *flag.FlagSet) {
+	registry.Lock()
+	defer registry.Unlock()
+	registry.flags = append(registry.flags, fs)
+	for _, c := range registry.checkers {
+		c.Flags(fs)
+	}
+}
+
+// checkersFor returns the built-in checkers of groups, followed by the
+// registered checkers if registered is set
+func checkersFor(groups []Group, registered bool) []Checker {
+	var checkers []Checker
+	for _, b := range builtinCheckers {
+		if slices.Contains(groups, b.group) {
+			checkers = append(checkers, b)
+		}
+	}
+	if registered {
+		registry.Lock()
+		checkers = append(checkers, registry.checkers...)
+		registry.Unlock()
+	}
+	return checkers
+}
@@ -0,0 +1,214 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"slices"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// cleanupMessage reports a cleanup function returned along with a resource
+// that the caller does not defer
+const cleanupMessage = "cleanup function returned by %s() must be deferred"
+
+// isReturnedWithCleanup checks if fn hands val to its caller together with a
+// returned cleanup function closing it, as in
+//
+//	func newTxn(client *spanner.Client) (*spanner.ReadOnlyTransaction, func()) {
+//		txn := client.ReadOnlyTransaction()
+//		return txn, txn.Close
+//	}
+//
+// The caller must defer the cleanup instead, see checkCleanup
+func isReturnedWithCleanup(val ssa.Value, rt *ResourceType) bool {
+	if val.Referrers() == nil {
+		return false
+	}
+
+	for _, ref := range *val.Referrers() {
+		switch ref := ref.(type) {
+		case *ssa.MakeClosure:
+			// Method values such as txn.Close bind the resource directly
+			if isReturned(ref) && closureClosesBinding(ref, val, rt) {
+				return true
+			}
+		case *ssa.Store:
+			// Captured variables are stored in a local cell shared with the closure
+			alloc, ok := ref.Addr.(*ssa.Alloc)
+			if !ok || ref.Val != val || alloc.Referrers() == nil {
+				continue
+			}
+			for _, allocRef := range *alloc.Referrers() {
+				if closure, ok := allocRef.(*ssa.MakeClosure); ok && isReturned(closure) && closureClosesBinding(closure, alloc, rt) {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// isReturned checks if val is one of the results of its function
+func isReturned(val ssa.Value) bool {
+	if val.Referrers() == nil {
+		return false
+	}
+	for _, ref := range *val.Referrers() {
+		if ret, ok := ref.(*ssa.Return); ok && slices.Contains(ret.Results, val) {
+			return true
+		}
+	}
+	return false
+}
+
+// returnedCleanup returns the call producing val when val is a resource
+// returned together with a cleanup function closing it, and the index of the
+// cleanup among the call's results. Functions of the same package must return
+// a closure closing the resource; for others, a func() result is trusted to
+// be the cleanup.
+func returnedCleanup(val ssa.Value, rt *ResourceType) (*ssa.Call, int, bool) {
+	extract, ok := val.(*ssa.Extract)
+	if !ok {
+		return nil, 0, false
+	}
+	call, ok := extract.Tuple.(*ssa.Call)
+	if !ok {
+		return nil, 0, false
+	}
+
+	results := call.Common().Signature().Results()
+	index := -1
+	for i := range results.Len() {
+		if i != extract.Index && isCleanupFunc(results.At(i).Type()) {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return nil, 0, false
+	}
+
+	callee := call.Common().StaticCallee()
+	if callee == nil || len(callee.Blocks) == 0 {
+		return call, index, true
+	}
+	for _, block := range callee.Blocks {
+		ret, ok := block.Instrs[len(block.Instrs)-1].(*ssa.Return)
+		if !ok || index >= len(ret.Results) {
+			continue
+		}
+		closure, ok := ret.Results[index].(*ssa.MakeClosure)
+		if !ok {
+			continue
+		}
+		for _, binding := range closure.Bindings {
+			if closureClosesBinding(closure, binding, rt) {
+				return call, index, true
+			}
+		}
+	}
+	return nil, 0, false
+}
+
+// returnsCleanup checks if one of the results of fn is a cleanup function
+func returnsCleanup(fn *ssa.Function) bool {
+	results := fn.Signature.Results()
+	for i := range results.Len() {
+		if isCleanupFunc(results.At(i).Type()) {
+			return true
+		}
+	}
+	return false
+}
+
+// isCleanupFunc checks if t is a function without parameters, such as
+// func() or func() error
+func isCleanupFunc(t types.Type) bool {
+	sig, ok := t.Underlying().(*types.Signature)
+	return ok && sig.Params().Len() == 0 && sig.Results().Len() <= 1
+}
+
+// checkCleanup returns the message to report for call if the cleanup function
+// among its results at index is neither deferred, registered as a lifecycle
+// hook, nor returned to the caller in turn
+func checkCleanup(call *ssa.Call, index int, opts *Options) (string, bool) {
+	if call.Referrers() != nil {
+		for _, ref := range *call.Referrers() {
+			if extract, ok := ref.(*ssa.Extract); ok && extract.Index == index && isCleanupHandled(extract, opts) {
+				return "", false
+			}
+		}
+	}
+
+	name := "function"
+	if callee := call.Common().StaticCallee(); callee != nil {
+		name = callee.Name()
+	} else if method := call.Common().Method; method != nil {
+		name = method.Name()
+	}
+	return fmt.Sprintf(cleanupMessage, name), true
+}
+
+// isCleanupHandled checks if the cleanup function is deferred, directly or
+// from a deferred closure, registered as a lifecycle hook, or returned
+func isCleanupHandled(cleanup ssa.Value, opts *Options) bool {
+	if cleanup.Referrers() == nil {
+		return false
+	}
+	if isReturned(cleanup) || flowsToHookRegistration(cleanup, slices.Concat(defaultLifecycleHooks, opts.LifecycleHooks), 0) {
+		return true
+	}
+
+	for _, ref := range *cleanup.Referrers() {
+		switch ref := ref.(type) {
+		case *ssa.Defer:
+			if ref.Common().Value == cleanup {
+				return true
+			}
+		case *ssa.Store:
+			// Captured by a deferred closure calling it: defer func() { cleanup() }()
+			alloc, ok := ref.Addr.(*ssa.Alloc)
+			if !ok || ref.Val != cleanup || alloc.Referrers() == nil {
+				continue
+			}
+			for _, allocRef := range *alloc.Referrers() {
+				if closure, ok := allocRef.(*ssa.MakeClosure); ok && closureDefer(closure) != nil && closureCallsBinding(closure, alloc) {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// closureCallsBinding checks if the closure body calls the function held in
+// the free variable bound to binding
+func closureCallsBinding(closure *ssa.MakeClosure, binding ssa.Value) bool {
+	fn, ok := closure.Fn.(*ssa.Function)
+	if !ok {
+		return false
+	}
+
+	for i, b := range closure.Bindings {
+		if b != binding || i >= len(fn.FreeVars) || fn.FreeVars[i].Referrers() == nil {
+			continue
+		}
+		for _, ref := range *fn.FreeVars[i].Referrers() {
+			load, ok := ref.(*ssa.UnOp)
+			if !ok || load.Op != token.MUL || load.Referrers() == nil {
+				continue
+			}
+			for _, loadRef := range *load.Referrers() {
+				if call, ok := loadRef.(ssa.CallInstruction); ok && call.Common().Value == load {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,134 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/types"
+	"path"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ssa"
+)
+
+const pathNetHTTP = "net/http"
+
+// clientConstructors are the functions creating a Spanner client, which
+// opens a session pool and gRPC connections
+var clientConstructors = map[string]bool{
+	"NewClient":              true,
+	"NewClientWithConfig":    true,
+	"NewMultiEndpointClient": true,
+}
+
+// invocationEvents are the parameter types of well-known callbacks invoked once
+// per event, such as controller-runtime Reconcile methods and Pub/Sub receivers
+var invocationEvents = []struct {
+	pkgPath, name string
+}{
+	{"sigs.k8s.io/controller-runtime/pkg/reconcile", "Request"},
+	{"cloud.google.com/go/pubsub", "Message"},
+}
+
+// checkClientConstruction reports Spanner clients created per HTTP request,
+// inside loops or in per-invocation callbacks, depending on opts. Every client
+// opens its own session pool, so creating one per request exhausts sessions
+// and adds connection setup to every request.
+func checkClientConstruction(pass *analysis.Pass, fn *ssa.Function, opts *Options) {
+	if fn == nil || isGeneratedFile(pass, fn.Pos()) {
+		return
+	}
+	perRequest := opts.ClientPerRequest && inHTTPHandler(fn)
+	event := ""
+	if opts.ClientInLoop {
+		event = invocationEvent(fn)
+	}
+
+	for _, block := range fn.Blocks {
+		for _, instr := range block.Instrs {
+			call, ok := instr.(*ssa.Call)
+			if !ok {
+				continue
+			}
+			name, ok := clientConstruction(call)
+			if !ok {
+				continue
+			}
+			switch {
+			case perRequest:
+				reportf(pass, call.Pos(), categoryClient, "%s() is called for every HTTP request, create the client once at process scope and share it", name)
+			case opts.ClientInLoop && inLoop(block):
+				reportf(pass, call.Pos(), categoryClient, "%s() is called inside a loop, create the client once and reuse it", name)
+			case event != "":
+				reportf(pass, call.Pos(), categoryClient, "%s() is called for every %s, create the client once and reuse it", name, event)
+			}
+		}
+	}
+}
+
+// clientConstruction checks if call creates a Spanner client and returns the
+// qualified name of the constructor, e.g. spanner.NewClient
+func clientConstruction(call *ssa.Call) (string, bool) {
+	callee := call.Common().StaticCallee()
+	if callee == nil || callee.Signature.Recv() != nil || callee.Pkg == nil || !clientConstructors[callee.Name()] {
+		return "", false
+	}
+	pkgPath := callee.Pkg.Pkg.Path()
+	if !matchesPkgPath(pkgPath, pathGoogleSpanner) && !matchesPkgPath(pkgPath, pathGoogleSpannerAPIv1) {
+		return "", false
+	}
+	return fmt.Sprintf("%s.%s", path.Base(normalizePkgPath(pkgPath)), callee.Name()), true
+}
+
+// inHTTPHandler checks if fn, or a function it is nested in, handles an HTTP
+// request, i.e. has the parameters of an http.HandlerFunc
+func inHTTPHandler(fn *ssa.Function) bool {
+	for ; fn != nil; fn = fn.Parent() {
+		if isHTTPHandler(fn.Signature) {
+			return true
+		}
+	}
+	return false
+}
+
+// invocationEvent returns the qualified event type if fn, or a function it is
+// nested in, is a callback invoked once per event, or "" otherwise
+func invocationEvent(fn *ssa.Function) string {
+	for ; fn != nil; fn = fn.Parent() {
+		params := fn.Signature.Params()
+		for i := 0; i < params.Len(); i++ {
+			t := params.At(i).Type()
+			if ptr, ok := t.(*types.Pointer); ok {
+				t = ptr.Elem()
+			}
+			named, ok := t.(*types.Named)
+			if !ok || named.Obj().Pkg() == nil {
+				continue
+			}
+			for _, ev := range invocationEvents {
+				if named.Obj().Name() == ev.name && matchesPkgPath(named.Obj().Pkg().Path(), ev.pkgPath) {
+					return path.Base(ev.pkgPath) + "." + ev.name
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// isHTTPHandler checks if sig takes an http.ResponseWriter and *http.Request
+func isHTTPHandler(sig *types.Signature) bool {
+	params := sig.Params()
+	if params.Len() != 2 {
+		return false
+	}
+	req, ok := params.At(1).Type().(*types.Pointer)
+	return ok && isNamedType(params.At(0).Type(), pathNetHTTP, "ResponseWriter") && isNamedType(req.Elem(), pathNetHTTP, "Request")
+}
+
+// isNamedType checks if t is the named type pkgPath.name
+func isNamedType(t types.Type, pkgPath, name string) bool {
+	named, ok := t.(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == pkgPath && obj.Name() == name
+}
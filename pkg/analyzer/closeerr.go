@@ -0,0 +1,169 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strconv"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ssa"
+)
+
+// closeErrMessage reports a deferred close whose error is discarded in a
+// function returning an error
+const closeErrMessage = "error of deferred %s.%s() is discarded although the function returns an error: join it into the result, as in defer func() { err = errors.Join(err, %s.%s()) }()"
+
+// checkCloseErrors reports deferred closes returning an error, such as the
+// Close() of custom resources wrapping database/sql rows, whose error is
+// discarded in functions returning an error:
+//
+//	func load(db *ourdb.DB) error {
+//		iter, err := db.Rows()
+//		...
+//		defer iter.Close() // the error of Close() is lost
+//	}
+//
+// Closes called as statements in deferred function literals are reported
+// too, while an explicit _ = iter.Close() is a deliberate discard. When the
+// error result is named and the file imports errors, the suggested fix joins
+// the error of the close into it.
+func checkCloseErrors(pass *analysis.Pass, fn *ssa.Function, spannerTypes map[*types.Named]*ResourceType) {
+	if fn == nil || isGeneratedFile(pass, fn.Pos()) || errorResult(fn) == nil {
+		return
+	}
+
+	for _, block := range fn.Blocks {
+		for _, instr := range block.Instrs {
+			d, ok := instr.(*ssa.Defer)
+			if !ok {
+				continue
+			}
+			if rt, ok := errorClose(d.Common(), spannerTypes); ok {
+				recv := receiverExpr(pass, d.Common())
+				pass.Report(analysis.Diagnostic{
+					Pos:            d.Pos(),
+					Category:       categoryCloseError,
+					Message:        fmt.Sprintf(closeErrMessage, rt.QualifiedName(), rt.CloseMethod, recv, rt.CloseMethod),
+					SuggestedFixes: joinCloseErrFixes(pass, fn, d, rt, recv),
+				})
+				continue
+			}
+			checkDeferredLiteralCloseErrors(pass, fn, d, spannerTypes)
+		}
+	}
+}
+
+// checkDeferredLiteralCloseErrors reports the closes returning an error that
+// the function literal deferred by d calls as statements
+func checkDeferredLiteralCloseErrors(pass *analysis.Pass, fn *ssa.Function, d *ssa.Defer, spannerTypes map[*types.Named]*ResourceType) {
+	lit, ok := d.Call.Value.(*ssa.Function)
+	if mc, isClosure := d.Call.Value.(*ssa.MakeClosure); isClosure {
+		lit, ok = mc.Fn.(*ssa.Function)
+	}
+	if !ok || lit.Parent() != fn {
+		return
+	}
+	for _, block := range lit.Blocks {
+		for _, instr := range block.Instrs {
+			call, ok := instr.(*ssa.Call)
+			if !ok {
+				continue
+			}
+			rt, ok := errorClose(call.Common(), spannerTypes)
+			if !ok || !isCallStmt(pass, call) {
+				continue
+			}
+			reportf(pass, call.Pos(), categoryCloseError, closeErrMessage, rt.QualifiedName(), rt.CloseMethod, receiverExpr(pass, call.Common()), rt.CloseMethod)
+		}
+	}
+}
+
+// errorClose checks if common closes a resource with a method returning an
+// error, and returns the resource type
+func errorClose(common *ssa.CallCommon, spannerTypes map[*types.Named]*ResourceType) (*ResourceType, bool) {
+	val := methodReceiver(common)
+	if val == nil {
+		return nil, false
+	}
+	rt := getSpannerType(val.Type(), spannerTypes)
+	if rt == nil || !isCloseCall(common, val, rt) {
+		return nil, false
+	}
+	results := common.Signature().Results()
+	return rt, results.Len() == 1 && isErrorType(results.At(0).Type())
+}
+
+// receiverExpr returns the source of the receiver of the method call common,
+// as iter in iter.Close(), or "x" if it is not found
+func receiverExpr(pass *analysis.Pass, common *ssa.CallCommon) string {
+	call, ok := findNode(pass, common.Pos(), func(n *ast.CallExpr) bool {
+		return n.Lparen == common.Pos()
+	})
+	if !ok {
+		return "x"
+	}
+	sel, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr)
+	if !ok {
+		return "x"
+	}
+	return types.ExprString(sel.X)
+}
+
+// errorResult returns the last result of fn if it is an error, or nil
+func errorResult(fn *ssa.Function) *types.Var {
+	results := fn.Signature.Results()
+	if results.Len() == 0 {
+		return nil
+	}
+	if last := results.At(results.Len() - 1); isErrorType(last.Type()) {
+		return last
+	}
+	return nil
+}
+
+// isErrorType checks if t is the predeclared error type
+func isErrorType(t types.Type) bool {
+	return types.Identical(t, types.Universe.Lookup("error").Type())
+}
+
+// joinCloseErrFixes returns a fix replacing the deferred close d with a
+// deferred function literal joining its error into the named error result of
+// fn, or nil if the result is unnamed or the file does not import errors
+func joinCloseErrFixes(pass *analysis.Pass, fn *ssa.Function, d *ssa.Defer, rt *ResourceType, recv string) []analysis.SuggestedFix {
+	result := errorResult(fn)
+	if recv == "x" || result.Name() == "" || result.Name() == "_" || !importsErrors(pass, d.Pos()) {
+		return nil
+	}
+	stmt, ok := findNode(pass, d.Pos(), func(n *ast.DeferStmt) bool {
+		return n.Defer == d.Pos()
+	})
+	if !ok {
+		return nil
+	}
+	return []analysis.SuggestedFix{{
+		Message: fmt.Sprintf("Join the error of %s.%s() into %s", rt.QualifiedName(), rt.CloseMethod, result.Name()),
+		TextEdits: []analysis.TextEdit{{
+			Pos:     stmt.Pos(),
+			End:     stmt.End(),
+			NewText: []byte(fmt.Sprintf("defer func() { %s = errors.Join(%s, %s.%s()) }()", result.Name(), result.Name(), recv, rt.CloseMethod)),
+		}},
+	}}
+}
+
+// importsErrors checks if the file containing pos imports the errors package
+// under its own name
+func importsErrors(pass *analysis.Pass, pos token.Pos) bool {
+	for _, f := range pass.Files {
+		if f.FileStart > pos || pos > f.FileEnd {
+			continue
+		}
+		for _, spec := range f.Imports {
+			if path, err := strconv.Unquote(spec.Path.Value); err == nil && path == "errors" && spec.Name == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
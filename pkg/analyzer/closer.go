@@ -0,0 +1,313 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"reflect"
+	"slices"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// closedParam is a parameter a function closes, by index among the
+// parameters of its signature, excluding the receiver. Method is empty for
+// parameters declared with directiveCloses, closed whatever their type.
+type closedParam struct {
+	Index  int
+	Method string
+	// Deferred is set when the close runs however the function returns
+	Deferred bool
+}
+
+// closesParamFact marks a function that closes some of its parameters.
+// Callers passing a resource to it need no defer when it defers the close:
+//
+//	func render(w io.Writer, iter *spanner.RowIterator) error {
+//		defer iter.Stop()
+//		...
+//	}
+type closesParamFact struct {
+	Params []closedParam
+}
+
+func (*closesParamFact) AFact() {}
+
+func (f *closesParamFact) String() string {
+	params := make([]string, 0, len(f.Params))
+	for _, p := range f.Params {
+		param := fmt.Sprintf("%d.%s()", p.Index, p.Method)
+		if p.Method == "" {
+			param = fmt.Sprintf("%d", p.Index)
+		}
+		if p.Deferred {
+			param = "defer " + param
+		}
+		params = append(params, param)
+	}
+	return "closes params " + strings.Join(params, ", ")
+}
+
+// maxCloserRounds limits how often functions are revisited to find helpers
+// calling other helpers of the same package
+const maxCloserRounds = 5
+
+// closerAnalyzer exports the parameters functions close as facts and
+// returns those visible to the package, including its own.
+// Like directiveAnalyzer it only inspects syntax, so that it can run on every
+// dependency without building SSA for it.
+var closerAnalyzer = &analysis.Analyzer{
+	Name:       "spannerclosecheckclosers",
+	Doc:        "collect functions closing their parameters",
+	Run:        runClosers,
+	FactTypes:  []analysis.Fact{new(closesParamFact)},
+	ResultType: reflect.TypeOf(map[*types.Func][]closedParam(nil)),
+}
+
+func runClosers(pass *analysis.Pass) (interface{}, error) {
+	closers := make(map[*types.Func][]closedParam)
+	for _, f := range pass.AllObjectFacts() {
+		if fn, ok := f.Object.(*types.Func); ok {
+			closers[fn] = f.Fact.(*closesParamFact).Params
+		}
+	}
+
+	var decls []*ast.FuncDecl
+	for _, file := range pass.Files {
+		for _, decl := range file.Decls {
+			if fd, ok := decl.(*ast.FuncDecl); ok && fd.Body != nil {
+				decls = append(decls, fd)
+			}
+		}
+	}
+
+	// Revisit functions until helpers calling other helpers are settled
+	for range maxCloserRounds {
+		changed := false
+		for _, fd := range decls {
+			fn, ok := pass.TypesInfo.Defs[fd.Name].(*types.Func)
+			if !ok {
+				continue
+			}
+			directed, _ := closesDirectiveParams(pass, fd)
+			params := slices.Concat(directed, paramCloses(pass, fd, closers))
+			if len(params) > len(closers[fn]) {
+				closers[fn] = params
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+
+	for fn, params := range closers {
+		if fn.Pkg() == pass.Pkg {
+			pass.ExportObjectFact(fn, &closesParamFact{Params: params})
+		}
+	}
+	return closers, nil
+}
+
+// paramCloses returns the parameters of fd its body closes: with
+// p.Close(), by passing p to a function closing it, such as closeTxn(p), or
+// within deferred function literals. Closes in a defer, or in a callee that
+// defers them, are marked Deferred.
+func paramCloses(pass *analysis.Pass, fd *ast.FuncDecl, closers map[*types.Func][]closedParam) []closedParam {
+	paramIndex := paramIndexes(pass.TypesInfo, fd)
+	if len(paramIndex) == 0 {
+		return nil
+	}
+	param := func(e ast.Expr) (int, bool) {
+		id, ok := ast.Unparen(e).(*ast.Ident)
+		if !ok {
+			return 0, false
+		}
+		index, ok := paramIndex[pass.TypesInfo.Uses[id]]
+		return index, ok
+	}
+
+	var params []closedParam
+	add := func(p closedParam) {
+		if !slices.Contains(params, p) {
+			params = append(params, p)
+		}
+	}
+	// closes records the parameters closed by call
+	closes := func(call *ast.CallExpr, deferred bool) {
+		// p.Close()
+		if sel, ok := call.Fun.(*ast.SelectorExpr); ok && isCloseLike(pass.TypesInfo, sel) {
+			if index, ok := param(sel.X); ok {
+				add(closedParam{Index: index, Method: sel.Sel.Name, Deferred: deferred})
+			}
+		}
+		// closeTxn(p)
+		callee := typeutil.StaticCallee(pass.TypesInfo, call)
+		if callee == nil {
+			return
+		}
+		for _, closed := range closers[callee.Origin()] {
+			if closed.Index >= len(call.Args) {
+				continue
+			}
+			if index, ok := param(call.Args[closed.Index]); ok {
+				add(closedParam{Index: index, Method: closed.Method, Deferred: deferred || closed.Deferred})
+			}
+		}
+	}
+
+	var inspect func(body ast.Node, deferred bool)
+	inspect = func(body ast.Node, deferred bool) {
+		ast.Inspect(body, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.FuncLit:
+				// Function literals that are not deferred run at another time
+				return false
+			case *ast.DeferStmt:
+				if lit, ok := n.Call.Fun.(*ast.FuncLit); ok {
+					inspect(lit.Body, true)
+				} else {
+					closes(n.Call, true)
+				}
+				return false
+			case *ast.CallExpr:
+				closes(n, deferred)
+			}
+			return true
+		})
+	}
+	inspect(fd.Body, false)
+
+	return params
+}
+
+// paramIndexes maps the parameters of fd to their index in its signature
+func paramIndexes(info *types.Info, fd *ast.FuncDecl) map[types.Object]int {
+	paramIndex := make(map[types.Object]int)
+	i := 0
+	for _, field := range fd.Type.Params.List {
+		for _, name := range field.Names {
+			if obj := info.Defs[name]; obj != nil {
+				paramIndex[obj] = i
+			}
+			i++
+		}
+		if len(field.Names) == 0 {
+			i++
+		}
+	}
+	return paramIndex
+}
+
+// closesDirectiveParams returns the parameters fd declares to close with a
+// directiveCloses comment, which callers may pass resources to without a
+// defer, and the names matching no parameter
+func closesDirectiveParams(pass *analysis.Pass, fd *ast.FuncDecl) ([]closedParam, []string) {
+	names, _, ok := findDirective(fd.Doc, directiveCloses)
+	if !ok {
+		return nil, nil
+	}
+
+	var params []closedParam
+	var unknown []string
+	for _, name := range names {
+		found := false
+		for obj, index := range paramIndexes(pass.TypesInfo, fd) {
+			if obj.Name() == name {
+				// Any close method: the function declares ownership
+				params = append(params, closedParam{Index: index, Deferred: true})
+				found = true
+			}
+		}
+		if !found {
+			unknown = append(unknown, name)
+		}
+	}
+	return params, unknown
+}
+
+// checkClosesDirectives reports directiveCloses comments naming no parameter
+func checkClosesDirectives(pass *analysis.Pass) {
+	for _, file := range pass.Files {
+		for _, decl := range file.Decls {
+			fd, ok := decl.(*ast.FuncDecl)
+			if !ok {
+				continue
+			}
+			_, pos, _ := findDirective(fd.Doc, directiveCloses)
+			_, unknown := closesDirectiveParams(pass, fd)
+			for _, name := range unknown {
+				reportf(pass, pos, categoryDirective, "%s has no parameter %s to close", fd.Name.Name, name)
+			}
+		}
+	}
+}
+
+// isCloseLike checks if sel selects a method that could close a resource:
+// one without parameters but a context, returning nothing or an error, like
+// Close(), Stop() or CloseWithContext(ctx)
+func isCloseLike(info *types.Info, sel *ast.SelectorExpr) bool {
+	selection, ok := info.Selections[sel]
+	if !ok || selection.Kind() != types.MethodVal {
+		return false
+	}
+	sig := selection.Type().(*types.Signature)
+	if sig.Params().Len() > 1 || sig.Params().Len() == 1 && !isContext(sig.Params().At(0).Type()) {
+		return false
+	}
+	results := sig.Results()
+	return results.Len() == 0 || results.Len() == 1 && types.Identical(results.At(0).Type(), types.Universe.Lookup("error").Type())
+}
+
+// isClosedByCallee checks if val is passed to a function that defers closing
+// it, according to the facts of closerAnalyzer
+func isClosedByCallee(pass *analysis.Pass, val ssa.Value, rt *ResourceType) bool {
+	if val.Referrers() == nil {
+		return false
+	}
+
+	for _, ref := range *val.Referrers() {
+		if call, ok := ref.(*ssa.Call); ok && calleeClosesArg(pass, call.Common(), val, rt) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// calleeClosesArg checks if common passes val to a function that defers
+// closing it
+func calleeClosesArg(pass *analysis.Pass, common *ssa.CallCommon, val ssa.Value, rt *ResourceType) bool {
+	callee := common.StaticCallee()
+	if callee == nil {
+		return false
+	}
+	obj, ok := callee.Object().(*types.Func)
+	if !ok {
+		return false
+	}
+	closers := pass.ResultOf[closerAnalyzer].(map[*types.Func][]closedParam)
+
+	// Static method calls pass the receiver as the first argument
+	offset := 0
+	if callee.Signature.Recv() != nil {
+		offset = 1
+	}
+	for _, closed := range closers[obj.Origin()] {
+		i := closed.Index + offset
+		if closed.Deferred && (closed.Method == "" || rt.isCloseMethod(closed.Method)) && i < len(common.Args) && common.Args[i] == val {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isContext checks if t is context.Context
+func isContext(t types.Type) bool {
+	named, ok := t.(*types.Named)
+	return ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == "context" && named.Obj().Name() == "Context"
+}
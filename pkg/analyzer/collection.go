@@ -0,0 +1,225 @@
+package analyzer
+
+import (
+	"go/token"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ssa"
+)
+
+// collectionMessage reports a resource stored into a slice or map whose
+// elements are not all closed with a defer
+const collectionMessage = "%s.%s() must be deferred for every element of the collection"
+
+// collectionStore is a store of a resource into a slice, array or map
+type collectionStore struct {
+	pos token.Pos
+	// colls are the values the collection is followed from
+	colls []ssa.Value
+}
+
+// collectionStores returns the stores of val into collections:
+// iters = append(iters, iter), iters[i] = iter or m[key] = iter.
+// Storing a resource transfers the close obligation to the collection.
+func collectionStores(val ssa.Value) []collectionStore {
+	if val.Referrers() == nil {
+		return nil
+	}
+
+	var stores []collectionStore
+	for _, ref := range *val.Referrers() {
+		switch ref := ref.(type) {
+		case *ssa.Store:
+			ia, ok := ref.Addr.(*ssa.IndexAddr)
+			if !ok || ref.Val != val {
+				continue
+			}
+			pos := ref.Pos()
+			if call := appendCall(ia.X); call != nil {
+				// The elements passed to append are stored into an implicit array
+				pos = call.Pos()
+			}
+			stores = append(stores, collectionStore{pos: pos, colls: collectionRoots(ia.X)})
+		case *ssa.MapUpdate:
+			if ref.Value == val {
+				stores = append(stores, collectionStore{pos: ref.Pos(), colls: collectionRoots(ref.Map)})
+			}
+		}
+	}
+
+	return stores
+}
+
+// appendCall returns the call to append taking the elements stored in the
+// array arr, or nil
+func appendCall(arr ssa.Value) *ssa.Call {
+	if _, ok := arr.(*ssa.Alloc); !ok || arr.Referrers() == nil {
+		return nil
+	}
+	for _, ref := range *arr.Referrers() {
+		slice, ok := ref.(*ssa.Slice)
+		if !ok || slice.Referrers() == nil {
+			continue
+		}
+		for _, sliceRef := range *slice.Referrers() {
+			if call, ok := sliceRef.(*ssa.Call); ok && isAppend(call.Common()) {
+				return call
+			}
+		}
+	}
+	return nil
+}
+
+// isAppend checks if common calls the append builtin
+func isAppend(common *ssa.CallCommon) bool {
+	builtin, ok := common.Value.(*ssa.Builtin)
+	return ok && builtin.Name() == "append"
+}
+
+// collectionRoots returns the values to follow the collection coll from:
+// coll, and the variable it is loaded from
+func collectionRoots(coll ssa.Value) []ssa.Value {
+	if load, ok := coll.(*ssa.UnOp); ok && load.Op == token.MUL {
+		if alloc, ok := load.X.(*ssa.Alloc); ok {
+			return []ssa.Value{coll, alloc}
+		}
+	}
+	return []ssa.Value{coll}
+}
+
+// checkCollectionStores reports the stores of a resource into collections
+// whose elements are not all closed with a defer
+func checkCollectionStores(pass *analysis.Pass, stores []collectionStore, rt *ResourceType) {
+	for _, store := range stores {
+		if isCollectionClosed(store.colls, rt) {
+			continue
+		}
+		reportf(pass, store.pos, categoryCollection, collectionMessage, rt.QualifiedName(), rt.CloseMethod)
+	}
+}
+
+// isCollectionClosed checks if the elements of the collection are closed with
+// a defer: in a deferred closure ranging over it,
+//
+//	defer func() {
+//		for _, iter := range iters {
+//			iter.Stop()
+//		}
+//	}()
+//
+// or one by one, as in for _, iter := range iters { defer iter.Stop() }
+func isCollectionClosed(colls []ssa.Value, rt *ResourceType) bool {
+	values := make(map[ssa.Value]bool)
+	for _, coll := range colls {
+		collectionValues(coll, false, values)
+	}
+
+	for coll, deferred := range values {
+		for _, elem := range collectionElements(coll) {
+			if findDeferredClose(elem, rt) != nil || deferred && closesValue(elem, rt, 0) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// collectionValues adds the values holding the collection v to values:
+// the results of appending to it, loop phis, and the cell of the variable
+// holding it with its loads, including those of closures capturing it.
+// Values within deferred closures are marked true.
+func collectionValues(v ssa.Value, deferred bool, values map[ssa.Value]bool) {
+	if _, ok := values[v]; ok || v.Referrers() == nil {
+		return
+	}
+	values[v] = deferred
+
+	for _, ref := range *v.Referrers() {
+		switch ref := ref.(type) {
+		case *ssa.Phi:
+			collectionValues(ref, deferred, values)
+		case *ssa.Slice:
+			collectionValues(ref, deferred, values)
+		case *ssa.Call:
+			if isAppend(ref.Common()) {
+				collectionValues(ref, deferred, values)
+			}
+		case *ssa.Store:
+			if alloc, ok := ref.Addr.(*ssa.Alloc); ok && ref.Val == v {
+				collectionValues(alloc, deferred, values)
+			}
+		case *ssa.UnOp:
+			if ref.Op == token.MUL {
+				collectionValues(ref, deferred, values)
+			}
+		case *ssa.MakeClosure:
+			fn, ok := ref.Fn.(*ssa.Function)
+			if !ok {
+				continue
+			}
+			for i, binding := range ref.Bindings {
+				if binding == v && i < len(fn.FreeVars) {
+					collectionValues(fn.FreeVars[i], deferred || closureDefer(ref) != nil, values)
+				}
+			}
+		}
+	}
+}
+
+// isCollectionElement checks if val is read from a slice, array or map by
+// indexing it, a lookup or a range loop
+func isCollectionElement(val ssa.Value) bool {
+	if extract, ok := val.(*ssa.Extract); ok {
+		val = extract.Tuple
+	}
+	switch val.(type) {
+	case *ssa.Index, *ssa.Lookup, *ssa.Next:
+		return true
+	}
+	return false
+}
+
+// collectionElements returns the elements read from the slice, array or map
+// coll: iters[i], and the values of range loops and lookups
+func collectionElements(coll ssa.Value) []ssa.Value {
+	var elems []ssa.Value
+	for _, ref := range *coll.Referrers() {
+		switch ref := ref.(type) {
+		case *ssa.IndexAddr:
+			if ref.X != coll || ref.Referrers() == nil {
+				continue
+			}
+			for _, addrRef := range *ref.Referrers() {
+				if load, ok := addrRef.(*ssa.UnOp); ok && load.Op == token.MUL {
+					elems = append(elems, load)
+				}
+			}
+		case *ssa.Index:
+			if ref.X == coll {
+				elems = append(elems, ref)
+			}
+		case *ssa.Lookup:
+			if ref.X != coll {
+				continue
+			}
+			if !ref.CommaOk {
+				elems = append(elems, ref)
+			} else if elem := resultExtract(ref, 0); elem != nil {
+				elems = append(elems, elem)
+			}
+		case *ssa.Range:
+			if ref.X != coll || ref.Referrers() == nil {
+				continue
+			}
+			for _, rangeRef := range *ref.Referrers() {
+				if next, ok := rangeRef.(*ssa.Next); ok {
+					if elem := resultExtract(next, 2); elem != nil {
+						elems = append(elems, elem)
+					}
+				}
+			}
+		}
+	}
+	return elems
+}
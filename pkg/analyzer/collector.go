@@ -0,0 +1,114 @@
+package analyzer
+
+import (
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// isAddedToClosedCollector checks if val is added to a collector of
+// opts.Collectors whose close method is deferred, as in
+//
+//	defer closers.CloseAll()
+//	closers.Add(txn)
+//
+// The resource may be added itself, as an interface or as a method value
+// such as closers.Add(txn.Close).
+func isAddedToClosedCollector(val ssa.Value, opts *Options) bool {
+	if len(opts.Collectors) == 0 || val.Referrers() == nil {
+		return false
+	}
+
+	for _, ref := range *val.Referrers() {
+		args := []ssa.Value{val}
+		switch ref := ref.(type) {
+		case *ssa.MakeInterface, *ssa.MakeClosure:
+			args = append(args, ref.(ssa.Value))
+		}
+		for _, arg := range args {
+			if arg.Referrers() == nil {
+				continue
+			}
+			for _, argRef := range *arg.Referrers() {
+				call, ok := argRef.(*ssa.Call)
+				if !ok {
+					continue
+				}
+				if c, recv := collectorAdd(call.Common(), arg, opts); c != nil && isCollectorClosed(recv, c) {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// collectorAdd returns the collector common adds arg to with its add method,
+// and the collector it is called on, or nil
+func collectorAdd(common *ssa.CallCommon, arg ssa.Value, opts *Options) (*Collector, ssa.Value) {
+	callee := common.StaticCallee()
+	if callee == nil || callee.Signature.Recv() == nil || len(common.Args) < 2 {
+		return nil, nil
+	}
+	c := collectorOf(callee, opts)
+	if c == nil || callee.Name() != c.AddMethod {
+		return nil, nil
+	}
+	for _, a := range common.Args[1:] {
+		if a == arg {
+			return c, common.Args[0]
+		}
+	}
+	return nil, nil
+}
+
+// collectorOf returns the collector declaring the method fn, or nil
+func collectorOf(fn *ssa.Function, opts *Options) *Collector {
+	named, ok := derefType(fn.Signature.Recv().Type()).(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return nil
+	}
+	for i := range opts.Collectors {
+		c := &opts.Collectors[i]
+		if named.Obj().Name() == c.Name && matchesPkgPath(named.Obj().Pkg().Path(), c.PkgPath) {
+			return c
+		}
+	}
+	return nil
+}
+
+// isCollectorClosed checks if the close method of c is deferred on recv, or
+// on the variable recv is loaded from
+func isCollectorClosed(recv ssa.Value, c *Collector) bool {
+	recvs := []ssa.Value{recv}
+	if load, ok := recv.(*ssa.UnOp); ok && load.Op == token.MUL {
+		recvs = append(recvs, load.X)
+		if load.X.Referrers() != nil {
+			for _, ref := range *load.X.Referrers() {
+				if other, ok := ref.(*ssa.UnOp); ok && other.Op == token.MUL && other != load {
+					recvs = append(recvs, other)
+				}
+			}
+		}
+	}
+
+	for _, r := range recvs {
+		if r.Referrers() == nil {
+			continue
+		}
+		for _, ref := range *r.Referrers() {
+			d, ok := ref.(*ssa.Defer)
+			if !ok {
+				continue
+			}
+			callee := d.Call.StaticCallee()
+			if callee != nil && callee.Name() == c.CloseMethod && len(d.Call.Args) > 0 && d.Call.Args[0] == r {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,56 @@
+package analyzer
+
+import (
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ssa"
+)
+
+// checkConditionalDefers reports deferred closes of val that do not run on
+// every path from the acquisition, such as a defer in one branch of an if:
+//
+//	txn := client.ReadOnlyTransaction()
+//	if debug {
+//		defer txn.Close()
+//	}
+//
+// Defers in every branch together cover the acquisition, as do defers of a
+// closure reading the variable registered before the acquisition. In lenient
+// mode, non-deferred closes also count.
+func checkConditionalDefers(pass *analysis.Pass, fn *ssa.Function, val ssa.Value, rt *ResourceType, deferClose *ssa.Defer, opts *Options) {
+	// Phis merge values acquired on their own
+	acq, ok := val.(ssa.Instruction)
+	if _, isPhi := val.(*ssa.Phi); !ok || isPhi {
+		return
+	}
+
+	defers := findDeferredCloses(val, rt)
+	if len(defers) == 0 {
+		defers = []*ssa.Defer{deferClose}
+	}
+	var instrs []ssa.Instruction
+	for _, d := range defers {
+		// Defers of other functions, e.g. the one declaring a captured
+		// variable, or registered before the acquisition always run
+		if d.Parent() != fn || dominates(d, acq) {
+			return
+		}
+		instrs = append(instrs, d)
+	}
+	if opts.Lenient {
+		instrs = append(instrs, nonDeferredCloses(val, rt)...)
+	}
+	if runsOnAllPaths(acq, val, instrs) {
+		return
+	}
+
+	pos := acquisitionPos(val)
+	reportAcquired(pass, pos, analysis.Diagnostic{
+		Pos:      deferClose.Pos(),
+		Category: categoryConditional,
+		Message:  rt.QualifiedName() + "." + rt.CloseMethod + "() must be deferred on every path from the acquisition",
+		Related: []analysis.RelatedInformation{{
+			Pos:     pos,
+			Message: "resource acquired here",
+		}},
+	})
+}
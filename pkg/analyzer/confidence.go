@@ -0,0 +1,96 @@
+package analyzer
+
+import (
+	"fmt"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// Confidence is how likely a report is a real leak or misuse. The core
+// checks of closes are precise, while the heuristics following resources
+// through goroutines and collections are noisier.
+type Confidence int
+
+const (
+	ConfidenceLow Confidence = iota + 1
+	ConfidenceMedium
+	ConfidenceHigh
+)
+
+// categoryConfidence is the confidence of the reports of each category below
+// ConfidenceHigh. Other categories, including those of registered checkers,
+// have ConfidenceHigh.
+var categoryConfidence = map[string]Confidence{
+	categoryGoroutineEscape: ConfidenceLow,
+	categoryCollection:      ConfidenceLow,
+	categoryForeignClose:    ConfidenceLow,
+	categoryCloseBeforeJoin: ConfidenceLow,
+	categoryWrapperField:    ConfidenceMedium,
+	categoryConditional:     ConfidenceMedium,
+	categoryDeferOrder:      ConfidenceMedium,
+	categoryLoopVar:         ConfidenceMedium,
+	categoryBorrowed:        ConfidenceMedium,
+	categoryGapicStream:     ConfidenceMedium,
+	categoryStreamCancel:    ConfidenceMedium,
+	categoryClient:          ConfidenceMedium,
+	categoryPackageClient:   ConfidenceMedium,
+	categoryCloseError:      ConfidenceMedium,
+	categorySingleUse:       ConfidenceMedium,
+}
+
+// ConfidenceOf returns the confidence of d, a diagnostic of the analyzers,
+// by its category
+func ConfidenceOf(d analysis.Diagnostic) Confidence {
+	if c, ok := categoryConfidence[d.Category]; ok {
+		return c
+	}
+	return ConfidenceHigh
+}
+
+// orDefault returns c, or def if c is unset
+func (c Confidence) orDefault(def Confidence) Confidence {
+	if c == 0 {
+		return def
+	}
+	return c
+}
+
+func (c Confidence) String() string {
+	switch c {
+	case 0, ConfidenceLow:
+		// Unset confidences default to low
+		return "low"
+	case ConfidenceMedium:
+		return "medium"
+	case ConfidenceHigh:
+		return "high"
+	}
+	return ""
+}
+
+func (c *Confidence) Set(value string) error {
+	for _, confidence := range []Confidence{ConfidenceLow, ConfidenceMedium, ConfidenceHigh} {
+		if value == confidence.String() {
+			*c = confidence
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid confidence %q: want low, medium or high", value)
+}
+
+// reportConfident returns a report function dropping the diagnostics of
+// pass of a lower confidence than min, and marking the others with their
+// confidence unless it is ConfidenceHigh
+func reportConfident(pass *analysis.Pass, report func(analysis.Diagnostic), min Confidence) func(analysis.Diagnostic) {
+	return func(d analysis.Diagnostic) {
+		confidence := ConfidenceOf(d)
+		if confidence < min {
+			suppress(pass, d, SuppressionMinConfidence, "confidence %s is below -min-confidence %s", confidence, min)
+			return
+		}
+		if confidence < ConfidenceHigh {
+			d.Message += fmt.Sprintf(" [confidence: %s]", confidence)
+		}
+		report(d)
+	}
+}
@@ -0,0 +1,349 @@
+package analyzer
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// DefaultConfigFile is the configuration file Analyzer looks for in the
+// working directory and its parents
+const DefaultConfigFile = ".spannerclosecheck.yaml"
+
+// EnvPrefix prefixes the environment variables setting options, named after
+// their flag, as in SPANNERCLOSECHECK_MIN_CONFIDENCE for -min-confidence
+const EnvPrefix = "SPANNERCLOSECHECK_"
+
+// configEntry is an option set by a configuration file, with the values of
+// its flag, several for repeatable flags
+type configEntry struct {
+	key    string
+	values []string
+	// list is set for values given as a list, which only repeatable flags
+	// take
+	list bool
+	line int
+}
+
+// configs are the configuration files applied to each Options, once for all
+// the analyzers sharing them
+var configs = struct {
+	sync.Mutex
+	m map[*Options]*configLoad
+}{m: make(map[*Options]*configLoad)}
+
+// configLoad is the application of the configuration file of an Options
+type configLoad struct {
+	// flags are the flag sets of the analyzers created with the options
+	flags []*flag.FlagSet
+	once  sync.Once
+	err   error
+}
+
+// configFor returns the configuration of opts
+func configFor(opts *Options) *configLoad {
+	configs.Lock()
+	defer configs.Unlock()
+	load, ok := configs.m[opts]
+	if !ok {
+		load = &configLoad{}
+		configs.m[opts] = load
+	}
+	return load
+}
+
+// bindConfig adds fs, the flags of an analyzer created with opts, to those
+// applyConfig sets
+func bindConfig(fs *flag.FlagSet, opts *Options) {
+	load := configFor(opts)
+	configs.Lock()
+	defer configs.Unlock()
+	load.flags = append(load.flags, fs)
+}
+
+// applyConfig sets the options of the environment and of the configuration
+// file of opts on the flags of the analyzers created with them, once for
+// every analyzer sharing opts. Flags set on the command line of any of them
+// take precedence over the environment, which takes precedence over the
+// file.
+func applyConfig(opts *Options) error {
+	load := configFor(opts)
+	load.once.Do(func() {
+		configs.Lock()
+		fs, set := mergeFlags(load.flags)
+		configs.Unlock()
+
+		if load.err = setEnv(fs, set, os.Environ()); load.err != nil || opts.Config == "" {
+			return
+		}
+		path, err := findConfig(opts.Config)
+		if err != nil || path == "" {
+			load.err = err
+			return
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			load.err = err
+			return
+		}
+		entries, err := parseConfig(data)
+		if err != nil {
+			load.err = fmt.Errorf("%s: %v", path, err)
+			return
+		}
+		load.err = setConfig(fs, set, entries, path)
+	})
+	return load.err
+}
+
+// mergeFlags returns a flag set with the flags of sets, which share their
+// values when they share a name, and the names of those already set
+func mergeFlags(sets []*flag.FlagSet) (*flag.FlagSet, map[string]bool) {
+	merged := flag.NewFlagSet("config", flag.ContinueOnError)
+	set := make(map[string]bool)
+	for _, fs := range sets {
+		fs.VisitAll(func(f *flag.Flag) {
+			if merged.Lookup(f.Name) == nil {
+				merged.Var(f.Value, f.Name, f.Usage)
+			}
+		})
+		fs.Visit(func(f *flag.Flag) {
+			set[f.Name] = true
+		})
+	}
+	return merged, set
+}
+
+// findConfig returns the path of the configuration file name. The default
+// file is looked for in the working directory and its parents, and is
+// optional; other files must exist.
+func findConfig(name string) (string, error) {
+	if name != DefaultConfigFile {
+		if _, err := os.Stat(name); err != nil {
+			return "", fmt.Errorf("config: %v", err)
+		}
+		return name, nil
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		for _, file := range []string{DefaultConfigFile, strings.TrimSuffix(DefaultConfigFile, ".yaml") + ".yml"} {
+			path := filepath.Join(dir, file)
+			if _, err := os.Stat(path); err == nil {
+				return path, nil
+			} else if !errors.Is(err, fs.ErrNotExist) {
+				return "", fmt.Errorf("config: %v", err)
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// setEnv sets the flags of the EnvPrefix variables of environ on fs, but
+// those already set, and adds them to set. Repeatable flags take a single
+// value.
+func setEnv(fs *flag.FlagSet, set map[string]bool, environ []string) error {
+	for _, kv := range environ {
+		name, value, _ := strings.Cut(kv, "=")
+		key, ok := strings.CutPrefix(name, EnvPrefix)
+		if !ok {
+			continue
+		}
+		key = strings.ReplaceAll(strings.ToLower(key), "_", "-")
+		if fs.Lookup(key) == nil {
+			return fmt.Errorf("%s: unknown option %q", name, key)
+		}
+		if set[key] {
+			continue
+		}
+		if err := fs.Set(key, value); err != nil {
+			return fmt.Errorf("%s: %s: %v", name, key, optionError(fs.Lookup(key), value, err))
+		}
+		set[key] = true
+	}
+	return nil
+}
+
+// setConfig sets the flags of entries on fs, but those in set
+func setConfig(fs *flag.FlagSet, set map[string]bool, entries []configEntry, path string) error {
+	for _, e := range entries {
+		if e.key == "config" || fs.Lookup(e.key) == nil {
+			return fmt.Errorf("%s:%d: unknown option %q", path, e.line, e.key)
+		}
+		f := fs.Lookup(e.key)
+		if e.list && !isRepeatable(f) {
+			if len(e.values) == 0 {
+				return fmt.Errorf("%s:%d: %s: missing value, want %s", path, e.line, e.key, optionType(f))
+			}
+			return fmt.Errorf("%s:%d: %s: got a list, want a single %s", path, e.line, e.key, optionType(f))
+		}
+		if set[e.key] {
+			continue
+		}
+		for _, v := range e.values {
+			if err := fs.Set(e.key, v); err != nil {
+				return fmt.Errorf("%s:%d: %s: %v", path, e.line, e.key, optionError(f, v, err))
+			}
+		}
+	}
+	return nil
+}
+
+// parseConfig parses a configuration file, in the subset of YAML mapping
+// flag names to scalars or lists of scalars:
+//
+//	lenient: true
+//	single-close: warning
+//	resource:
+//	  - example.com/ourdb.Txn:Release:acquire=Begin
+//	exempt-constructor: [OneShotTxn, CachedTxn]
+func parseConfig(data []byte) ([]configEntry, error) {
+	var entries []configEntry
+	// block is the entry a block list continues, or -1
+	block := -1
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := stripComment(scanner.Text())
+		if strings.TrimSpace(line) == "" || strings.TrimSpace(line) == "---" {
+			continue
+		}
+
+		// List items of the previous key
+		if item, ok := strings.CutPrefix(strings.TrimSpace(line), "- "); ok && line[0] == ' ' {
+			if block < 0 {
+				return nil, fmt.Errorf("line %d: list item without a key", n)
+			}
+			value, err := unquote(item)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", n, err)
+			}
+			entries[block].values = append(entries[block].values, value)
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok || key != strings.TrimSpace(key) || key == "" {
+			return nil, fmt.Errorf("line %d: want key: value", n)
+		}
+		for _, e := range entries {
+			if e.key == key {
+				return nil, fmt.Errorf("line %d: duplicate option %q", n, key)
+			}
+		}
+		e := configEntry{key: key, line: n}
+		block = -1
+		switch value = strings.TrimSpace(value); {
+		case value == "":
+			// A block list follows
+			block = len(entries)
+			e.list = true
+		case strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]"):
+			e.list = true
+			for _, item := range strings.Split(strings.TrimSuffix(strings.TrimPrefix(value, "["), "]"), ",") {
+				if item = strings.TrimSpace(item); item == "" {
+					continue
+				}
+				item, err := unquote(item)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %v", n, err)
+				}
+				e.values = append(e.values, item)
+			}
+		default:
+			value, err := unquote(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", n, err)
+			}
+			e.values = []string{value}
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// WriteConfig writes a configuration file setting every flag of fs to its
+// default, each commented with its usage. Flags without a default, such as
+// repeatable ones, are commented out, and flags sharing their option with an
+// earlier one, such as -skip-generated and -include-generated, left out.
+func WriteConfig(w io.Writer, fs *flag.FlagSet) error {
+	written := make(map[uintptr]bool)
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# %s configures spannerclosecheck for this directory and its\n", DefaultConfigFile)
+	fmt.Fprintf(&b, "# subdirectories. Keys are flag names; flags set on the command line and\n")
+	fmt.Fprintf(&b, "# %s* environment variables take precedence.\n", EnvPrefix)
+	fs.VisitAll(func(f *flag.Flag) {
+		if f.Name == "config" {
+			return
+		}
+		if v := reflect.ValueOf(f.Value); v.Kind() == reflect.Pointer {
+			if written[v.Pointer()] {
+				return
+			}
+			written[v.Pointer()] = true
+		}
+		fmt.Fprintf(&b, "\n# %s\n", f.Usage)
+		if f.DefValue == "" {
+			if isRepeatable(f) {
+				fmt.Fprintf(&b, "# %s: []\n", f.Name)
+			} else {
+				fmt.Fprintf(&b, "# %s: \"\"\n", f.Name)
+			}
+			return
+		}
+		fmt.Fprintf(&b, "%s: %s\n", f.Name, quoteConfig(f.DefValue))
+	})
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// quoteConfig quotes value if parseConfig would not read it back as is
+func quoteConfig(value string) string {
+	if strings.ContainsAny(value, "#:,'\"[]") || strings.TrimSpace(value) != value {
+		return `"` + value + `"`
+	}
+	return value
+}
+
+// stripComment removes a comment starting with # outside quotes from line
+func stripComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return strings.TrimRight(line[:i], " \t")
+		}
+	}
+	return strings.TrimRight(line, " \t")
+}
+
+// unquote returns s without its single or double quotes
+func unquote(s string) (string, error) {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') {
+		if s[len(s)-1] != s[0] {
+			return "", fmt.Errorf("unterminated string %s", s)
+		}
+		return s[1 : len(s)-1], nil
+	}
+	return s, nil
+}
@@ -0,0 +1,107 @@
+package analyzer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The configuration is applied before packages are analyzed, so these tests
+// call applyConfig directly instead of loading packages with analysistest
+
+// newConfigOptions returns the options of a new analyzer reading the
+// configuration file with content, or no file if content is empty
+func newConfigOptions(t *testing.T, content string) *Options {
+	t.Helper()
+	opts := &Options{}
+	if content != "" {
+		opts.Config = filepath.Join(t.TempDir(), DefaultConfigFile)
+		if err := os.WriteFile(opts.Config, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	NewAnalyzer(opts)
+	return opts
+}
+
+func TestApplyConfigInvalid(t *testing.T) {
+	for content, want := range map[string]string{
+		"lenient true\n":                  "line 1: want key: value",
+		"  - OneShotTxn\n":                "line 1: list item without a key",
+		"lenient: true\nlenient: false\n": `line 2: duplicate option "lenient"`,
+		"strict: true\n":                  `:1: unknown option "strict"`,
+		"single-close: loud\n":            `:1: single-close: invalid severity "loud"`,
+		"exempt-constructor: 'A\n":        "line 1: unterminated string 'A",
+		"lenient: yes\n":                  `:1: lenient: invalid value "yes", want true or false`,
+		"defer-within: soon\n":            `:1: defer-within: invalid value "soon", want an integer`,
+		"lenient: [true]\n":               ":1: lenient: got a list, want a single boolean",
+		"single-close:\n  - off\n":        ":1: single-close: got a list, want a single value",
+		"lenient:\n":                      ":1: lenient: missing value, want boolean",
+	} {
+		opts := newConfigOptions(t, content)
+		if err := applyConfig(opts); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: got error %v, want %q", content, err, want)
+		}
+	}
+
+	opts := &Options{Config: filepath.Join(t.TempDir(), "missing.yaml")}
+	NewAnalyzer(opts)
+	if err := applyConfig(opts); err == nil {
+		t.Error("got no error for a missing configuration file")
+	}
+}
+
+func TestApplyEnvInvalid(t *testing.T) {
+	for name, want := range map[string]string{
+		"SPANNERCLOSECHECK_STRICT":         `SPANNERCLOSECHECK_STRICT: unknown option "strict"`,
+		"SPANNERCLOSECHECK_MIN_CONFIDENCE": `SPANNERCLOSECHECK_MIN_CONFIDENCE: min-confidence: `,
+		"SPANNERCLOSECHECK_LENIENT":        `SPANNERCLOSECHECK_LENIENT: lenient: invalid value "loud", want true or false`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(name, "loud")
+			if err := applyConfig(newConfigOptions(t, "")); err == nil || !strings.Contains(err.Error(), want) {
+				t.Errorf("got error %v, want %q", err, want)
+			}
+		})
+	}
+}
+
+func TestApplyEnvPrecedence(t *testing.T) {
+	const config = "duplicate-defers: true\n"
+
+	// Over the configuration file
+	t.Setenv("SPANNERCLOSECHECK_DUPLICATE_DEFERS", "false")
+	opts := newConfigOptions(t, config)
+	if err := applyConfig(opts); err != nil {
+		t.Fatal(err)
+	}
+	if opts.DuplicateDefers {
+		t.Error("SPANNERCLOSECHECK_DUPLICATE_DEFERS=false does not override the configuration file")
+	}
+
+	// Under the command line
+	t.Setenv("SPANNERCLOSECHECK_DUPLICATE_DEFERS", "true")
+	opts = &Options{}
+	a := NewAnalyzer(opts)
+	if err := a.Flags.Set("duplicate-defers", "false"); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyConfig(opts); err != nil {
+		t.Fatal(err)
+	}
+	if opts.DuplicateDefers {
+		t.Error("-duplicate-defers=false does not override SPANNERCLOSECHECK_DUPLICATE_DEFERS")
+	}
+
+	// Selecting the configuration file
+	os.Unsetenv("SPANNERCLOSECHECK_DUPLICATE_DEFERS")
+	t.Setenv("SPANNERCLOSECHECK_CONFIG", newConfigOptions(t, config).Config)
+	opts = newConfigOptions(t, "")
+	if err := applyConfig(opts); err != nil {
+		t.Fatal(err)
+	}
+	if !opts.DuplicateDefers {
+		t.Error("SPANNERCLOSECHECK_CONFIG does not select the configuration file")
+	}
+}
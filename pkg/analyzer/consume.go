@@ -0,0 +1,70 @@
+package analyzer
+
+import (
+	"slices"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// isConsumed checks if val is closed by calling one of the consuming methods
+// of rt on it, as in client.Single().Query(ctx, stmt).Do(f), or by passing it
+// to a consuming function, as in spanner.SelectAll(iter, &rows)
+func isConsumed(val ssa.Value, rt *ResourceType, opts *Options) bool {
+	if val.Referrers() == nil {
+		return false
+	}
+
+	for _, ref := range *val.Referrers() {
+		switch ref := ref.(type) {
+		case ssa.CallInstruction:
+			common := ref.Common()
+			if isMethodCallOn(common, val, rt.ConsumingMethods) || isConsumingCall(common, val, rt, opts) {
+				return true
+			}
+		case *ssa.MakeInterface:
+			// Consuming functions often take an interface, e.g. SelectAll's rowIterator
+			if ref.Referrers() == nil {
+				continue
+			}
+			for _, ifaceRef := range *ref.Referrers() {
+				if call, ok := ifaceRef.(ssa.CallInstruction); ok && isConsumingCall(call.Common(), ref, rt, opts) {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// isConsumingCall checks if common passes arg to a consuming function of rt's
+// package or to one of opts.ConsumingFuncs
+func isConsumingCall(common *ssa.CallCommon, arg ssa.Value, rt *ResourceType, opts *Options) bool {
+	if common.Value == arg || !slices.Contains(common.Args, arg) {
+		return false
+	}
+	if callsOneOf(common, opts.ConsumingFuncs) {
+		return true
+	}
+
+	callee := common.StaticCallee()
+	if callee == nil || callee.Pkg == nil || callee.Signature.Recv() != nil {
+		return false
+	}
+	return matchesPkgPath(callee.Pkg.Pkg.Path(), rt.PkgPath) && slices.Contains(rt.ConsumingFuncs, callee.Name())
+}
+
+// isMethodCallOn checks if common calls one of the named methods on val
+func isMethodCallOn(common *ssa.CallCommon, val ssa.Value, names []string) bool {
+	// Interface method call: val.Do(f)
+	if common.IsInvoke() {
+		return common.Value == val && slices.Contains(names, common.Method.Name())
+	}
+
+	// Concrete method call: (*T).Do(val, f)
+	callee := common.StaticCallee()
+	if callee == nil || callee.Signature.Recv() == nil || len(common.Args) == 0 || common.Args[0] != val {
+		return false
+	}
+	return slices.Contains(names, callee.Name())
+}
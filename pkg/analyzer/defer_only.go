@@ -1,8 +1,11 @@
 package analyzer
 
 import (
+	"fmt"
+	"go/ast"
 	"go/token"
 	"go/types"
+	"slices"
 	"strings"
 
 	"golang.org/x/tools/go/analysis"
@@ -10,225 +13,881 @@ import (
 	"golang.org/x/tools/go/ssa"
 )
 
-func deferOnlyAnalyzer(pass *analysis.Pass) (interface{}, error) {
+func deferOnlyAnalyzer(pass *analysis.Pass, opts *Options, returnsAnalyzer *analysis.Analyzer, groups []Group, registered bool) (interface{}, error) {
 	pssa := pass.ResultOf[buildssa.Analyzer].(*buildssa.SSA)
 
-	// Map to store Spanner types
-	spannerTypes := make(map[*types.Named]string)
+	if slices.Contains(groups, GroupClose) {
+		checkResourceDirectives(pass)
+	}
+	if slices.Contains(groups, GroupOwnership) {
+		checkClosesDirectives(pass)
+	}
+
+	spannerTypes := resourceTypeMap(pass, opts)
+	returns := pass.ResultOf[returnsAnalyzer].(resourceReturns)
+
+	if len(spannerTypes) == 0 {
+		return &Result{Funcs: make(map[*ssa.Function][]*Acquisition)}, nil
+	}
+
+	if opts.WholeProgram {
+		loadWholeProgram()
+	}
 
-	// Find Spanner package and register types
-	for _, pkg := range pssa.Pkg.Prog.AllPackages() {
-		if pkg.Pkg.Path() == pathGoogleSpanner {
-			registerType(pkg, typeNameReadOnlyTransaction, spannerTypes)
-			registerType(pkg, typeNameBatchReadOnlyTransaction, spannerTypes)
-			registerType(pkg, typeNameRowIterator, spannerTypes)
-			break
+	// Clients are checked for closes by functions borrowing them
+	clientTypes := make(map[*types.Named]*ResourceType)
+	for _, pkg := range transitiveImports(pass.Pkg) {
+		for i := range sharedClientTypes {
+			if rt := &sharedClientTypes[i]; matchesPkgPath(pkg.Path(), rt.PkgPath) {
+				registerType(pkg, rt, clientTypes)
+			}
 		}
 	}
 
-	if len(spannerTypes) == 0 {
-		return nil, nil
+	// Package-level checks
+	if slices.Contains(groups, GroupClose) && opts.PackageClients {
+		checkPackageClients(pass, pssa.Pkg, pssa.SrcFuncs, clientTypes, opts)
+	}
+	if slices.Contains(groups, GroupStyle) && opts.SessionPool {
+		checkSessionPoolConfigs(pass)
 	}
 
 	// Check each function
+	resources := &Resources{Types: spannerTypes, Clients: clientTypes, opts: opts, returns: returns}
+	fileResources := make(map[*token.File]*Resources)
+	for file, fo := range fileOptions(pass, opts, slices.Contains(groups, GroupClose)) {
+		fileResources[file] = &Resources{Types: spannerTypes, Clients: clientTypes, opts: fo, returns: returns}
+	}
+	checkers := checkersFor(groups, registered)
 	for _, fn := range pssa.SrcFuncs {
-		checkFunc(pass, fn, spannerTypes)
+		res := resources
+		if fr, ok := fileResources[pass.Fset.File(fn.Pos())]; ok {
+			res = fr
+		}
+		for _, c := range checkers {
+			c.CheckFunc(pass, fn, res)
+		}
+	}
+
+	return inventory(pssa.SrcFuncs, spannerTypes, returns, opts), nil
+}
+
+// resourceTypeMap registers the resource types of the packages visible to the
+// package: Spanner types, along with custom resources from options and
+// directives. Indirect imports are included, as resources may come from
+// factories in other packages without their package being imported directly.
+func resourceTypeMap(pass *analysis.Pass, opts *Options) map[*types.Named]*ResourceType {
+	spannerTypes := make(map[*types.Named]*ResourceType)
+	directives := pass.ResultOf[directiveAnalyzer].(*directives)
+	resourceTypes := slices.DeleteFunc(slices.Concat(spannerResourceTypes, opts.Resources, directives.Resources), func(rt ResourceType) bool {
+		return slices.ContainsFunc(opts.DisabledResources, rt.isNamed)
+	})
+	for _, pkg := range transitiveImports(pass.Pkg) {
+		for i := range resourceTypes {
+			if rt := &resourceTypes[i]; matchesPkgPath(pkg.Path(), rt.PkgPath) {
+				registerType(pkg, rt, spannerTypes)
+			}
+		}
+	}
+	return spannerTypes
+}
+
+// matchesPkgPath checks if path refers to the package want, ignoring vendor
+// directories and major version suffixes, so that vendored copies and
+// e.g. cloud.google.com/go/spanner/v2 are recognized, as well as the mirrors
+// of Options.SpannerPaths
+func matchesPkgPath(path, want string) bool {
+	return normalizePkgPath(path) == normalizePkgPath(want)
+}
+
+// normalizePkgPath strips vendor prefixes and /vN major version elements from
+// path, and maps Spanner mirrors to cloud.google.com/go/spanner
+func normalizePkgPath(path string) string {
+	if i := strings.LastIndex(path, "/vendor/"); i >= 0 {
+		path = path[i+len("/vendor/"):]
 	}
+	path = strings.TrimPrefix(path, "vendor/")
 
-	return nil, nil
+	elems := strings.Split(path, "/")
+	kept := elems[:0]
+	for i, elem := range elems {
+		if i > 0 && isMajorVersion(elem) {
+			continue
+		}
+		kept = append(kept, elem)
+	}
+	return canonicalSpannerPath(strings.Join(kept, "/"))
+}
+
+// isMajorVersion checks if elem is a major version path element such as v2
+func isMajorVersion(elem string) bool {
+	if len(elem) < 2 || elem[0] != 'v' || elem[1] < '1' || elem[1] > '9' {
+		return false
+	}
+	for _, c := range elem[2:] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return elem != "v1"
+}
+
+// transitiveImports returns pkg and all packages it imports, directly or indirectly
+func transitiveImports(pkg *types.Package) []*types.Package {
+	seen := map[*types.Package]bool{pkg: true}
+	pkgs := []*types.Package{pkg}
+	for i := 0; i < len(pkgs); i++ {
+		for _, imp := range pkgs[i].Imports() {
+			if !seen[imp] {
+				seen[imp] = true
+				pkgs = append(pkgs, imp)
+			}
+		}
+	}
+	return pkgs
 }
 
-func registerType(pkg *ssa.Package, name string, spannerTypes map[*types.Named]string) {
-	obj := pkg.Pkg.Scope().Lookup(name)
+func registerType(pkg *types.Package, rt *ResourceType, spannerTypes map[*types.Named]*ResourceType) {
+	obj := pkg.Scope().Lookup(rt.Name)
 	if obj != nil {
 		if named, ok := obj.Type().(*types.Named); ok {
-			spannerTypes[named] = name
+			spannerTypes[named] = rt
 		}
 	}
 }
 
-func checkFunc(pass *analysis.Pass, fn *ssa.Function, spannerTypes map[*types.Named]string) {
+func checkFunc(pass *analysis.Pass, fn *ssa.Function, spannerTypes map[*types.Named]*ResourceType, returns resourceReturns, opts *Options) {
 	if fn == nil {
 		return
 	}
 
-	// Skip generated files (e.g., .yo.go files)
-	if isGeneratedFile(pass, fn.Pos()) {
+	// Skip generated files (e.g., .yo.go files), and functions whose
+	// resources live as long as the process
+	if isGeneratedFile(pass, fn.Pos()) || isExemptFunc(fn, opts) {
 		return
 	}
 
 	// Check all instructions for Spanner resource allocations
 	for _, block := range fn.Blocks {
 		for _, instr := range block.Instrs {
+			if call, ok := instr.(*ssa.Call); ok {
+				checkDroppedTuple(pass, call, spannerTypes, opts)
+			}
+
 			// Check if this instruction produces a Spanner type value
-			if val, ok := instr.(ssa.Value); ok {
-				typeName := getSpannerType(val.Type(), spannerTypes)
-				if typeName != "" {
-					// Only check resource creation instructions, not loads/uses
-					// Skip UnOp (loads from variables) - we only want to check the allocation
-					if _, isUnOp := val.(*ssa.UnOp); isUnOp {
-						continue
-					}
+			if val, rt, ok := resourceValue(fn, instr, spannerTypes); ok {
+				checkResource(pass, fn, val, rt, spannerTypes, returns, opts)
+			}
+		}
+	}
+}
 
-					// Skip ReadOnlyTransaction from Single() - it auto-releases
-					if typeName == typeNameReadOnlyTransaction && isFromSingle(val) {
-						continue
-					}
+// isExemptFunc checks if fn, or the function declaring it, is one of the
+// functions of Options.ExemptFuncs
+func isExemptFunc(fn *ssa.Function, opts *Options) bool {
+	if len(opts.ExemptFuncs) == 0 {
+		return false
+	}
+	for ; fn != nil; fn = fn.Parent() {
+		if obj, ok := fn.Object().(*types.Func); ok && isObjNamed(obj, opts.ExemptFuncs) {
+			return true
+		}
+	}
+	return false
+}
 
-					// Skip RowIterator that's returned from a function - caller is responsible
-					if typeName == typeNameRowIterator && isReturnedFromFunction(fn, val) {
-						continue
-					}
+// resourceValue returns the resource created by instr and its type, as
+// opposed to loads, conversions and parts of other values. The resource may
+// be an acquisition, see checkResource.
+func resourceValue(fn *ssa.Function, instr ssa.Instruction, spannerTypes map[*types.Named]*ResourceType) (ssa.Value, *ResourceType, bool) {
+	val, ok := instr.(ssa.Value)
+	if !ok {
+		return nil, nil, false
+	}
+	rt := getSpannerType(val.Type(), spannerTypes)
+	if rt == nil {
+		return nil, nil, false
+	}
 
-					// Found a Spanner resource - check if it has a deferred Close/Stop
-					if !hasDeferredClose(val) {
-						// Get the position - for Extract, use the tuple call's position
-						pos := val.Pos()
-						if extract, ok := val.(*ssa.Extract); ok {
-							if extract.Tuple != nil {
-								pos = extract.Tuple.Pos()
-							}
-						}
+	// Only check resource creation instructions, not loads/uses
+	// Skip UnOp (loads from variables) - we only want to check the allocation
+	if _, isUnOp := val.(*ssa.UnOp); isUnOp {
+		return nil, nil, false
+	}
 
-						// Check for nolint directive
-						if !hasNolintDirective(pass, pos) {
-							// Use unified error message from error.go
-							if rt, ok := spannerResourceTypes[typeName]; ok {
-								pass.Reportf(pos, "%s", rt.CloseMessage())
-							}
-						}
-					}
-				}
+	// Skip type assertions and type switch cases, which convert an
+	// existing value, e.g. in helpers closing resources passed as any
+	if isTypeAssertion(val) {
+		return nil, nil, false
+	}
+
+	// Skip elements read from slices and maps, which were
+	// acquired when stored into them
+	if isCollectionElement(val) {
+		return nil, nil, false
+	}
+
+	// Wrappers embedding a resource are acquired when a resource is stored
+	// into them, and the wrapper owns it from then on
+	if !isResourceType(val.Type(), rt) {
+		switch val := val.(type) {
+		case *ssa.FieldAddr, *ssa.Field:
+			// Part of another value, not an acquisition
+			return nil, nil, false
+		case *ssa.Alloc:
+			if !isWrapperAcquisition(val, rt) {
+				return nil, nil, false
 			}
 		}
+		if isReturnedFromFunction(fn, val) {
+			return nil, nil, false
+		}
+	} else if isStoredInWrapper(val, rt) {
+		return nil, nil, false
 	}
+	return val, rt, true
 }
 
-// hasDeferredClose checks if a value has a deferred Close() or Stop() method call
-// now only detects the close is called directly for the same variable
-// Cases will be alarmed, even if being closed:
-// Case 1: Variable reassignment before defer. Its not recommended to add unnecessary indirection.
-// Case 2: Stored in struct (an TODO item Add Heuristics for Common Patterns (Future Enhancement))
-// Case 3: Passed to helper function. This is anti-pattern since it violates locality principle.
-// Passing without closing (or closed somewhere inside helper function) is 1. Hard to track ownership, 2. Caller doesn't know if callee closes it, 3. Fragile - callee changes break caller
-// Better to : A.Caller owns and closes or B.Helper creates and manages its own
-func hasDeferredClose(val ssa.Value) bool {
+// checkResource reports val, a value of the resource type rt, if it is an
+// acquisition that is not closed with defer or in another accepted way
+func checkResource(pass *analysis.Pass, fn *ssa.Function, val ssa.Value, rt *ResourceType, spannerTypes map[*types.Named]*ResourceType, returns resourceReturns, opts *Options) {
+	// Skip values not produced by one of the acquiring constructors, or by a
+	// function returning a resource it acquired
+	if !isAcquisition(val, rt, opts) && returns.callResource(val, spannerTypes) == nil &&
+		!(opts.WholeProgram && isReturnedByProgramCallee(val, rt, opts)) {
+		return
+	}
+
+	// Skip exempt constructors, e.g. ReadOnlyTransaction from Single() - it auto-releases
+	if isFromExemptConstructor(val, rt, opts) {
+		return
+	}
+
+	// Skip resources returned from the function, including through named
+	// results - their callers acquire the resource and are checked instead.
+	// Functions also returning a cleanup function hand over the cleanup
+	// instead, which must close the resource, see isReturnedWithCleanup.
+	if isReturnedFromFunction(fn, val) && !returnsCleanup(fn) {
+		return
+	}
+
+	// Skip resources closed by a consuming method or function,
+	// e.g. iter.Do(f) or spanner.SelectAll(iter, &rows)
+	if isConsumed(val, rt, opts) {
+		return
+	}
+
+	// Skip resources passed to functions that defer closing them,
+	// in this package or in imported ones
+	if isClosedByCallee(pass, val, rt) || opts.WholeProgram && isClosedByProgramCallee(val, rt) {
+		return
+	}
+
+	// Skip resources added to a collector closing them with a deferred call
+	if isAddedToClosedCollector(val, opts) {
+		return
+	}
+
+	// Skip resources handed to a goroutine that defers closing them
+	if isClosedInGoroutine(pass, val, rt) {
+		return
+	}
+
+	// Skip resources stored into struct fields declared to own them
+	if isStoredInOwningField(val, pass.ResultOf[directiveAnalyzer].(*directives).OwningFields) {
+		return
+	}
+
+	// Resources returned in a field of a wrapper struct are owned by the
+	// wrapper, which must close them in a method of its own
+	if wrapper, index, ok := returnedWrapperField(fn, val, rt); ok {
+		if !wrapperClosesField(fn.Prog, wrapper, index, rt) {
+			field := wrapper.Underlying().(*types.Struct).Field(index)
+			reportf(pass, acquisitionPos(val), categoryWrapperField, wrapperFieldMessage, rt.QualifiedName(), rt.CloseMethod, wrapper.Obj().Name(), field.Name())
+		}
+		return
+	}
+
+	// Skip resources handed to the caller with a cleanup function closing them
+	if isReturnedWithCleanup(val, rt) {
+		return
+	}
+
+	// Resources received with a cleanup function are closed by deferring it
+	if call, index, ok := returnedCleanup(val, rt); ok {
+		if message, report := checkCleanup(call, index, opts); report {
+			reportf(pass, acquisitionPos(val), categoryCleanup, "%s", message)
+		}
+		return
+	}
+
+	// Resources whose variable is reassigned before they are released leak,
+	// even if the variable is closed later
+	if id := reassignedVar(pass, fn, val, rt); id != nil {
+		reportAcquired(pass, acquisitionPos(val), analysis.Diagnostic{
+			Pos:      id.Pos(),
+			Category: categoryReassigned,
+			Message:  fmt.Sprintf(reassignMessage, rt.QualifiedName(), rt.CloseMethod, id.Name),
+		})
+		return
+	}
+
+	// Found a Spanner resource - check if it has a deferred Close/Stop
+	deferClose := findDeferredClose(val, rt)
+	if deferClose == nil {
+		deferClose = findDeferredHelperClose(val, rt, opts)
+	}
+	if deferClose != nil && !isClosedWithoutDefer(fn, val, rt, opts) {
+		checkConditionalDefers(pass, fn, val, rt, deferClose, opts)
+	}
+	if deferClose != nil {
+		checkDeferBeforeErrCheck(pass, val, rt, deferClose)
+		checkDeferInLoop(pass, fn, val, rt, deferClose)
+	}
+	if deferClose != nil && opts.DeferBeforeUse {
+		checkDeferBeforeUse(pass, val, rt, deferClose)
+	}
+	if deferClose != nil && opts.DeferWithin > 0 {
+		checkDeferDistance(pass, val, rt, deferClose, opts.DeferWithin)
+	}
+	if deferClose == nil && !isClosedWithoutDefer(fn, val, rt, opts) {
+		// Resources stored into slices or maps are closed through the collection
+		if stores := collectionStores(val); len(stores) > 0 {
+			checkCollectionStores(pass, stores, rt)
+			return
+		}
+
+		pos := acquisitionPos(val)
+
+		// Resources discarded with the blank identifier, or dropped by calls
+		// used as statements, can never be closed
+		if message, ok := discardMessage(pass, val, rt); ok {
+			pass.Report(analysis.Diagnostic{
+				Pos:      pos,
+				Category: categoryDiscarded,
+				Message:  message,
+			})
+			return
+		}
+
+		// Tuple results are reported at the variable they are assigned to
+		reportPos, message := pos, rt.CloseMessage()
+		if id := tupleVar(pass, val); id != nil {
+			reportPos = id.Pos()
+			message += " for " + id.Name
+		}
+		message += shadowingDefer(pass, fn, val, rt)
+
+		fixes := deferFixes(pass, val, rt, pos)
+		escaped := len(spawnedGoroutines(val)) > 0
+		if escaped || isGoroutineBody(fn) {
+			message += goroutineMessage
+			if escaped {
+				// A defer here would close it under the goroutine
+				fixes = nil
+			}
+		}
+		if hasNonDeferredClose(val, rt) && recoversPanics(fn) {
+			message += fmt.Sprintf(recoverMessage, rt.CloseMethod)
+		}
+		message += earlyLoopExit(pass, fn, val, rt)
+		category := categoryUnclosed
+		if escaped {
+			category = categoryGoroutineEscape
+		} else if hasNonDeferredClose(val, rt) {
+			category = categoryNotDeferred
+		}
+		reportAcquired(pass, pos, analysis.Diagnostic{
+			Pos:            reportPos,
+			Category:       category,
+			Message:        message,
+			SuggestedFixes: fixes,
+		})
+	}
+}
+
+// isClosedWithoutDefer checks if val is reliably closed without a defer in fn:
+// by a hook registered with a lifecycle manager or a test's Cleanup, which run
+// at shutdown or at the end of the test, in the teardown of a testify suite,
+// or, in lenient mode, by a close on every path to a return
+func isClosedWithoutDefer(fn *ssa.Function, val ssa.Value, rt *ResourceType, opts *Options) bool {
+	return isClosedByLifecycleHook(val, rt, opts) ||
+		isClosedInSuiteTeardown(fn, val, rt) ||
+		(opts.Lenient && closedOnAllPaths(val, rt))
+}
+
+// isTypeAssertion checks if val is the result of a type assertion,
+// including the value of a comma-ok assertion
+func isTypeAssertion(val ssa.Value) bool {
+	if extract, ok := val.(*ssa.Extract); ok {
+		val = extract.Tuple
+	}
+	_, ok := val.(*ssa.TypeAssert)
+	return ok
+}
+
+// acquisitionPos returns the position to report for an acquired resource
+func acquisitionPos(val ssa.Value) token.Pos {
+	// Get the position - for Extract, use the tuple call's position
+	if extract, ok := val.(*ssa.Extract); ok && extract.Tuple != nil {
+		return extract.Tuple.Pos()
+	}
+	return val.Pos()
+}
+
+// tupleVar returns the variable a resource extracted from a tuple is assigned
+// to, as txn in txn, err := client.BatchReadOnlyTransaction(ctx, tb), or nil
+// when it is not assigned to a named variable
+func tupleVar(pass *analysis.Pass, val ssa.Value) *ast.Ident {
+	if _, ok := val.(*ssa.Extract); !ok {
+		return nil
+	}
+	return assignedIdent(pass, val)
+}
+
+// assignedIdent returns the variable the resource val, produced by a call, is
+// assigned to, or nil when it is not assigned to a named variable
+func assignedIdent(pass *analysis.Pass, val ssa.Value) *ast.Ident {
+	id, ok := assignedExpr(pass, val).(*ast.Ident)
+	if !ok || id.Name == "_" {
+		return nil
+	}
+	return id
+}
+
+// isDiscarded checks if the resource val, produced by a call, is assigned to
+// the blank identifier, as in _ = client.ReadOnlyTransaction()
+func isDiscarded(pass *analysis.Pass, val ssa.Value) bool {
+	id, ok := assignedExpr(pass, val).(*ast.Ident)
+	return ok && id.Name == "_"
+}
+
+// discardMessage returns the message reporting val if it is discarded with
+// the blank identifier or returned by a call used as a statement
+func discardMessage(pass *analysis.Pass, val ssa.Value, rt *ResourceType) (string, bool) {
+	if isDiscarded(pass, val) {
+		return rt.DiscardMessage(), true
+	}
+	if call, ok := val.(*ssa.Call); ok && isCallStmt(pass, call) {
+		return rt.DropMessage(constructorName(call)), true
+	}
+	return "", false
+}
+
+// isCallStmt checks if call is used as an expression statement, dropping its
+// results
+func isCallStmt(pass *analysis.Pass, call *ssa.Call) bool {
+	_, ok := findNode(pass, call.Pos(), func(n *ast.ExprStmt) bool {
+		expr, ok := ast.Unparen(n.X).(*ast.CallExpr)
+		return ok && expr.Lparen == call.Pos()
+	})
+	return ok
+}
+
+// checkDroppedTuple reports the resources among the results of call, a call
+// returning several values used as a statement, such as
+// client.BatchReadOnlyTransaction(ctx, tb) on its own line
+func checkDroppedTuple(pass *analysis.Pass, call *ssa.Call, spannerTypes map[*types.Named]*ResourceType, opts *Options) {
+	results, ok := call.Type().(*types.Tuple)
+	if !ok || len(*call.Referrers()) > 0 || !isCallStmt(pass, call) {
+		return
+	}
+	for i := 0; i < results.Len(); i++ {
+		rt := getSpannerType(results.At(i).Type(), spannerTypes)
+		if rt == nil || !isResourceType(results.At(i).Type(), rt) {
+			continue
+		}
+		if !isAcquisition(call, rt, opts) || isFromExemptConstructor(call, rt, opts) {
+			continue
+		}
+		pass.Report(analysis.Diagnostic{
+			Pos:      call.Pos(),
+			Category: categoryDiscarded,
+			Message:  rt.DropMessage(constructorName(call)),
+		})
+	}
+}
+
+// assignedExpr returns the expression the resource val, produced by a call, is
+// assigned to, or nil
+func assignedExpr(pass *analysis.Pass, val ssa.Value) ast.Expr {
+	index := -1
+	switch val := val.(type) {
+	case *ssa.Extract:
+		if val.Tuple == nil {
+			return nil
+		}
+		index = val.Index
+	case *ssa.Call:
+	default:
+		return nil
+	}
+	pos := acquisitionPos(val)
+	isCall := func(expr ast.Expr) bool {
+		call, ok := ast.Unparen(expr).(*ast.CallExpr)
+		return ok && call.Lparen == pos
+	}
+	// lhsFor returns the expression assigned the result of the call among rhs
+	lhsFor := func(lhs []ast.Expr, rhs []ast.Expr) ast.Expr {
+		if index >= 0 {
+			if len(rhs) == 1 && isCall(rhs[0]) && index < len(lhs) {
+				return lhs[index]
+			}
+			return nil
+		}
+		for i, expr := range rhs {
+			if isCall(expr) && len(lhs) == len(rhs) {
+				return lhs[i]
+			}
+		}
+		return nil
+	}
+
+	var target ast.Expr
+	if _, ok := findNode(pass, pos, func(n *ast.AssignStmt) bool {
+		target = lhsFor(n.Lhs, n.Rhs)
+		return target != nil
+	}); !ok {
+		findNode(pass, pos, func(n *ast.ValueSpec) bool {
+			lhs := make([]ast.Expr, len(n.Names))
+			for i, name := range n.Names {
+				lhs[i] = name
+			}
+			target = lhsFor(lhs, n.Values)
+			return target != nil
+		})
+	}
+
+	return target
+}
+
+// findDeferredClose returns the defer instruction closing val, or nil if there is none
+func findDeferredClose(val ssa.Value, rt *ResourceType) *ssa.Defer {
+	if defers := findDeferredCloses(val, rt); len(defers) > 0 {
+		return defers[0]
+	}
+	return nil
+}
+
+// findDeferredCloses returns the defer instructions closing val
+func findDeferredCloses(val ssa.Value, rt *ResourceType) []*ssa.Defer {
 	if val.Referrers() == nil {
-		return false
+		return nil
 	}
 
+	var defers []*ssa.Defer
+
 	for _, ref := range *val.Referrers() {
 		// Check if the reference is in a defer instruction
-		if _, ok := ref.(*ssa.Defer); ok {
+		if d, ok := ref.(*ssa.Defer); ok {
 			// This value is used directly in a defer
-			return true
+			defers = append(defers, d)
 		}
 
 		// Check if the reference is a method call (Close/Stop) in a defer
 		if call, ok := ref.(*ssa.Call); ok {
 			if call.Common().Method != nil {
-				methodName := call.Common().Method.Name()
-				if methodName == methodNameClose || methodName == methodNameStop {
+				if rt.isCloseMethod(call.Common().Method.Name()) {
 					// Check if this call is in a defer by looking at its referrers
 					if call.Referrers() != nil {
 						for _, callRef := range *call.Referrers() {
-							if _, ok := callRef.(*ssa.Defer); ok {
-								return true
+							if d, ok := callRef.(*ssa.Defer); ok {
+								defers = append(defers, d)
 							}
 						}
 					}
 				}
 			}
 		}
+
+		// Check if a wrapper's embedded resource is closed through a promoted method
+		if fa, ok := ref.(*ssa.FieldAddr); ok && fa.X == val && isEmbeddedResourceField(fa, rt) {
+			if d := findDeferredCloseThroughField(fa, rt); d != nil {
+				defers = append(defers, d)
+			}
+		}
+
+		// Check if a deferred method value closes it, e.g.
+		// cleanup := txn.Close; defer cleanup(), or defer closeOnce.Do(txn.Close)
+		if closure, ok := ref.(*ssa.MakeClosure); ok {
+			if d := closureDefer(closure); d != nil && closureClosesBinding(closure, val, rt) {
+				defers = append(defers, d)
+			}
+		}
+
+		// Check if the value is captured by a deferred closure that closes it,
+		// e.g. defer func() { err = errors.Join(err, closeTxn(txn)) }()
+		// Captured variables are stored in a local cell shared with the closure.
+		if store, ok := ref.(*ssa.Store); ok && store.Val == val {
+			if alloc, ok := store.Addr.(*ssa.Alloc); ok {
+				defers = append(defers, findDeferredClosureClosing(alloc, rt)...)
+			}
+		}
 	}
 
+	return defers
+}
+
+// findDeferredClosureClosing returns the defers of closures closing a captured
+// variable, or of closes in the function declaring the variable, which reads
+// it from its cell: defer txn.Close() with txn captured by a closure
+func findDeferredClosureClosing(alloc *ssa.Alloc, rt *ResourceType) []*ssa.Defer {
+	if alloc.Referrers() == nil {
+		return nil
+	}
+
+	var defers []*ssa.Defer
+	for _, ref := range *alloc.Referrers() {
+		switch ref := ref.(type) {
+		case *ssa.MakeClosure:
+			if d := closureDefer(ref); d != nil && closureClosesBinding(ref, alloc, rt) {
+				defers = append(defers, d)
+			}
+		case *ssa.UnOp:
+			if ref.Op != token.MUL {
+				continue
+			}
+			defers = append(defers, findDeferredCloses(ref, rt)...)
+		}
+	}
+
+	return defers
+}
+
+// closureDefer returns the defer statement invoking closure, or nil
+func closureDefer(closure *ssa.MakeClosure) *ssa.Defer {
+	if closure.Referrers() == nil {
+		return nil
+	}
+
+	for _, ref := range *closure.Referrers() {
+		if d, ok := ref.(*ssa.Defer); ok && invokesClosure(d, closure) {
+			return d
+		}
+	}
+
+	return nil
+}
+
+// isCalledInPlace checks if closure is called, or deferred, by the function
+// creating it, directly or through sync.Once.Do
+func isCalledInPlace(closure *ssa.MakeClosure) bool {
+	if closure.Referrers() == nil {
+		return false
+	}
+	for _, ref := range *closure.Referrers() {
+		if call, ok := ref.(ssa.CallInstruction); ok && invokesClosure(call, closure) {
+			return true
+		}
+	}
 	return false
 }
 
-func getSpannerType(t types.Type, spannerTypes map[*types.Named]string) string {
-	// Strip pointer
-	if ptr, ok := t.(*types.Pointer); ok {
-		t = ptr.Elem()
+// closureClosesBinding checks if the closure body closes the free variable bound to binding
+func closureClosesBinding(closure *ssa.MakeClosure, binding ssa.Value, rt *ResourceType) bool {
+	fn, ok := closure.Fn.(*ssa.Function)
+	if !ok {
+		return false
 	}
 
-	// Check if it's a named Spanner type
-	if named, ok := t.(*types.Named); ok {
-		if name, ok := spannerTypes[named]; ok {
-			return name
+	for i, b := range closure.Bindings {
+		if b == binding && i < len(fn.FreeVars) && closesValue(fn.FreeVars[i], rt, 0) {
+			return true
 		}
 	}
 
-	return ""
+	return false
 }
 
-// isFromSingle checks if a value comes from a Client.Single() call
-func isFromSingle(val ssa.Value) bool {
-	// Direct call check
-	if call, ok := val.(*ssa.Call); ok {
-		// Check method call (for interface-based calls)
-		if call.Common().Method != nil {
-			methodName := call.Common().Method.Name()
-			if methodName == methodNameSingle {
+// maxHelperDepth limits how deep close helpers are followed
+const maxHelperDepth = 3
+
+// closesValue checks if val is closed within its function, either by a direct
+// Close()/Stop() call or by passing it to a helper that closes it.
+// Calls nested in argument expressions such as errors.Join(err, closeTxn(txn))
+// are separate SSA instructions, so they are found like any other call.
+// A close on any branch counts, so guarded closes such as
+// if txn != nil { txn.Close() } in deferred closures are accepted.
+func closesValue(val ssa.Value, rt *ResourceType, depth int) bool {
+	if val.Referrers() == nil {
+		return false
+	}
+
+	for _, ref := range *val.Referrers() {
+		// Captured variables are accessed through loads: *txn
+		if load, ok := ref.(*ssa.UnOp); ok && load.Op == token.MUL {
+			if closesValue(load, rt, depth) {
 				return true
 			}
+			continue
 		}
-		// Check function value call (for concrete type calls)
-		if call.Common().Value != nil {
-			if call.Common().Value.Name() == methodNameSingle {
+
+		// Nested function literals called in place close the variable they
+		// capture, e.g. defer func() { func() { txn.Close() }() }()
+		if closure, ok := ref.(*ssa.MakeClosure); ok {
+			if depth < maxHelperDepth && isCalledInPlace(closure) && closureClosesBinding(closure, val, rt) {
 				return true
 			}
+			continue
+		}
+
+		call, ok := ref.(ssa.CallInstruction)
+		if !ok {
+			continue
+		}
+
+		common := call.Common()
+		if isCloseCall(common, val, rt) {
+			return true
+		}
+
+		// Follow helpers defined in the same package, e.g. closeTxn(txn) error
+		if depth < maxHelperDepth && closesArgAt(common, val, rt, depth+1) {
+			return true
 		}
 	}
+
 	return false
 }
 
-// isReturnedFromFunction checks if a value is returned from the function
-func isReturnedFromFunction(fn *ssa.Function, val ssa.Value) bool {
-	if val.Referrers() == nil {
+// closesArg checks if common calls a helper that closes its argument val
+func closesArg(common *ssa.CallCommon, val ssa.Value, rt *ResourceType) bool {
+	return closesArgAt(common, val, rt, 1)
+}
+
+// closesArgAt is closesArg for helpers called at the given helper depth
+func closesArgAt(common *ssa.CallCommon, val ssa.Value, rt *ResourceType, depth int) bool {
+	callee := common.StaticCallee()
+	if callee == nil || len(callee.Blocks) == 0 {
 		return false
 	}
+	for i, arg := range common.Args {
+		if arg == val && i < len(callee.Params) && closesValue(callee.Params[i], rt, depth) {
+			return true
+		}
+	}
+	return false
+}
 
-	for _, ref := range *val.Referrers() {
-		// Check if the value is used in a Return instruction
-		if ret, ok := ref.(*ssa.Return); ok {
-			// Check if val is one of the return values
-			for _, result := range ret.Results {
-				if result == val {
-					return true
-				}
-			}
+// isCloseCall checks if the call invokes the close method of rt on val
+func isCloseCall(common *ssa.CallCommon, val ssa.Value, rt *ResourceType) bool {
+	return isMethodCallOn(common, val, rt.closeMethodNames())
+}
+
+func getSpannerType(t types.Type, spannerTypes map[*types.Named]*ResourceType) *ResourceType {
+	// Strip pointer
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+
+	// Check if it's a named Spanner type
+	if named, ok := t.(*types.Named); ok {
+		if rt, ok := spannerTypes[named]; ok {
+			return rt
 		}
 	}
 
-	return false
+	// Check if it's a wrapper struct embedding a Spanner type
+	return embeddedResourceType(t, spannerTypes, 0)
 }
 
-// isGeneratedFile checks if a position is in a generated file
-func isGeneratedFile(pass *analysis.Pass, pos token.Pos) bool {
-	file := pass.Fset.File(pos)
-	if file == nil {
+// producedBy checks if val is the result of a call to one of the named
+// functions or methods. Names are either bare, e.g. Single, or fully
+// qualified, e.g. (*cloud.google.com/go/spanner.Client).Single.
+func producedBy(val ssa.Value, names []string) bool {
+	if len(names) == 0 {
 		return false
 	}
 
-	filename := file.Name()
+	// Tuple results, e.g. txn, err := client.BatchReadOnlyTransaction(...)
+	if extract, ok := val.(*ssa.Extract); ok {
+		val = extract.Tuple
+	}
 
-	// Check for common generated file patterns
-	if strings.HasSuffix(filename, ".yo.go") {
-		return true
+	call, ok := val.(*ssa.Call)
+	if !ok {
+		return false
 	}
-	if strings.HasSuffix(filename, ".pb.go") {
-		return true
+	return callsOneOf(call.Common(), names)
+}
+
+// callsOneOf checks if common calls one of the named functions or methods,
+// matched like in producedBy
+func callsOneOf(common *ssa.CallCommon, names []string) bool {
+	// Check method call (for interface-based calls)
+	if method := common.Method; method != nil {
+		return slices.Contains(names, method.Name()) || slices.Contains(names, method.FullName())
 	}
-	if strings.HasSuffix(filename, "_gen.go") {
-		return true
+	// Check function value call (for concrete type calls)
+	if callee := common.StaticCallee(); callee != nil {
+		return slices.Contains(names, callee.Name()) || slices.Contains(names, callee.String())
 	}
-	if strings.Contains(filename, "generated") {
-		return true
+	if common.Value != nil {
+		return slices.Contains(names, common.Value.Name())
 	}
+	return false
+}
+
+// isAcquisition checks if val is produced by one of the acquiring constructors of rt
+// or by a configured acquisition function.
+// Every value is an acquisition when no constructors are configured.
+func isAcquisition(val ssa.Value, rt *ResourceType, opts *Options) bool {
+	return len(rt.Constructors) == 0 || producedBy(val, rt.Constructors) || producedBy(val, opts.AcquireFuncs)
+}
 
-	// Check for file-level nolint directive
-	if hasFileLevelNolint(pass, pos) {
-		return true
+// isFromExemptConstructor checks if val comes from a constructor that releases
+// the resource automatically, such as Client.Single()
+func isFromExemptConstructor(val ssa.Value, rt *ResourceType, opts *Options) bool {
+	return producedBy(val, exemptConstructors(rt, opts))
+}
+
+// exemptConstructors returns the exempt constructors of rt: its own, and
+// those of opts for any resource type or, given as Type=Func, for rt
+func exemptConstructors(rt *ResourceType, opts *Options) []string {
+	names := slices.Clip(rt.ExemptConstructors)
+	for _, name := range opts.ExemptConstructors {
+		if typeName, fn, ok := strings.Cut(name, "="); ok {
+			if !rt.isNamed(typeName) {
+				continue
+			}
+			name = fn
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// isReturnedFromFunction checks if a value is returned from the function
+func isReturnedFromFunction(fn *ssa.Function, val ssa.Value) bool {
+	if val.Referrers() == nil {
+		return false
+	}
+
+	for _, ref := range *val.Referrers() {
+		switch ref := ref.(type) {
+		case *ssa.Return:
+			// Check if val is one of the return values
+			if slices.Contains(ref.Results, val) {
+				return true
+			}
+		case *ssa.MakeInterface:
+			// Returned as an interface, e.g. func Query() RowSource
+			if isReturnedFromFunction(fn, ref) {
+				return true
+			}
+		case *ssa.Store:
+			// Named results and variables captured by closures live in a
+			// local cell, loaded by bare returns: txn = ...; return
+			alloc, ok := ref.Addr.(*ssa.Alloc)
+			if !ok || ref.Val != val {
+				continue
+			}
+			for _, allocRef := range *alloc.Referrers() {
+				if load, ok := allocRef.(*ssa.UnOp); ok && load.Op == token.MUL && isReturnedFromFunction(fn, load) {
+					return true
+				}
+			}
+		}
 	}
 
 	return false
@@ -270,6 +929,29 @@ func hasFileLevelNolint(pass *analysis.Pass, pos token.Pos) bool {
 	return false
 }
 
+// reportNolint returns a report function dropping the diagnostics silenced by
+// a nolint comment on their line, passing them to SuppressedOutput, and
+// passing the others to report
+func reportNolint(pass *analysis.Pass, report func(analysis.Diagnostic)) func(analysis.Diagnostic) {
+	return func(d analysis.Diagnostic) {
+		if hasNolintDirective(pass, d.Pos) {
+			suppress(pass, d, SuppressionNolint, "a nolint comment silences it")
+			return
+		}
+		report(d)
+	}
+}
+
+// reportAcquired reports d, a finding about the resource acquired at pos,
+// unless a nolint comment silences it at the acquisition too
+func reportAcquired(pass *analysis.Pass, pos token.Pos, d analysis.Diagnostic) {
+	if hasNolintDirective(pass, pos) {
+		suppress(pass, d, SuppressionNolint, "a nolint comment silences the acquisition")
+		return
+	}
+	pass.Report(d)
+}
+
 // hasNolintDirective checks if there's a nolint comment for this position
 func hasNolintDirective(pass *analysis.Pass, pos token.Pos) bool {
 	// Get the file and position
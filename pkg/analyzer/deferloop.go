@@ -0,0 +1,130 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ssa"
+)
+
+// deferInLoopMessage reports a deferred close of a resource acquired in the
+// same loop, which holds every iteration's resource until the function returns
+const deferInLoopMessage = "%s.%s() deferred inside a loop only runs when the function returns, holding the resource of every iteration until then: close it at the end of each iteration or move the loop body into a function"
+
+// checkDeferInLoop reports deferClose when it closes a resource acquired in
+// the same loop of fn:
+//
+//	for _, stmt := range stmts {
+//		iter := txn.Query(ctx, stmt)
+//		defer iter.Stop()
+//	}
+//
+// Deferring the close of resources acquired before the loop, such as the
+// elements of a collection, holds nothing longer than needed and is accepted.
+// The suggested fix moves the loop body into a function literal called on
+// every iteration, when the body has no statements leaving it.
+func checkDeferInLoop(pass *analysis.Pass, fn *ssa.Function, val ssa.Value, rt *ResourceType, deferClose *ssa.Defer) {
+	body := funcBody(fn)
+	if body == nil || deferClose.Parent() != fn {
+		return
+	}
+	loop := enclosingLoop(pass, deferClose.Pos(), body)
+	if loop == nil {
+		return
+	}
+	// Acquisitions in the init statement of a for loop run once
+	block := loopBody(loop)
+	pos := acquisitionPos(val)
+	if pos < block.Pos() || pos >= block.End() {
+		return
+	}
+	reportAcquired(pass, pos, analysis.Diagnostic{
+		Pos:            deferClose.Pos(),
+		Category:       categoryDeferInLoop,
+		Message:        fmt.Sprintf(deferInLoopMessage, rt.QualifiedName(), rt.CloseMethod),
+		SuggestedFixes: loopBodyFuncFixes(pass, loop),
+	})
+}
+
+// loopBodyFuncFixes returns a fix wrapping the body of loop in a function
+// literal called on every iteration, so that its defers run at the end of the
+// iteration. Bodies with return, break, continue or goto statements would
+// change meaning and get no fix, as do bodies with multi-line literals, whose
+// lines cannot be indented.
+func loopBodyFuncFixes(pass *analysis.Pass, loop ast.Stmt) []analysis.SuggestedFix {
+	block := loopBody(loop)
+	if len(block.List) == 0 {
+		return nil
+	}
+
+	file := pass.Fset.File(block.Pos())
+	if file == nil {
+		return nil
+	}
+	startLine, endLine := file.Line(block.Lbrace), file.Line(block.Rbrace)
+	if endLine-startLine < 2 {
+		return nil
+	}
+	leaves := false
+	ast.Inspect(block, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.ReturnStmt, *ast.BranchStmt:
+			leaves = true
+		case *ast.BasicLit:
+			if file.Line(n.Pos()) != file.Line(n.End()) {
+				leaves = true
+			}
+		}
+		return !leaves
+	})
+	if leaves {
+		return nil
+	}
+
+	// Open the function on a line of its own, then indent the body
+	indent := lineIndent(pass, block.List[0].Pos())
+	var edits []analysis.TextEdit
+	for line := startLine + 1; line < endLine; line++ {
+		start := file.LineStart(line)
+		text := "\t"
+		if isBlankLine(pass, start) {
+			text = ""
+		}
+		if line == startLine+1 {
+			text = indent + "func() {\n" + text
+		}
+		if text != "" {
+			edits = append(edits, analysis.TextEdit{Pos: start, End: start, NewText: []byte(text)})
+		}
+	}
+	closing := file.LineStart(endLine)
+	edits = append(edits, analysis.TextEdit{Pos: closing, End: closing, NewText: []byte(indent + "}()\n")})
+
+	return []analysis.SuggestedFix{{
+		Message:   "Move the loop body into a function",
+		TextEdits: edits,
+	}}
+}
+
+// loopBody returns the body of the for or range statement loop
+func loopBody(loop ast.Stmt) *ast.BlockStmt {
+	if loop, ok := loop.(*ast.ForStmt); ok {
+		return loop.Body
+	}
+	return loop.(*ast.RangeStmt).Body
+}
+
+// isBlankLine checks if the line starting at start is empty
+func isBlankLine(pass *analysis.Pass, start token.Pos) bool {
+	file := pass.Fset.File(start)
+	content, err := pass.ReadFile(file.Name())
+	if err != nil {
+		return false
+	}
+	offset := file.Offset(start)
+	return offset >= len(content) || content[offset] == '\n' || content[offset] == '\r'
+}
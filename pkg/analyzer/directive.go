@@ -0,0 +1,318 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"reflect"
+	"slices"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ssa"
+)
+
+// directiveResource declares the type it documents as a resource, e.g. an ORM
+// adapter wrapping a RowIterator:
+//
+//	//spannerclosecheck:resource Stop
+//	type UserIter struct{ iter *spanner.RowIterator }
+//
+// The close method defaults to Close when omitted. Further methods closing
+// the resource follow it, as in //spannerclosecheck:resource Close Release.
+const directiveResource = "//spannerclosecheck:resource"
+
+// directiveCloses declares that a function takes ownership of the resources
+// passed as the named parameters and closes them, so callers need no defer:
+//
+//	//spannerclosecheck:closes txn
+//	func finish(txn *spanner.ReadOnlyTransaction) { go drainAndClose(txn) }
+const directiveCloses = "//spannerclosecheck:closes"
+
+// directiveOwns declares that the struct field it documents owns the resource
+// stored into it, which its struct closes, e.g. in a Close method:
+//
+//	type Repository struct {
+//		txn *spanner.ReadOnlyTransaction //spannerclosecheck:owns
+//	}
+const directiveOwns = "//spannerclosecheck:owns"
+
+// directiveShutdown declares that the function it documents runs when the
+// process shuts down, so that closing package-level clients in it releases
+// them:
+//
+//	//spannerclosecheck:shutdown
+//	func Shutdown() { client.Close() }
+const directiveShutdown = "//spannerclosecheck:shutdown"
+
+// directiveEnforce above the package clause of a generated file checks it
+// anyway, for generated code that must still be verified:
+//
+//	// Code generated by yo. DO NOT EDIT.
+//
+//	//spannerclosecheck:enforce
+//	package models
+const directiveEnforce = "//spannerclosecheck:enforce"
+
+// directiveConfig above the package clause of a file sets options for its
+// functions only, by the names of their flags, e.g. for legacy files that
+// cannot be refactored yet:
+//
+//	//spannerclosecheck:config lenient single-close=off
+//	package legacy
+//
+// Only the options checked function by function are accepted, see
+// fileConfigOptions.
+const directiveConfig = "//spannerclosecheck:config"
+
+// resourceFact marks a type declared as a resource with directiveResource,
+// so that packages importing it check its values as well
+type resourceFact struct {
+	CloseMethod  string
+	CloseMethods []string
+}
+
+func (*resourceFact) AFact() {}
+
+func (f *resourceFact) String() string {
+	return "resource " + strings.Join(append([]string{f.CloseMethod}, f.CloseMethods...), " ")
+}
+
+// ownsFact marks a struct field declared with directiveOwns, so that storing
+// a resource into it is accepted in packages importing its struct as well
+type ownsFact struct{}
+
+func (*ownsFact) AFact() {}
+
+func (*ownsFact) String() string {
+	return "owns"
+}
+
+// directives are the declarations made with directive comments that are
+// visible to a package, including its own
+type directives struct {
+	// Resources are the types declared with directiveResource
+	Resources []ResourceType
+	// OwningFields are the struct fields declared with directiveOwns
+	OwningFields map[*types.Var]bool
+}
+
+// forEachResourceDirective calls f for every type of the package declared as a
+// resource with directiveResource, with the declared close methods and the
+// position of the directive
+func forEachResourceDirective(pass *analysis.Pass, f func(obj *types.TypeName, closeMethods []string, pos token.Pos)) {
+	for _, file := range pass.Files {
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				ts := spec.(*ast.TypeSpec)
+				doc := ts.Doc
+				if doc == nil && len(gen.Specs) == 1 {
+					doc = gen.Doc
+				}
+				closeMethods, pos, ok := resourceDirective(doc)
+				if !ok {
+					continue
+				}
+				if obj, ok := pass.TypesInfo.Defs[ts.Name].(*types.TypeName); ok {
+					f(obj, closeMethods, pos)
+				}
+			}
+		}
+	}
+}
+
+// checkResourceDirectives reports directives naming a close method the type lacks
+func checkResourceDirectives(pass *analysis.Pass) {
+	forEachResourceDirective(pass, func(obj *types.TypeName, closeMethods []string, pos token.Pos) {
+		for _, closeMethod := range closeMethods {
+			if !hasMethod(obj, closeMethod) {
+				reportf(pass, pos, categoryDirective, "%s has no method %s() to close it with", obj.Name(), closeMethod)
+			}
+		}
+	})
+}
+
+// resourceDirective returns the close methods declared by a
+// directiveResource comment in doc and the comment's position
+func resourceDirective(doc *ast.CommentGroup) ([]string, token.Pos, bool) {
+	args, pos, ok := findDirective(doc, directiveResource)
+	if !ok {
+		return nil, token.NoPos, false
+	}
+	if len(args) == 0 {
+		return []string{methodNameClose}, pos, true
+	}
+	return args, pos, true
+}
+
+// findDirective returns the arguments of the directive comment in doc and
+// the comment's position
+func findDirective(doc *ast.CommentGroup, directive string) ([]string, token.Pos, bool) {
+	if doc == nil {
+		return nil, token.NoPos, false
+	}
+	for _, c := range doc.List {
+		rest, ok := strings.CutPrefix(c.Text, directive)
+		if !ok || (rest != "" && rest[0] != ' ' && rest[0] != '\t') {
+			continue
+		}
+		// Trailing comments are not arguments
+		rest, _, _ = strings.Cut(rest, "//")
+		return strings.Fields(rest), c.Pos(), true
+	}
+	return nil, token.NoPos, false
+}
+
+// hasEnforceDirective checks if file has a directiveEnforce comment above
+// its package clause
+func hasEnforceDirective(file *ast.File) bool {
+	return len(fileDirectives(file, directiveEnforce)) > 0
+}
+
+// fileDirective is a directive comment above the package clause of a file
+type fileDirective struct {
+	args []string
+	pos  token.Pos
+}
+
+// fileDirectives returns the directive comments above the package clause of
+// file, in order
+func fileDirectives(file *ast.File, directive string) []fileDirective {
+	var found []fileDirective
+	for _, cg := range file.Comments {
+		if cg.Pos() > file.Package {
+			break
+		}
+		for _, c := range cg.List {
+			if args, pos, ok := findDirective(&ast.CommentGroup{List: []*ast.Comment{c}}, directive); ok {
+				found = append(found, fileDirective{args: args, pos: pos})
+			}
+		}
+	}
+	return found
+}
+
+// forEachOwningField calls f for every struct field of the package declared
+// with directiveOwns, in its doc or line comment
+func forEachOwningField(pass *analysis.Pass, f func(field *types.Var)) {
+	for _, file := range pass.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			st, ok := n.(*ast.StructType)
+			if !ok {
+				return true
+			}
+			for _, field := range st.Fields.List {
+				_, _, inDoc := findDirective(field.Doc, directiveOwns)
+				_, _, inComment := findDirective(field.Comment, directiveOwns)
+				if !inDoc && !inComment {
+					continue
+				}
+				for _, name := range field.Names {
+					if v, ok := pass.TypesInfo.Defs[name].(*types.Var); ok {
+						f(v)
+					}
+				}
+			}
+			return true
+		})
+	}
+}
+
+// hasMethod checks if values of the type or pointers to it have the named method
+func hasMethod(tn *types.TypeName, name string) bool {
+	obj, _, _ := types.LookupFieldOrMethod(types.NewPointer(tn.Type()), true, tn.Pkg(), name)
+	_, ok := obj.(*types.Func)
+	return ok
+}
+
+// directiveAnalyzer exports resources declared with directiveResource and
+// fields declared with directiveOwns as facts, and returns the directives
+// visible to the package.
+// It is separate from the main analyzer, as analyzers with facts also run on
+// every dependency, which must not require building SSA for all of them.
+var directiveAnalyzer = &analysis.Analyzer{
+	Name:       "spannerclosecheckdirectives",
+	Doc:        "collect types and fields declared with " + directiveResource + " and " + directiveOwns,
+	Run:        runDirectives,
+	FactTypes:  []analysis.Fact{new(resourceFact), new(ownsFact)},
+	ResultType: reflect.TypeOf((*directives)(nil)),
+}
+
+func runDirectives(pass *analysis.Pass) (interface{}, error) {
+	forEachResourceDirective(pass, func(obj *types.TypeName, closeMethods []string, _ token.Pos) {
+		// Invalid directives are reported by the main analyzer
+		if !slices.ContainsFunc(closeMethods, func(m string) bool { return !hasMethod(obj, m) }) {
+			pass.ExportObjectFact(obj, &resourceFact{CloseMethod: closeMethods[0], CloseMethods: closeMethods[1:]})
+		}
+	})
+	forEachOwningField(pass, func(field *types.Var) {
+		pass.ExportObjectFact(field, new(ownsFact))
+	})
+
+	owning := make(map[*types.Var]bool)
+	for _, f := range pass.AllObjectFacts() {
+		if field, ok := f.Object.(*types.Var); ok {
+			if _, ok := f.Fact.(*ownsFact); ok {
+				owning[field] = true
+			}
+		}
+	}
+	return &directives{
+		Resources:    directiveResources(pass, transitiveImports(pass.Pkg)),
+		OwningFields: owning,
+	}, nil
+}
+
+// directiveResources returns the resource types declared with
+// directiveResource in the given packages
+func directiveResources(pass *analysis.Pass, pkgs []*types.Package) []ResourceType {
+	var resources []ResourceType
+	for _, pkg := range pkgs {
+		scope := pkg.Scope()
+		for _, name := range scope.Names() {
+			obj, ok := scope.Lookup(name).(*types.TypeName)
+			if !ok {
+				continue
+			}
+			var fact resourceFact
+			if pass.ImportObjectFact(obj, &fact) {
+				resources = append(resources, ResourceType{
+					Name:         obj.Name(),
+					CloseMethod:  fact.CloseMethod,
+					CloseMethods: fact.CloseMethods,
+					PkgPath:      pkg.Path(),
+				})
+			}
+		}
+	}
+	return resources
+}
+
+// isStoredInOwningField checks if val is stored into a struct field declared
+// with directiveOwns, which takes over closing it
+func isStoredInOwningField(val ssa.Value, owning map[*types.Var]bool) bool {
+	if len(owning) == 0 || val.Referrers() == nil {
+		return false
+	}
+
+	for _, ref := range *val.Referrers() {
+		store, ok := ref.(*ssa.Store)
+		if !ok || store.Val != val {
+			continue
+		}
+		fa, ok := store.Addr.(*ssa.FieldAddr)
+		if !ok {
+			continue
+		}
+		st, ok := derefType(fa.X.Type()).Underlying().(*types.Struct)
+		if ok && owning[st.Field(fa.Field).Origin()] {
+			return true
+		}
+	}
+
+	return false
+}
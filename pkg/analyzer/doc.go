@@ -12,8 +12,16 @@
 //   - BatchReadOnlyTransaction: Must call Close() with defer
 //   - RowIterator: Must call Stop() with defer
 //   - Client: Must call Close() with defer
+//   - apiv1.Client: Must call Close() with defer
+//
+// Streams opened by the low-level apiv1 client (ExecuteStreamingSql,
+// StreamingRead, BatchWrite) must be drained with Recv() in a loop, or their
+// context must be cancelled with defer.
 //
 // ReadWriteTransaction is explicitly excluded as it's managed by the client.
+// Storing the transaction passed to a ReadWriteTransaction callback where it
+// outlives the callback is reported, as it is only valid until the callback
+// returns.
 //
 // # Examples
 //
@@ -0,0 +1,176 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ssa"
+)
+
+// doubleCloseMessage reports a close running after another close of the same
+// resource on some path
+const doubleCloseMessage = "%s%s.%s() closes the resource again after %s() at line %d"
+
+// checkDoubleClose reports closes of a resource that run after another close
+// of it on the same path, at the second one: explicit closes following
+// another, and deferred closes of a resource also closed explicitly, which run
+// when the function returns:
+//
+//	defer txn.Close()
+//	...
+//	txn.Close() // the deferred Close() runs again on return
+func checkDoubleClose(pass *analysis.Pass, fn *ssa.Function, spannerTypes map[*types.Named]*ResourceType) {
+	if fn == nil || isGeneratedFile(pass, fn.Pos()) {
+		return
+	}
+
+	checked := make(map[ssa.Value]bool)
+	for _, block := range fn.Blocks {
+		for _, instr := range block.Instrs {
+			call, ok := instr.(*ssa.Call)
+			if !ok {
+				continue
+			}
+			val := methodReceiver(call.Common())
+			if val == nil || checked[val] {
+				continue
+			}
+			checked[val] = true
+			if rt := getSpannerType(val.Type(), spannerTypes); rt != nil {
+				reportDoubleCloses(pass, fn, val, rt)
+			}
+		}
+	}
+}
+
+// reportDoubleCloses reports the closes of val in fn that may run twice
+func reportDoubleCloses(pass *analysis.Pass, fn *ssa.Function, val ssa.Value, rt *ResourceType) {
+	var closes []*ssa.Call
+	for _, ref := range *val.Referrers() {
+		if call, ok := ref.(*ssa.Call); ok && call.Parent() == fn && isCloseCall(call.Common(), val, rt) {
+			closes = append(closes, call)
+		}
+	}
+	if len(closes) == 0 {
+		return
+	}
+
+	report := func(pos ssa.CallInstruction, prefix string, first *ssa.Call) {
+		pass.Report(analysis.Diagnostic{
+			Pos:      pos.Pos(),
+			Category: categoryDoubleClose,
+			Message:  fmt.Sprintf(doubleCloseMessage, prefix, rt.QualifiedName(), rt.closeMethodOf(pos.Common(), val), rt.closeMethodOf(first.Common(), val), pass.Fset.Position(first.Pos()).Line),
+			Related: []analysis.RelatedInformation{{
+				Pos:     first.Pos(),
+				Message: "first closed here",
+			}},
+		})
+	}
+
+	// Explicit closes reachable from another one
+	for _, second := range closes {
+		for _, first := range closes {
+			// A close reaching itself inside the loop reading the resource
+			// is reported by checkCloseInNextLoop
+			if first == second && nextCallInLoop(pass, val, first) != nil {
+				continue
+			}
+			if reachableAfter(first, second, val) {
+				report(second, "", first)
+				break
+			}
+		}
+	}
+
+	// Deferred closes run on return, after any explicit close on their path
+	for _, d := range findDeferredCloses(val, rt) {
+		if d.Parent() != fn {
+			continue
+		}
+		for _, c := range closes {
+			if reachableAfter(d, c, val) || reachableAfter(c, d, val) {
+				report(d, "deferred ", c)
+				break
+			}
+		}
+	}
+}
+
+// duplicateDeferMessage reports a deferred close of a resource already closed
+// by another defer
+const duplicateDeferMessage = "deferred %s.%s() duplicates the defer at line %d: the resource is closed twice on return, remove one of them"
+
+// checkDuplicateDefers reports deferred closes of a resource that already has
+// a deferred close on the same path, at the second one:
+//
+//	txn := client.ReadOnlyTransaction()
+//	defer txn.Close()
+//	...
+//	defer txn.Close() // harmless, but hints at a shadowed or copied variable
+//
+// The suggested fix removes the second defer.
+func checkDuplicateDefers(pass *analysis.Pass, fn *ssa.Function, spannerTypes map[*types.Named]*ResourceType) {
+	if fn == nil || isGeneratedFile(pass, fn.Pos()) {
+		return
+	}
+
+	checked := make(map[ssa.Value]bool)
+	for _, block := range fn.Blocks {
+		for _, instr := range block.Instrs {
+			d, ok := instr.(*ssa.Defer)
+			if !ok {
+				continue
+			}
+			val := methodReceiver(d.Common())
+			if val == nil || checked[val] {
+				continue
+			}
+			checked[val] = true
+			rt := getSpannerType(val.Type(), spannerTypes)
+			if rt == nil || !isCloseCall(d.Common(), val, rt) {
+				continue
+			}
+
+			var defers []*ssa.Defer
+			for _, d := range findDeferredCloses(val, rt) {
+				if d.Parent() == fn {
+					defers = append(defers, d)
+				}
+			}
+			reportDuplicateDefers(pass, val, rt, defers)
+		}
+	}
+}
+
+// reportDuplicateDefers reports the defers reachable from another one of
+// defers, all closing val. A defer reaching itself inside a loop is left to
+// checkDeferInLoop.
+func reportDuplicateDefers(pass *analysis.Pass, val ssa.Value, rt *ResourceType, defers []*ssa.Defer) {
+	for _, second := range defers {
+		for _, first := range defers {
+			if first == second || !reachableAfter(first, second, val) {
+				continue
+			}
+			var fixes []analysis.SuggestedFix
+			if stmt, ok := callStmt(pass, second); ok {
+				start, end := stmtLineRange(pass, stmt)
+				fixes = []analysis.SuggestedFix{{
+					Message:   fmt.Sprintf("Remove the duplicated deferred %s.%s()", rt.QualifiedName(), rt.CloseMethod),
+					TextEdits: []analysis.TextEdit{{Pos: start, End: end}},
+				}}
+			}
+			pass.Report(analysis.Diagnostic{
+				Pos:      second.Pos(),
+				Category: categoryDuplicateDefer,
+				Message:  fmt.Sprintf(duplicateDeferMessage, rt.QualifiedName(), rt.CloseMethod, pass.Fset.Position(first.Pos()).Line),
+				Related: []analysis.RelatedInformation{{
+					Pos:     first.Pos(),
+					Message: "first deferred here",
+				}},
+				SuggestedFixes: fixes,
+			})
+			break
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ssa"
+)
+
+// earlyExitMessage completes the report of a resource read in a loop that can
+// leave before the resource is exhausted
+const earlyExitMessage = ": the loop reading it with Next() at line %d can leave at line %d before reaching iterator.Done, and a partially read %s holds its stream until %s() is called"
+
+// earlyLoopExit returns the message suffix explaining a loop reading val with
+// Next() that can leave before Next() reports the end of the rows:
+//
+//	for {
+//		row, err := iter.Next()
+//		if err == iterator.Done {
+//			break
+//		}
+//		if found(row) {
+//			break // leaves the stream open
+//		}
+//	}
+//
+// Exits guarded by a condition on the error of Next() or on iterator.Done end
+// the reading. It returns "" if there is no early exit.
+func earlyLoopExit(pass *analysis.Pass, fn *ssa.Function, val ssa.Value, rt *ResourceType) string {
+	body := funcBody(fn)
+	if body == nil || val.Referrers() == nil {
+		return ""
+	}
+	for _, ref := range *val.Referrers() {
+		next, ok := ref.(*ssa.Call)
+		if !ok || methodName(next.Common(), val) != "Next" {
+			continue
+		}
+		loop := enclosingLoop(pass, next.Pos(), body)
+		if loop == nil {
+			continue
+		}
+		if exit := earlyExit(pass, loop, nextErr(pass, next)); exit.IsValid() {
+			return fmt.Sprintf(earlyExitMessage, pass.Fset.Position(next.Pos()).Line, pass.Fset.Position(exit).Line, rt.QualifiedName(), rt.CloseMethod)
+		}
+	}
+	return ""
+}
+
+// nextErr returns the variable the error of the call next is assigned to, or nil
+func nextErr(pass *analysis.Pass, next *ssa.Call) types.Object {
+	assign, ok := findNode(pass, next.Pos(), func(n *ast.AssignStmt) bool {
+		if len(n.Rhs) != 1 || len(n.Lhs) != 2 {
+			return false
+		}
+		call, ok := ast.Unparen(n.Rhs[0]).(*ast.CallExpr)
+		return ok && call.Lparen == next.Pos()
+	})
+	if !ok {
+		return nil
+	}
+	id, ok := ast.Unparen(assign.Lhs[1]).(*ast.Ident)
+	if !ok || id.Name == "_" {
+		return nil
+	}
+	return pass.TypesInfo.ObjectOf(id)
+}
+
+// earlyExit returns the position of the first return, or break leaving loop,
+// that is not guarded by a condition on errObj or iterator.Done, or
+// token.NoPos
+func earlyExit(pass *analysis.Pass, loop ast.Stmt, errObj types.Object) token.Pos {
+	var loopBody *ast.BlockStmt
+	switch loop := loop.(type) {
+	case *ast.ForStmt:
+		loopBody = loop.Body
+	case *ast.RangeStmt:
+		loopBody = loop.Body
+	}
+	label := loopLabel(pass, loop)
+
+	exit := token.NoPos
+	var guards []ast.Node
+	var visit func(n ast.Node, breakable bool)
+	visit = func(n ast.Node, breakable bool) {
+		ast.Inspect(n, func(n ast.Node) bool {
+			if exit.IsValid() || n == nil {
+				return false
+			}
+			switch n := n.(type) {
+			case *ast.FuncLit:
+				return false
+			case *ast.ForStmt, *ast.RangeStmt, *ast.SwitchStmt, *ast.TypeSwitchStmt, *ast.SelectStmt:
+				// An unlabeled break inside leaves the inner statement
+				for _, child := range childStmts(n) {
+					visit(child, false)
+				}
+				return false
+			case *ast.IfStmt:
+				guards = append(guards, n.Cond)
+				visit(n.Body, breakable)
+				if n.Else != nil {
+					visit(n.Else, breakable)
+				}
+				guards = guards[:len(guards)-1]
+				return false
+			case *ast.CaseClause:
+				for _, expr := range n.List {
+					guards = append(guards, expr)
+				}
+				for _, stmt := range n.Body {
+					visit(stmt, breakable)
+				}
+				guards = guards[:len(guards)-len(n.List)]
+				return false
+			case *ast.ReturnStmt:
+				if !guardedByEnd(pass, guards, errObj) {
+					exit = n.Pos()
+				}
+			case *ast.BranchStmt:
+				leaves := n.Tok == token.BREAK && (n.Label == nil && breakable || n.Label != nil && label != nil && pass.TypesInfo.ObjectOf(n.Label) == label)
+				if leaves && !guardedByEnd(pass, guards, errObj) {
+					exit = n.Pos()
+				}
+			}
+			return true
+		})
+	}
+	visit(loopBody, true)
+	return exit
+}
+
+// childStmts returns the bodies of the loop, switch or select statement n
+func childStmts(n ast.Node) []ast.Node {
+	switch n := n.(type) {
+	case *ast.ForStmt:
+		return []ast.Node{n.Body}
+	case *ast.RangeStmt:
+		return []ast.Node{n.Body}
+	case *ast.SwitchStmt:
+		return []ast.Node{n.Body}
+	case *ast.TypeSwitchStmt:
+		return []ast.Node{n.Body}
+	case *ast.SelectStmt:
+		return []ast.Node{n.Body}
+	}
+	return nil
+}
+
+// loopLabel returns the label of loop, or nil
+func loopLabel(pass *analysis.Pass, loop ast.Stmt) types.Object {
+	labeled, ok := findNode(pass, loop.Pos(), func(n *ast.LabeledStmt) bool {
+		return n.Stmt == loop
+	})
+	if !ok {
+		return nil
+	}
+	return pass.TypesInfo.ObjectOf(labeled.Label)
+}
+
+// guardedByEnd checks if one of guards refers to errObj or to iterator.Done
+func guardedByEnd(pass *analysis.Pass, guards []ast.Node, errObj types.Object) bool {
+	for _, guard := range guards {
+		found := false
+		ast.Inspect(guard, func(n ast.Node) bool {
+			id, ok := n.(*ast.Ident)
+			if found || !ok {
+				return !found
+			}
+			obj := pass.TypesInfo.ObjectOf(id)
+			if obj == nil {
+				return true
+			}
+			if errObj != nil && obj == errObj {
+				found = true
+			}
+			if obj.Name() == "Done" && obj.Pkg() != nil && matchesPkgPath(obj.Pkg().Path(), pathGoogleAPIIterator) {
+				found = true
+			}
+			return !found
+		})
+		if found {
+			return true
+		}
+	}
+	return false
+}
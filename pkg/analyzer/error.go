@@ -1,18 +1,90 @@
 package analyzer
 
-import "fmt"
+import (
+	"fmt"
+	"path"
+	"slices"
 
+	"golang.org/x/tools/go/ssa"
+)
+
+// ResourceType describes a type whose values must be closed with defer
 type ResourceType struct {
 	Name        string
 	CloseMethod string
+	// CloseMethods are other methods closing the resource, such as
+	// CloseWithContext or Release, accepted like CloseMethod. Reports and
+	// suggested fixes name CloseMethod.
+	CloseMethods []string
+	// PkgPath is the import path of the package declaring the type
+	PkgPath string
+	// Constructors are the functions or methods that acquire the resource.
+	// When empty, every value of the type created in a function is checked.
+	Constructors []string
+	// ExemptConstructors are the functions or methods whose results release
+	// themselves and need no close, such as Client.Single()
+	ExemptConstructors []string
+	// ConsumingMethods are the methods that close the resource they are
+	// called on, such as RowIterator.Do, which stops the iterator
+	ConsumingMethods []string
+	// ConsumingFuncs are the functions of the resource's package that close
+	// the resource passed to them, such as spanner.SelectAll
+	ConsumingFuncs []string
 }
 
 func (rt ResourceType) CloseMessage() string {
-	return fmt.Sprintf("%s.%s() must be deferred", rt.Name, rt.CloseMethod)
+	return fmt.Sprintf("%s.%s() must be deferred", rt.QualifiedName(), rt.CloseMethod)
+}
+
+// DiscardMessage reports a resource discarded with the blank identifier
+func (rt ResourceType) DiscardMessage() string {
+	return fmt.Sprintf("%s acquired and discarded: the blank identifier drops the only reference, so %s() can never be called", rt.QualifiedName(), rt.CloseMethod)
+}
+
+// DropMessage reports a resource returned by a call used as a statement,
+// whose results are dropped entirely
+func (rt ResourceType) DropMessage(constructor string) string {
+	return fmt.Sprintf("%s acquired and dropped: the result of %s() is unused, so %s() can never be called", rt.QualifiedName(), constructor, rt.CloseMethod)
+}
+
+// isCloseMethod checks if name is CloseMethod or one of CloseMethods
+func (rt ResourceType) isCloseMethod(name string) bool {
+	return name == rt.CloseMethod || slices.Contains(rt.CloseMethods, name)
+}
+
+// closeMethodNames returns CloseMethod followed by CloseMethods
+func (rt ResourceType) closeMethodNames() []string {
+	return append([]string{rt.CloseMethod}, rt.CloseMethods...)
+}
+
+// closeMethodOf returns the name of the close method common calls on val,
+// or CloseMethod if it calls none
+func (rt ResourceType) closeMethodOf(common *ssa.CallCommon, val ssa.Value) string {
+	if name := methodName(common, val); rt.isCloseMethod(name) {
+		return name
+	}
+	return rt.CloseMethod
+}
+
+// QualifiedName returns the type name, qualified by its package name
+// for types outside the main Spanner package
+func (rt ResourceType) QualifiedName() string {
+	if rt.PkgPath == "" || matchesPkgPath(rt.PkgPath, pathGoogleSpanner) {
+		return rt.Name
+	}
+	return path.Base(normalizePkgPath(rt.PkgPath)) + "." + rt.Name
+}
+
+// isNamed checks if name refers to rt, by its qualified name, such as
+// RowIterator or ourdb.Txn, or by its import path and name, such as
+// example.com/ourdb.Txn
+func (rt ResourceType) isNamed(name string) bool {
+	return name == rt.QualifiedName() || name == normalizePkgPath(rt.PkgPath)+"."+rt.Name
 }
 
-var spannerResourceTypes = map[string]ResourceType{
-	"ReadOnlyTransaction":      {"ReadOnlyTransaction", "Close"},
-	"BatchReadOnlyTransaction": {"BatchReadOnlyTransaction", "Close"},
-	"RowIterator":              {"RowIterator", "Stop"},
+var spannerResourceTypes = []ResourceType{
+	{Name: typeNameReadOnlyTransaction, CloseMethod: methodNameClose, PkgPath: pathGoogleSpanner, ExemptConstructors: []string{methodNameSingle}},
+	{Name: typeNameBatchReadOnlyTransaction, CloseMethod: methodNameClose, PkgPath: pathGoogleSpanner},
+	{Name: typeNameRowIterator, CloseMethod: methodNameStop, PkgPath: pathGoogleSpanner, ConsumingMethods: []string{methodNameDo}, ConsumingFuncs: []string{funcNameSelectAll}},
+	{Name: typeNameClient, CloseMethod: methodNameClose, PkgPath: pathGoogleSpannerAPIv1},
 }
@@ -0,0 +1,181 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// excludeRegexpPrefix marks the patterns of Options.Exclude that are
+// regular expressions rather than globs
+const excludeRegexpPrefix = "re:"
+
+// exclusions are the compiled patterns of Options.Exclude and
+// Options.ExcludeFuncs, and the files Options.SkipTests and
+// Options.TestsOnly exclude
+type exclusions struct {
+	paths     []*regexp.Regexp
+	funcs     []*regexp.Regexp
+	skipTests bool
+	testsOnly bool
+}
+
+// compileExclusions compiles the exclusion patterns of opts
+func compileExclusions(opts *Options) (*exclusions, error) {
+	if opts.SkipTests && opts.TestsOnly {
+		return nil, fmt.Errorf("-skip-tests and -tests-only exclude each other")
+	}
+	ex := &exclusions{skipTests: opts.SkipTests, testsOnly: opts.TestsOnly}
+	for _, pattern := range opts.Exclude {
+		expr, ok := strings.CutPrefix(pattern, excludeRegexpPrefix)
+		if !ok {
+			expr = globRegexp(pattern)
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("exclude %q: %v", pattern, err)
+		}
+		ex.paths = append(ex.paths, re)
+	}
+	for _, pattern := range opts.ExcludeFuncs {
+		// Function patterns match whole names
+		re, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("exclude-func %q: %v", pattern, err)
+		}
+		ex.funcs = append(ex.funcs, re)
+	}
+	return ex, nil
+}
+
+// globRegexp returns the regular expression of a glob matching whole
+// elements of slash-separated paths, at any depth: * and ? match within an
+// element, ** matches across them, so that third_party matches
+// example.com/app/third_party/lib and /src/app/third_party/lib/lib.go.
+func globRegexp(glob string) string {
+	var b strings.Builder
+	b.WriteString("(^|/)")
+	for i := 0; i < len(glob); i++ {
+		switch {
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case glob[i] == '*':
+			b.WriteString("[^/]*")
+		case glob[i] == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	b.WriteString("(/|$)")
+	return b.String()
+}
+
+// excludesByTest checks if ex excludes filename for being a test file, or
+// for not being one
+func (ex *exclusions) excludesByTest(filename string) bool {
+	isTest := strings.HasSuffix(filename, "_test.go")
+	return ex.skipTests && isTest || ex.testsOnly && !isTest
+}
+
+// excludesPackage checks if ex excludes every file of pass: by its import
+// path, or for having no test files in tests-only mode
+func (ex *exclusions) excludesPackage(pass *analysis.Pass) bool {
+	if ex.isExcluded(pass.Pkg.Path()) {
+		return true
+	}
+	if !ex.testsOnly {
+		return false
+	}
+	for _, file := range pass.Files {
+		if !ex.excludesByTest(pass.Fset.File(file.Pos()).Name()) {
+			return false
+		}
+	}
+	return true
+}
+
+// isExcluded checks if path, an import path or a file path, matches one of
+// the path patterns
+func (ex *exclusions) isExcluded(path string) bool {
+	path = filepath.ToSlash(path)
+	for _, re := range ex.paths {
+		if re.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// excludedFuncs returns the declarations of the functions of pass whose
+// full name, such as example.com/app.NewFixture or
+// (*example.com/app.mockStore).Get, matches one of the function patterns
+func (ex *exclusions) excludedFuncs(pass *analysis.Pass) []*ast.FuncDecl {
+	if len(ex.funcs) == 0 {
+		return nil
+	}
+	var decls []*ast.FuncDecl
+	for _, file := range pass.Files {
+		for _, decl := range file.Decls {
+			fd, ok := decl.(*ast.FuncDecl)
+			if !ok {
+				continue
+			}
+			fn, ok := pass.TypesInfo.Defs[fd.Name].(*types.Func)
+			if !ok {
+				continue
+			}
+			for _, re := range ex.funcs {
+				if re.MatchString(fn.FullName()) {
+					decls = append(decls, fd)
+					break
+				}
+			}
+		}
+	}
+	return decls
+}
+
+// reportIncluded wraps report to drop the diagnostics of pass in the files
+// and functions ex excludes, or all of them if ex excludes its package
+func reportIncluded(pass *analysis.Pass, report func(analysis.Diagnostic), ex *exclusions) func(analysis.Diagnostic) {
+	if len(ex.paths) == 0 && len(ex.funcs) == 0 && !ex.skipTests && !ex.testsOnly {
+		return report
+	}
+	excluded := ex.excludesPackage(pass)
+	funcs := ex.excludedFuncs(pass)
+	inFunc := func(pos token.Pos) bool {
+		for _, fd := range funcs {
+			if fd.Pos() <= pos && pos < fd.End() {
+				return true
+			}
+		}
+		return false
+	}
+	return func(d analysis.Diagnostic) {
+		// Diagnostics are positioned through //line directives, in the
+		// files generated code comes from, while the Go file is the one
+		// compiled, and tested
+		filename := pass.Fset.PositionFor(d.Pos, false).Filename
+		source := pass.Fset.Position(d.Pos).Filename
+		switch {
+		case ex.excludesByTest(filename) && ex.skipTests:
+			suppress(pass, d, SuppressionSkipTests, "-skip-tests skips test files")
+		case ex.excludesByTest(filename):
+			suppress(pass, d, SuppressionTestsOnly, "-tests-only checks test files only")
+		case excluded || ex.isExcluded(filename) || ex.isExcluded(source):
+			suppress(pass, d, SuppressionExclude, "the file or its package matches -exclude")
+		case inFunc(d.Pos):
+			suppress(pass, d, SuppressionExcludeFunc, "the function matches -exclude-func")
+		default:
+			report(d)
+		}
+	}
+}
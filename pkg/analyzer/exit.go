@@ -0,0 +1,126 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ssa"
+)
+
+// Messages reporting a deferred close skipped by os.Exit on every path
+// following the defer, or on some of them
+const (
+	exitAfterDeferMessage      = "deferred %s.%s() does not run: os.Exit() at line %d exits without running deferred calls, move the defer and the code using the resource into a helper returning the exit code, as in os.Exit(run())"
+	exitMaybeAfterDeferMessage = "deferred %s.%s() may not run: os.Exit() at line %d exits without running deferred calls, move the defer and the code using the resource into a helper returning the exit code, as in os.Exit(run())"
+)
+
+// exitFuncs are the functions exiting the process without running the
+// deferred calls of the goroutine
+var exitFuncs = []string{"os.Exit"}
+
+// checkExitAfterDefer reports deferred closes of resources and clients in fn
+// that an os.Exit() following them skips, as in TestMain:
+//
+//	func TestMain(m *testing.M) {
+//		client, _ := spanner.NewClient(ctx, db)
+//		defer client.Close() // never runs
+//		os.Exit(m.Run())
+//	}
+func checkExitAfterDefer(pass *analysis.Pass, fn *ssa.Function, spannerTypes, clientTypes map[*types.Named]*ResourceType) {
+	if fn == nil || isGeneratedFile(pass, fn.Pos()) {
+		return
+	}
+
+	var exits, defers []ssa.CallInstruction
+	for _, block := range fn.Blocks {
+		for _, instr := range block.Instrs {
+			switch instr := instr.(type) {
+			case *ssa.Call:
+				if callsOneOf(instr.Common(), exitFuncs) {
+					exits = append(exits, instr)
+				}
+			case *ssa.Defer:
+				defers = append(defers, instr)
+			}
+		}
+	}
+	if len(exits) == 0 {
+		return
+	}
+
+	for _, d := range defers {
+		val := methodReceiver(d.Common())
+		if val == nil {
+			continue
+		}
+		rt := getSpannerType(val.Type(), spannerTypes)
+		if rt == nil {
+			rt = getSpannerType(val.Type(), clientTypes)
+		}
+		if rt == nil || !isCloseCall(d.Common(), val, rt) {
+			continue
+		}
+		for _, exit := range exits {
+			if !reachableAfter(d, exit, nil) {
+				continue
+			}
+			message := exitMaybeAfterDeferMessage
+			if exitsOnEveryPath(d, exits) {
+				message = exitAfterDeferMessage
+			}
+			pass.Report(analysis.Diagnostic{
+				Pos:      d.Pos(),
+				Category: categoryExitAfterDefer,
+				Message:  fmt.Sprintf(message, rt.QualifiedName(), rt.CloseMethod, pass.Fset.Position(exit.Pos()).Line),
+				Related: []analysis.RelatedInformation{{
+					Pos:     exit.Pos(),
+					Message: "exits here",
+				}},
+			})
+			break
+		}
+	}
+}
+
+// exitsOnEveryPath checks if every path from instr to the end of its function
+// goes through one of exits, so that the deferred calls registered before
+// never run. Paths returning or panicking run them.
+func exitsOnEveryPath(instr ssa.Instruction, exits []ssa.CallInstruction) bool {
+	isExit := make(map[ssa.Instruction]bool)
+	for _, exit := range exits {
+		isExit[exit] = true
+	}
+	seen := make(map[*ssa.BasicBlock]bool)
+	var exitsFrom func(block *ssa.BasicBlock, start int) bool
+	exitsFrom = func(block *ssa.BasicBlock, start int) bool {
+		for _, instr := range block.Instrs[start:] {
+			if isExit[instr] {
+				return true
+			}
+		}
+		if len(block.Succs) == 0 {
+			return false
+		}
+		for _, succ := range block.Succs {
+			// A loop leaves through its other paths
+			if seen[succ] {
+				continue
+			}
+			seen[succ] = true
+			if !exitsFrom(succ, 0) {
+				return false
+			}
+		}
+		return true
+	}
+
+	block := instr.Block()
+	seen[block] = true
+	for i, in := range block.Instrs {
+		if in == instr {
+			return exitsFrom(block, i+1)
+		}
+	}
+	return false
+}
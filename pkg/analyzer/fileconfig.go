@@ -0,0 +1,85 @@
+package analyzer
+
+import (
+	"flag"
+	"fmt"
+	"go/token"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// fileConfigOptions are the flags directiveConfig accepts: those of the
+// options checked function by function. Options applying to whole packages,
+// such as resource types or exclusions, are set for the package only.
+var fileConfigOptions = []string{
+	"suggest-single", "defer-before-use", "defer-within", "client-per-request", "client-in-loop",
+	"stream-cancel", "owner-goroutine", "close-errors", "duplicate-defers", "single-close",
+	"lenient", "exempt-constructor", "exempt-func", "close-helper", "consuming-func",
+}
+
+// fileOptions returns the options of the files of pass with directiveConfig
+// comments, set on top of opts. Invalid directives are skipped, and reported
+// if report is set.
+func fileOptions(pass *analysis.Pass, opts *Options, report bool) map[*token.File]*Options {
+	files := make(map[*token.File]*Options)
+	for _, file := range pass.Files {
+		directives := fileDirectives(file, directiveConfig)
+		if len(directives) == 0 {
+			continue
+		}
+		fo := opts.clone()
+		fs := flag.NewFlagSet(directiveConfig, flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		fo.bindFlags(fs)
+		for _, d := range directives {
+			for _, arg := range d.args {
+				if err := setFileOption(fs, arg); err != nil && report {
+					reportf(pass, d.pos, categoryDirective, "%s %s: %v", directiveConfig, arg, err)
+				}
+			}
+		}
+		files[pass.Fset.File(file.Pos())] = fo
+	}
+	return files
+}
+
+// setFileOption sets an option of a directiveConfig comment on fs, given as
+// name=value, or as name alone for boolean options
+func setFileOption(fs *flag.FlagSet, arg string) error {
+	name, value, ok := strings.Cut(arg, "=")
+	if !slices.Contains(fileConfigOptions, name) {
+		return fmt.Errorf("not an option that can be set per file, want one of %s", strings.Join(fileConfigOptions, ", "))
+	}
+	if !ok {
+		if b, isBool := fs.Lookup(name).Value.(interface{ IsBoolFlag() bool }); !isBool || !b.IsBoolFlag() {
+			return fmt.Errorf("missing value, want %s=value", name)
+		}
+		value = "true"
+	}
+	return fs.Set(name, value)
+}
+
+// clone returns a copy of o whose lists and maps can be set without
+// changing those of o
+func (o *Options) clone() *Options {
+	c := *o
+	c.Severities = maps.Clone(o.Severities)
+	c.GeneratedPatterns = slices.Clip(o.GeneratedPatterns)
+	c.Exclude = slices.Clip(o.Exclude)
+	c.ExcludeFuncs = slices.Clip(o.ExcludeFuncs)
+	c.Resources = slices.Clip(o.Resources)
+	c.SpannerPaths = slices.Clip(o.SpannerPaths)
+	c.DisabledResources = slices.Clip(o.DisabledResources)
+	c.ExemptConstructors = slices.Clip(o.ExemptConstructors)
+	c.ExemptFuncs = slices.Clip(o.ExemptFuncs)
+	c.AcquireFuncs = slices.Clip(o.AcquireFuncs)
+	c.LifecycleHooks = slices.Clip(o.LifecycleHooks)
+	c.CloseHelpers = slices.Clip(o.CloseHelpers)
+	c.ConsumingFuncs = slices.Clip(o.ConsumingFuncs)
+	c.Collectors = slices.Clip(o.Collectors)
+	return &c
+}
@@ -0,0 +1,206 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/ssa"
+)
+
+// deferFixes returns a fix inserting a deferred close right after the
+// statement acquiring val, or after the error check that follows it.
+// A non-deferred close statement on the same variable is removed.
+func deferFixes(pass *analysis.Pass, val ssa.Value, rt *ResourceType, pos token.Pos) []analysis.SuggestedFix {
+	point, ok := findDeferInsertPoint(pass, val, pos)
+	if !ok {
+		return nil
+	}
+
+	edits := []analysis.TextEdit{{
+		Pos:     point.pos,
+		End:     point.pos,
+		NewText: []byte(point.indent + "defer " + point.ident.Name + "." + rt.CloseMethod + "()\n"),
+	}}
+
+	// Remove explicit, non-deferred close statements
+	if val.Referrers() != nil {
+		for _, ref := range *val.Referrers() {
+			call, ok := ref.(*ssa.Call)
+			if !ok || !isCloseCall(call.Common(), val, rt) {
+				continue
+			}
+			stmt, ok := findNode(pass, call.Pos(), func(n *ast.ExprStmt) bool {
+				c, ok := n.X.(*ast.CallExpr)
+				return ok && c.Lparen == call.Pos()
+			})
+			if ok {
+				start, end := stmtLineRange(pass, stmt)
+				edits = append(edits, analysis.TextEdit{Pos: start, End: end})
+			}
+		}
+	}
+
+	return []analysis.SuggestedFix{{
+		Message:   "Defer " + rt.Name + "." + rt.CloseMethod + "()",
+		TextEdits: edits,
+	}}
+}
+
+// deferInsertPoint is where a deferred close of an acquired resource belongs
+type deferInsertPoint struct {
+	// pos is the start of the line following the acquisition or its error check
+	pos token.Pos
+	// indent is the indentation of the acquiring statement
+	indent string
+	// ident is the variable the resource is assigned to
+	ident *ast.Ident
+	// stmts is the statement list containing the acquiring statement
+	stmts []ast.Stmt
+	// after is the acquiring statement or its error check, in stmts
+	after ast.Stmt
+}
+
+// findDeferInsertPoint locates the statement acquiring val at pos and returns
+// the position right after it, or after the error check that follows it
+func findDeferInsertPoint(pass *analysis.Pass, val ssa.Value, pos token.Pos) (deferInsertPoint, bool) {
+	index := 0
+	if extract, ok := val.(*ssa.Extract); ok {
+		index = extract.Index
+	}
+
+	assign, ok := findNode(pass, pos, func(n *ast.AssignStmt) bool {
+		for _, rhs := range n.Rhs {
+			if call, ok := ast.Unparen(rhs).(*ast.CallExpr); ok && call.Lparen == pos {
+				return true
+			}
+		}
+		return false
+	})
+	if !ok {
+		return deferInsertPoint{}, false
+	}
+
+	// Find the variable the resource is assigned to
+	var ident *ast.Ident
+	if len(assign.Lhs) == len(assign.Rhs) {
+		for i, rhs := range assign.Rhs {
+			if call, ok := ast.Unparen(rhs).(*ast.CallExpr); ok && call.Lparen == pos {
+				ident, _ = assign.Lhs[i].(*ast.Ident)
+			}
+		}
+	} else if len(assign.Rhs) == 1 && index < len(assign.Lhs) {
+		ident, _ = assign.Lhs[index].(*ast.Ident)
+	}
+	if ident == nil || ident.Name == "_" {
+		return deferInsertPoint{}, false
+	}
+
+	// Insert after the error check of tuple acquisitions
+	after := ast.Stmt(assign)
+	stmts := enclosingStmtList(pass, assign)
+	if stmts == nil {
+		// Init statements of if and switch statements: the variable is scoped
+		// to the branches, and no single statement defers it on all of them
+		return deferInsertPoint{}, false
+	}
+	for i, stmt := range stmts {
+		if stmt == assign && i+1 < len(stmts) && isErrCheck(stmts[i+1], assign) {
+			after = stmts[i+1]
+		}
+	}
+
+	// Insert on a new line, keeping trailing comments of the statement in place
+	insert, ok := nextLineStart(pass, after.End())
+	if !ok {
+		return deferInsertPoint{}, false
+	}
+	return deferInsertPoint{
+		pos:    insert,
+		indent: lineIndent(pass, assign.Pos()),
+		ident:  ident,
+		stmts:  stmts,
+		after:  after,
+	}, true
+}
+
+// enclosingStmtList returns the statement list directly containing stmt
+func enclosingStmtList(pass *analysis.Pass, stmt ast.Stmt) []ast.Stmt {
+	for _, f := range pass.Files {
+		if f.FileStart > stmt.Pos() || stmt.Pos() > f.FileEnd {
+			continue
+		}
+		path, _ := astutil.PathEnclosingInterval(f, stmt.Pos(), stmt.End())
+		for i, node := range path {
+			if node != stmt || i+1 >= len(path) {
+				continue
+			}
+			switch parent := path[i+1].(type) {
+			case *ast.BlockStmt:
+				return parent.List
+			case *ast.CaseClause:
+				return parent.Body
+			case *ast.CommClause:
+				return parent.Body
+			}
+		}
+	}
+	return nil
+}
+
+// isErrCheck checks if stmt is an if statement testing an error assigned by assign
+func isErrCheck(stmt ast.Stmt, assign *ast.AssignStmt) bool {
+	ifStmt, ok := stmt.(*ast.IfStmt)
+	if !ok || ifStmt.Init != nil {
+		return false
+	}
+	cond, ok := ifStmt.Cond.(*ast.BinaryExpr)
+	if !ok || cond.Op != token.NEQ {
+		return false
+	}
+	x, ok := cond.X.(*ast.Ident)
+	if !ok {
+		return false
+	}
+	if y, ok := cond.Y.(*ast.Ident); !ok || y.Name != "nil" {
+		return false
+	}
+	for _, lhs := range assign.Lhs {
+		if ident, ok := lhs.(*ast.Ident); ok && ident.Name == x.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// lineIndent returns the leading whitespace of the line containing pos
+func lineIndent(pass *analysis.Pass, pos token.Pos) string {
+	file := pass.Fset.File(pos)
+	if file == nil {
+		return ""
+	}
+	content, err := pass.ReadFile(file.Name())
+	if err != nil {
+		return ""
+	}
+	start := file.Offset(file.LineStart(file.Line(pos)))
+	end := start
+	for end < len(content) && (content[end] == ' ' || content[end] == '\t') {
+		end++
+	}
+	return string(content[start:end])
+}
+
+// nextLineStart returns the start of the line following pos
+func nextLineStart(pass *analysis.Pass, pos token.Pos) (token.Pos, bool) {
+	file := pass.Fset.File(pos)
+	if file == nil {
+		return token.NoPos, false
+	}
+	line := file.Line(pos)
+	if line >= file.LineCount() {
+		return token.NoPos, false
+	}
+	return file.LineStart(line + 1), true
+}
@@ -0,0 +1,159 @@
+package analyzer
+
+import (
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ssa"
+)
+
+const methodNameRecv = "Recv"
+
+// gapicStreamingMethods are the apiv1 Client methods that open a server stream
+var gapicStreamingMethods = map[string]bool{
+	"ExecuteStreamingSql": true,
+	"StreamingRead":       true,
+	"BatchWrite":          true,
+}
+
+// contextCancelFuncs are the context constructors that return a cancel function
+var contextCancelFuncs = map[string]bool{
+	"WithCancel":        true,
+	"WithCancelCause":   true,
+	"WithTimeout":       true,
+	"WithTimeoutCause":  true,
+	"WithDeadline":      true,
+	"WithDeadlineCause": true,
+}
+
+// checkGapicStreams reports streaming calls on the low-level apiv1 Client
+// whose stream is neither drained nor bound to a context that is cancelled
+// with defer. gRPC only releases a stream once it is read to the end or its
+// context is done.
+func checkGapicStreams(pass *analysis.Pass, fn *ssa.Function) {
+	if fn == nil || isGeneratedFile(pass, fn.Pos()) {
+		return
+	}
+
+	for _, block := range fn.Blocks {
+		for _, instr := range block.Instrs {
+			call, ok := instr.(*ssa.Call)
+			if !ok || !isGapicStreamingCall(call) {
+				continue
+			}
+
+			if hasDeferredCancel(call.Common().Args[1]) || isStreamDrained(call) {
+				continue
+			}
+
+			reportf(pass, call.Pos(), categoryGapicStream, "apiv1.Client.%s() stream must be drained or its context cancelled with defer",
+				call.Common().StaticCallee().Name())
+		}
+	}
+}
+
+// isGapicStreamingCall checks if call opens a stream on an apiv1 Client
+func isGapicStreamingCall(call *ssa.Call) bool {
+	callee := call.Common().StaticCallee()
+	if callee == nil || !gapicStreamingMethods[callee.Name()] || len(call.Common().Args) < 2 {
+		return false
+	}
+
+	recv := callee.Signature.Recv()
+	if recv == nil {
+		return false
+	}
+	t := recv.Type()
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, ok := t.(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return false
+	}
+	return named.Obj().Name() == typeNameClient && matchesPkgPath(named.Obj().Pkg().Path(), pathGoogleSpannerAPIv1)
+}
+
+// hasDeferredCancel checks if ctx comes from a context constructor whose cancel
+// function is deferred, e.g. ctx, cancel := context.WithCancel(ctx); defer cancel()
+func hasDeferredCancel(ctx ssa.Value) bool {
+	extract, ok := ctx.(*ssa.Extract)
+	if !ok || extract.Index != 0 {
+		return false
+	}
+	call, ok := extract.Tuple.(*ssa.Call)
+	if !ok {
+		return false
+	}
+	callee := call.Common().StaticCallee()
+	if callee == nil || callee.Pkg == nil || callee.Pkg.Pkg.Path() != "context" || !contextCancelFuncs[callee.Name()] {
+		return false
+	}
+
+	if call.Referrers() == nil {
+		return false
+	}
+	for _, ref := range *call.Referrers() {
+		if cancel, ok := ref.(*ssa.Extract); ok && cancel.Index == 1 && isDeferredCall(cancel) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isDeferredCall checks if fn is called by a defer statement
+func isDeferredCall(fn ssa.Value) bool {
+	if fn.Referrers() == nil {
+		return false
+	}
+
+	for _, ref := range *fn.Referrers() {
+		if d, ok := ref.(*ssa.Defer); ok && d.Call.Value == fn {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isStreamDrained checks if the stream returned by call is read with Recv() in a loop
+func isStreamDrained(call *ssa.Call) bool {
+	if call.Referrers() == nil {
+		return false
+	}
+
+	for _, ref := range *call.Referrers() {
+		stream, ok := ref.(*ssa.Extract)
+		if !ok || stream.Index != 0 || stream.Referrers() == nil {
+			continue
+		}
+		for _, use := range *stream.Referrers() {
+			recv, ok := use.(*ssa.Call)
+			if ok && recv.Common().IsInvoke() && recv.Common().Method.Name() == methodNameRecv && inLoop(recv.Block()) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// inLoop checks if block is part of a cycle in the control flow graph
+func inLoop(block *ssa.BasicBlock) bool {
+	seen := make(map[*ssa.BasicBlock]bool)
+	stack := append([]*ssa.BasicBlock(nil), block.Succs...)
+	for len(stack) > 0 {
+		b := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if b == block {
+			return true
+		}
+		if seen[b] {
+			continue
+		}
+		seen[b] = true
+		stack = append(stack, b.Succs...)
+	}
+	return false
+}
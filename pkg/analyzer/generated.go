@@ -0,0 +1,133 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// DefaultGeneratedPatterns are the file name patterns of generated files
+// used when Options.GeneratedPatterns is nil, for generators that do not
+// write the standard "Code generated ... DO NOT EDIT." comment
+var DefaultGeneratedPatterns = []string{"*.yo.go", "*.pb.go", "*_gen.go", "*generated*"}
+
+// generatedPatternsNone is the value of -generated-pattern turning file name
+// patterns off, leaving the standard comment only
+const generatedPatternsNone = "none"
+
+// generatedFiles tells the generated files skipped by the analyzers run with
+// an Options
+type generatedFiles struct {
+	// check checks generated files, see Options.CheckGenerated
+	check    bool
+	patterns []*regexp.Regexp
+}
+
+// compileGenerated compiles the generated file patterns of opts
+func compileGenerated(opts *Options) (*generatedFiles, error) {
+	patterns := opts.GeneratedPatterns
+	if patterns == nil {
+		patterns = DefaultGeneratedPatterns
+	}
+	g := &generatedFiles{check: opts.CheckGenerated}
+	for _, pattern := range patterns {
+		expr, ok := strings.CutPrefix(pattern, excludeRegexpPrefix)
+		if !ok {
+			expr = globRegexp(pattern)
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("generated-pattern %q: %v", pattern, err)
+		}
+		g.patterns = append(g.patterns, re)
+	}
+	return g, nil
+}
+
+// defaultGenerated are the generated files of the default options, for
+// passes without registered ones
+var defaultGenerated = sync.OnceValue(func() *generatedFiles {
+	g, _ := compileGenerated(&Options{})
+	return g
+})
+
+// registeredGenerated are the generated files of the passes analyzed, see
+// generatedFiles.register
+var registeredGenerated sync.Map
+
+// register makes isGeneratedFile use g for pass, and returns a function to
+// call when pass is done
+func (g *generatedFiles) register(pass *analysis.Pass) func() {
+	registeredGenerated.Store(pass, g)
+	return func() { registeredGenerated.Delete(pass) }
+}
+
+// matches checks if filename matches one of the file name patterns
+func (g *generatedFiles) matches(filename string) bool {
+	filename = filepath.ToSlash(filename)
+	for _, re := range g.patterns {
+		if re.MatchString(filename) {
+			return true
+		}
+	}
+	return false
+}
+
+// isGeneratedFile checks if a position is in a generated file, or in a file
+// excluded with a file-level nolint directive. Generated files have the
+// standard "Code generated ... DO NOT EDIT." comment, or a name matching a
+// generated file pattern, and no directiveEnforce comment.
+func isGeneratedFile(pass *analysis.Pass, pos token.Pos) bool {
+	file := pass.Fset.File(pos)
+	if file == nil {
+		return false
+	}
+	g := defaultGenerated()
+	if v, ok := registeredGenerated.Load(pass); ok {
+		g = v.(*generatedFiles)
+	}
+	if hasFileLevelNolint(pass, pos) {
+		return true
+	}
+	if g.check {
+		return false
+	}
+
+	for _, f := range pass.Files {
+		if pass.Fset.File(f.Pos()) == file {
+			return (ast.IsGenerated(f) || g.matches(file.Name())) && !hasEnforceDirective(f)
+		}
+	}
+	return g.matches(file.Name())
+}
+
+// generatedPatternsFlag is a repeatable flag setting the generated file
+// patterns, replacing the defaults
+type generatedPatternsFlag []string
+
+func (f *generatedPatternsFlag) String() string {
+	if f == nil || *f == nil {
+		return strings.Join(DefaultGeneratedPatterns, ",")
+	}
+	if len(*f) == 0 {
+		return generatedPatternsNone
+	}
+	return strings.Join(*f, ",")
+}
+
+func (f *generatedPatternsFlag) Set(value string) error {
+	if value == generatedPatternsNone {
+		*f = []string{}
+		return nil
+	}
+	if *f == nil {
+		*f = []string{}
+	}
+	return (*stringsFlag)(f).Set(value)
+}
@@ -0,0 +1,160 @@
+package analyzer
+
+import (
+	"go/token"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ssa"
+)
+
+// goroutineMessage is appended to the message of resources acquired in a
+// goroutine, or escaping into one, so that the close is looked for there
+const goroutineMessage = " in the goroutine"
+
+// goroutineSpawners are the functions running the function passed to them in
+// a new goroutine, like a go statement
+var goroutineSpawners = []string{
+	"(*golang.org/x/sync/errgroup.Group).Go",
+	"(*golang.org/x/sync/errgroup.Group).TryGo",
+}
+
+// spawnedFunc returns the function instr runs in a new goroutine, or nil:
+// the callee of a go statement, or the function passed to a goroutineSpawner,
+// as in g.Go(func() error { ... })
+func spawnedFunc(instr ssa.Instruction) ssa.Value {
+	switch instr := instr.(type) {
+	case *ssa.Go:
+		return instr.Call.Value
+	case *ssa.Call:
+		if args := instr.Call.Args; len(args) > 0 && callsOneOf(&instr.Call, goroutineSpawners) {
+			return args[len(args)-1]
+		}
+	}
+	return nil
+}
+
+// isGoroutineBody checks if fn is a function literal run in a new goroutine:
+// go func() { ... }() or g.Go(func() error { ... })
+func isGoroutineBody(fn *ssa.Function) bool {
+	parent := fn.Parent()
+	if parent == nil {
+		return false
+	}
+
+	for _, block := range parent.Blocks {
+		for _, instr := range block.Instrs {
+			switch v := spawnedFunc(instr).(type) {
+			case *ssa.Function:
+				if v == fn {
+					return true
+				}
+			case *ssa.MakeClosure:
+				if v.Fn == fn {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// spawnedGoroutines returns the instructions starting the goroutines val
+// escapes into: as an argument, as in go process(txn), or captured by the
+// function literal they run, directly or through the cell of a captured
+// variable
+func spawnedGoroutines(val ssa.Value) []ssa.Instruction {
+	if val.Referrers() == nil {
+		return nil
+	}
+
+	var spawned []ssa.Instruction
+	for _, ref := range *val.Referrers() {
+		switch ref := ref.(type) {
+		case *ssa.Go:
+			spawned = append(spawned, ref)
+		case *ssa.MakeClosure:
+			for _, closureRef := range *ref.Referrers() {
+				if spawnedFunc(closureRef) == ref {
+					spawned = append(spawned, closureRef)
+				}
+			}
+		case *ssa.Store:
+			if alloc, ok := ref.Addr.(*ssa.Alloc); ok && ref.Val == val {
+				spawned = append(spawned, spawnedGoroutines(alloc)...)
+			}
+		}
+	}
+
+	return spawned
+}
+
+// isClosedInGoroutine checks if val is handed to a goroutine deferring its
+// close, which owns it from then on:
+//
+//	g.Go(func() error {
+//		defer txn.Close()
+//		...
+//	})
+//
+// or go process(txn), where process defers closing its parameter
+func isClosedInGoroutine(pass *analysis.Pass, val ssa.Value, rt *ResourceType) bool {
+	for _, instr := range spawnedGoroutines(val) {
+		if g, ok := instr.(*ssa.Go); ok && calleeClosesArg(pass, g.Common(), val, rt) {
+			return true
+		}
+
+		closure, ok := spawnedFunc(instr).(*ssa.MakeClosure)
+		if !ok {
+			continue
+		}
+		fn, ok := closure.Fn.(*ssa.Function)
+		if !ok {
+			continue
+		}
+		for i, binding := range closure.Bindings {
+			if i >= len(fn.FreeVars) || !isBindingOf(binding, val) {
+				continue
+			}
+			if defersClose(fn.FreeVars[i], rt) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// isBindingOf checks if binding is val or the cell of a variable holding val
+func isBindingOf(binding, val ssa.Value) bool {
+	if binding == val {
+		return true
+	}
+	alloc, ok := binding.(*ssa.Alloc)
+	if !ok || val.Referrers() == nil {
+		return false
+	}
+	for _, ref := range *val.Referrers() {
+		if store, ok := ref.(*ssa.Store); ok && store.Addr == alloc {
+			return true
+		}
+	}
+	return false
+}
+
+// defersClose checks if the free variable fv, or a load of it, is closed with
+// a defer in its function
+func defersClose(fv ssa.Value, rt *ResourceType) bool {
+	if findDeferredClose(fv, rt) != nil {
+		return true
+	}
+	if fv.Referrers() == nil {
+		return false
+	}
+	for _, ref := range *fv.Referrers() {
+		if load, ok := ref.(*ssa.UnOp); ok && load.Op == token.MUL && findDeferredClose(load, rt) != nil {
+			return true
+		}
+	}
+	return false
+}
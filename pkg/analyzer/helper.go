@@ -0,0 +1,168 @@
+package analyzer
+
+import (
+	"go/token"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// findDeferredHelperClose returns the defer of a close helper receiving val
+// along with other resources, such as
+//
+//	defer closeAll(txn, iter)            // func closeAll(cs ...interface{ Close() })
+//	defer closeAll(txn.Close, iter.Stop) // func closeAll(fs ...func())
+//
+// The resource reaches the helper converted to an interface or bound in a
+// method value, possibly as an element of a variadic argument. The helper is
+// either one of opts.CloseHelpers or a function of the same package closing
+// each argument it receives.
+func findDeferredHelperClose(val ssa.Value, rt *ResourceType, opts *Options) *ssa.Defer {
+	if val.Referrers() == nil {
+		return nil
+	}
+
+	for _, ref := range *val.Referrers() {
+		switch ref := ref.(type) {
+		case *ssa.MakeInterface:
+			if d := deferredHelperCall(ref, rt, opts, false); d != nil {
+				return d
+			}
+		case *ssa.MakeClosure:
+			// Method values such as txn.Close bind the resource directly
+			if closureClosesBinding(ref, val, rt) {
+				if d := deferredHelperCall(ref, rt, opts, true); d != nil {
+					return d
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// deferredHelperCall returns the defer of a close helper receiving arg, either
+// directly or as an element of a variadic argument. isFunc reports whether arg
+// is a function closing the resource rather than the resource itself.
+func deferredHelperCall(arg ssa.Value, rt *ResourceType, opts *Options, isFunc bool) *ssa.Defer {
+	for _, ref := range *arg.Referrers() {
+		switch ref := ref.(type) {
+		case *ssa.Defer:
+			if d := closingHelperDefer(ref, arg, rt, opts, isFunc, false); d != nil {
+				return d
+			}
+		case *ssa.Store:
+			// Variadic arguments are stored into a backing array, which is
+			// sliced and passed to the helper
+			ia, ok := ref.Addr.(*ssa.IndexAddr)
+			if !ok || ref.Val != arg || ia.X.Referrers() == nil {
+				continue
+			}
+			for _, arrRef := range *ia.X.Referrers() {
+				slice, ok := arrRef.(*ssa.Slice)
+				if !ok || slice.Referrers() == nil {
+					continue
+				}
+				for _, sliceRef := range *slice.Referrers() {
+					if d, ok := sliceRef.(*ssa.Defer); ok {
+						if d := closingHelperDefer(d, slice, rt, opts, isFunc, true); d != nil {
+							return d
+						}
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// closingHelperDefer returns d if the deferred call closes what is passed as
+// arg, or as the elements of arg when elems is set
+func closingHelperDefer(d *ssa.Defer, arg ssa.Value, rt *ResourceType, opts *Options, isFunc, elems bool) *ssa.Defer {
+	common := d.Common()
+	if common.Value == arg {
+		return nil
+	}
+	if callsOneOf(common, opts.CloseHelpers) {
+		return d
+	}
+
+	callee := common.StaticCallee()
+	if callee == nil || len(callee.Blocks) == 0 {
+		return nil
+	}
+	for i, a := range common.Args {
+		if a != arg || i >= len(callee.Params) {
+			continue
+		}
+		param := callee.Params[i]
+		if elems && closesElements(param, rt, isFunc) || !elems && closesParam(param, rt, isFunc) {
+			return d
+		}
+	}
+
+	return nil
+}
+
+// closesElements checks if the helper closes the elements of the slice
+// param it ranges over
+func closesElements(param ssa.Value, rt *ResourceType, isFunc bool) bool {
+	if param.Referrers() == nil {
+		return false
+	}
+
+	for _, ref := range *param.Referrers() {
+		ia, ok := ref.(*ssa.IndexAddr)
+		if !ok || ia.Referrers() == nil {
+			continue
+		}
+		for _, iaRef := range *ia.Referrers() {
+			if load, ok := iaRef.(*ssa.UnOp); ok && load.Op == token.MUL && closesParam(load, rt, isFunc) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// closesParam checks if the helper closes the resource held by v: by calling
+// it when it is a function, or by calling its close method, also after a
+// type assertion or type switch on an interface
+func closesParam(v ssa.Value, rt *ResourceType, isFunc bool) bool {
+	if v.Referrers() == nil {
+		return false
+	}
+	if !isFunc && closesValue(v, rt, 1) {
+		return true
+	}
+
+	for _, ref := range *v.Referrers() {
+		switch ref := ref.(type) {
+		case ssa.CallInstruction:
+			if isFunc && ref.Common().Value == v {
+				return true
+			}
+		case *ssa.TypeAssert:
+			if isFunc {
+				continue
+			}
+			if !ref.CommaOk {
+				if closesValue(ref, rt, 1) {
+					return true
+				}
+				continue
+			}
+			if ref.Referrers() == nil {
+				continue
+			}
+			for _, taRef := range *ref.Referrers() {
+				if extract, ok := taRef.(*ssa.Extract); ok && extract.Index == 0 && closesValue(extract, rt, 1) {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
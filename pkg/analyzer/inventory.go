@@ -0,0 +1,111 @@
+package analyzer
+
+import (
+	"go/token"
+	"go/types"
+	"reflect"
+	"slices"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// Result is the result of the analyzers, for analyzers requiring them to
+// build on the resources they track:
+//
+//	res := pass.ResultOf[analyzer.Analyzer].(*analyzer.Result)
+//	for fn, acquired := range res.Funcs { ... }
+//
+// The functions are those of buildssa.Analyzer.
+type Result struct {
+	// Funcs are the resources acquired in each source function of the
+	// package, including function literals, in instruction order. Functions
+	// acquiring none are absent.
+	Funcs map[*ssa.Function][]*Acquisition
+}
+
+// Acquisition is a resource acquired in a function
+type Acquisition struct {
+	// Value is the resource. For calls also returning an error, it is the
+	// extract of the resource.
+	Value ssa.Value
+	Type  *ResourceType
+	// Pos is the position of the acquiring call
+	Pos token.Pos
+	// Closes are the close calls of the resource, explicit or deferred, in
+	// the function and in the deferred function literals capturing it
+	Closes []CloseSite
+	// Returned reports if the function returns the resource, handing the
+	// close to its callers
+	Returned bool
+}
+
+// CloseSite is a close call of an acquired resource
+type CloseSite struct {
+	// Call is the close call, a *ssa.Call, *ssa.Defer or *ssa.Go
+	Call     ssa.CallInstruction
+	Deferred bool
+}
+
+// resultType is the type of the result of the analyzers
+var resultType = reflect.TypeOf((*Result)(nil))
+
+// inventory returns the resources acquired in the source functions of pass
+func inventory(funcs []*ssa.Function, spannerTypes map[*types.Named]*ResourceType, returns resourceReturns, opts *Options) *Result {
+	res := &Result{Funcs: make(map[*ssa.Function][]*Acquisition)}
+	for _, fn := range funcs {
+		if acquired := functionInventory(fn, spannerTypes, returns, opts); len(acquired) > 0 {
+			res.Funcs[fn] = acquired
+		}
+	}
+	return res
+}
+
+// functionInventory returns the resources acquired in fn
+func functionInventory(fn *ssa.Function, spannerTypes map[*types.Named]*ResourceType, returns resourceReturns, opts *Options) []*Acquisition {
+	var acquired []*Acquisition
+	for _, block := range fn.Blocks {
+		for _, instr := range block.Instrs {
+			val, rt, ok := resourceValue(fn, instr, spannerTypes)
+			if !ok || (!isAcquisition(val, rt, opts) && returns.callResource(val, spannerTypes) == nil) || isFromExemptConstructor(val, rt, opts) {
+				continue
+			}
+			acquired = append(acquired, &Acquisition{
+				Value:    val,
+				Type:     rt,
+				Pos:      acquisitionPos(val),
+				Closes:   closeSites(val, rt),
+				Returned: isReturnedFromFunction(fn, val),
+			})
+		}
+	}
+	return acquired
+}
+
+// closeSites returns the explicit and deferred closes of val, in source
+// order
+func closeSites(val ssa.Value, rt *ResourceType) []CloseSite {
+	var sites []CloseSite
+	seen := make(map[ssa.CallInstruction]bool)
+	for _, d := range findDeferredCloses(val, rt) {
+		if !seen[d] {
+			seen[d] = true
+			sites = append(sites, CloseSite{Call: d, Deferred: true})
+		}
+	}
+	if val.Referrers() == nil {
+		return sites
+	}
+	for _, ref := range *val.Referrers() {
+		call, ok := ref.(ssa.CallInstruction)
+		if !ok || seen[call] || !isCloseCall(call.Common(), val, rt) {
+			continue
+		}
+		seen[call] = true
+		_, deferred := call.(*ssa.Defer)
+		sites = append(sites, CloseSite{Call: call, Deferred: deferred})
+	}
+	slices.SortFunc(sites, func(a, b CloseSite) int {
+		return int(a.Call.Pos() - b.Call.Pos())
+	})
+	return sites
+}
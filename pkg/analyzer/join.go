@@ -0,0 +1,145 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ssa"
+)
+
+// closeBeforeJoinMessage reports a close that may run while goroutines
+// sharing the resource still use it
+const closeBeforeJoinMessage = "%s%s.%s() may run before the goroutine started at line %d is done with the resource: wait for the goroutines, e.g. with wg.Wait(), before closing it"
+
+// goroutineJoins are the methods waiting for the goroutines started with a
+// WaitGroup or a goroutineSpawner to finish
+var goroutineJoins = []string{
+	"(*sync.WaitGroup).Wait",
+	"(*golang.org/x/sync/errgroup.Group).Wait",
+}
+
+// checkCloseBeforeJoin reports closes in fn of resources shared with
+// goroutines that do not close them themselves, when no wait for the
+// goroutines runs between their start and the close:
+//
+//	defer wg.Wait()
+//	defer txn.Close() // runs first, while the partitions are executed
+//	for _, p := range partitions {
+//		wg.Add(1)
+//		go func() {
+//			defer wg.Done()
+//			iter := txn.Execute(ctx, p)
+//			...
+//		}()
+//	}
+//
+// This is a heuristic: a deferred close is accepted after any wait following
+// the start of the goroutines, or a wait deferred after it, which runs first.
+// An explicit close must follow a wait on every path.
+func checkCloseBeforeJoin(pass *analysis.Pass, fn *ssa.Function, spannerTypes map[*types.Named]*ResourceType) {
+	if fn == nil || isGeneratedFile(pass, fn.Pos()) {
+		return
+	}
+
+	joins := goroutineJoinCalls(fn)
+	for _, block := range fn.Blocks {
+		for _, instr := range block.Instrs {
+			// Closes run in a goroutine of their own, as in go txn.Close(),
+			// wait for nothing
+			closeCall, ok := instr.(ssa.CallInstruction)
+			if _, isGo := instr.(*ssa.Go); !ok || isGo {
+				continue
+			}
+			val := methodReceiver(closeCall.Common())
+			if val == nil {
+				continue
+			}
+			rt := getSpannerType(val.Type(), spannerTypes)
+			if rt == nil || !isCloseCall(closeCall.Common(), val, rt) {
+				continue
+			}
+			spawned, closed := spawnedGoroutines(val), isClosedInGoroutine(pass, val, rt)
+			if cell := variableCell(val); cell != nil {
+				// Function literals capture the variable holding the resource
+				spawned = append(spawned, spawnedGoroutines(cell)...)
+				closed = closed || isClosedInGoroutine(pass, cell, rt)
+			}
+			if len(spawned) == 0 || closed {
+				continue
+			}
+			reportCloseBeforeJoin(pass, rt, closeCall, spawned, joins)
+		}
+	}
+}
+
+// reportCloseBeforeJoin reports closeCall if one of the spawned goroutines is
+// not waited for before it runs
+func reportCloseBeforeJoin(pass *analysis.Pass, rt *ResourceType, closeCall ssa.CallInstruction, spawned []ssa.Instruction, joins []ssa.CallInstruction) {
+	prefix := ""
+	if _, ok := closeCall.(*ssa.Defer); ok {
+		prefix = "deferred "
+	}
+	for _, spawn := range spawned {
+		if joinedBefore(spawn, closeCall, joins) {
+			continue
+		}
+		pass.Report(analysis.Diagnostic{
+			Pos:      closeCall.Pos(),
+			Category: categoryCloseBeforeJoin,
+			Message:  fmt.Sprintf(closeBeforeJoinMessage, prefix, rt.QualifiedName(), rt.CloseMethod, pass.Fset.Position(spawn.Pos()).Line),
+			Related: []analysis.RelatedInformation{{
+				Pos:     spawn.Pos(),
+				Message: "goroutine started here",
+			}},
+		})
+		return
+	}
+}
+
+// variableCell returns the variable val is loaded from, or nil
+func variableCell(val ssa.Value) *ssa.Alloc {
+	if load, ok := val.(*ssa.UnOp); ok && load.Op == token.MUL {
+		alloc, _ := load.X.(*ssa.Alloc)
+		return alloc
+	}
+	return nil
+}
+
+// goroutineJoinCalls returns the calls to one of the goroutineJoins in fn,
+// deferred or not
+func goroutineJoinCalls(fn *ssa.Function) []ssa.CallInstruction {
+	var joins []ssa.CallInstruction
+	for _, block := range fn.Blocks {
+		for _, instr := range block.Instrs {
+			if call, ok := instr.(ssa.CallInstruction); ok && callsOneOf(call.Common(), goroutineJoins) {
+				joins = append(joins, call)
+			}
+		}
+	}
+	return joins
+}
+
+// joinedBefore checks if one of joins waits for the goroutine started by
+// spawn before close runs: a wait following the start that precedes an
+// explicit close on every path, or that precedes the return for a deferred
+// close, or a wait deferred after a deferred close
+func joinedBefore(spawn, close ssa.Instruction, joins []ssa.CallInstruction) bool {
+	_, deferred := close.(*ssa.Defer)
+	for _, join := range joins {
+		if _, ok := join.(*ssa.Defer); ok {
+			if deferred && dominates(close, join) {
+				return true
+			}
+			continue
+		}
+		if !reachableAfter(spawn, join, nil) {
+			continue
+		}
+		if deferred || dominates(join, close) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+package analyzer
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// langEnglish is the language of the messages of the checks, used as is
+const langEnglish = "en"
+
+// translation translates the text matching an English message format
+type translation struct {
+	english string
+	re      *regexp.Regexp
+	format  string
+}
+
+// catalogs holds the translations of each language, added with
+// RegisterMessages
+var catalogs struct {
+	sync.Mutex
+	m map[string][]translation
+}
+
+// RegisterMessages adds translations of the report messages to the
+// catalog of lang, as selected by Options.Lang. Keys are English messages,
+// or parts of them such as the explanations appended to the messages, with
+// %s and %d verbs standing for the variable parts, as in
+// "%s.%s() must be deferred". Values are their translations, referring to
+// the variable parts with indexed %s verbs, as in "%[1]s.%[2]s() を defer".
+// Longer keys take precedence, and text matching no key is left in English.
+// RegisterMessages panics if a key has no literal text; call it from an init
+// function.
+func RegisterMessages(lang string, messages map[string]string) {
+	catalogs.Lock()
+	defer catalogs.Unlock()
+	if catalogs.m == nil {
+		catalogs.m = make(map[string][]translation)
+	}
+	translations := catalogs.m[lang]
+	for english, format := range messages {
+		if strings.NewReplacer("%s", "", "%d", "").Replace(english) == "" {
+			panic(fmt.Sprintf("spannerclosecheck: message %q of language %q has no text", english, lang))
+		}
+		translations = slices.DeleteFunc(translations, func(t translation) bool { return t.english == english })
+		translations = append(translations, translation{english: english, re: messageRegexp(english), format: format})
+	}
+	slices.SortFunc(translations, func(a, b translation) int {
+		if n := len(b.english) - len(a.english); n != 0 {
+			return n
+		}
+		return strings.Compare(a.english, b.english)
+	})
+	catalogs.m[lang] = translations
+}
+
+// messageRegexp returns the regular expression matching the messages of an
+// English format. Arguments are names, expressions or line numbers, which
+// do not contain the commas and colons separating the parts of messages.
+func messageRegexp(format string) *regexp.Regexp {
+	var b strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 == len(format) {
+			b.WriteString(regexp.QuoteMeta(format[i : i+1]))
+			continue
+		}
+		i++
+		switch format[i] {
+		case 'd':
+			b.WriteString(`(\d+)`)
+		case 's':
+			if i+1 == len(format) {
+				// Nothing delimits a final argument but its end: take a
+				// name, as translated text may follow
+				b.WriteString(`([\w.]+)`)
+			} else {
+				b.WriteString(`([^,:]+?)`)
+			}
+		default:
+			b.WriteString(regexp.QuoteMeta(format[i-1 : i+1]))
+		}
+	}
+	return regexp.MustCompile(b.String())
+}
+
+// messageCatalog returns the translations of the language of opts, nil for
+// English
+func messageCatalog(opts *Options) ([]translation, error) {
+	if opts.Lang == "" || opts.Lang == langEnglish {
+		return nil, nil
+	}
+	catalogs.Lock()
+	defer catalogs.Unlock()
+	translations, ok := catalogs.m[opts.Lang]
+	if !ok {
+		langs := []string{langEnglish}
+		for lang := range catalogs.m {
+			langs = append(langs, lang)
+		}
+		slices.Sort(langs)
+		return nil, fmt.Errorf("lang %q: no messages, want one of %s", opts.Lang, strings.Join(langs, ", "))
+	}
+	// RegisterMessages may update the catalog in place
+	return slices.Clone(translations), nil
+}
+
+// translate returns message with the parts matching a translation
+// translated
+func translate(message string, translations []translation) string {
+	for _, t := range translations {
+		message = t.re.ReplaceAllStringFunc(message, func(s string) string {
+			var args []interface{}
+			for _, arg := range t.re.FindStringSubmatch(s)[1:] {
+				args = append(args, arg)
+			}
+			return fmt.Sprintf(t.format, args...)
+		})
+	}
+	return message
+}
+
+// reportTranslated wraps report to translate the messages of the
+// diagnostics, of their related information and of their suggested fixes
+func reportTranslated(report func(analysis.Diagnostic), translations []translation) func(analysis.Diagnostic) {
+	if len(translations) == 0 {
+		return report
+	}
+	return func(d analysis.Diagnostic) {
+		d.Message = translate(d.Message, translations)
+		d.Related = slices.Clone(d.Related)
+		for i := range d.Related {
+			d.Related[i].Message = translate(d.Related[i].Message, translations)
+		}
+		d.SuggestedFixes = slices.Clone(d.SuggestedFixes)
+		for i := range d.SuggestedFixes {
+			d.SuggestedFixes[i].Message = translate(d.SuggestedFixes[i].Message, translations)
+		}
+		report(d)
+	}
+}
@@ -0,0 +1,103 @@
+package analyzer
+
+// langJapanese is the language of the Japanese messages
+const langJapanese = "ja"
+
+func init() {
+	RegisterMessages(langJapanese, map[string]string{
+		// Unclosed resources, and the explanations appended to them
+		"%s.%s() must be deferred":             "%[1]s.%[2]s() を defer で呼び出す必要があります",
+		"%s.%s() must be deferred for %s":      "%[3]s の %[1]s.%[2]s() を defer で呼び出す必要があります",
+		" in the goroutine":                    "（goroutine 内）",
+		" in a previous iteration of the loop": "（ループの前のイテレーションで）",
+		", the deferred %s() at line %d closes the %s declared at line %d instead":                                                                                       "。%[2]s 行目で defer された %[1]s() は、代わりに %[4]s 行目で宣言された %[3]s を閉じます",
+		": the function recovers from panics, so a non-deferred %s() is skipped when one occurs":                                                                         "：この関数は panic から recover するため、defer されていない %[1]s() は panic の発生時に実行されません",
+		": the loop reading it with Next() at line %d can leave at line %d before reaching iterator.Done, and a partially read %s holds its stream until %s() is called": "：%[1]s 行目で Next() により読み出すループは iterator.Done に達する前に %[2]s 行目で抜ける可能性があり、読み出し途中の %[3]s は %[4]s() が呼ばれるまでストリームを保持します",
+		" [confidence: %s]": " [確信度: %[1]s]",
+
+		// Discarded resources
+		"%s acquired and discarded: the blank identifier drops the only reference, so %s() can never be called": "%[1]s が取得後に破棄されています：ブランク識別子により唯一の参照が失われるため、%[2]s() を呼び出せません",
+		"%s acquired and dropped: the result of %s() is unused, so %s() can never be called":                    "%[1]s が取得後に捨てられています：%[2]s() の結果が使われていないため、%[3]s() を呼び出せません",
+
+		// Placement of the defer
+		"%s.%s() must be deferred on every path from the acquisition":                                       "取得後のすべての経路で %[1]s.%[2]s() を defer する必要があります",
+		"%s.%s() must be deferred before the resource is first used":                                        "リソースを最初に使う前に %[1]s.%[2]s() を defer する必要があります",
+		"%s.%s() must be deferred after the error check, the resource may be nil when an error is returned": "%[1]s.%[2]s() はエラーチェックの後で defer する必要があります。エラーが返されたとき、リソースは nil の可能性があります",
+		"%s.%s() must be deferred within %d statements of the acquisition, found %d":                        "%[1]s.%[2]s() は取得から %[3]s 文以内に defer する必要があります（%[4]s 文後）",
+		"%s.%s() must be deferred for every element of the collection":                                      "コレクションのすべての要素について %[1]s.%[2]s() を defer する必要があります",
+		"%s.%s() must be called before %s is reassigned, the previous value leaks":                          "%[3]s を再代入する前に %[1]s.%[2]s() を呼び出す必要があります。以前の値がリークします",
+		"%s.%s() must be called by a Close method of %s, which owns it through field %s":                    "%[1]s.%[2]s() は、フィールド %[4]s を通じてリソースを所有する %[3]s の Close メソッドで呼び出す必要があります",
+		"cleanup function returned by %s() must be deferred":                                                "%[1]s() が返すクリーンアップ関数を defer で呼び出す必要があります",
+		"%s.%s() deferred inside a loop only runs when the function returns, holding the resource of every iteration until then: close it at the end of each iteration or move the loop body into a function":  "ループ内で defer された %[1]s.%[2]s() は関数の return 時にしか実行されず、それまで各イテレーションのリソースを保持します：各イテレーションの最後で閉じるか、ループ本体を関数に切り出してください",
+		"%s.%s() in a deferred closure only closes the last value of loop variable %s before Go 1.22 (file version %s), defer %s.%s() directly":                                                                "defer されたクロージャ内の %[1]s.%[2]s() は、Go 1.22 より前（ファイルのバージョン %[4]s）ではループ変数 %[3]s の最後の値しか閉じません。%[5]s.%[6]s() を直接 defer してください",
+		"deferred %s.%s() does not run: os.Exit() at line %d exits without running deferred calls, move the defer and the code using the resource into a helper returning the exit code, as in os.Exit(run())": "defer された %[1]s.%[2]s() は実行されません：%[3]s 行目の os.Exit() は defer された呼び出しを実行せずに終了します。os.Exit(run()) のように、defer とリソースを使うコードを終了コードを返すヘルパーに移してください",
+		"deferred %s.%s() may not run: os.Exit() at line %d exits without running deferred calls, move the defer and the code using the resource into a helper returning the exit code, as in os.Exit(run())":  "defer された %[1]s.%[2]s() は実行されない可能性があります：%[3]s 行目の os.Exit() は defer された呼び出しを実行せずに終了します。os.Exit(run()) のように、defer とリソースを使うコードを終了コードを返すヘルパーに移してください",
+		"error of deferred %s.%s() is discarded although the function returns an error: join it into the result, as in defer func() { err = errors.Join(err, %s.%s()) }()":                                     "関数は error を返しますが、defer された %[1]s.%[2]s() のエラーが破棄されています：defer func() { err = errors.Join(err, %[3]s.%[4]s()) }() のように結果に結合してください",
+
+		// Uses and closes after the close
+		"%s.%s() is called after %s() at line %d closed it":                                                            "%[1]s.%[2]s() が、%[4]s 行目の %[3]s() で閉じた後に呼ばれています",
+		"%s.%s() closes the resource again after %s() at line %d":                                                      "%[1]s.%[2]s() は %[4]s 行目の %[3]s() の後で再びリソースを閉じています",
+		"deferred %s.%s() closes the resource again after %s() at line %d":                                             "defer された %[1]s.%[2]s() は %[4]s 行目の %[3]s() の後で再びリソースを閉じています",
+		"deferred %s.%s() duplicates the defer at line %d: the resource is closed twice on return, remove one of them": "defer された %[1]s.%[2]s() は %[3]s 行目の defer と重複しています：return 時にリソースが二度閉じられるため、どちらかを削除してください",
+		"%s.%s() is called inside the loop reading it with Next() at line %d, which ends the iteration early: the next iteration reads a closed resource, close it after the loop instead": "%[1]s.%[2]s() が %[3]s 行目で Next() により読み出すループの中で呼ばれ、イテレーションが途中で終わります：次のイテレーションは閉じたリソースを読むため、ループの後で閉じてください",
+		"%s.%s() may run before the goroutine started at line %d is done with the resource: wait for the goroutines, e.g. with wg.Wait(), before closing it":                               "%[1]s.%[2]s() は、%[3]s 行目で開始した goroutine がリソースを使い終わる前に実行される可能性があります：閉じる前に wg.Wait() などで goroutine を待ってください",
+		"deferred %s.%s() may run before the goroutine started at line %d is done with the resource: wait for the goroutines, e.g. with wg.Wait(), before closing it":                      "defer された %[1]s.%[2]s() は、%[3]s 行目で開始した goroutine がリソースを使い終わる前に実行される可能性があります：閉じる前に wg.Wait() などで goroutine を待ってください",
+
+		// Ownership
+		"%s.%s() closes a client borrowed from the caller, leave closing it to its owner":                                                                                                                  "%[1]s.%[2]s() が呼び出し元から借りたクライアントを閉じています。閉じるのは所有者に任せてください",
+		"%s.%s() runs in a different goroutine than the one acquiring the resource at line %d: close it in the acquiring goroutine, after the other goroutines are done with it":                           "%[1]s.%[2]s() は %[3]s 行目でリソースを取得した goroutine とは別の goroutine で実行されています：ほかの goroutine が使い終わった後、取得した goroutine で閉じてください",
+		"ReadWriteTransaction is stored in %s, which outlives the callback receiving it: the transaction is only valid until the callback returns, use it inside the callback only":                        "ReadWriteTransaction が、受け取ったコールバックより長く生存する %[1]s に保存されています：トランザクションはコールバックが return するまでしか有効でないため、コールバック内でのみ使ってください",
+		"ReadWriteTransaction is stored in field %s, which outlives the callback receiving it: the transaction is only valid until the callback returns, use it inside the callback only":                  "ReadWriteTransaction が、受け取ったコールバックより長く生存する フィールド %[1]s に保存されています：トランザクションはコールバックが return するまでしか有効でないため、コールバック内でのみ使ってください",
+		"ReadWriteTransaction is stored in captured variable %s, which outlives the callback receiving it: the transaction is only valid until the callback returns, use it inside the callback only":      "ReadWriteTransaction が、受け取ったコールバックより長く生存する キャプチャされた変数 %[1]s に保存されています：トランザクションはコールバックが return するまでしか有効でないため、コールバック内でのみ使ってください",
+		"ReadWriteTransaction is stored in package-level variable %s, which outlives the callback receiving it: the transaction is only valid until the callback returns, use it inside the callback only": "ReadWriteTransaction が、受け取ったコールバックより長く生存する パッケージレベル変数 %[1]s に保存されています：トランザクションはコールバックが return するまでしか有効でないため、コールバック内でのみ使ってください",
+		"ReadWriteTransaction is stored in a channel, which outlives the callback receiving it: the transaction is only valid until the callback returns, use it inside the callback only":                 "ReadWriteTransaction が、受け取ったコールバックより長く生存するチャネルに保存されています：トランザクションはコールバックが return するまでしか有効でないため、コールバック内でのみ使ってください",
+
+		// Clients and streams
+		"%s() is called for every HTTP request, create the client once at process scope and share it":                                                                                                                                "%[1]s() が HTTP リクエストごとに呼ばれています。クライアントはプロセス単位で一度だけ作成して共有してください",
+		"%s() is called inside a loop, create the client once and reuse it":                                                                                                                                                          "%[1]s() がループ内で呼ばれています。クライアントは一度だけ作成して再利用してください",
+		"%s() is called for every %s, create the client once and reuse it":                                                                                                                                                           "%[1]s() が %[2]s ごとに呼ばれています。クライアントは一度だけ作成して再利用してください",
+		"%s() is assigned to package-level variable %s, which is not closed on shutdown: defer %s.%s() in main, close it in a lifecycle hook or in a function declared with " + directiveShutdown:                                    "%[1]s() がパッケージレベル変数 %[2]s に代入されていますが、シャットダウン時に閉じられません：main で %[3]s.%[4]s() を defer するか、ライフサイクルフック、または " + directiveShutdown + " を付けた関数で閉じてください",
+		"apiv1.Client.%s() stream must be drained or its context cancelled with defer":                                                                                                                                               "apiv1.Client.%[1]s() のストリームは最後まで読み切るか、そのコンテキストを defer で cancel する必要があります",
+		"cancel of %s() is not deferred: the context is used by the streaming read %s() at line %d, whose gRPC stream leaks if the function returns or panics before cancel() runs, defer cancel() right after creating the context": "%[1]s() の cancel が defer されていません：このコンテキストを使う %[3]s 行目のストリーミング読み取り %[2]s() は、cancel() の実行前に関数が return または panic すると gRPC ストリームがリークします。コンテキストの作成直後に cancel() を defer してください",
+
+		// Style
+		"%s.%s() is redundant: the %s from %s() releases itself":                                                                                                                    "%[1]s.%[2]s() は不要です：%[4]s() から得た %[3]s は自動的に解放されます",
+		"ReadOnlyTransaction is used for a single statement, use Client.Single() instead":                                                                                           "ReadOnlyTransaction が単一のステートメントにしか使われていません。代わりに Client.Single() を使ってください",
+		"SessionPoolConfig.MinOpened %s is greater than MaxOpened %s: NewClientWithConfig fails, lower MinOpened or raise MaxOpened":                                                "SessionPoolConfig.MinOpened %[1]s が MaxOpened %[2]s より大きいため NewClientWithConfig が失敗します：MinOpened を下げるか MaxOpened を上げてください",
+		"SessionPoolConfig.%s is negative: NewClientWithConfig fails":                                                                                                               "SessionPoolConfig.%[1]s が負の値のため NewClientWithConfig が失敗します",
+		"SessionPoolConfig.WriteSessions %s is outside [0, 1]: NewClientWithConfig fails":                                                                                           "SessionPoolConfig.WriteSessions %[1]s が [0, 1] の範囲外のため NewClientWithConfig が失敗します",
+		"SessionPoolConfig.MaxIdle is 0 with MaxOpened %s: sessions beyond MinOpened are deleted as soon as they are idle and created again under load, raise MaxIdle or MinOpened": "MaxOpened %[1]s に対して SessionPoolConfig.MaxIdle が 0 です：MinOpened を超えるセッションはアイドルになるとすぐ削除され、負荷がかかると再作成されます。MaxIdle か MinOpened を上げてください",
+
+		// Directives
+		"%s has no method %s() to close it with": "%[1]s には閉じるためのメソッド %[2]s() がありません",
+		"%s has no parameter %s to close":        "%[1]s には閉じる対象の引数 %[2]s がありません",
+
+		// Severities of the reports printed by the spannerclosecheck command
+		"warning: ": "警告: ",
+		"info: ":    "情報: ",
+
+		// Related information
+		"resource acquired here":                   "ここでリソースを取得",
+		"acquired here":                            "ここで取得",
+		"resource used before the defer statement": "defer 文より前にリソースを使用",
+		"error checked here":                       "ここでエラーをチェック",
+		"first closed here":                        "ここで最初に閉じています",
+		"first deferred here":                      "ここで最初に defer",
+		"closed here":                              "ここで閉じています",
+		"exits here":                               "ここで終了",
+		"goroutine started here":                   "ここで goroutine を開始",
+		"read here":                                "ここで読み出し",
+		"streaming read":                           "ストリーミング読み取り",
+
+		// Suggested fixes
+		"Defer %s.%s()":                          "%[1]s.%[2]s() を defer する",
+		"Move defer after the acquisition":       "defer を取得の直後に移動する",
+		"Move defer before the first use":        "defer を最初の使用の前に移動する",
+		"Move defer after the error check":       "defer をエラーチェックの後に移動する",
+		"Move the loop body into a function":     "ループ本体を関数に切り出す",
+		"Remove the duplicated deferred %s.%s()": "重複した defer の %[1]s.%[2]s() を削除する",
+		"Remove %s.%s()":                         "%[1]s.%[2]s() を削除する",
+		"Use Client.Single()":                    "Client.Single() を使う",
+		"Join the error of %s.%s() into %s":      "%[1]s.%[2]s() のエラーを %[3]s に結合する",
+	})
+}
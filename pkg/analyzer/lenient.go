@@ -0,0 +1,138 @@
+package analyzer
+
+import (
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// closedOnAllPaths checks if a non-deferred close of val runs on every path
+// from the acquisition to a return of the function, i.e. the closes together
+// post-dominate the acquisition. Paths ending in a panic are not considered,
+// nor are the error branches of the acquiring call, where val is not valid.
+func closedOnAllPaths(val ssa.Value, rt *ResourceType) bool {
+	acq, ok := val.(ssa.Instruction)
+	if !ok {
+		return false
+	}
+	closes := nonDeferredCloses(val, rt)
+	if len(closes) == 0 {
+		return false
+	}
+	return runsOnAllPaths(acq, val, closes)
+}
+
+// runsOnAllPaths checks if one of instrs runs on every path from acq, the
+// instruction acquiring val, to a return of the function, leaving out paths
+// ending in a panic and the error branches of the acquiring call
+func runsOnAllPaths(acq ssa.Instruction, val ssa.Value, instrs []ssa.Instruction) bool {
+	closes := instrs
+	closeBlocks := make(map[*ssa.BasicBlock]bool)
+	for _, c := range closes {
+		if c.Block() == acq.Block() && !dominates(acq, c) {
+			continue
+		}
+		closeBlocks[c.Block()] = true
+	}
+
+	// A close later in the acquiring block runs on every path
+	start := acq.Block()
+	if closeBlocks[start] {
+		return true
+	}
+
+	errVal := acquisitionError(val)
+	seen := make(map[*ssa.BasicBlock]bool)
+	stack := liveSuccs(start, errVal)
+	for len(stack) > 0 {
+		b := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[b] || closeBlocks[b] {
+			continue
+		}
+		seen[b] = true
+
+		// Looping back to the acquisition acquires again before closing
+		if b == start {
+			return false
+		}
+		switch b.Instrs[len(b.Instrs)-1].(type) {
+		case *ssa.Return:
+			return false
+		case *ssa.Panic:
+			continue
+		}
+		stack = append(stack, liveSuccs(b, errVal)...)
+	}
+	return true
+}
+
+// acquisitionError returns the error result of the call acquiring val, e.g.
+// err in txn, err := client.BatchReadOnlyTransaction(...), or nil
+func acquisitionError(val ssa.Value) ssa.Value {
+	extract, ok := val.(*ssa.Extract)
+	if !ok || extract.Tuple.Referrers() == nil {
+		return nil
+	}
+	tuple := extract.Tuple.Type().(*types.Tuple)
+	last := tuple.Len() - 1
+	if !types.Identical(tuple.At(last).Type(), types.Universe.Lookup("error").Type()) {
+		return nil
+	}
+	for _, ref := range *extract.Tuple.Referrers() {
+		if e, ok := ref.(*ssa.Extract); ok && e.Index == last {
+			return e
+		}
+	}
+	return nil
+}
+
+// liveSuccs returns the successors of b, leaving out the branch taken when
+// errVal is not nil
+func liveSuccs(b *ssa.BasicBlock, errVal ssa.Value) []*ssa.BasicBlock {
+	succs := b.Succs
+	if errVal == nil {
+		return succs
+	}
+	ifInstr, ok := b.Instrs[len(b.Instrs)-1].(*ssa.If)
+	if !ok {
+		return succs
+	}
+	cond, ok := ifInstr.Cond.(*ssa.BinOp)
+	if !ok || !comparesWithNil(cond, errVal) {
+		return succs
+	}
+	switch cond.Op {
+	case token.NEQ:
+		return succs[1:]
+	case token.EQL:
+		return succs[:1]
+	}
+	return succs
+}
+
+// comparesWithNil checks if cond compares v with nil, directly or loaded from
+// the variable v is stored into, such as a named result captured by a closure
+func comparesWithNil(cond *ssa.BinOp, v ssa.Value) bool {
+	isNil := func(x ssa.Value) bool {
+		c, ok := x.(*ssa.Const)
+		return ok && c.IsNil()
+	}
+	isV := func(x ssa.Value) bool {
+		if x == v {
+			return true
+		}
+		load, ok := x.(*ssa.UnOp)
+		if !ok || load.Op != token.MUL || v.Referrers() == nil {
+			return false
+		}
+		for _, ref := range *v.Referrers() {
+			if store, ok := ref.(*ssa.Store); ok && store.Val == v && store.Addr == load.X {
+				return true
+			}
+		}
+		return false
+	}
+	return (isV(cond.X) && isNil(cond.Y)) || (isV(cond.Y) && isNil(cond.X))
+}
@@ -0,0 +1,126 @@
+package analyzer
+
+import (
+	"go/token"
+	"slices"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// defaultLifecycleHooks are the lifecycle registration functions recognized
+// without configuration, including the cleanup functions of tests, which run
+// when the test and its subtests complete
+var defaultLifecycleHooks = []string{
+	"(go.uber.org/fx.Lifecycle).Append",
+	"(testing.TB).Cleanup",
+	// testing.T, B and F promote Cleanup from their embedded common
+	"(*testing.common).Cleanup",
+}
+
+// maxHookFlowDepth limits how many values a hook is followed through on its
+// way to the registration call, e.g. closure -> fx.Hook{OnStop: ...} -> Append
+const maxHookFlowDepth = 5
+
+// isClosedByLifecycleHook checks if val is closed by a closure or method value
+// registered with a lifecycle manager, such as
+//
+//	lc.Append(fx.Hook{OnStop: func(context.Context) error { return c.Close() }})
+//	t.Cleanup(txn.Close)
+func isClosedByLifecycleHook(val ssa.Value, rt *ResourceType, opts *Options) bool {
+	if val.Referrers() == nil {
+		return false
+	}
+
+	for _, ref := range *val.Referrers() {
+		switch ref := ref.(type) {
+		case *ssa.MakeClosure:
+			// Method values such as c.Close bind the resource directly
+			if isRegisteredHook(ref, val, rt, opts) {
+				return true
+			}
+		case *ssa.Store:
+			// Captured variables are stored in a local cell shared with the closure
+			alloc, ok := ref.Addr.(*ssa.Alloc)
+			if !ok || ref.Val != val || alloc.Referrers() == nil {
+				continue
+			}
+			for _, allocRef := range *alloc.Referrers() {
+				switch allocRef := allocRef.(type) {
+				case *ssa.MakeClosure:
+					if isRegisteredHook(allocRef, alloc, rt, opts) {
+						return true
+					}
+				case *ssa.UnOp:
+					// Method values of the variable read from the cell: t.Cleanup(txn.Close)
+					// with txn captured by a subtest
+					if allocRef.Op == token.MUL && isClosedByLifecycleHook(allocRef, rt, opts) {
+						return true
+					}
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// isRegisteredHook checks if closure closes the resource bound to binding and
+// is passed to a lifecycle registration function
+func isRegisteredHook(closure *ssa.MakeClosure, binding ssa.Value, rt *ResourceType, opts *Options) bool {
+	hooks := slices.Concat(defaultLifecycleHooks, opts.LifecycleHooks)
+	return flowsToHookRegistration(closure, hooks, 0) && closureClosesBinding(closure, binding, rt)
+}
+
+// flowsToHookRegistration checks if val reaches an argument of a call to one
+// of hooks, directly or through struct fields, conversions and the results of
+// hook constructors like fx.StopHook
+func flowsToHookRegistration(val ssa.Value, hooks []string, depth int) bool {
+	if depth > maxHookFlowDepth || val.Referrers() == nil {
+		return false
+	}
+
+	for _, ref := range *val.Referrers() {
+		switch ref := ref.(type) {
+		case ssa.CallInstruction:
+			common := ref.Common()
+			if common.Value == val {
+				continue
+			}
+			if callsOneOf(common, hooks) {
+				return true
+			}
+			if call, ok := ref.(*ssa.Call); ok && flowsToHookRegistration(call, hooks, depth+1) {
+				return true
+			}
+		case *ssa.Store:
+			if ref.Val != val {
+				continue
+			}
+			// Follow the enclosing struct or array of element stores:
+			// fx.Hook{OnStop: f}, or the arguments of a variadic call
+			addr := ref.Addr
+			for {
+				if fa, ok := addr.(*ssa.FieldAddr); ok {
+					addr = fa.X
+				} else if ia, ok := addr.(*ssa.IndexAddr); ok {
+					addr = ia.X
+				} else {
+					break
+				}
+			}
+			if flowsToHookRegistration(addr, hooks, depth+1) {
+				return true
+			}
+		case *ssa.UnOp:
+			if ref.Op == token.MUL && flowsToHookRegistration(ref, hooks, depth+1) {
+				return true
+			}
+		case *ssa.MakeInterface, *ssa.ChangeType, *ssa.Slice:
+			if flowsToHookRegistration(ref.(ssa.Value), hooks, depth+1) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
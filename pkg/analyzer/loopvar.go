@@ -0,0 +1,121 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"go/version"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ssa"
+)
+
+// loopVarMessage reports a deferred closure closing a loop variable shared by
+// all iterations, so that every deferred call closes the last value
+const loopVarMessage = "%s.%s() in a deferred closure only closes the last value of loop variable %s before Go 1.22 (file version %s), defer %s.%s() directly"
+
+// loopVarSemantics is the first Go version declaring a new loop variable for
+// each iteration
+const loopVarSemantics = "go1.22"
+
+// checkLoopVarCaptures reports deferred closures in fn closing a variable
+// declared by an enclosing for or range statement, in files whose language
+// version predates per-iteration loop variables:
+//
+//	for _, iter := range iters {
+//		defer func() { iter.Stop() }()
+//	}
+//
+// The closures run when fn returns, after the loop has overwritten the
+// variable, so only the last value is closed, as many times as the loop ran.
+// The language version comes from go.mod, or from a //go:build constraint of
+// the file. Files of an unknown version get the current semantics.
+func checkLoopVarCaptures(pass *analysis.Pass, fn *ssa.Function, spannerTypes map[*types.Named]*ResourceType) {
+	body := funcBody(fn)
+	if body == nil || isGeneratedFile(pass, fn.Pos()) {
+		return
+	}
+	v := fileVersion(pass, body.Pos())
+	if v == "" || version.Compare(v, loopVarSemantics) >= 0 {
+		return
+	}
+
+	// Loop variables and deferred closures of fn itself, nested function
+	// literals are checked as functions of their own
+	loopVars := make(map[*types.Var]bool)
+	var closures []*ast.FuncLit
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.RangeStmt:
+			if n.Tok == token.DEFINE {
+				addLoopVars(pass, loopVars, n.Key, n.Value)
+			}
+		case *ast.ForStmt:
+			if init, ok := n.Init.(*ast.AssignStmt); ok && init.Tok == token.DEFINE {
+				addLoopVars(pass, loopVars, init.Lhs...)
+			}
+		case *ast.DeferStmt:
+			if lit, ok := ast.Unparen(n.Call.Fun).(*ast.FuncLit); ok {
+				closures = append(closures, lit)
+			}
+		}
+		return true
+	})
+	if len(loopVars) == 0 {
+		return
+	}
+
+	for _, lit := range closures {
+		ast.Inspect(lit.Body, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			sel, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			x, ok := ast.Unparen(sel.X).(*ast.Ident)
+			if !ok {
+				return true
+			}
+			obj, ok := pass.TypesInfo.Uses[x].(*types.Var)
+			if !ok || !loopVars[obj] {
+				return true
+			}
+			rt := getSpannerType(obj.Type(), spannerTypes)
+			if rt == nil || !rt.isCloseMethod(sel.Sel.Name) {
+				return true
+			}
+			reportf(pass, call.Pos(), categoryLoopVar, loopVarMessage, rt.QualifiedName(), rt.CloseMethod, x.Name, v, x.Name, rt.CloseMethod)
+			return true
+		})
+	}
+}
+
+// addLoopVars adds the variables declared by the identifiers among exprs
+func addLoopVars(pass *analysis.Pass, loopVars map[*types.Var]bool, exprs ...ast.Expr) {
+	for _, expr := range exprs {
+		if id, ok := expr.(*ast.Ident); ok {
+			if obj, ok := pass.TypesInfo.Defs[id].(*types.Var); ok {
+				loopVars[obj] = true
+			}
+		}
+	}
+}
+
+// fileVersion returns the Go language version of the file containing pos,
+// from its //go:build constraint or the module, or "" if unknown
+func fileVersion(pass *analysis.Pass, pos token.Pos) string {
+	for _, f := range pass.Files {
+		if f.FileStart > pos || pos > f.FileEnd {
+			continue
+		}
+		if v := pass.TypesInfo.FileVersions[f]; v != "" {
+			return v
+		}
+	}
+	return pass.Pkg.GoVersion()
+}
@@ -0,0 +1,52 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// MessageData is the data of Options.MessageTemplate, for one report
+type MessageData struct {
+	// Message is the wording of the report, in Options.Lang
+	Message string
+	// Category is the category of the report, such as not-deferred
+	Category string
+	// Confidence is the confidence of the report: low, medium or high
+	Confidence string
+}
+
+// compileMessageTemplate compiles the message template of opts, or returns
+// nil if it has none
+func compileMessageTemplate(opts *Options) (*template.Template, error) {
+	if opts.MessageTemplate == "" {
+		return nil, nil
+	}
+	tmpl, err := template.New("message-template").Parse(opts.MessageTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("message-template: %v", err)
+	}
+	// Fields missing from MessageData only fail on execution
+	if err := tmpl.Execute(new(strings.Builder), MessageData{}); err != nil {
+		return nil, fmt.Errorf("message-template: %v", err)
+	}
+	return tmpl, nil
+}
+
+// reportTemplated wraps report to format the messages of the diagnostics
+// with tmpl, if not nil
+func reportTemplated(report func(analysis.Diagnostic), tmpl *template.Template) func(analysis.Diagnostic) {
+	if tmpl == nil {
+		return report
+	}
+	return func(d analysis.Diagnostic) {
+		var b strings.Builder
+		data := MessageData{Message: d.Message, Category: d.Category, Confidence: ConfidenceOf(d).String()}
+		if err := tmpl.Execute(&b, data); err == nil {
+			d.Message = b.String()
+		}
+		report(d)
+	}
+}
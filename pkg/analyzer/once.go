@@ -0,0 +1,17 @@
+package analyzer
+
+import "golang.org/x/tools/go/ssa"
+
+const funcNameOnceDo = "(*sync.Once).Do"
+
+// invokesClosure checks if call runs closure: either by calling it directly,
+// or through sync.Once.Do, which calls its function on first use, as in
+// closeOnce.Do(txn.Close)
+func invokesClosure(call ssa.CallInstruction, closure ssa.Value) bool {
+	common := call.Common()
+	if common.Value == closure {
+		return true
+	}
+	callee := common.StaticCallee()
+	return callee != nil && callee.String() == funcNameOnceDo && len(common.Args) == 2 && common.Args[1] == closure
+}
@@ -0,0 +1,490 @@
+package analyzer
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Options configures optional checks of the analyzer
+type Options struct {
+	// SuggestSingle reports ReadOnlyTransactions that run a single statement
+	// and could use Client.Single() instead
+	SuggestSingle bool
+
+	// DeferBeforeUse requires the deferred close to be registered before the
+	// resource is first used, so a panic in between cannot leak it
+	DeferBeforeUse bool
+
+	// DeferWithin requires the deferred close to be registered at most this
+	// many statements after the acquisition, or after its error check,
+	// counting the defer statement: 1 requires it on the next statement.
+	// Zero turns the check off.
+	DeferWithin int
+
+	// ClientPerRequest reports Spanner clients created inside HTTP request
+	// handlers instead of once at process scope
+	ClientPerRequest bool
+
+	// ClientInLoop reports Spanner clients created inside loops or callbacks
+	// invoked once per event, such as controller-runtime Reconcile methods
+	ClientInLoop bool
+
+	// PackageClients reports Spanner clients assigned to package-level
+	// variables that are not closed on a recognized shutdown path
+	PackageClients bool
+
+	// SessionPool reports spanner.SessionPoolConfig literals with suspicious
+	// values, such as a MinOpened greater than MaxOpened
+	SessionPool bool
+
+	// StreamCancel reports contexts derived with context.WithCancel,
+	// WithTimeout or WithDeadline and passed to Spanner streaming reads, whose
+	// cancel function is not deferred
+	StreamCancel bool
+
+	// OwnerGoroutine reports closes of resources running in another
+	// goroutine than the one acquiring them
+	OwnerGoroutine bool
+
+	// CloseErrors reports deferred closes returning an error, such as those of
+	// custom resources, whose error is discarded in functions returning an
+	// error
+	CloseErrors bool
+
+	// DuplicateDefers reports deferred closes of a resource that already has
+	// a deferred close
+	DuplicateDefers bool
+
+	// WholeProgram builds the SSA of every dependency from source, to follow
+	// resources into the functions of other packages that return or close
+	// them where the facts of their packages do not tell. It needs
+	// ProgramPackages, which only the spannerclosecheck command sets.
+	WholeProgram bool
+
+	// SingleClose sets the severity of the report of closes of transactions
+	// from Client.Single() or another exempt constructor, which release
+	// themselves, of category single-close. It defaults to SeverityInfo;
+	// like warnings, info reports are printed to WarningOutput when it is
+	// set. A single-close key of Severities takes precedence.
+	SingleClose Severity
+
+	// MinConfidence drops the reports of a lower confidence, see Confidence.
+	// It defaults to ConfidenceLow, reporting everything.
+	MinConfidence Confidence
+
+	// Severities sets the severity of checks, by the category of their
+	// reports, such as not-deferred, or of resource types, by the name their
+	// reports mention, such as RowIterator: SeverityError, the default, or
+	// SeverityWarning. Categories take precedence over resource types.
+	// Warnings are printed to WarningOutput when it is set.
+	Severities map[string]Severity
+
+	// MessageTemplate formats the messages of the reports, as a text/template
+	// of MessageData, such as "{{.Message}} (runbook: https://wiki/spanner#{{.Category}})",
+	// to add team-specific text. Empty keeps the default wording.
+	MessageTemplate string
+
+	// Lang is the language of the reports: en, the default, ja, or a
+	// language registered with RegisterMessages
+	Lang string
+
+	// CheckGenerated checks generated files, which are skipped by default:
+	// files with the standard "Code generated ... DO NOT EDIT." comment, and
+	// files matching GeneratedPatterns
+	CheckGenerated bool
+
+	// GeneratedPatterns are the file names of generated files lacking the
+	// standard comment: globs matched like Exclude, such as *.yo.go, or
+	// regular expressions prefixed with "re:". Nil means
+	// DefaultGeneratedPatterns, and an empty slice the comment only.
+	GeneratedPatterns []string
+
+	// Exclude skips the reports in files or packages matching one of its
+	// patterns: globs matching whole elements of file paths or import
+	// paths, such as third_party or **/migrations/*.go, or regular
+	// expressions prefixed with "re:"
+	Exclude []string
+
+	// ExcludeFuncs skips the reports in functions whose full name, such as
+	// example.com/app.NewFixture or (*example.com/app.mockStore).Get,
+	// matches one of its regular expressions, including the function
+	// literals they declare
+	ExcludeFuncs []string
+
+	// SkipTests skips the reports in _test.go files, to check production
+	// code only
+	SkipTests bool
+
+	// TestsOnly skips the reports outside _test.go files, to hunt for
+	// resources leaked by test suites
+	TestsOnly bool
+
+	// Lenient accepts a non-deferred Close()/Stop() that runs on every path
+	// from the acquisition to a return, instead of requiring defer
+	Lenient bool
+
+	// Resources registers additional resource types, such as in-house
+	// wrappers holding Spanner resources, that must be closed with defer
+	Resources []ResourceType
+
+	// SpannerPaths are other import paths of the Spanner package, such as
+	// internal mirrors or forks like example.com/third_party/spanner, checked
+	// like cloud.google.com/go/spanner along with their subpackages, such as
+	// apiv1. They apply to every analyzer of the process.
+	SpannerPaths []string
+
+	// DisabledResources turns off the checks of resource types by their
+	// qualified name, such as RowIterator or ourdb.Txn, or by their import
+	// path and name, such as example.com/ourdb.Txn
+	DisabledResources []string
+
+	// ExemptConstructors lists additional functions or methods, for any
+	// resource type, whose results release themselves like Client.Single().
+	// Names are either bare, e.g. OneShotTxn, or fully qualified,
+	// e.g. (*example.com/ourdb.DB).OneShotTxn. Names given as Type=Func,
+	// e.g. ReadOnlyTransaction=CachedTxn, apply to the resource type named
+	// like in DisabledResources only.
+	ExemptConstructors []string
+
+	// ExemptFuncs lists functions or methods, matched like
+	// ExemptConstructors, whose resources live as long as the process, such
+	// as main, TestMain or the setup of long-lived daemons. Resources they
+	// and the function literals they declare acquire need no close, and
+	// deferred closes skipped by os.Exit in them are not reported.
+	ExemptFuncs []string
+
+	// AcquireFuncs lists additional functions or methods, matched like
+	// ExemptConstructors, that return a resource their callers must close,
+	// such as project factories. Their results are acquisitions for every
+	// resource type, and returning a resource from them is not flagged.
+	AcquireFuncs []string
+
+	// LifecycleHooks lists additional functions or methods that register
+	// shutdown hooks with a lifecycle manager, matched like ExemptConstructors.
+	// A resource closed in a hook passed to one of them, or to
+	// (go.uber.org/fx.Lifecycle).Append, needs no defer.
+	LifecycleHooks []string
+
+	// CloseHelpers lists additional functions or methods, matched like
+	// ExemptConstructors, that close every resource passed to them, such as
+	// closeAll helpers of other packages. Deferring a call to one of them
+	// closes each resource among its arguments.
+	CloseHelpers []string
+
+	// ConsumingFuncs lists additional functions or methods, matched like
+	// ExemptConstructors, that take ownership of a resource passed to them
+	// and close it, such as helpers reading all rows of an iterator. Passing
+	// a resource to one of them counts as closing it.
+	ConsumingFuncs []string
+
+	// Collectors registers types collecting resources to close them all at
+	// once. Adding a resource to a collector whose close method is deferred
+	// counts as closing it.
+	Collectors []Collector
+
+	// Config is the configuration file setting options by the names of their
+	// flags. DefaultConfigFile is looked for in the working directory and its
+	// parents, and is optional. Flags set on the command line take precedence.
+	Config string
+
+	// CheckConcurrency limits how many packages the checks run on
+	// concurrently. It does not bound the SSA form of packages, which the
+	// driver builds before and bounds with its own concurrency. Zero means
+	// no limit.
+	CheckConcurrency int
+
+	// MemoryLimit sets a soft memory limit in bytes for the Go runtime of
+	// the spannerclosecheck command, through SetMemoryLimit. Other drivers
+	// reject it. Zero leaves the limit unchanged.
+	MemoryLimit int64
+}
+
+// bindFlags registers a flag for every option on fs
+func (o *Options) bindFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&o.SuggestSingle, "suggest-single", o.SuggestSingle,
+		"suggest Client.Single() for ReadOnlyTransactions used for a single Query/Read")
+	fs.BoolVar(&o.DeferBeforeUse, "defer-before-use", o.DeferBeforeUse,
+		"require the deferred Close()/Stop() to come before the first use of a resource")
+	fs.IntVar(&o.DeferWithin, "defer-within", o.DeferWithin,
+		"require the deferred Close()/Stop() within this many statements of the acquisition or its error check (0 turns the check off)")
+	fs.BoolVar(&o.ClientPerRequest, "client-per-request", o.ClientPerRequest,
+		"report Spanner clients created inside HTTP request handlers")
+	fs.BoolVar(&o.ClientInLoop, "client-in-loop", o.ClientInLoop,
+		"report Spanner clients created inside loops or per-invocation callbacks like Reconcile")
+	fs.BoolVar(&o.PackageClients, "package-clients", o.PackageClients,
+		"report Spanner clients in package-level variables that are not closed on shutdown")
+	fs.BoolVar(&o.SessionPool, "session-pool", o.SessionPool,
+		"report spanner.SessionPoolConfig literals with suspicious values, like MinOpened > MaxOpened")
+	fs.BoolVar(&o.StreamCancel, "stream-cancel", o.StreamCancel,
+		"report contexts of Spanner streaming reads whose cancel() is not deferred")
+	fs.BoolVar(&o.OwnerGoroutine, "owner-goroutine", o.OwnerGoroutine,
+		"report Close()/Stop() running in another goroutine than the one acquiring the resource")
+	fs.BoolVar(&o.CloseErrors, "close-errors", o.CloseErrors,
+		"report deferred closes whose error is discarded in functions returning an error")
+	fs.BoolVar(&o.DuplicateDefers, "duplicate-defers", o.DuplicateDefers,
+		"report deferred Close()/Stop() of a resource that already has one")
+	fs.BoolVar(&o.WholeProgram, "whole-program", o.WholeProgram,
+		"build the SSA of all dependencies from source to follow resources across packages, spannerclosecheck command only (slow)")
+	fs.Var(&o.SingleClose, "single-close",
+		"severity of the report of Close() on Client.Single() transactions: info, warning or off")
+	fs.Var(&o.MinConfidence, "min-confidence",
+		"minimum confidence of the reports: low, medium or high")
+	fs.Var((*severitiesFlag)(&o.Severities), "severity",
+		"severity of a check or resource type as check=severity, like not-deferred=warning or RowIterator=warning: error or warning (repeatable)")
+	fs.StringVar(&o.MessageTemplate, "message-template", o.MessageTemplate,
+		"text/template of the report messages, with fields .Message, .Category and .Confidence, like '{{.Message}} (see RUNBOOK-12)'")
+	fs.StringVar(&o.Lang, "lang", o.Lang,
+		"language of the reports: en or ja")
+	fs.BoolVar(&o.CheckGenerated, "include-generated", o.CheckGenerated,
+		"check generated files, such as .pb.go and .yo.go files, like -skip-generated=false")
+	fs.Var((*invertedBoolFlag)(&o.CheckGenerated), "skip-generated",
+		"skip generated files, such as .pb.go and .yo.go files")
+	fs.Var((*generatedPatternsFlag)(&o.GeneratedPatterns), "generated-pattern",
+		"file name glob, or regular expression prefixed with re:, of generated files without a Code generated comment, replacing the defaults; none turns them off (repeatable)")
+	fs.Var((*stringsFlag)(&o.Exclude), "exclude",
+		"skip files or packages matching a glob, or a regular expression prefixed with re: (repeatable)")
+	fs.Var((*stringsFlag)(&o.ExcludeFuncs), "exclude-func",
+		"skip functions whose full name, like (*example.com/app.mockStore).Get, matches a regular expression (repeatable)")
+	fs.BoolVar(&o.SkipTests, "skip-tests", o.SkipTests,
+		"skip _test.go files")
+	fs.BoolVar(&o.TestsOnly, "tests-only", o.TestsOnly,
+		"check _test.go files only")
+	fs.BoolVar(&o.Lenient, "lenient", o.Lenient,
+		"accept a non-deferred Close()/Stop() that runs on every path to a return")
+	fs.Var((*resourcesFlag)(&o.Resources), "resource",
+		"additional resource type as pkgpath.Type:CloseMethod[:close=M1,M2][:acquire=F1,F2][:exempt=F3] (repeatable)")
+	fs.Var((*stringsFlag)(&o.SpannerPaths), "spanner-path",
+		"import path of a mirror or fork of cloud.google.com/go/spanner to check like it (repeatable)")
+	fs.Var((*stringsFlag)(&o.DisabledResources), "disable-resource",
+		"resource type not to check, like RowIterator or ourdb.Txn (repeatable)")
+	fs.Var((*stringsFlag)(&o.ExemptConstructors), "exempt-constructor",
+		"function or method whose results release themselves like Client.Single(), or Type=Func for one resource type (repeatable)")
+	fs.Var((*stringsFlag)(&o.ExemptFuncs), "exempt-func",
+		"function or method whose resources live as long as the process, like main or TestMain, so they need no close (repeatable)")
+	fs.Var((*stringsFlag)(&o.AcquireFuncs), "acquire-func",
+		"function or method returning a resource its callers must close (repeatable)")
+	fs.Var((*stringsFlag)(&o.LifecycleHooks), "lifecycle-hook",
+		"function or method registering shutdown hooks, like fx.Lifecycle.Append (repeatable)")
+	fs.Var((*stringsFlag)(&o.CloseHelpers), "close-helper",
+		"function or method closing every resource passed to it, like closeAll(txn, iter) (repeatable)")
+	fs.Var((*stringsFlag)(&o.ConsumingFuncs), "consuming-func",
+		"function or method closing a resource passed to it, like spanner.SelectAll (repeatable)")
+	fs.Var((*collectorsFlag)(&o.Collectors), "collector",
+		"type collecting resources as pkgpath.Type:AddMethod:CloseMethod, like closers.Add and closers.CloseAll (repeatable)")
+	fs.StringVar(&o.Config, "config", o.Config,
+		"configuration file setting options by flag name, looked for in the working directory and its parents by default")
+	fs.IntVar(&o.CheckConcurrency, "check-concurrency", o.CheckConcurrency,
+		"maximum number of packages the checks run on concurrently, after the driver built their SSA form (0 means no limit)")
+	fs.Var((*byteSizeFlag)(&o.MemoryLimit), "memory-limit",
+		"soft memory limit of the spannerclosecheck command, e.g. 6GiB, rejected by other drivers (0 leaves the limit unchanged)")
+}
+
+// Severity is the severity of a report, see Options.Severities and
+// Options.SingleClose. Reports of warning and info severity go to
+// WarningOutput, or to SeverityOutput, so that their message is left as is.
+type Severity string
+
+const (
+	SeverityInfo    Severity = "info"
+	SeverityWarning Severity = "warning"
+	SeverityOff     Severity = "off"
+)
+
+// orDefault returns s, or def if s is unset
+func (s Severity) orDefault(def Severity) Severity {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+func (s *Severity) String() string {
+	if s == nil {
+		return ""
+	}
+	return string(s.orDefault(SeverityInfo))
+}
+
+func (s *Severity) Set(value string) error {
+	switch severity := Severity(value); severity {
+	case SeverityInfo, SeverityWarning, SeverityOff:
+		*s = severity
+		return nil
+	}
+	return fmt.Errorf("invalid severity %q: want info, warning or off", value)
+}
+
+// invertedBoolFlag is a boolean flag setting the negation of its option,
+// so that options keep false as their default
+type invertedBoolFlag bool
+
+func (f *invertedBoolFlag) String() string {
+	if f == nil {
+		return "true"
+	}
+	return strconv.FormatBool(!bool(*f))
+}
+
+func (f *invertedBoolFlag) Set(value string) error {
+	v, err := strconv.ParseBool(value)
+	if err != nil {
+		return err
+	}
+	*f = invertedBoolFlag(!v)
+	return nil
+}
+
+func (f *invertedBoolFlag) IsBoolFlag() bool { return true }
+
+// stringsFlag is a repeatable flag collecting strings, also accepting
+// comma-separated lists
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(value string) error {
+	for _, s := range strings.Split(value, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			*f = append(*f, s)
+		}
+	}
+	return nil
+}
+
+// resourcesFlag is a repeatable flag adding custom resource types
+type resourcesFlag []ResourceType
+
+func (f *resourcesFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	specs := make([]string, 0, len(*f))
+	for _, rt := range *f {
+		specs = append(specs, FormatResourceSpec(rt))
+	}
+	return strings.Join(specs, " ")
+}
+
+func (f *resourcesFlag) Set(value string) error {
+	rt, err := ParseResourceSpec(value)
+	if err != nil {
+		return err
+	}
+	*f = append(*f, rt)
+	return nil
+}
+
+// ParseResourceSpec parses a resource descriptor of the form
+// pkgpath.Type:CloseMethod[:close=M1,M2][:acquire=F1,F2][:exempt=F3,F4], as
+// accepted by the -resource flag. The methods of close also close the
+// resource, see ResourceType.CloseMethods.
+func ParseResourceSpec(spec string) (ResourceType, error) {
+	parts := strings.Split(spec, ":")
+	if len(parts) < 2 {
+		return ResourceType{}, fmt.Errorf("invalid resource %q: want pkgpath.Type:CloseMethod", spec)
+	}
+
+	// The type name follows the last dot after the final path element
+	qualified := parts[0]
+	slash := strings.LastIndex(qualified, "/")
+	dot := strings.LastIndex(qualified, ".")
+	if dot <= slash+1 || dot == len(qualified)-1 {
+		return ResourceType{}, fmt.Errorf("invalid resource %q: want pkgpath.Type", spec)
+	}
+	rt := ResourceType{
+		PkgPath:     qualified[:dot],
+		Name:        qualified[dot+1:],
+		CloseMethod: parts[1],
+	}
+	if rt.CloseMethod == "" {
+		return ResourceType{}, fmt.Errorf("invalid resource %q: missing close method", spec)
+	}
+
+	for _, part := range parts[2:] {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return ResourceType{}, fmt.Errorf("invalid resource %q: want key=value, got %q", spec, part)
+		}
+		switch key {
+		case "close":
+			rt.CloseMethods = append(rt.CloseMethods, strings.Split(value, ",")...)
+		case "acquire":
+			rt.Constructors = append(rt.Constructors, strings.Split(value, ",")...)
+		case "exempt":
+			rt.ExemptConstructors = append(rt.ExemptConstructors, strings.Split(value, ",")...)
+		default:
+			return ResourceType{}, fmt.Errorf("invalid resource %q: unknown key %q", spec, key)
+		}
+	}
+
+	return rt, nil
+}
+
+// FormatResourceSpec returns the descriptor of rt, the inverse of ParseResourceSpec
+func FormatResourceSpec(rt ResourceType) string {
+	spec := rt.PkgPath + "." + rt.Name + ":" + rt.CloseMethod
+	if len(rt.CloseMethods) > 0 {
+		spec += ":close=" + strings.Join(rt.CloseMethods, ",")
+	}
+	if len(rt.Constructors) > 0 {
+		spec += ":acquire=" + strings.Join(rt.Constructors, ",")
+	}
+	if len(rt.ExemptConstructors) > 0 {
+		spec += ":exempt=" + strings.Join(rt.ExemptConstructors, ",")
+	}
+	return spec
+}
+
+// Collector describes a type collecting resources with AddMethod and closing
+// them all with CloseMethod:
+//
+//	defer closers.CloseAll()
+//	closers.Add(txn)
+type Collector struct {
+	// PkgPath is the import path of the package declaring the type
+	PkgPath     string
+	Name        string
+	AddMethod   string
+	CloseMethod string
+}
+
+// collectorsFlag is a repeatable flag adding collector types
+type collectorsFlag []Collector
+
+func (f *collectorsFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	specs := make([]string, 0, len(*f))
+	for _, c := range *f {
+		specs = append(specs, c.PkgPath+"."+c.Name+":"+c.AddMethod+":"+c.CloseMethod)
+	}
+	return strings.Join(specs, " ")
+}
+
+func (f *collectorsFlag) Set(value string) error {
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 || parts[1] == "" || parts[2] == "" {
+		return fmt.Errorf("invalid collector %q: want pkgpath.Type:AddMethod:CloseMethod", value)
+	}
+	qualified := parts[0]
+	slash := strings.LastIndex(qualified, "/")
+	dot := strings.LastIndex(qualified, ".")
+	if dot <= slash+1 || dot == len(qualified)-1 {
+		return fmt.Errorf("invalid collector %q: want pkgpath.Type", value)
+	}
+	*f = append(*f, Collector{
+		PkgPath:     qualified[:dot],
+		Name:        qualified[dot+1:],
+		AddMethod:   parts[1],
+		CloseMethod: parts[2],
+	})
+	return nil
+}
@@ -0,0 +1,300 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"slices"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ssa"
+)
+
+// checkDeferBeforeUse reports a deferred close that does not dominate every
+// use of the resource. A panic in a use that runs before the defer statement
+// leaks the resource even though a deferred close exists.
+func checkDeferBeforeUse(pass *analysis.Pass, val ssa.Value, rt *ResourceType, deferClose *ssa.Defer) {
+	use := firstUseBeforeDefer(val, deferClose)
+	if use == nil {
+		return
+	}
+
+	pos := acquisitionPos(val)
+	reportAcquired(pass, pos, analysis.Diagnostic{
+		Pos:      deferClose.Pos(),
+		Category: categoryDeferOrder,
+		Message:  rt.QualifiedName() + "." + rt.CloseMethod + "() must be deferred before the resource is first used",
+		Related: []analysis.RelatedInformation{{
+			Pos:     use.Pos(),
+			Message: "resource used before the defer statement",
+		}},
+		SuggestedFixes: moveDeferFixes(pass, val, deferClose, pos),
+	})
+}
+
+// checkDeferDistance reports a deferred close placed more than limit
+// statements after the acquisition of the resource, or after its error
+// check, counting the defer statement. An early return between the
+// acquisition and the defer leaks the resource.
+func checkDeferDistance(pass *analysis.Pass, val ssa.Value, rt *ResourceType, deferClose *ssa.Defer, limit int) {
+	pos := acquisitionPos(val)
+	point, ok := findDeferInsertPoint(pass, val, pos)
+	if !ok {
+		return
+	}
+	start := slices.Index(point.stmts, point.after)
+	end := slices.IndexFunc(point.stmts, func(stmt ast.Stmt) bool {
+		return stmt.Pos() <= deferClose.Pos() && deferClose.Pos() < stmt.End()
+	})
+	// Defers in another statement list, or before the acquisition as in
+	// loops, are checked otherwise
+	if start < 0 || end <= start || end-start <= limit {
+		return
+	}
+	var fixes []analysis.SuggestedFix
+	if deferStmt, ok := point.stmts[end].(*ast.DeferStmt); ok {
+		fixes = moveStmtFixes(pass, deferStmt, point.pos, point.indent, "Move defer after the acquisition")
+	}
+	reportAcquired(pass, pos, analysis.Diagnostic{
+		Pos:      deferClose.Pos(),
+		Category: categoryDeferOrder,
+		Message:  fmt.Sprintf("%s.%s() must be deferred within %d statements of the acquisition, found %d", rt.QualifiedName(), rt.CloseMethod, limit, end-start),
+		Related: []analysis.RelatedInformation{{
+			Pos:     pos,
+			Message: "resource acquired here",
+		}},
+		SuggestedFixes: fixes,
+	})
+}
+
+// firstUseBeforeDefer returns a call using val that is not dominated by deferClose
+func firstUseBeforeDefer(val ssa.Value, deferClose *ssa.Defer) ssa.Instruction {
+	for _, use := range valueUses(val) {
+		if _, ok := use.(*ssa.Defer); ok {
+			continue
+		}
+		if _, ok := use.(ssa.CallInstruction); !ok {
+			continue
+		}
+		if !dominates(deferClose, use) {
+			return use
+		}
+	}
+	return nil
+}
+
+// valueUses returns the instructions using val in its function, looking
+// through the local cell when val is captured by a closure, and counting the
+// calls of closures capturing val as uses
+func valueUses(val ssa.Value) []ssa.Instruction {
+	if val.Referrers() == nil {
+		return nil
+	}
+
+	var uses []ssa.Instruction
+	for _, ref := range *val.Referrers() {
+		store, ok := ref.(*ssa.Store)
+		if !ok || store.Val != val {
+			uses = append(uses, ref)
+			if mc, ok := ref.(*ssa.MakeClosure); ok {
+				uses = append(uses, closureCalls(mc)...)
+			}
+			continue
+		}
+		alloc, ok := store.Addr.(*ssa.Alloc)
+		if !ok || alloc.Referrers() == nil {
+			continue
+		}
+		for _, cellRef := range *alloc.Referrers() {
+			if mc, ok := cellRef.(*ssa.MakeClosure); ok {
+				uses = append(uses, closureCalls(mc)...)
+			}
+			load, ok := cellRef.(*ssa.UnOp)
+			if !ok || load.Referrers() == nil {
+				continue
+			}
+			uses = append(uses, *load.Referrers()...)
+		}
+	}
+	return uses
+}
+
+// dominates checks if instruction a runs before b on every path reaching b
+func dominates(a, b ssa.Instruction) bool {
+	if a.Block() != b.Block() {
+		return a.Block().Dominates(b.Block())
+	}
+	for _, instr := range a.Block().Instrs {
+		switch instr {
+		case a:
+			return true
+		case b:
+			return false
+		}
+	}
+	return false
+}
+
+// moveDeferFixes returns a fix moving the defer statement right after the
+// acquisition of val, or after the error check that follows it
+func moveDeferFixes(pass *analysis.Pass, val ssa.Value, deferClose *ssa.Defer, pos token.Pos) []analysis.SuggestedFix {
+	point, ok := findDeferInsertPoint(pass, val, pos)
+	if !ok {
+		return nil
+	}
+	deferStmt, ok := findNode(pass, deferClose.Pos(), func(n *ast.DeferStmt) bool { return n.Defer == deferClose.Pos() })
+	if !ok {
+		return nil
+	}
+
+	// Moving a defer out of a nested block would change when it runs
+	inList := false
+	for _, stmt := range point.stmts {
+		if stmt == deferStmt {
+			inList = true
+		}
+	}
+	if !inList {
+		return nil
+	}
+
+	return moveStmtFixes(pass, deferStmt, point.pos, point.indent, "Move defer before the first use")
+}
+
+// moveStmtFixes returns a fix moving stmt, with its trailing comment, to a new
+// line at pos indented with indent
+func moveStmtFixes(pass *analysis.Pass, stmt ast.Stmt, pos token.Pos, indent, message string) []analysis.SuggestedFix {
+	text, start, end, ok := stmtLines(pass, stmt)
+	if !ok {
+		return nil
+	}
+	return []analysis.SuggestedFix{{
+		Message: message,
+		TextEdits: []analysis.TextEdit{
+			{Pos: pos, End: pos, NewText: []byte(indent + text + "\n")},
+			{Pos: start, End: end},
+		},
+	}}
+}
+
+// deferBeforeErrCheckMessage reports a deferred close of a resource registered
+// before the error check of the call acquiring it
+const deferBeforeErrCheckMessage = "%s.%s() must be deferred after the error check, the resource may be nil when an error is returned"
+
+// checkDeferBeforeErrCheck reports a deferred close of a resource returned
+// with an error that runs before the error is checked:
+//
+//	txn, err := client.BatchReadOnlyTransaction(ctx, tb)
+//	defer txn.Close()
+//	if err != nil {
+//		return err
+//	}
+//
+// When the call fails, the deferred close runs on a nil resource. The
+// suggested fix moves the defer after the error check.
+func checkDeferBeforeErrCheck(pass *analysis.Pass, val ssa.Value, rt *ResourceType, deferClose *ssa.Defer) {
+	errVal := acquisitionError(val)
+	if errVal == nil {
+		return
+	}
+	check := errCheck(errVal)
+	if check == nil || !dominates(deferClose, check) {
+		return
+	}
+
+	pos := acquisitionPos(val)
+	reportAcquired(pass, pos, analysis.Diagnostic{
+		Pos:      deferClose.Pos(),
+		Category: categoryDeferOrder,
+		Message:  fmt.Sprintf(deferBeforeErrCheckMessage, rt.QualifiedName(), rt.CloseMethod),
+		Related: []analysis.RelatedInformation{{
+			Pos:     check.Pos(),
+			Message: "error checked here",
+		}},
+		SuggestedFixes: moveAfterErrCheckFixes(pass, deferClose, check),
+	})
+}
+
+// errCheck returns the comparison of errVal with nil branched on, as in
+// if err != nil, directly or through the variable errVal is stored into, or
+// nil if there is none
+func errCheck(errVal ssa.Value) *ssa.BinOp {
+	values := []ssa.Value{errVal}
+	for _, ref := range *errVal.Referrers() {
+		store, ok := ref.(*ssa.Store)
+		if !ok || store.Val != errVal || store.Addr.Referrers() == nil {
+			continue
+		}
+		for _, addrRef := range *store.Addr.Referrers() {
+			if load, ok := addrRef.(*ssa.UnOp); ok && load.Op == token.MUL {
+				values = append(values, load)
+			}
+		}
+	}
+
+	for _, v := range values {
+		if v.Referrers() == nil {
+			continue
+		}
+		for _, ref := range *v.Referrers() {
+			cond, ok := ref.(*ssa.BinOp)
+			if !ok || !comparesWithNil(cond, errVal) || cond.Referrers() == nil {
+				continue
+			}
+			for _, condRef := range *cond.Referrers() {
+				if _, ok := condRef.(*ssa.If); ok {
+					return cond
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// moveAfterErrCheckFixes returns a fix moving the defer statement right after
+// the if statement testing check, when both are in the same statement list
+func moveAfterErrCheckFixes(pass *analysis.Pass, deferClose *ssa.Defer, check *ssa.BinOp) []analysis.SuggestedFix {
+	deferStmt, ok := findNode(pass, deferClose.Pos(), func(n *ast.DeferStmt) bool { return n.Defer == deferClose.Pos() })
+	if !ok {
+		return nil
+	}
+	ifStmt, ok := findNode(pass, check.Pos(), func(n *ast.IfStmt) bool {
+		cond, ok := ast.Unparen(n.Cond).(*ast.BinaryExpr)
+		return ok && cond.OpPos == check.Pos()
+	})
+	if !ok || !slices.Contains(enclosingStmtList(pass, deferStmt), ast.Stmt(ifStmt)) {
+		return nil
+	}
+
+	insert, ok := nextLineStart(pass, ifStmt.End())
+	if !ok {
+		return nil
+	}
+	return moveStmtFixes(pass, deferStmt, insert, lineIndent(pass, deferStmt.Pos()), "Move defer after the error check")
+}
+
+// stmtLines returns the source text of stmt including a trailing comment,
+// and the range of the whole lines it occupies
+func stmtLines(pass *analysis.Pass, stmt ast.Stmt) (string, token.Pos, token.Pos, bool) {
+	file := pass.Fset.File(stmt.Pos())
+	if file == nil {
+		return "", token.NoPos, token.NoPos, false
+	}
+	content, err := pass.ReadFile(file.Name())
+	if err != nil {
+		return "", token.NoPos, token.NoPos, false
+	}
+
+	lineStart := file.LineStart(file.Line(stmt.Pos()))
+	lineEnd := token.Pos(file.Base() + file.Size())
+	if line := file.Line(stmt.End()); line < file.LineCount() {
+		lineEnd = file.LineStart(line + 1)
+	}
+
+	if strings.TrimSpace(string(content[file.Offset(lineStart):file.Offset(stmt.Pos())])) != "" {
+		return "", token.NoPos, token.NoPos, false
+	}
+	text := strings.TrimRight(string(content[file.Offset(stmt.Pos()):file.Offset(lineEnd)]), " \t\r\n")
+	return text, lineStart, lineEnd, true
+}
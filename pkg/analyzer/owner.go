@@ -0,0 +1,172 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ssa"
+)
+
+// foreignCloseMessage reports a close running in another goroutine than the
+// one acquiring the resource
+const foreignCloseMessage = "%s.%s() runs in a different goroutine than the one acquiring the resource at line %d: close it in the acquiring goroutine, after the other goroutines are done with it"
+
+// checkForeignCloses reports closes in fn of resources acquired in another
+// goroutine, which race with the uses of the acquiring goroutine unless they
+// are synchronized:
+//
+//	iter := txn.Query(ctx, stmt)
+//	go func() {
+//		<-ctx.Done()
+//		iter.Stop() // races with iter.Next() below
+//	}()
+//	for { iter.Next() ... }
+//
+// Resources are followed through the variables and values captured by
+// function literals, in both directions: a resource acquired in a goroutine
+// and closed by its parent is reported too. Closes run with a go statement,
+// as in go iter.Stop(), always run in another goroutine.
+func checkForeignCloses(pass *analysis.Pass, fn *ssa.Function, spannerTypes map[*types.Named]*ResourceType) {
+	if fn == nil || isGeneratedFile(pass, fn.Pos()) {
+		return
+	}
+
+	for _, block := range fn.Blocks {
+		for _, instr := range block.Instrs {
+			closeCall, ok := instr.(ssa.CallInstruction)
+			if !ok {
+				continue
+			}
+			val := methodReceiver(closeCall.Common())
+			if val == nil {
+				continue
+			}
+			rt := getSpannerType(val.Type(), spannerTypes)
+			if rt == nil || !isCloseCall(closeCall.Common(), val, rt) {
+				continue
+			}
+
+			_, spawned := closeCall.(*ssa.Go)
+			for _, acq := range acquisitions(val) {
+				if acq.Parent() == nil || !spawned && goroutineOf(acq.Parent()) == goroutineOf(fn) {
+					continue
+				}
+				pass.Report(analysis.Diagnostic{
+					Pos:      closeCall.Pos(),
+					Category: categoryForeignClose,
+					Message:  fmt.Sprintf(foreignCloseMessage, rt.QualifiedName(), rt.CloseMethod, pass.Fset.Position(acquisitionPos(acq)).Line),
+					Related: []analysis.RelatedInformation{{
+						Pos:     acquisitionPos(acq),
+						Message: "acquired here",
+					}},
+				})
+				break
+			}
+		}
+	}
+}
+
+// acquisitions returns the values val, the receiver of a close, may hold,
+// following captured values to the enclosing function and captured variables
+// to the values stored into them, in any function. Parameters are acquired by
+// the caller, in the goroutine of their function.
+func acquisitions(val ssa.Value) []ssa.Value {
+	if load, ok := val.(*ssa.UnOp); ok && load.Op == token.MUL {
+		alloc, ok := capturedValue(load.X).(*ssa.Alloc)
+		if !ok {
+			return nil
+		}
+		var stored []ssa.Value
+		for _, cell := range variableCells(alloc) {
+			for _, ref := range *cell.Referrers() {
+				store, ok := ref.(*ssa.Store)
+				if !ok || store.Addr != cell {
+					continue
+				}
+				if _, isConst := store.Val.(*ssa.Const); !isConst {
+					stored = append(stored, store.Val)
+				}
+			}
+		}
+		return stored
+	}
+	if val = capturedValue(val); val == nil {
+		return nil
+	}
+	return []ssa.Value{val}
+}
+
+// capturedValue returns the value bound to the free variable val by the
+// function literal declaring it, through nested literals, or val itself
+func capturedValue(val ssa.Value) ssa.Value {
+	for {
+		fv, ok := val.(*ssa.FreeVar)
+		if !ok {
+			return val
+		}
+		binding := closureBinding(fv)
+		if binding == nil {
+			return nil
+		}
+		val = binding
+	}
+}
+
+// closureBinding returns the value bound to fv where its function literal is
+// created, or nil
+func closureBinding(fv *ssa.FreeVar) ssa.Value {
+	fn := fv.Parent()
+	index := -1
+	for i, v := range fn.FreeVars {
+		if v == fv {
+			index = i
+		}
+	}
+	if index < 0 || fn.Parent() == nil {
+		return nil
+	}
+	for _, block := range fn.Parent().Blocks {
+		for _, instr := range block.Instrs {
+			if mc, ok := instr.(*ssa.MakeClosure); ok && mc.Fn == fn && index < len(mc.Bindings) {
+				return mc.Bindings[index]
+			}
+		}
+	}
+	return nil
+}
+
+// variableCells returns the cell of a variable and the free variables of the
+// function literals capturing it, at any depth
+func variableCells(alloc *ssa.Alloc) []ssa.Value {
+	cells := []ssa.Value{alloc}
+	for i := 0; i < len(cells); i++ {
+		if cells[i].Referrers() == nil {
+			continue
+		}
+		for _, ref := range *cells[i].Referrers() {
+			mc, ok := ref.(*ssa.MakeClosure)
+			if !ok {
+				continue
+			}
+			fn := mc.Fn.(*ssa.Function)
+			for j, binding := range mc.Bindings {
+				if binding == cells[i] && j < len(fn.FreeVars) {
+					cells = append(cells, fn.FreeVars[j])
+				}
+			}
+		}
+	}
+	return cells
+}
+
+// goroutineOf returns the function starting the goroutine fn runs in: the
+// nearest function literal run with go or a goroutineSpawner enclosing fn,
+// or the top-level function
+func goroutineOf(fn *ssa.Function) *ssa.Function {
+	for fn.Parent() != nil && !isGoroutineBody(fn) {
+		fn = fn.Parent()
+	}
+	return fn
+}
@@ -0,0 +1,191 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"slices"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/ssa"
+)
+
+// reassignMessage reports a resource whose variable is assigned again before
+// the resource is released, leaking the previous value
+const reassignMessage = "%s.%s() must be called before %s is reassigned, the previous value leaks"
+
+// reassignedVar returns the variable val is assigned to when the variable is
+// assigned again before val is released, as in
+//
+//	iter := txn.Query(ctx, stmt1)
+//	iter = txn.Query(ctx, stmt2)
+//	defer iter.Stop()
+//
+// or by the same assignment in the next iteration of a loop, when the
+// variable is declared outside of it. Only the last value reaches the close,
+// whether deferred after the loop or in a deferred closure reading the
+// variable. Any use of the variable other than calling another method on it
+// or comparing it is taken as releasing the value, such as closing it,
+// deferring its close or passing it to a function.
+func reassignedVar(pass *analysis.Pass, fn *ssa.Function, val ssa.Value, rt *ResourceType) *ast.Ident {
+	id := assignedIdent(pass, val)
+	if id == nil {
+		return nil
+	}
+	obj, ok := pass.TypesInfo.ObjectOf(id).(*types.Var)
+	if !ok {
+		return nil
+	}
+	body := funcBody(fn)
+	if body == nil {
+		return nil
+	}
+
+	assigns, releases := varAccesses(pass, body, obj, rt)
+	released := func(from, to token.Pos) bool {
+		for _, pos := range releases {
+			if from < pos && pos < to {
+				return true
+			}
+		}
+		return false
+	}
+
+	// The next assignment executed after this one, unless in another branch
+	for _, pos := range assigns {
+		if pos > id.Pos() && !inExclusiveBranches(pass, id.Pos(), pos) {
+			if !released(id.Pos(), pos) {
+				return id
+			}
+			break
+		}
+	}
+
+	// The same assignment in the next iteration of an enclosing loop
+	if loop := enclosingLoop(pass, id.Pos(), body); loop != nil && (obj.Pos() < loop.Pos() || obj.Pos() >= loop.End()) {
+		if !released(loop.Pos(), loop.End()) {
+			return id
+		}
+	}
+
+	return nil
+}
+
+// funcBody returns the body of the function or function literal fn is built
+// from, or nil
+func funcBody(fn *ssa.Function) *ast.BlockStmt {
+	switch syntax := fn.Syntax().(type) {
+	case *ast.FuncDecl:
+		return syntax.Body
+	case *ast.FuncLit:
+		return syntax.Body
+	}
+	return nil
+}
+
+// varAccesses returns the positions, in source order, where obj is assigned
+// within body, and where its value is released, see reassignedVar. Nested
+// function literals are skipped, as they read the variable when they run,
+// not where they are declared.
+func varAccesses(pass *analysis.Pass, body *ast.BlockStmt, obj *types.Var, rt *ResourceType) (assigns, releases []token.Pos) {
+	isVar := func(expr ast.Expr) bool {
+		id, ok := ast.Unparen(expr).(*ast.Ident)
+		return ok && pass.TypesInfo.ObjectOf(id) == obj
+	}
+
+	// Idents in positions that neither assign nor release the value
+	skip := make(map[ast.Expr]bool)
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.AssignStmt:
+			for _, lhs := range n.Lhs {
+				if isVar(lhs) {
+					assigns = append(assigns, lhs.Pos())
+					skip[ast.Unparen(lhs)] = true
+				}
+			}
+		case *ast.ValueSpec:
+			for _, name := range n.Names {
+				if isVar(name) {
+					assigns = append(assigns, name.Pos())
+					skip[name] = true
+				}
+			}
+		case *ast.SelectorExpr:
+			if isVar(n.X) && !rt.isCloseMethod(n.Sel.Name) {
+				skip[ast.Unparen(n.X)] = true
+			}
+		case *ast.BinaryExpr:
+			for _, operand := range []ast.Expr{n.X, n.Y} {
+				if isVar(operand) {
+					skip[ast.Unparen(operand)] = true
+				}
+			}
+		case *ast.Ident:
+			if isVar(n) && !skip[n] {
+				releases = append(releases, n.Pos())
+			}
+		}
+		return true
+	})
+
+	return assigns, releases
+}
+
+// enclosingLoop returns the innermost for or range statement within body
+// containing pos, or nil
+func enclosingLoop(pass *analysis.Pass, pos token.Pos, body *ast.BlockStmt) ast.Stmt {
+	for _, node := range enclosingPath(pass, pos) {
+		if node == body {
+			break
+		}
+		switch node := node.(type) {
+		case *ast.ForStmt, *ast.RangeStmt:
+			return node.(ast.Stmt)
+		}
+	}
+	return nil
+}
+
+// inExclusiveBranches checks if a and b lie in different branches of the same
+// if, switch or select statement, so that at most one of them runs
+func inExclusiveBranches(pass *analysis.Pass, a, b token.Pos) bool {
+	pathA, pathB := enclosingPath(pass, a), enclosingPath(pass, b)
+	for i, node := range pathA {
+		j := slices.Index(pathB, node)
+		if j < 0 {
+			continue
+		}
+		// node is the innermost common ancestor, see which children hold a and b
+		if i == 0 || j == 0 {
+			return false
+		}
+		childA, childB := pathA[i-1], pathB[j-1]
+		switch node := node.(type) {
+		case *ast.IfStmt:
+			return childA == node.Body && childB == node.Else || childA == node.Else && childB == node.Body
+		case *ast.BlockStmt:
+			// Switch and select bodies hold one clause per case
+			_, isCaseA := childA.(*ast.CaseClause)
+			_, isCommA := childA.(*ast.CommClause)
+			return (isCaseA || isCommA) && childA != childB
+		}
+		return false
+	}
+	return false
+}
+
+// enclosingPath returns the nodes enclosing pos, from the innermost outwards
+func enclosingPath(pass *analysis.Pass, pos token.Pos) []ast.Node {
+	for _, f := range pass.Files {
+		if f.FileStart > pos || pos > f.FileEnd {
+			continue
+		}
+		path, _ := astutil.PathEnclosingInterval(f, pos, pos)
+		return path
+	}
+	return nil
+}
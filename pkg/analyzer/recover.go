@@ -0,0 +1,94 @@
+package analyzer
+
+import (
+	"go/token"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+const builtinRecover = "recover"
+
+// recoverMessage is appended to findings for non-deferred closes in functions
+// that recover from panics and return normally, skipping the close
+const recoverMessage = ": the function recovers from panics, so a non-deferred %s() is skipped when one occurs"
+
+// recoversPanics checks if fn defers a function that calls recover()
+func recoversPanics(fn *ssa.Function) bool {
+	for _, block := range fn.Blocks {
+		for _, instr := range block.Instrs {
+			d, ok := instr.(*ssa.Defer)
+			if !ok {
+				continue
+			}
+			if callee := d.Call.StaticCallee(); callee != nil && callsRecover(callee) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// callsRecover checks if fn calls the recover builtin directly
+func callsRecover(fn *ssa.Function) bool {
+	for _, block := range fn.Blocks {
+		for _, instr := range block.Instrs {
+			call, ok := instr.(*ssa.Call)
+			if !ok {
+				continue
+			}
+			if builtin, ok := call.Common().Value.(*ssa.Builtin); ok && builtin.Name() == builtinRecover {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasNonDeferredClose checks if val is closed by a plain, non-deferred call
+func hasNonDeferredClose(val ssa.Value, rt *ResourceType) bool {
+	return len(nonDeferredCloses(val, rt)) > 0
+}
+
+// nonDeferredCloses returns the plain, non-deferred calls closing val, either
+// directly or through a helper defined in the same package
+func nonDeferredCloses(val ssa.Value, rt *ResourceType) []ssa.Instruction {
+	if val.Referrers() == nil {
+		return nil
+	}
+
+	var closes []ssa.Instruction
+	for _, ref := range *val.Referrers() {
+		switch ref := ref.(type) {
+		case *ssa.Call:
+			if isCloseCall(ref.Common(), val, rt) || closesArg(ref.Common(), val, rt) {
+				closes = append(closes, ref)
+			}
+		case *ssa.UnOp:
+			if ref.Op == token.MUL {
+				closes = append(closes, nonDeferredCloses(ref, rt)...)
+			}
+		case *ssa.MakeClosure:
+			// closeOnce.Do(txn.Close), or a closure called in place
+			if closureClosesBinding(ref, val, rt) {
+				closes = append(closes, closureCalls(ref)...)
+			}
+		case *ssa.Store:
+			if alloc, ok := ref.Addr.(*ssa.Alloc); ok && ref.Val == val {
+				closes = append(closes, nonDeferredCloses(alloc, rt)...)
+			}
+		}
+	}
+
+	return closes
+}
+
+// closureCalls returns the plain, non-deferred calls running closure
+func closureCalls(closure *ssa.MakeClosure) []ssa.Instruction {
+	var calls []ssa.Instruction
+	for _, ref := range *closure.Referrers() {
+		if call, ok := ref.(*ssa.Call); ok && invokesClosure(call, closure) {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
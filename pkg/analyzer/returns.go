@@ -0,0 +1,353 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"reflect"
+	"slices"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// returnedResource is a result of a function holding a resource the function
+// acquired, which its callers must close, by index among the results
+type returnedResource struct {
+	Index   int
+	PkgPath string
+	Name    string
+}
+
+// returnsResourceFact marks a function returning resources it acquired, so
+// that callers in other packages are checked for closing them, even when the
+// result is declared as an interface or the resource type restricts its
+// acquiring constructors
+type returnsResourceFact struct {
+	Results []returnedResource
+}
+
+func (*returnsResourceFact) AFact() {}
+
+func (f *returnsResourceFact) String() string {
+	results := make([]string, 0, len(f.Results))
+	for _, r := range f.Results {
+		results = append(results, fmt.Sprintf("%d:%s.%s", r.Index, r.PkgPath, r.Name))
+	}
+	return "returns resources " + strings.Join(results, ", ")
+}
+
+// resourceReturns maps functions to the resources they return
+type resourceReturns map[*types.Func][]returnedResource
+
+// newReturnsAnalyzer returns an analyzer exporting the resources functions
+// return as facts, and returning those visible to the package, including its
+// own. Which values are resources depends on opts. Like directiveAnalyzer it
+// only inspects syntax, so that it can run on every dependency.
+func newReturnsAnalyzer(opts *Options) *analysis.Analyzer {
+	return &analysis.Analyzer{
+		Name:       "spannerclosecheckreturns",
+		Doc:        "collect functions returning resources their callers must close",
+		Requires:   []*analysis.Analyzer{directiveAnalyzer},
+		FactTypes:  []analysis.Fact{new(returnsResourceFact)},
+		ResultType: reflect.TypeOf(resourceReturns(nil)),
+		Run: func(pass *analysis.Pass) (interface{}, error) {
+			// Invalid options are reported by the analyzers requiring this
+			// one, as their prerequisites failing would hide the error.
+			// Excluded packages still export the facts of their functions.
+			run, err := compileOptions(opts)
+			if err != nil {
+				return make(resourceReturns), nil
+			}
+			defer run.generated.register(pass)()
+			return runReturns(pass, opts)
+		},
+	}
+}
+
+func runReturns(pass *analysis.Pass, opts *Options) (interface{}, error) {
+	returns := make(resourceReturns)
+	for _, f := range pass.AllObjectFacts() {
+		if fn, ok := f.Object.(*types.Func); ok {
+			returns[fn] = f.Fact.(*returnsResourceFact).Results
+		}
+	}
+
+	spannerTypes := resourceTypeMap(pass, opts)
+	if len(spannerTypes) == 0 {
+		return returns, nil
+	}
+
+	var decls []*ast.FuncDecl
+	for _, file := range pass.Files {
+		for _, decl := range file.Decls {
+			if fd, ok := decl.(*ast.FuncDecl); ok && fd.Body != nil && !isGeneratedFile(pass, fd.Pos()) {
+				decls = append(decls, fd)
+			}
+		}
+	}
+
+	// Revisit functions until factories returning the results of other
+	// factories are settled
+	for range maxCloserRounds {
+		changed := false
+		for _, fd := range decls {
+			fn, ok := pass.TypesInfo.Defs[fd.Name].(*types.Func)
+			if !ok {
+				continue
+			}
+			results := returnedResources(pass, fd, spannerTypes, returns, opts)
+			if len(results) > len(returns[fn]) {
+				returns[fn] = results
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+
+	for fn, results := range returns {
+		if fn.Pkg() == pass.Pkg {
+			pass.ExportObjectFact(fn, &returnsResourceFact{Results: results})
+		}
+	}
+	return returns, nil
+}
+
+// returnedResources returns the results of fd holding a resource it acquired:
+// the result of an acquiring call, returned directly or through a local
+// variable. Resources read from fields or parameters are borrowed and not
+// returned to the caller's ownership.
+func returnedResources(pass *analysis.Pass, fd *ast.FuncDecl, spannerTypes map[*types.Named]*ResourceType, returns resourceReturns, opts *Options) []returnedResource {
+	info := pass.TypesInfo
+	acquired := make(map[types.Object]*ResourceType)
+	var results []returnedResource
+	add := func(index int, rt *ResourceType) {
+		r := returnedResource{Index: index, PkgPath: rt.PkgPath, Name: rt.Name}
+		if !slices.Contains(results, r) {
+			results = append(results, r)
+		}
+	}
+
+	// acquiredResult returns the resource type of the result at index of an
+	// acquiring call, or nil
+	acquiredResult := func(call *ast.CallExpr, index int) *ResourceType {
+		t := info.TypeOf(call)
+		if tuple, ok := t.(*types.Tuple); ok {
+			if index >= tuple.Len() {
+				return nil
+			}
+			t = tuple.At(index).Type()
+		} else if index > 0 {
+			return nil
+		}
+
+		callee, _ := typeutil.Callee(info, call).(*types.Func)
+		if callee != nil {
+			if rt := returns.resourceType(callee, index, spannerTypes); rt != nil {
+				return rt
+			}
+		}
+		rt := getSpannerType(t, spannerTypes)
+		if rt == nil {
+			return nil
+		}
+		if callee == nil {
+			if len(rt.Constructors) == 0 {
+				return rt
+			}
+			return nil
+		}
+		if isObjNamed(callee, exemptConstructors(rt, opts)) {
+			return nil
+		}
+		if len(rt.Constructors) == 0 || isObjNamed(callee, rt.Constructors) || isObjNamed(callee, opts.AcquireFuncs) {
+			return rt
+		}
+		return nil
+	}
+
+	// acquiredExpr returns the resource type of an acquired value, or nil
+	acquiredExpr := func(e ast.Expr) *ResourceType {
+		switch e := ast.Unparen(e).(type) {
+		case *ast.CallExpr:
+			return acquiredResult(e, 0)
+		case *ast.Ident:
+			return acquired[info.Uses[e]]
+		}
+		return nil
+	}
+
+	// Statements are visited in source order, so variables are assigned
+	// before the return statements using them
+	ast.Inspect(fd.Body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			// Returns of function literals are theirs
+			return false
+		case *ast.AssignStmt:
+			if n.Tok != token.DEFINE && n.Tok != token.ASSIGN {
+				return true
+			}
+			for i, lhs := range n.Lhs {
+				id, ok := lhs.(*ast.Ident)
+				if !ok {
+					continue
+				}
+				obj := info.ObjectOf(id)
+				if obj == nil {
+					continue
+				}
+				var rt *ResourceType
+				if len(n.Rhs) == 1 && len(n.Lhs) > 1 {
+					// Tuple assignment: txn, err := db.Begin()
+					if call, ok := ast.Unparen(n.Rhs[0]).(*ast.CallExpr); ok {
+						rt = acquiredResult(call, i)
+					}
+				} else if i < len(n.Rhs) {
+					rt = acquiredExpr(n.Rhs[i])
+				}
+				if rt != nil {
+					acquired[obj] = rt
+				}
+			}
+		case *ast.ReturnStmt:
+			sig := info.Defs[fd.Name].Type().(*types.Signature)
+			if len(n.Results) == 0 {
+				// Bare return of named results: txn = client.ReadOnlyTransaction(); return
+				for i := range sig.Results().Len() {
+					if rt := acquired[sig.Results().At(i)]; rt != nil {
+						add(i, rt)
+					}
+				}
+				return true
+			}
+			if len(n.Results) == 1 && sig.Results().Len() > 1 {
+				// Forwarded tuple: return db.Begin()
+				if call, ok := ast.Unparen(n.Results[0]).(*ast.CallExpr); ok {
+					for i := range sig.Results().Len() {
+						if rt := acquiredResult(call, i); rt != nil {
+							add(i, rt)
+						}
+					}
+				}
+				return true
+			}
+			for i, e := range n.Results {
+				if rt := acquiredExpr(e); rt != nil {
+					add(i, rt)
+				}
+			}
+		}
+		return true
+	})
+
+	return results
+}
+
+// isObjNamed checks if fn is one of the named functions or methods, matched
+// like in producedBy
+func isObjNamed(fn *types.Func, names []string) bool {
+	return slices.Contains(names, fn.Name()) || slices.Contains(names, fn.FullName())
+}
+
+// resourceType returns the resource type fn returns at result index, or nil
+func (r resourceReturns) resourceType(fn *types.Func, index int, spannerTypes map[*types.Named]*ResourceType) *ResourceType {
+	for _, res := range r[fn.Origin()] {
+		if res.Index != index {
+			continue
+		}
+		for _, rt := range spannerTypes {
+			if rt.Name == res.Name && matchesPkgPath(rt.PkgPath, res.PkgPath) {
+				return rt
+			}
+		}
+	}
+	return nil
+}
+
+// callResource returns the resource type of val when it is a result of a call
+// to a function returning a resource it acquired, or nil
+func (r resourceReturns) callResource(val ssa.Value, spannerTypes map[*types.Named]*ResourceType) *ResourceType {
+	index := 0
+	if extract, ok := val.(*ssa.Extract); ok {
+		val, index = extract.Tuple, extract.Index
+	}
+	call, ok := val.(*ssa.Call)
+	if !ok {
+		return nil
+	}
+	callee := call.Common().StaticCallee()
+	if callee == nil {
+		return nil
+	}
+	obj, ok := callee.Object().(*types.Func)
+	if !ok {
+		return nil
+	}
+	return r.resourceType(obj, index, spannerTypes)
+}
+
+// checkReturnedResources checks the results of calls in fn to functions
+// returning resources that the main loop of checkFunc does not see: results
+// declared as interfaces, and results discarded with the blank identifier
+func checkReturnedResources(pass *analysis.Pass, fn *ssa.Function, spannerTypes map[*types.Named]*ResourceType, returns resourceReturns, opts *Options) {
+	for _, block := range fn.Blocks {
+		for _, instr := range block.Instrs {
+			call, ok := instr.(*ssa.Call)
+			if !ok {
+				continue
+			}
+			callee := call.Common().StaticCallee()
+			if callee == nil {
+				continue
+			}
+			obj, ok := callee.Object().(*types.Func)
+			if !ok || len(returns[obj.Origin()]) == 0 {
+				continue
+			}
+
+			results := call.Common().Signature().Results()
+			for _, res := range returns[obj.Origin()] {
+				rt := returns.resourceType(obj, res.Index, spannerTypes)
+				if rt == nil || res.Index >= results.Len() {
+					continue
+				}
+
+				var val ssa.Value = call
+				if results.Len() > 1 {
+					val = resultExtract(call, res.Index)
+				}
+				if val == nil {
+					// Discarded with the blank identifier: _, err := repo.Query(ctx)
+					message := rt.DiscardMessage()
+					if isCallStmt(pass, call) {
+						message = rt.DropMessage(constructorName(call))
+					}
+					pass.Report(analysis.Diagnostic{Pos: call.Pos(), Category: categoryDiscarded, Message: message})
+					continue
+				}
+				if getSpannerType(val.Type(), spannerTypes) == nil {
+					checkResource(pass, fn, val, rt, spannerTypes, returns, opts)
+				}
+			}
+		}
+	}
+}
+
+// resultExtract returns the extraction of the result at index of tuple, or nil
+func resultExtract(tuple ssa.Value, index int) ssa.Value {
+	if tuple.Referrers() == nil {
+		return nil
+	}
+	for _, ref := range *tuple.Referrers() {
+		if extract, ok := ref.(*ssa.Extract); ok && extract.Index == index {
+			return extract
+		}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package analyzer
+
+import (
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ssa"
+)
+
+// retainedTxnMessage reports a ReadWriteTransaction stored where it outlives
+// the callback receiving it
+const retainedTxnMessage = "ReadWriteTransaction is stored in %s, which outlives the callback receiving it: the transaction is only valid until the callback returns, use it inside the callback only"
+
+// checkRetainedReadWriteTxns reports parameters of fn of type
+// *spanner.ReadWriteTransaction, as received by the callback of
+// Client.ReadWriteTransaction, stored where they outlive fn:
+//
+//	var saved *spanner.ReadWriteTransaction
+//	client.ReadWriteTransaction(ctx, func(ctx context.Context, txn *spanner.ReadWriteTransaction) error {
+//		saved = txn // used after the transaction ended
+//		return nil
+//	})
+//
+// Stores into variables captured from an enclosing function, package-level
+// variables and fields of structs fn does not allocate, and sends on channels,
+// are reported. Structs allocated in fn, such as transaction-scoped
+// repositories, are left alone.
+func checkRetainedReadWriteTxns(pass *analysis.Pass, fn *ssa.Function) {
+	if fn == nil || isGeneratedFile(pass, fn.Pos()) {
+		return
+	}
+
+	for _, param := range fn.Params {
+		if !isReadWriteTransaction(param.Type()) || param.Referrers() == nil {
+			continue
+		}
+		for _, ref := range *param.Referrers() {
+			var target string
+			switch ref := ref.(type) {
+			case *ssa.Store:
+				if ref.Val == param {
+					target = outlivingAddr(ref.Addr)
+				}
+			case *ssa.Send:
+				if ref.X == param {
+					target = "a channel"
+				}
+			}
+			if target == "" {
+				continue
+			}
+			reportf(pass, ref.Pos(), categoryRetainedTxn, retainedTxnMessage, target)
+		}
+	}
+}
+
+// outlivingAddr describes addr if it outlives the function storing into it:
+// a captured variable, a package-level variable or a field of a struct the
+// function does not allocate. It returns "" otherwise.
+func outlivingAddr(addr ssa.Value) string {
+	switch addr := addr.(type) {
+	case *ssa.FreeVar:
+		return "captured variable " + addr.Name()
+	case *ssa.Global:
+		return "package-level variable " + addr.Name()
+	case *ssa.FieldAddr:
+		if _, local := addr.X.(*ssa.Alloc); local {
+			return ""
+		}
+		st, ok := derefType(addr.X.Type()).Underlying().(*types.Struct)
+		if !ok {
+			return ""
+		}
+		return "field " + st.Field(addr.Field).Name()
+	}
+	return ""
+}
+
+// isReadWriteTransaction checks if t is *spanner.ReadWriteTransaction
+func isReadWriteTransaction(t types.Type) bool {
+	ptr, ok := t.(*types.Pointer)
+	if !ok {
+		return false
+	}
+	named, ok := types.Unalias(ptr.Elem()).(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return false
+	}
+	return named.Obj().Name() == typeNameReadWriteTransaction && matchesPkgPath(named.Obj().Pkg().Path(), pathGoogleSpanner)
+}
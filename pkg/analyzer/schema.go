@@ -0,0 +1,125 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+)
+
+// optionValues are the values accepted by the options taking one of a set
+var optionValues = map[reflect.Type][]string{
+	reflect.TypeOf((*Severity)(nil)):   {string(SeverityInfo), string(SeverityWarning), string(SeverityOff)},
+	reflect.TypeOf((*Confidence)(nil)): {ConfidenceLow.String(), ConfidenceMedium.String(), ConfidenceHigh.String()},
+}
+
+// isRepeatable checks if f is a repeatable flag, which configuration files
+// set with a list
+func isRepeatable(f *flag.Flag) bool {
+	v := reflect.ValueOf(f.Value)
+	if v.Kind() != reflect.Pointer {
+		return false
+	}
+	kind := v.Elem().Kind()
+	return kind == reflect.Slice || kind == reflect.Map
+}
+
+// isBoolFlag checks if f is a boolean flag
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// isIntFlag checks if f is an integer flag
+func isIntFlag(f *flag.Flag) bool {
+	g, ok := f.Value.(flag.Getter)
+	if !ok {
+		return false
+	}
+	_, ok = g.Get().(int)
+	return ok
+}
+
+// optionType describes the values of the option of f, for error messages
+func optionType(f *flag.Flag) string {
+	switch {
+	case isBoolFlag(f):
+		return "boolean"
+	case isIntFlag(f):
+		return "integer"
+	case isRepeatable(f):
+		return "value or list of values"
+	}
+	return "value"
+}
+
+// optionError returns the error of setting f to value, describing the
+// values f takes where the flag package does not
+func optionError(f *flag.Flag, value string, err error) error {
+	switch {
+	case isBoolFlag(f):
+		return fmt.Errorf("invalid value %q, want true or false", value)
+	case isIntFlag(f):
+		return fmt.Errorf("invalid value %q, want an integer", value)
+	}
+	return err
+}
+
+// optionSchema returns the JSON Schema of the values of the option of f
+func optionSchema(f *flag.Flag) map[string]any {
+	schema := map[string]any{"description": f.Usage}
+	switch {
+	case isBoolFlag(f):
+		schema["type"] = "boolean"
+		if b, err := strconv.ParseBool(f.DefValue); err == nil {
+			schema["default"] = b
+		}
+	case isIntFlag(f):
+		schema["type"] = "integer"
+		if n, err := strconv.Atoi(f.DefValue); err == nil {
+			schema["default"] = n
+		}
+	case isRepeatable(f):
+		schema["anyOf"] = []map[string]any{
+			{"type": "string"},
+			{"type": "array", "items": map[string]any{"type": "string"}},
+		}
+	default:
+		schema["type"] = "string"
+		if values, ok := optionValues[reflect.TypeOf(f.Value)]; ok {
+			schema["enum"] = values
+		}
+		if f.DefValue != "" {
+			schema["default"] = f.DefValue
+		}
+	}
+	return schema
+}
+
+// WriteConfigSchema writes the JSON Schema of the configuration files setting
+// the flags of fs, for editors to validate and complete them, as with the
+// yaml-language-server comment:
+//
+//	# yaml-language-server: $schema=spannerclosecheck.schema.json
+func WriteConfigSchema(w io.Writer, fs *flag.FlagSet) error {
+	properties := make(map[string]any)
+	fs.VisitAll(func(f *flag.Flag) {
+		if f.Name != "config" {
+			properties[f.Name] = optionSchema(f)
+		}
+	})
+	data, err := json.MarshalIndent(map[string]any{
+		"$schema":              "http://json-schema.org/draft-07/schema#",
+		"title":                DefaultConfigFile,
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
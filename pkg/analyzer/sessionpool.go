@@ -0,0 +1,99 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// highThroughputMaxOpened is the MaxOpened from which a pool is assumed to
+// serve enough traffic for idle sessions to matter
+const highThroughputMaxOpened = 100
+
+// checkSessionPoolConfigs reports spanner.SessionPoolConfig literals with
+// suspicious constant values, which make NewClientWithConfig fail or churn
+// sessions:
+//
+//   - MinOpened greater than a non-zero MaxOpened, which fails
+//   - negative HealthCheckWorkers or HealthCheckInterval, which fail
+//   - WriteSessions outside [0, 1], which fails
+//   - MaxIdle explicitly set to 0 with a MaxOpened of at least
+//     highThroughputMaxOpened, which deletes the sessions beyond MinOpened
+//     as soon as they are idle and creates them again under load
+//
+// Only the fields set in the literal with constant values are checked.
+func checkSessionPoolConfigs(pass *analysis.Pass) {
+	for _, f := range pass.Files {
+		if isGeneratedFile(pass, f.Pos()) {
+			continue
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			lit, ok := n.(*ast.CompositeLit)
+			if ok && isSessionPoolConfig(pass.TypesInfo.TypeOf(lit)) {
+				checkSessionPoolConfig(pass, lit)
+			}
+			return true
+		})
+	}
+}
+
+// isSessionPoolConfig checks if t is spanner.SessionPoolConfig
+func isSessionPoolConfig(t types.Type) bool {
+	named, ok := types.Unalias(t).(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return false
+	}
+	return named.Obj().Name() == typeNameSessionPoolConfig && matchesPkgPath(named.Obj().Pkg().Path(), pathGoogleSpanner)
+}
+
+// checkSessionPoolConfig reports the suspicious values of the
+// SessionPoolConfig literal lit
+func checkSessionPoolConfig(pass *analysis.Pass, lit *ast.CompositeLit) {
+	fields := make(map[string]*ast.KeyValueExpr)
+	values := make(map[string]constant.Value)
+	for _, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			continue
+		}
+		key, ok := kv.Key.(*ast.Ident)
+		if !ok {
+			continue
+		}
+		fields[key.Name] = kv
+		if tv, ok := pass.TypesInfo.Types[kv.Value]; ok && tv.Value != nil {
+			values[key.Name] = tv.Value
+		}
+	}
+
+	report := func(field, format string, args ...interface{}) {
+		reportf(pass, fields[field].Pos(), categorySessionPool, format, args...)
+	}
+	sign := func(field string) int {
+		if v, ok := values[field]; ok {
+			return constant.Sign(v)
+		}
+		return 0
+	}
+
+	maxOpened, hasMaxOpened := values["MaxOpened"]
+	if minOpened, ok := values["MinOpened"]; ok && hasMaxOpened && sign("MaxOpened") > 0 &&
+		constant.Compare(minOpened, token.GTR, maxOpened) {
+		report("MinOpened", "SessionPoolConfig.MinOpened %s is greater than MaxOpened %s: NewClientWithConfig fails, lower MinOpened or raise MaxOpened", minOpened, maxOpened)
+	}
+	for _, field := range []string{"HealthCheckWorkers", "HealthCheckInterval"} {
+		if sign(field) < 0 {
+			report(field, "SessionPoolConfig.%s is negative: NewClientWithConfig fails", field)
+		}
+	}
+	if v, ok := values["WriteSessions"]; ok && (constant.Sign(v) < 0 || constant.Compare(v, token.GTR, constant.MakeInt64(1))) {
+		report("WriteSessions", "SessionPoolConfig.WriteSessions %s is outside [0, 1]: NewClientWithConfig fails", v)
+	}
+	if _, ok := values["MaxIdle"]; ok && sign("MaxIdle") == 0 && hasMaxOpened &&
+		constant.Compare(maxOpened, token.GEQ, constant.MakeInt64(highThroughputMaxOpened)) {
+		report("MaxIdle", "SessionPoolConfig.MaxIdle is 0 with MaxOpened %s: sessions beyond MinOpened are deleted as soon as they are idle and created again under load, raise MaxIdle or MinOpened", maxOpened)
+	}
+}
@@ -0,0 +1,151 @@
+package analyzer
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// SeverityError is the default severity of the reports of a check
+const SeverityError Severity = "error"
+
+// WarningOutput, when set, receives the reports of warning and info
+// severity, see Options.Severities and Options.SingleClose, instead of the
+// driver, so that they are printed but do not fail the run. The
+// spannerclosecheck command sets it to os.Stderr; drivers such as
+// golangci-lint get them as diagnostics.
+var WarningOutput io.Writer
+
+// SeverityOutput, when set while WarningOutput is not, receives the reports
+// of warning and info severity, with their severity, before they are passed
+// to the driver. Messages do not carry their severity, so drivers tell them
+// from errors with it: the spannerclosecheck command sets it for -format
+// json and sarif. Other drivers report them like errors.
+var SeverityOutput func(pass *analysis.Pass, d analysis.Diagnostic, s Severity)
+
+// warningOutputMu serializes the writes of passes to WarningOutput and the
+// calls to SeverityOutput
+var warningOutputMu sync.Mutex
+
+// severityKey is a key of Options.Severities, matching the diagnostics of a
+// category or those mentioning a resource type
+type severityKey struct {
+	key      string
+	severity Severity
+	resource *regexp.Regexp
+}
+
+// severityKeys returns the keys of severities, sorted so that the first
+// resource type a message mentions is the same on every run
+func severityKeys(severities map[string]Severity) []severityKey {
+	keys := make([]severityKey, 0, len(severities))
+	for key, severity := range severities {
+		keys = append(keys, severityKey{
+			key:      key,
+			severity: severity,
+			resource: regexp.MustCompile(`(^|[^\w.])` + regexp.QuoteMeta(key) + `\b`),
+		})
+	}
+	slices.SortFunc(keys, func(a, b severityKey) int {
+		return strings.Compare(a.key, b.key)
+	})
+	return keys
+}
+
+// severityOf returns the severity of d, set by the key of its category, by
+// the default of its category in defaults, or else by the first resource
+// type its message mentions
+func severityOf(d analysis.Diagnostic, keys []severityKey, defaults map[string]Severity) Severity {
+	for _, k := range keys {
+		if k.key == d.Category {
+			return k.severity
+		}
+	}
+	if severity, ok := defaults[d.Category]; ok {
+		return severity
+	}
+	for _, k := range keys {
+		if k.resource.MatchString(d.Message) {
+			return k.severity
+		}
+	}
+	return SeverityError
+}
+
+// reportSeverities wraps report to format the messages of the diagnostics
+// with run, and to print those of warning and info severity to
+// WarningOutput when it is set, or else to pass them to SeverityOutput
+// before reporting them. The severity is set by the diagnostic as the checks
+// report it, before its message is translated or templated. Categories
+// default to their severity in categorySeverities, and redundant closes to
+// that of opts.SingleClose.
+func reportSeverities(pass *analysis.Pass, report func(analysis.Diagnostic), opts *Options, run *runOptions) func(analysis.Diagnostic) {
+	keys := severityKeys(opts.Severities)
+	defaults := maps.Clone(categorySeverities)
+	defaults[categorySingleClose] = opts.SingleClose.orDefault(categorySeverities[categorySingleClose])
+	formatted := run.format(report)
+	return func(d analysis.Diagnostic) {
+		severity := severityOf(d, keys, defaults)
+		if severity == SeverityError {
+			formatted(d)
+			return
+		}
+		warningOutputMu.Lock()
+		defer warningOutputMu.Unlock()
+		if WarningOutput != nil {
+			label := translate(string(severity)+": ", run.translations)
+			run.format(func(d analysis.Diagnostic) {
+				fmt.Fprintf(WarningOutput, "%s: %s%s\n", pass.Fset.Position(d.Pos), label, d.Message)
+			})(d)
+			return
+		}
+		if SeverityOutput != nil {
+			SeverityOutput(pass, d, severity)
+		}
+		formatted(d)
+	}
+}
+
+// severitiesFlag is a repeatable flag setting the severity of checks or
+// resource types as key=severity
+type severitiesFlag map[string]Severity
+
+func (f *severitiesFlag) String() string {
+	if f == nil || *f == nil {
+		return ""
+	}
+	var settings []string
+	for key, severity := range *f {
+		settings = append(settings, key+"="+string(severity))
+	}
+	slices.Sort(settings)
+	return strings.Join(settings, ",")
+}
+
+func (f *severitiesFlag) Set(value string) error {
+	for _, setting := range strings.Split(value, ",") {
+		if setting = strings.TrimSpace(setting); setting == "" {
+			continue
+		}
+		key, severity, ok := strings.Cut(setting, "=")
+		if !ok || key == "" {
+			return fmt.Errorf("invalid severity %q: want check=severity", setting)
+		}
+		switch Severity(severity) {
+		case SeverityError, SeverityWarning:
+		default:
+			return fmt.Errorf("invalid severity %q: want error or warning", setting)
+		}
+		if *f == nil {
+			*f = make(severitiesFlag)
+		}
+		(*f)[key] = Severity(severity)
+	}
+	return nil
+}
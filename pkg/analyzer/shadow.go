@@ -0,0 +1,84 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ssa"
+)
+
+// shadowMessage completes the report of a resource whose variable is shadowed
+// by another variable of the same name, closed by a defer that does not close
+// the resource
+const shadowMessage = ", the deferred %s() at line %d closes the %s declared at line %d instead"
+
+// shadowingDefer returns the message suffix explaining a defer that looks like
+// it closes val but closes a variable shadowing the one val is assigned to:
+//
+//	txn := client.ReadOnlyTransaction()
+//	if fresh {
+//		txn := client.ReadOnlyTransaction()
+//		defer txn.Close()
+//	}
+//
+// Deferred closures closing the shadowing variable are included. It returns
+// "" if there is none.
+func shadowingDefer(pass *analysis.Pass, fn *ssa.Function, val ssa.Value, rt *ResourceType) string {
+	id := assignedIdent(pass, val)
+	if id == nil {
+		return ""
+	}
+	obj, ok := pass.TypesInfo.ObjectOf(id).(*types.Var)
+	if !ok || obj.Parent() == nil {
+		return ""
+	}
+	body := funcBody(fn)
+	if body == nil {
+		return ""
+	}
+
+	message := ""
+	ast.Inspect(body, func(n ast.Node) bool {
+		if message != "" {
+			return false
+		}
+		d, ok := n.(*ast.DeferStmt)
+		if !ok {
+			return true
+		}
+		ast.Inspect(d.Call, func(n ast.Node) bool {
+			sel, ok := n.(*ast.SelectorExpr)
+			if message != "" || !ok || !rt.isCloseMethod(sel.Sel.Name) {
+				return message == ""
+			}
+			x, ok := ast.Unparen(sel.X).(*ast.Ident)
+			if !ok {
+				return true
+			}
+			if shadow, ok := pass.TypesInfo.ObjectOf(x).(*types.Var); ok && shadows(shadow, obj) {
+				message = fmt.Sprintf(shadowMessage, x.Name+"."+sel.Sel.Name,
+					pass.Fset.Position(d.Pos()).Line, x.Name, pass.Fset.Position(shadow.Pos()).Line)
+			}
+			return true
+		})
+		return false
+	})
+
+	return message
+}
+
+// shadows checks if v is declared in a scope nested within the scope of obj,
+// with the same name and type
+func shadows(v, obj *types.Var) bool {
+	if v == obj || v.Name() != obj.Name() || !types.Identical(v.Type(), obj.Type()) {
+		return false
+	}
+	for scope := v.Parent(); scope != nil; scope = scope.Parent() {
+		if scope == obj.Parent() {
+			return v.Parent() != obj.Parent()
+		}
+	}
+	return false
+}
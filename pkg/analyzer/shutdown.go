@@ -0,0 +1,204 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"slices"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ssa"
+)
+
+// packageClientMessage reports a client stored into a package-level variable
+// that is never closed on shutdown
+const packageClientMessage = "%s() is assigned to package-level variable %s, which is not closed on shutdown: defer %s.%s() in main, close it in a lifecycle hook or in a function declared with " + directiveShutdown
+
+// checkPackageClients reports Spanner clients created for package-level
+// variables, in init() or in their declaration, that are not closed on a
+// recognized shutdown path:
+//
+//   - a deferred close in main, directly or in a deferred closure
+//   - a close in a hook registered with a lifecycle manager, see
+//     defaultLifecycleHooks and Options.LifecycleHooks
+//   - a close in a function declared with directiveShutdown
+func checkPackageClients(pass *analysis.Pass, pkg *ssa.Package, srcFuncs []*ssa.Function, clientTypes map[*types.Named]*ResourceType, opts *Options) {
+	globals := make(map[*ssa.Global]*ResourceType)
+	for _, member := range pkg.Members {
+		g, ok := member.(*ssa.Global)
+		if !ok {
+			continue
+		}
+		// Globals are addresses of the variable
+		if named, ok := derefType(derefType(g.Type())).(*types.Named); ok && clientTypes[named] != nil {
+			globals[g] = clientTypes[named]
+		}
+	}
+	if len(globals) == 0 {
+		return
+	}
+
+	// Package initializers are synthesized and not among the source functions
+	funcs := slices.Clone(srcFuncs)
+	if init := pkg.Func("init"); init != nil {
+		funcs = append(funcs, init)
+		funcs = append(funcs, init.AnonFuncs...)
+	}
+
+	type creation struct {
+		call *ssa.Call
+		name string
+	}
+	created := make(map[*ssa.Global][]creation)
+	closed := make(map[*ssa.Global]bool)
+	hooks := slices.Concat(defaultLifecycleHooks, opts.LifecycleHooks)
+	for _, fn := range funcs {
+		if isGeneratedFile(pass, fn.Pos()) {
+			continue
+		}
+		for _, block := range fn.Blocks {
+			for _, instr := range block.Instrs {
+				switch instr := instr.(type) {
+				case *ssa.Store:
+					g, ok := instr.Addr.(*ssa.Global)
+					if !ok || globals[g] == nil {
+						continue
+					}
+					if call, name, ok := storedClientConstruction(instr.Val); ok {
+						created[g] = append(created[g], creation{call, name})
+					}
+				case *ssa.MakeClosure:
+					// Method values registered as hooks: t.Cleanup(client.Close)
+					if g := loadedGlobal(instr.Bindings); g != nil && globals[g] != nil &&
+						globals[g].isCloseMethod(strings.TrimSuffix(instr.Fn.Name(), "$bound")) &&
+						flowsToHookRegistration(instr, hooks, 0) {
+						closed[g] = true
+					}
+				case ssa.CallInstruction:
+					recv := methodReceiver(instr.Common())
+					if recv == nil {
+						continue
+					}
+					g := loadedGlobal([]ssa.Value{recv})
+					if g == nil || globals[g] == nil || !isCloseCall(instr.Common(), recv, globals[g]) {
+						continue
+					}
+					if isShutdownClose(pass, fn, instr, hooks) {
+						closed[g] = true
+					}
+				}
+			}
+		}
+	}
+
+	for g, creations := range created {
+		if closed[g] {
+			continue
+		}
+		rt := globals[g]
+		for _, c := range creations {
+			reportf(pass, c.call.Pos(), categoryPackageClient, packageClientMessage, c.name, g.Name(), g.Name(), rt.CloseMethod)
+		}
+	}
+}
+
+// storedClientConstruction returns the call creating the client val, stored
+// into a variable, and its qualified name
+func storedClientConstruction(val ssa.Value) (*ssa.Call, string, bool) {
+	if extract, ok := val.(*ssa.Extract); ok {
+		val = extract.Tuple
+	}
+	call, ok := val.(*ssa.Call)
+	if !ok {
+		return nil, "", false
+	}
+	name, ok := clientConstruction(call)
+	return call, name, ok
+}
+
+// loadedGlobal returns the global the first of values is loaded from, or nil
+func loadedGlobal(values []ssa.Value) *ssa.Global {
+	if len(values) == 0 {
+		return nil
+	}
+	load, ok := values[0].(*ssa.UnOp)
+	if !ok || load.Op != token.MUL {
+		return nil
+	}
+	g, _ := load.X.(*ssa.Global)
+	return g
+}
+
+// isShutdownClose checks if closeCall, a close in fn, runs on shutdown: it is
+// deferred in main, or fn, or a function it is nested in, is declared with
+// directiveShutdown, registered as a lifecycle hook or deferred in main
+func isShutdownClose(pass *analysis.Pass, fn *ssa.Function, closeCall ssa.CallInstruction, hooks []string) bool {
+	if _, deferred := closeCall.(*ssa.Defer); deferred && isMainFunc(fn) {
+		return true
+	}
+	for ; fn != nil; fn = fn.Parent() {
+		if decl, ok := fn.Syntax().(*ast.FuncDecl); ok {
+			if _, _, ok := findDirective(decl.Doc, directiveShutdown); ok {
+				return true
+			}
+		}
+		if fn.Parent() == nil {
+			break
+		}
+		if isRegisteredFunc(fn, hooks) || isDeferredIn(fn, fn.Parent()) && isMainFunc(fn.Parent()) {
+			return true
+		}
+	}
+	return false
+}
+
+// isMainFunc checks if fn is the main function of a main package
+func isMainFunc(fn *ssa.Function) bool {
+	return fn.Name() == "main" && fn.Parent() == nil && fn.Signature.Recv() == nil &&
+		fn.Pkg != nil && fn.Pkg.Pkg.Name() == "main"
+}
+
+// isRegisteredFunc checks if the function literal fn, or a closure of it, is
+// passed to one of hooks
+func isRegisteredFunc(fn *ssa.Function, hooks []string) bool {
+	if flowsToHookRegistration(fn, hooks, 0) {
+		return true
+	}
+	for _, anon := range funcValues(fn) {
+		if flowsToHookRegistration(anon, hooks, 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// isDeferredIn checks if the function literal fn is deferred in parent:
+// defer func() { ... }()
+func isDeferredIn(fn, parent *ssa.Function) bool {
+	values := append(funcValues(fn), fn)
+	for _, block := range parent.Blocks {
+		for _, instr := range block.Instrs {
+			if d, ok := instr.(*ssa.Defer); ok && slices.Contains(values, d.Call.Value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// funcValues returns the closures of the function literal fn in its parent
+func funcValues(fn *ssa.Function) []ssa.Value {
+	var values []ssa.Value
+	if fn.Parent() == nil {
+		return nil
+	}
+	for _, block := range fn.Parent().Blocks {
+		for _, instr := range block.Instrs {
+			if mc, ok := instr.(*ssa.MakeClosure); ok && mc.Fn == fn {
+				values = append(values, mc)
+			}
+		}
+	}
+	return values
+}
@@ -0,0 +1,225 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ssa"
+)
+
+const methodNameReadOnlyTransaction = "ReadOnlyTransaction"
+
+// statementMethods are the ReadOnlyTransaction methods that run a single statement
+var statementMethods = map[string]bool{
+	"Query":              true,
+	"QueryWithOptions":   true,
+	"QueryWithStats":     true,
+	"AnalyzeQuery":       true,
+	"Read":               true,
+	"ReadWithOptions":    true,
+	"ReadUsingIndex":     true,
+	"ReadRow":            true,
+	"ReadRowWithOptions": true,
+	"ReadRowUsingIndex":  true,
+}
+
+// checkSingleUse reports ReadOnlyTransactions that run exactly one statement
+// and are then closed, with defer or explicitly. Such transactions can use
+// Client.Single() instead, which releases its session automatically.
+func checkSingleUse(pass *analysis.Pass, fn *ssa.Function, spannerTypes map[*types.Named]*ResourceType) {
+	if fn == nil || isGeneratedFile(pass, fn.Pos()) {
+		return
+	}
+
+	for _, block := range fn.Blocks {
+		for _, instr := range block.Instrs {
+			call, ok := instr.(*ssa.Call)
+			if !ok {
+				continue
+			}
+			rt := getSpannerType(call.Type(), spannerTypes)
+			if rt == nil || rt.Name != typeNameReadOnlyTransaction {
+				continue
+			}
+
+			// Only transactions from Client.ReadOnlyTransaction() can be replaced
+			callee := call.Common().StaticCallee()
+			if callee == nil || callee.Name() != methodNameReadOnlyTransaction || callee.Signature.Recv() == nil {
+				continue
+			}
+
+			closeCall, ok := singleUseClose(call, rt)
+			if !ok {
+				continue
+			}
+
+			pass.Report(analysis.Diagnostic{
+				Pos:            call.Pos(),
+				Category:       categorySingleUse,
+				Message:        "ReadOnlyTransaction is used for a single statement, use Client.Single() instead",
+				SuggestedFixes: singleUseFixes(pass, call, closeCall),
+			})
+		}
+	}
+}
+
+// singleUseClose checks that txn is used by exactly one statement method and
+// a Close(), deferred or following the statement, and returns the close
+func singleUseClose(txn *ssa.Call, rt *ResourceType) (ssa.CallInstruction, bool) {
+	if txn.Referrers() == nil {
+		return nil, false
+	}
+
+	var closeCall ssa.CallInstruction
+	var statement *ssa.Call
+	for _, ref := range *txn.Referrers() {
+		switch ref := ref.(type) {
+		case *ssa.Defer:
+			if closeCall != nil || !isCloseCall(ref.Common(), txn, rt) {
+				return nil, false
+			}
+			closeCall = ref
+		case *ssa.Call:
+			if isCloseCall(ref.Common(), txn, rt) {
+				if closeCall != nil {
+					return nil, false
+				}
+				closeCall = ref
+				continue
+			}
+			callee := ref.Common().StaticCallee()
+			if callee == nil || !statementMethods[callee.Name()] || ref.Common().Args[0] != txn || statement != nil {
+				return nil, false
+			}
+			statement = ref
+		default:
+			// Any other use (stored, passed to a function, ...) may need the transaction
+			return nil, false
+		}
+	}
+	if closeCall == nil || statement == nil {
+		return nil, false
+	}
+
+	// An explicit close must come after the statement
+	if call, ok := closeCall.(*ssa.Call); ok && !dominates(statement, call) {
+		return nil, false
+	}
+	// A statement in a loop runs once per iteration on the same transaction
+	if inLoopAfter(statement.Block(), txn.Block()) {
+		return nil, false
+	}
+	return closeCall, true
+}
+
+// inLoopAfter checks if block is part of a cycle in the control flow graph
+// that does not go through start, so that it may run more than once after
+// start
+func inLoopAfter(block, start *ssa.BasicBlock) bool {
+	if block == start {
+		return false
+	}
+	seen := map[*ssa.BasicBlock]bool{start: true}
+	stack := append([]*ssa.BasicBlock(nil), block.Succs...)
+	for len(stack) > 0 {
+		b := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if b == block {
+			return true
+		}
+		if seen[b] {
+			continue
+		}
+		seen[b] = true
+		stack = append(stack, b.Succs...)
+	}
+	return false
+}
+
+// singleUseFixes rewrites client.ReadOnlyTransaction() to client.Single()
+// and removes the Close() statement
+func singleUseFixes(pass *analysis.Pass, txn *ssa.Call, closeCall ssa.CallInstruction) []analysis.SuggestedFix {
+	callExpr, ok := findNode(pass, txn.Pos(), func(n *ast.CallExpr) bool { return n.Lparen == txn.Pos() })
+	if !ok {
+		return nil
+	}
+	sel, ok := callExpr.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return nil
+	}
+	closeStmt, ok := callStmt(pass, closeCall)
+	if !ok {
+		return nil
+	}
+
+	start, end := stmtLineRange(pass, closeStmt)
+	return []analysis.SuggestedFix{{
+		Message: "Use Client.Single()",
+		TextEdits: []analysis.TextEdit{
+			{Pos: sel.Sel.Pos(), End: sel.Sel.End(), NewText: []byte(methodNameSingle)},
+			{Pos: start, End: end},
+		},
+	}}
+}
+
+// callStmt returns the defer or expression statement of call
+func callStmt(pass *analysis.Pass, call ssa.CallInstruction) (ast.Stmt, bool) {
+	if _, deferred := call.(*ssa.Defer); deferred {
+		return findNode(pass, call.Pos(), func(n *ast.DeferStmt) bool { return n.Defer == call.Pos() })
+	}
+	return findNode(pass, call.Pos(), func(n *ast.ExprStmt) bool {
+		expr, ok := n.X.(*ast.CallExpr)
+		return ok && expr.Lparen == call.Pos()
+	})
+}
+
+// findNode returns the first node of type T in the file containing pos that satisfies match
+func findNode[T ast.Node](pass *analysis.Pass, pos token.Pos, match func(T) bool) (T, bool) {
+	var found T
+	ok := false
+	for _, f := range pass.Files {
+		if f.FileStart > pos || pos > f.FileEnd {
+			continue
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			if ok || n == nil || n.Pos() > pos || n.End() < pos {
+				return false
+			}
+			if t, isT := n.(T); isT && match(t) {
+				found, ok = t, true
+				return false
+			}
+			return true
+		})
+	}
+	return found, ok
+}
+
+// stmtLineRange returns the range to delete for stmt, covering its whole lines
+// when nothing else shares them
+func stmtLineRange(pass *analysis.Pass, stmt ast.Stmt) (token.Pos, token.Pos) {
+	file := pass.Fset.File(stmt.Pos())
+	if file == nil {
+		return stmt.Pos(), stmt.End()
+	}
+	content, err := pass.ReadFile(file.Name())
+	if err != nil {
+		return stmt.Pos(), stmt.End()
+	}
+
+	lineStart := file.LineStart(file.Line(stmt.Pos()))
+	lineEnd := token.Pos(file.Base() + file.Size())
+	if line := file.Line(stmt.End()); line < file.LineCount() {
+		lineEnd = file.LineStart(line + 1)
+	}
+
+	before := content[file.Offset(lineStart):file.Offset(stmt.Pos())]
+	after := content[file.Offset(stmt.End()):file.Offset(lineEnd)]
+	if strings.TrimSpace(string(before)) != "" || strings.TrimSpace(string(after)) != "" {
+		return stmt.Pos(), stmt.End()
+	}
+	return lineStart, lineEnd
+}
@@ -0,0 +1,79 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ssa"
+)
+
+// singleCloseMessage reports a close of a resource that releases itself
+const singleCloseMessage = "%s.%s() is redundant: the %s from %s() releases itself"
+
+// checkSingleClose reports closes of resources from exempt constructors,
+// such as Client.Single(), which release themselves:
+//
+//	txn := client.Single()
+//	defer txn.Close() // redundant
+//
+// The suggested fix removes the close. The reports have the severity of
+// Options.SingleClose, applied by reportSeverities.
+func checkSingleClose(pass *analysis.Pass, fn *ssa.Function, spannerTypes map[*types.Named]*ResourceType, opts *Options) {
+	if fn == nil || isGeneratedFile(pass, fn.Pos()) {
+		return
+	}
+
+	for _, block := range fn.Blocks {
+		for _, instr := range block.Instrs {
+			closeCall, ok := instr.(ssa.CallInstruction)
+			if _, isGo := instr.(*ssa.Go); !ok || isGo {
+				continue
+			}
+			val := methodReceiver(closeCall.Common())
+			if val == nil {
+				continue
+			}
+			rt := getSpannerType(val.Type(), spannerTypes)
+			if rt == nil || !isCloseCall(closeCall.Common(), val, rt) || !isFromExemptConstructor(val, rt, opts) {
+				continue
+			}
+			var fixes []analysis.SuggestedFix
+			if stmt, ok := callStmt(pass, closeCall); ok {
+				start, end := stmtLineRange(pass, stmt)
+				fixes = []analysis.SuggestedFix{{
+					Message:   fmt.Sprintf("Remove %s.%s()", rt.QualifiedName(), rt.CloseMethod),
+					TextEdits: []analysis.TextEdit{{Pos: start, End: end}},
+				}}
+			}
+			pass.Report(analysis.Diagnostic{
+				Pos:            closeCall.Pos(),
+				Category:       categorySingleClose,
+				Message:        fmt.Sprintf(singleCloseMessage, rt.QualifiedName(), rt.CloseMethod, rt.Name, constructorName(val)),
+				SuggestedFixes: fixes,
+			})
+		}
+	}
+}
+
+// constructorName returns the name of the function or method val is the
+// result of, as in Client.Single
+func constructorName(val ssa.Value) string {
+	if extract, ok := val.(*ssa.Extract); ok {
+		val = extract.Tuple
+	}
+	call, ok := val.(*ssa.Call)
+	if !ok {
+		return ""
+	}
+	callee := call.Common().StaticCallee()
+	if callee == nil {
+		return ""
+	}
+	if recv := callee.Signature.Recv(); recv != nil {
+		if named, ok := types.Unalias(derefType(recv.Type())).(*types.Named); ok {
+			return named.Obj().Name() + "." + callee.Name()
+		}
+	}
+	return callee.Name()
+}
@@ -0,0 +1,44 @@
+package analyzer
+
+import (
+	"cmp"
+	"go/token"
+	"slices"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// reportSorted replaces the report function of pass with one collecting the
+// diagnostics, and returns a function reporting them sorted with
+// compareDiagnostics. Checks report in the order SSA functions and blocks
+// are visited, which does not follow the source; sorting keeps the output of
+// runs and baselines comparable line by line.
+func reportSorted(pass *analysis.Pass) (flush func()) {
+	report := pass.Report
+	var diags []analysis.Diagnostic
+	pass.Report = func(d analysis.Diagnostic) {
+		diags = append(diags, d)
+	}
+	return func() {
+		slices.SortStableFunc(diags, func(a, b analysis.Diagnostic) int {
+			return compareDiagnostics(pass.Fset, a, b)
+		})
+		for _, d := range diags {
+			report(d)
+		}
+	}
+}
+
+// compareDiagnostics orders diagnostics by file, line and column, then by
+// category, naming the check reporting them, and by message
+func compareDiagnostics(fset *token.FileSet, a, b analysis.Diagnostic) int {
+	pa, pb := fset.Position(a.Pos), fset.Position(b.Pos)
+	return cmp.Or(
+		strings.Compare(pa.Filename, pb.Filename),
+		cmp.Compare(pa.Line, pb.Line),
+		cmp.Compare(pa.Column, pb.Column),
+		strings.Compare(a.Category, b.Category),
+		strings.Compare(a.Message, b.Message),
+	)
+}
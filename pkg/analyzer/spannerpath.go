@@ -0,0 +1,44 @@
+package analyzer
+
+import (
+	"slices"
+	"strings"
+	"sync"
+)
+
+// spannerPaths are the other import paths of the Spanner package, set with
+// Options.SpannerPaths by the analyzers run so far. Package paths are
+// compared without a pass to look the options up with, so the paths of
+// every Options apply.
+var spannerPaths struct {
+	sync.RWMutex
+	paths []string
+}
+
+// registerSpannerPaths adds the Spanner package paths of opts to those
+// normalizePkgPath maps to pathGoogleSpanner
+func registerSpannerPaths(opts *Options) {
+	if len(opts.SpannerPaths) == 0 {
+		return
+	}
+	spannerPaths.Lock()
+	defer spannerPaths.Unlock()
+	for _, p := range opts.SpannerPaths {
+		if p = strings.TrimSuffix(p, "/"); p != "" && !slices.Contains(spannerPaths.paths, p) {
+			spannerPaths.paths = append(spannerPaths.paths, p)
+		}
+	}
+}
+
+// canonicalSpannerPath returns path, in a mirror or fork of the Spanner
+// package or one of its subpackages, in cloud.google.com/go/spanner
+func canonicalSpannerPath(path string) string {
+	spannerPaths.RLock()
+	defer spannerPaths.RUnlock()
+	for _, p := range spannerPaths.paths {
+		if rest, ok := strings.CutPrefix(path, p); ok && (rest == "" || rest[0] == '/') {
+			return pathGoogleSpanner + rest
+		}
+	}
+	return path
+}
@@ -0,0 +1,88 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ssa"
+)
+
+// closeInNextLoopMessage reports a close inside the loop reading the resource
+// with Next(), which the following iterations call on a closed resource
+const closeInNextLoopMessage = "%s.%s() is called inside the loop reading it with Next() at line %d, which ends the iteration early: the next iteration reads a closed resource, close it after the loop instead"
+
+// checkCloseInNextLoop reports non-deferred closes of a resource inside the
+// loop reading it with Next(), when the loop can go on to call Next() again:
+//
+//	for {
+//		row, err := iter.Next()
+//		...
+//		iter.Stop() // the next iteration reads a stopped iterator
+//	}
+//
+// Closes followed by a break or return leave the loop and are accepted.
+func checkCloseInNextLoop(pass *analysis.Pass, fn *ssa.Function, spannerTypes map[*types.Named]*ResourceType) {
+	if fn == nil || isGeneratedFile(pass, fn.Pos()) {
+		return
+	}
+
+	for _, block := range fn.Blocks {
+		for _, instr := range block.Instrs {
+			closeCall, ok := instr.(*ssa.Call)
+			if !ok {
+				continue
+			}
+			val := methodReceiver(closeCall.Common())
+			if val == nil {
+				continue
+			}
+			rt := getSpannerType(val.Type(), spannerTypes)
+			if rt == nil || !isCloseCall(closeCall.Common(), val, rt) {
+				continue
+			}
+			next := nextCallInLoop(pass, val, closeCall)
+			if next == nil {
+				continue
+			}
+			pass.Report(analysis.Diagnostic{
+				Pos:      closeCall.Pos(),
+				Category: categoryCloseInLoop,
+				Message:  fmt.Sprintf(closeInNextLoopMessage, rt.QualifiedName(), rt.CloseMethod, pass.Fset.Position(next.Pos()).Line),
+				Related: []analysis.RelatedInformation{{
+					Pos:     next.Pos(),
+					Message: "read here",
+				}},
+			})
+		}
+	}
+}
+
+// nextCallInLoop returns the call to the Next() method of val in the innermost
+// loop enclosing closeCall that can run after it, or nil
+func nextCallInLoop(pass *analysis.Pass, val ssa.Value, closeCall *ssa.Call) ssa.CallInstruction {
+	body := funcBody(closeCall.Parent())
+	if body == nil {
+		return nil
+	}
+	loop := enclosingLoop(pass, closeCall.Pos(), body)
+	if loop == nil {
+		return nil
+	}
+	for _, ref := range *val.Referrers() {
+		use, ok := ref.(ssa.CallInstruction)
+		if !ok || use.Parent() != closeCall.Parent() || methodName(use.Common(), val) != "Next" {
+			continue
+		}
+		if _, isDefer := use.(*ssa.Defer); isDefer {
+			continue
+		}
+		if use.Pos() < loop.Pos() || use.Pos() >= loop.End() {
+			continue
+		}
+		if reachableAfter(closeCall, use, val) {
+			return use
+		}
+	}
+	return nil
+}
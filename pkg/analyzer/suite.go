@@ -0,0 +1,99 @@
+package analyzer
+
+import (
+	"go/types"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+const pathTestifySuite = "github.com/stretchr/testify/suite"
+
+// suiteSetupMethods are the testify suite methods run before tests
+var suiteSetupMethods = map[string]bool{
+	"SetupSuite":   true,
+	"SetupTest":    true,
+	"SetupSubTest": true,
+	"BeforeTest":   true,
+}
+
+// suiteTeardownMethods are the testify suite methods run after tests
+var suiteTeardownMethods = []string{
+	"TearDownSuite",
+	"TearDownTest",
+	"TearDownSubTest",
+	"AfterTest",
+}
+
+// isClosedInSuiteTeardown checks if val is acquired in the setup method of a
+// testify suite, stored in a field of the suite, and closed from that field in
+// a teardown method of the same suite type
+func isClosedInSuiteTeardown(fn *ssa.Function, val ssa.Value, rt *ResourceType) bool {
+	recv := fn.Signature.Recv()
+	if recv == nil || !suiteSetupMethods[fn.Name()] || len(fn.Params) == 0 || !isTestifySuite(recv.Type()) {
+		return false
+	}
+	field, ok := storedReceiverField(fn, val)
+	if !ok {
+		return false
+	}
+
+	mset := fn.Prog.MethodSets.MethodSet(recv.Type())
+	for _, name := range suiteTeardownMethods {
+		sel := mset.Lookup(fn.Pkg.Pkg, name)
+		if sel == nil {
+			continue
+		}
+		teardown := fn.Prog.MethodValue(sel)
+		if teardown != nil && len(teardown.Params) > 0 && closesReceiverField(teardown, field, rt) {
+			return true
+		}
+	}
+	return false
+}
+
+// isTestifySuite checks if t, or the type it points to, embeds suite.Suite
+func isTestifySuite(t types.Type) bool {
+	st, ok := derefType(t).Underlying().(*types.Struct)
+	if !ok {
+		return false
+	}
+	for i := 0; i < st.NumFields(); i++ {
+		f := st.Field(i)
+		if f.Embedded() && isNamedType(derefType(f.Type()), pathTestifySuite, "Suite") {
+			return true
+		}
+	}
+	return false
+}
+
+// storedReceiverField returns the index of the receiver field val is stored in
+func storedReceiverField(fn *ssa.Function, val ssa.Value) (int, bool) {
+	if val.Referrers() == nil {
+		return 0, false
+	}
+	for _, ref := range *val.Referrers() {
+		store, ok := ref.(*ssa.Store)
+		if !ok || store.Val != val {
+			continue
+		}
+		if fa, ok := store.Addr.(*ssa.FieldAddr); ok && fa.X == fn.Params[0] {
+			return fa.Field, true
+		}
+	}
+	return 0, false
+}
+
+// closesReceiverField checks if fn closes the resource held in the given
+// field of its receiver
+func closesReceiverField(fn *ssa.Function, field int, rt *ResourceType) bool {
+	recv := fn.Params[0]
+	if recv.Referrers() == nil {
+		return false
+	}
+	for _, ref := range *recv.Referrers() {
+		if fa, ok := ref.(*ssa.FieldAddr); ok && fa.Field == field && closesValue(fa, rt, 0) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package analyzer
+
+import (
+	"fmt"
+	"sync"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// Kinds of Suppression, named after the options and comments dropping
+// diagnostics
+const (
+	SuppressionNolint        = "nolint"
+	SuppressionExclude       = "exclude"
+	SuppressionExcludeFunc   = "exclude-func"
+	SuppressionSkipTests     = "skip-tests"
+	SuppressionTestsOnly     = "tests-only"
+	SuppressionMinConfidence = "min-confidence"
+	SuppressionMaxIssues     = "max-issues"
+)
+
+// Suppression tells why a diagnostic is not reported
+type Suppression struct {
+	// Kind is the option or comment dropping the diagnostic, such as exclude
+	Kind string
+	// Justification explains it, as in "the file matches -exclude"
+	Justification string
+}
+
+// SuppressedOutput, when set, receives the diagnostics the options and
+// nolint comments drop, with their suppression, for output formats listing
+// them. Files excluded with a file-level nolint comment are not checked, and
+// their findings are not passed to it. The spannerclosecheck command sets it
+// for -format json.
+var SuppressedOutput func(pass *analysis.Pass, d analysis.Diagnostic, s Suppression)
+
+// suppressedOutputMu serializes the calls of passes to SuppressedOutput
+var suppressedOutputMu sync.Mutex
+
+// suppress passes d, a diagnostic of pass dropped by the option of kind, to
+// SuppressedOutput when it is set
+func suppress(pass *analysis.Pass, d analysis.Diagnostic, kind, format string, args ...interface{}) {
+	if SuppressedOutput == nil {
+		return
+	}
+	suppressedOutputMu.Lock()
+	defer suppressedOutputMu.Unlock()
+	SuppressedOutput(pass, d, Suppression{Kind: kind, Justification: fmt.Sprintf(format, args...)})
+}
@@ -0,0 +1,68 @@
+package a
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for helpers returning a resource together with a cleanup function
+
+func newTxnWithCleanup(client *spanner.Client) (*spanner.ReadOnlyTransaction, func()) {
+	txn := client.ReadOnlyTransaction()
+	return txn, txn.Close
+}
+
+func newIterWithCleanup(ctx context.Context, client *spanner.Client) (*spanner.RowIterator, func(), error) {
+	txn := client.ReadOnlyTransaction()
+	iter := txn.Query(ctx, spanner.Statement{})
+	return iter, func() {
+		iter.Stop()
+		txn.Close()
+	}, nil
+}
+
+func newTxnWithNoopCleanup(client *spanner.Client) (*spanner.ReadOnlyTransaction, func()) {
+	txn := client.ReadOnlyTransaction() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred"
+	return txn, func() {}
+}
+
+func goodCleanupDeferred(client *spanner.Client) {
+	txn, cleanup := newTxnWithCleanup(client)
+	defer cleanup()
+	_ = txn
+}
+
+func goodCleanupDeferredInClosure(ctx context.Context, client *spanner.Client) error {
+	iter, cleanup, err := newIterWithCleanup(ctx, client)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		cleanup()
+	}()
+	_ = iter
+	return nil
+}
+
+func goodCleanupReturned(client *spanner.Client) (*spanner.ReadOnlyTransaction, func()) {
+	txn, cleanup := newTxnWithCleanup(client)
+	return txn, cleanup
+}
+
+func badCleanupNotDeferred(client *spanner.Client) {
+	txn, cleanup := newTxnWithCleanup(client) // want "cleanup function returned by newTxnWithCleanup\\(\\) must be deferred"
+	_ = txn
+	cleanup()
+}
+
+func badCleanupDiscarded(ctx context.Context, client *spanner.Client) {
+	iter, _, _ := newIterWithCleanup(ctx, client) // want "cleanup function returned by newIterWithCleanup\\(\\) must be deferred"
+	_ = iter
+}
+
+func badNoopCleanupDeferred(client *spanner.Client) {
+	txn, cleanup := newTxnWithNoopCleanup(client) // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred"
+	defer cleanup()
+	_ = txn
+}
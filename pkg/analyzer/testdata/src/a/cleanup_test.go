@@ -0,0 +1,58 @@
+package a
+
+import (
+	"context"
+	"testing"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for resources closed with testing.TB.Cleanup
+
+func TestGoodCleanupMethodValue(t *testing.T) {
+	client := &spanner.Client{}
+	txn := client.ReadOnlyTransaction()
+	t.Cleanup(txn.Close)
+}
+
+func TestGoodCleanupIteratorStop(t *testing.T) {
+	client := &spanner.Client{}
+	iter := client.Single().Query(context.Background(), spanner.Statement{})
+	t.Cleanup(iter.Stop)
+}
+
+func TestGoodCleanupClosure(t *testing.T) {
+	client := &spanner.Client{}
+	txn := client.ReadOnlyTransaction()
+	t.Cleanup(func() {
+		txn.Close()
+	})
+}
+
+func BenchmarkGoodCleanup(b *testing.B) {
+	client := &spanner.Client{}
+	txn := client.ReadOnlyTransaction()
+	b.Cleanup(txn.Close)
+}
+
+// goodCleanupHelper hands the transaction to the test, which closes it when done
+func goodCleanupHelper(tb testing.TB, client *spanner.Client) *spanner.ReadOnlyTransaction {
+	tb.Helper()
+	txn := client.ReadOnlyTransaction()
+	tb.Cleanup(txn.Close)
+	return txn
+}
+
+func TestBadCleanupNotClosing(t *testing.T) {
+	client := &spanner.Client{}
+	txn := client.ReadOnlyTransaction() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred"
+	t.Cleanup(func() {
+		_ = txn
+	})
+}
+
+func TestBadNoCleanup(t *testing.T) {
+	client := &spanner.Client{}
+	txn := client.ReadOnlyTransaction() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred"
+	t.Log(txn)
+}
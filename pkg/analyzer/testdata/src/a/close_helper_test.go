@@ -0,0 +1,89 @@
+package a
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for helpers closing several resources passed to them
+
+type closer interface{ Close() }
+
+type stopper interface{ Stop() }
+
+func closeAll(closers ...closer) {
+	for _, c := range closers {
+		c.Close()
+	}
+}
+
+func releaseAll(resources ...any) {
+	for _, r := range resources {
+		switch r := r.(type) {
+		case *spanner.ReadOnlyTransaction:
+			r.Close()
+		case *spanner.RowIterator:
+			r.Stop()
+		}
+	}
+}
+
+func runAll(funcs ...func()) {
+	for _, f := range funcs {
+		f()
+	}
+}
+
+func closeBoth(c closer, s stopper) {
+	c.Close()
+	s.Stop()
+}
+
+func logAll(resources ...any) {
+	for _, r := range resources {
+		_ = r
+	}
+}
+
+func goodDeferredVariadicHelper(client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	batch, _ := client.BatchReadOnlyTransaction(context.Background(), spanner.StrongRead())
+	defer closeAll(txn, batch)
+}
+
+func goodDeferredTypeSwitchHelper(ctx context.Context, client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	iter := txn.Query(ctx, spanner.Statement{})
+	defer releaseAll(txn, iter)
+}
+
+func goodDeferredMethodValueHelper(ctx context.Context, client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	iter := txn.Query(ctx, spanner.Statement{})
+	defer runAll(iter.Stop, txn.Close)
+}
+
+func goodDeferredMultiArgHelper(ctx context.Context, client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	iter := txn.Query(ctx, spanner.Statement{})
+	defer closeBoth(txn, iter)
+}
+
+func badDeferredHelperNotClosing(ctx context.Context, client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()         // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred"
+	iter := txn.Query(ctx, spanner.Statement{}) // want "RowIterator\\.Stop\\(\\) must be deferred"
+	defer logAll(txn, iter)
+}
+
+func badDeferredHelperMissingType(ctx context.Context, client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	iter := txn.Query(ctx, spanner.Statement{}) // want "RowIterator\\.Stop\\(\\) must be deferred"
+	defer closeAll(txn)
+	_ = iter
+}
+
+func badVariadicHelperNotDeferred(client *spanner.Client) {
+	txn := client.ReadOnlyTransaction() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred"
+	closeAll(txn)
+}
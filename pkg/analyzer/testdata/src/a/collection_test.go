@@ -0,0 +1,91 @@
+package a
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for resources stored in slices and maps
+
+func goodDeferredLoopStopsSlice(ctx context.Context, txn *spanner.ReadOnlyTransaction, stmts []spanner.Statement) {
+	var iters []*spanner.RowIterator
+	defer func() {
+		for _, iter := range iters {
+			iter.Stop()
+		}
+	}()
+	for _, stmt := range stmts {
+		iter := txn.Query(ctx, stmt)
+		iters = append(iters, iter)
+	}
+}
+
+func goodDeferPerElement(ctx context.Context, txn *spanner.ReadOnlyTransaction, stmts []spanner.Statement) {
+	iters := make([]*spanner.RowIterator, 0, len(stmts))
+	for _, stmt := range stmts {
+		iters = append(iters, txn.Query(ctx, stmt))
+	}
+	for _, iter := range iters {
+		defer iter.Stop()
+	}
+}
+
+func goodDeferredLoopIndexStore(ctx context.Context, txn *spanner.ReadOnlyTransaction, stmts []spanner.Statement) {
+	iters := make([]*spanner.RowIterator, len(stmts))
+	defer func() {
+		for i := range iters {
+			iters[i].Stop()
+		}
+	}()
+	for i, stmt := range stmts {
+		iters[i] = txn.Query(ctx, stmt)
+	}
+}
+
+func goodDeferredLoopStopsMap(ctx context.Context, txn *spanner.ReadOnlyTransaction, stmts map[string]spanner.Statement) {
+	iters := make(map[string]*spanner.RowIterator)
+	defer func() {
+		for _, iter := range iters {
+			iter.Stop()
+		}
+	}()
+	for name, stmt := range stmts {
+		iters[name] = txn.Query(ctx, stmt)
+	}
+}
+
+func badSliceNeverStopped(ctx context.Context, txn *spanner.ReadOnlyTransaction, stmts []spanner.Statement) []int {
+	var iters []*spanner.RowIterator
+	for _, stmt := range stmts {
+		iter := txn.Query(ctx, stmt)
+		iters = append(iters, iter) // want "RowIterator\\.Stop\\(\\) must be deferred for every element of the collection"
+	}
+	return make([]int, len(iters))
+}
+
+func badSliceStoppedWithoutDefer(ctx context.Context, txn *spanner.ReadOnlyTransaction, stmts []spanner.Statement) {
+	var iters []*spanner.RowIterator
+	for _, stmt := range stmts {
+		iters = append(iters, txn.Query(ctx, stmt)) // want "RowIterator\\.Stop\\(\\) must be deferred for every element of the collection"
+	}
+	for _, iter := range iters {
+		iter.Stop()
+	}
+}
+
+func badMapNeverStopped(ctx context.Context, txn *spanner.ReadOnlyTransaction, stmts map[string]spanner.Statement) int {
+	iters := make(map[string]*spanner.RowIterator)
+	for name, stmt := range stmts {
+		iters[name] = txn.Query(ctx, stmt) // want "RowIterator\\.Stop\\(\\) must be deferred for every element of the collection"
+	}
+	return len(iters)
+}
+
+func goodNolintCollection(ctx context.Context, txn *spanner.ReadOnlyTransaction, stmts []spanner.Statement) int {
+	var iters []*spanner.RowIterator
+	for _, stmt := range stmts {
+		iters = append(iters, txn.Query(ctx, stmt)) //nolint:spannerclosecheck
+	}
+	return len(iters)
+}
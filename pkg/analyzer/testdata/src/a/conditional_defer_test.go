@@ -0,0 +1,95 @@
+package a
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for deferred closes that do not run on every path
+
+func badDeferInOneBranch(client *spanner.Client, debug bool) {
+	txn := client.ReadOnlyTransaction()
+	if debug {
+		defer txn.Close() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred on every path from the acquisition"
+	}
+	_ = txn
+}
+
+func badReturnBeforeDefer(ctx context.Context, txn *spanner.ReadOnlyTransaction, skip bool) {
+	iter := txn.Query(ctx, spanner.Statement{})
+	if skip {
+		return
+	}
+	defer iter.Stop() // want "RowIterator\\.Stop\\(\\) must be deferred on every path from the acquisition"
+}
+
+func badDeferInSwitchWithoutDefault(client *spanner.Client, mode int) {
+	txn := client.ReadOnlyTransaction()
+	switch mode {
+	case 0:
+		defer txn.Close() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred on every path from the acquisition"
+	case 1:
+		defer txn.Close()
+	}
+}
+
+func goodDeferInEveryBranch(client *spanner.Client, debug bool) {
+	txn := client.ReadOnlyTransaction()
+	if debug {
+		defer txn.Close()
+	} else {
+		defer txn.Close()
+	}
+}
+
+func goodDeferInEverySwitchCase(client *spanner.Client, mode int) {
+	txn := client.ReadOnlyTransaction()
+	switch mode {
+	case 0:
+		defer txn.Close()
+	default:
+		defer txn.Close()
+	}
+}
+
+func goodDeferAfterErrorCheck(ctx context.Context, client *spanner.Client) error {
+	txn, err := client.BatchReadOnlyTransaction(ctx, spanner.StrongRead())
+	if err != nil {
+		return err
+	}
+	defer txn.Close()
+	return nil
+}
+
+func goodDeferAfterNamedErrorCheck(ctx context.Context, client *spanner.Client) (err error) {
+	txn, err := client.BatchReadOnlyTransaction(ctx, spanner.StrongRead())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		txn.Close()
+		_ = err
+	}()
+	return nil
+}
+
+func goodDeferredClosureBeforeAcquisition(ctx context.Context, txn *spanner.ReadOnlyTransaction, stmt *spanner.Statement) {
+	var iter *spanner.RowIterator
+	defer func() {
+		if iter != nil {
+			iter.Stop()
+		}
+	}()
+	if stmt != nil {
+		iter = txn.Query(ctx, *stmt)
+	}
+}
+
+func goodNolintConditionalDefer(client *spanner.Client, debug bool) {
+	txn := client.ReadOnlyTransaction()
+	if debug {
+		defer txn.Close() //nolint:spannerclosecheck
+	}
+	_ = txn
+}
@@ -0,0 +1,188 @@
+package a
+
+import (
+	"context"
+	"errors"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for deferred closures that close resources
+
+func closeTxn(txn *spanner.ReadOnlyTransaction) error {
+	txn.Close()
+	return nil
+}
+
+func stopIter(iter *spanner.RowIterator) error {
+	iter.Stop()
+	return nil
+}
+
+func inspectTxn(txn *spanner.ReadOnlyTransaction) error {
+	_ = txn
+	return nil
+}
+
+func goodDeferredErrorsJoinHelper(client *spanner.Client) (err error) {
+	txn := client.ReadOnlyTransaction()
+	defer func() {
+		err = errors.Join(err, closeTxn(txn))
+	}()
+	return nil
+}
+
+func goodDeferredErrorsJoinIterator(client *spanner.Client) (err error) {
+	ctx := context.Background()
+	txn := client.ReadOnlyTransaction()
+	defer txn.Close()
+
+	iter := txn.Query(ctx, spanner.Statement{})
+	defer func() {
+		err = errors.Join(err, stopIter(iter))
+	}()
+	return nil
+}
+
+func goodDeferredErrorsJoinMultiple(client *spanner.Client) (err error) {
+	ctx := context.Background()
+	txn := client.ReadOnlyTransaction()
+	iter := txn.Query(ctx, spanner.Statement{})
+	defer func() {
+		err = errors.Join(err, stopIter(iter), closeTxn(txn))
+	}()
+	return nil
+}
+
+func badDeferredErrorsJoinNotClosing(client *spanner.Client) (err error) {
+	txn := client.ReadOnlyTransaction() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred"
+	defer func() {
+		err = errors.Join(err, inspectTxn(txn))
+	}()
+	return nil
+}
+
+func badErrorsJoinNotDeferred(client *spanner.Client) (err error) {
+	txn := client.ReadOnlyTransaction() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred"
+	return errors.Join(err, closeTxn(txn))
+}
+
+func goodDeferredFuncLiteral(client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	defer func() {
+		txn.Close()
+	}()
+}
+
+func goodDeferredFuncLiteralIterator(client *spanner.Client) {
+	ctx := context.Background()
+	iter := client.Single().Query(ctx, spanner.Statement{})
+	defer func() { iter.Stop() }()
+}
+
+func goodDeferredFuncLiteralArg(client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	defer func(t *spanner.ReadOnlyTransaction) {
+		t.Close()
+	}(txn)
+}
+
+func goodDeferredClosureVariable(client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	cleanup := func() {
+		txn.Close()
+	}
+	defer cleanup()
+}
+
+func goodDeferredMethodValue(client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	cleanup := txn.Close
+	defer cleanup()
+}
+
+func goodDeferredNestedFuncLiteral(client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	defer func() {
+		func() {
+			txn.Close()
+		}()
+	}()
+}
+
+func badDeferredFuncLiteralNotClosing(client *spanner.Client) {
+	txn := client.ReadOnlyTransaction() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred"
+	defer func() {
+		_ = txn
+	}()
+}
+
+func badDeferredNestedFuncLiteralNotCalled(client *spanner.Client) {
+	txn := client.ReadOnlyTransaction() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred"
+	defer func() {
+		cleanup := func() {
+			txn.Close()
+		}
+		_ = cleanup
+	}()
+}
+
+func badClosureVariableNotDeferred(client *spanner.Client) {
+	txn := client.ReadOnlyTransaction() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred"
+	cleanup := func() {
+		txn.Close()
+	}
+	cleanup()
+}
+
+func goodDeferredNilGuard(client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	defer func() {
+		if txn != nil {
+			txn.Close()
+		}
+	}()
+}
+
+func goodDeferredNilGuardDeclaredFirst(ctx context.Context, client *spanner.Client) (err error) {
+	var txn *spanner.BatchReadOnlyTransaction
+	defer func() {
+		if txn != nil {
+			txn.Close()
+		}
+	}()
+	txn, err = client.BatchReadOnlyTransaction(ctx, spanner.StrongRead())
+	return err
+}
+
+func goodDeferredErrorGuard(ctx context.Context, client *spanner.Client) (err error) {
+	iter := client.Single().Query(ctx, spanner.Statement{})
+	defer func() {
+		if err != nil {
+			iter.Stop()
+			return
+		}
+		iter.Stop()
+	}()
+	return nil
+}
+
+func goodDeferredSwitchGuard(client *spanner.Client, mode int) {
+	txn := client.ReadOnlyTransaction()
+	defer func() {
+		switch {
+		case txn == nil:
+		default:
+			txn.Close()
+		}
+	}()
+}
+
+func badDeferredNilGuardNotClosing(client *spanner.Client) {
+	txn := client.ReadOnlyTransaction() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred"
+	defer func() {
+		if txn != nil {
+			_ = txn
+		}
+	}()
+}
@@ -0,0 +1,30 @@
+package a
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for resources discarded with the blank identifier
+
+func badDiscardedTransaction(client *spanner.Client) {
+	_ = client.ReadOnlyTransaction() // want "ReadOnlyTransaction acquired and discarded: the blank identifier drops the only reference, so Close\\(\\) can never be called"
+}
+
+func badDiscardedIterator(ctx context.Context, txn *spanner.ReadOnlyTransaction) {
+	_ = txn.Query(ctx, spanner.Statement{}) // want "RowIterator acquired and discarded: the blank identifier drops the only reference, so Stop\\(\\) can never be called"
+}
+
+func badDiscardedInVarDecl(client *spanner.Client) {
+	var _ = client.ReadOnlyTransaction() // want "ReadOnlyTransaction acquired and discarded"
+}
+
+func badAssignedNotDeferred(client *spanner.Client) {
+	txn := client.ReadOnlyTransaction() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred"
+	_ = txn
+}
+
+func goodNolintDiscarded(client *spanner.Client) {
+	_ = client.ReadOnlyTransaction() //nolint:spannerclosecheck
+}
@@ -0,0 +1,55 @@
+package a
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for resources closed twice on the same path
+
+func badCloseAndDeferredClose(client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	defer txn.Close() // want "deferred ReadOnlyTransaction\\.Close\\(\\) closes the resource again after Close\\(\\) at line 14"
+	txn.Close()
+}
+
+func badStopTwice(iter *spanner.RowIterator) {
+	iter.Stop()
+	iter.Stop() // want "RowIterator\\.Stop\\(\\) closes the resource again after Stop\\(\\) at line 18"
+}
+
+func badStopInBranchThenDeferred(ctx context.Context, txn *spanner.ReadOnlyTransaction, done bool) {
+	iter := txn.Query(ctx, spanner.Statement{})
+	defer iter.Stop() // want "deferred RowIterator\\.Stop\\(\\) closes the resource again after Stop\\(\\) at line 26"
+	if done {
+		iter.Stop()
+		return
+	}
+	_, _ = iter.Next()
+}
+
+func badCloseTwiceInBranches(txn *spanner.ReadOnlyTransaction, done bool) {
+	if done {
+		txn.Close()
+	}
+	txn.Close() // want "ReadOnlyTransaction\\.Close\\(\\) closes the resource again after Close\\(\\) at line 34"
+}
+
+func goodCloseInExclusiveBranches(txn *spanner.ReadOnlyTransaction, done bool) {
+	if done {
+		txn.Close()
+	} else {
+		txn.Close()
+	}
+}
+
+func goodDeferredCloseOnly(client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	defer txn.Close()
+}
+
+func goodNolintDoubleClose(iter *spanner.RowIterator) {
+	iter.Stop()
+	iter.Stop() //nolint:spannerclosecheck
+}
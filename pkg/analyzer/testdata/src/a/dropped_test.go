@@ -0,0 +1,30 @@
+package a
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for resources returned by calls used as statements
+
+func badTransactionDropped(client *spanner.Client) {
+	client.ReadOnlyTransaction() // want "ReadOnlyTransaction acquired and dropped: the result of Client\\.ReadOnlyTransaction\\(\\) is unused, so Close\\(\\) can never be called"
+}
+
+func badQueryDropped(ctx context.Context, txn *spanner.ReadOnlyTransaction) {
+	txn.Query(ctx, spanner.Statement{}) // want "RowIterator acquired and dropped: the result of ReadOnlyTransaction\\.Query\\(\\) is unused, so Stop\\(\\) can never be called"
+}
+
+func badBatchTransactionDropped(ctx context.Context, client *spanner.Client) {
+	client.BatchReadOnlyTransaction(ctx, spanner.StrongRead()) // want "BatchReadOnlyTransaction acquired and dropped: the result of Client\\.BatchReadOnlyTransaction\\(\\) is unused"
+}
+
+func goodSingleDropped(client *spanner.Client) {
+	client.Single()
+}
+
+func goodNolintDropped(ctx context.Context, client *spanner.Client) {
+	client.ReadOnlyTransaction()                               //nolint:spannerclosecheck
+	client.BatchReadOnlyTransaction(ctx, spanner.StrongRead()) //nolint:spannerclosecheck
+}
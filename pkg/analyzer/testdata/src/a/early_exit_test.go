@@ -0,0 +1,66 @@
+package a
+
+import (
+	"context"
+	"errors"
+
+	"cloud.google.com/go/spanner"
+	"google.golang.org/api/iterator"
+)
+
+// Tests for iterators read in a loop that can leave before iterator.Done
+
+func badBreakOnMatch(ctx context.Context, txn *spanner.ReadOnlyTransaction) (*spanner.Row, error) {
+	iter := txn.Query(ctx, spanner.Statement{}) // want "RowIterator\\.Stop\\(\\) must be deferred: the loop reading it with Next\\(\\) at line 16 can leave at line 24 before reaching iterator\\.Done, and a partially read RowIterator holds its stream until Stop\\(\\) is called"
+	for {
+		row, err := iter.Next()
+		if err == iterator.Done {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if row != nil {
+			return row, nil
+		}
+	}
+}
+
+func badLabeledBreak(ctx context.Context, txn *spanner.ReadOnlyTransaction, limit int) {
+	iter := txn.Query(ctx, spanner.Statement{}) // want "the loop reading it with Next\\(\\) at line 34 can leave at line 39 before reaching iterator\\.Done"
+	n := 0
+rows:
+	for {
+		_, err := iter.Next()
+		switch {
+		case errors.Is(err, iterator.Done):
+			break rows
+		case n == limit:
+			break rows
+		}
+		n++
+	}
+}
+
+func badNotStoppedReadToEnd(ctx context.Context, txn *spanner.ReadOnlyTransaction) {
+	iter := txn.Query(ctx, spanner.Statement{}) // want "^RowIterator\\.Stop\\(\\) must be deferred$"
+	for {
+		if _, err := iter.Next(); err != nil {
+			break
+		}
+	}
+}
+
+func goodEarlyExitDeferredStop(ctx context.Context, txn *spanner.ReadOnlyTransaction) *spanner.Row {
+	iter := txn.Query(ctx, spanner.Statement{})
+	defer iter.Stop()
+	for {
+		row, err := iter.Next()
+		if err != nil {
+			return nil
+		}
+		if row != nil {
+			return row
+		}
+	}
+}
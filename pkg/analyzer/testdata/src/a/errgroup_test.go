@@ -0,0 +1,76 @@
+package a
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+	"golang.org/x/sync/errgroup"
+)
+
+// Tests for closures run by errgroup.Group
+
+func goodDeferInErrgroup(ctx context.Context, client *spanner.Client) error {
+	g, ctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		iter := client.Single().Query(ctx, spanner.Statement{})
+		defer iter.Stop()
+		return nil
+	})
+	return g.Wait()
+}
+
+func badNoDeferInErrgroup(ctx context.Context, client *spanner.Client) error {
+	g, ctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		iter := client.Single().Query(ctx, spanner.Statement{}) // want "RowIterator\\.Stop\\(\\) must be deferred in the goroutine"
+		iter.Stop()
+		return nil
+	})
+	return g.Wait()
+}
+
+func badNoDeferInErrgroupTryGo(ctx context.Context, client *spanner.Client) bool {
+	var g errgroup.Group
+	return g.TryGo(func() error {
+		txn := client.ReadOnlyTransaction() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred in the goroutine"
+		_ = txn
+		return nil
+	})
+}
+
+func goodCapturedByDeferringErrgroup(ctx context.Context, client *spanner.Client) error {
+	txn := client.ReadOnlyTransaction()
+	var g errgroup.Group
+	g.Go(func() error {
+		defer txn.Close()
+		iter := txn.Query(ctx, spanner.Statement{})
+		defer iter.Stop()
+		return nil
+	})
+	return g.Wait()
+}
+
+func badCapturedByErrgroupWithoutDefer(ctx context.Context, client *spanner.Client) error {
+	txn := client.ReadOnlyTransaction() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred in the goroutine"
+	var g errgroup.Group
+	g.Go(func() error {
+		_ = txn
+		txn.Close()
+		return nil
+	})
+	return g.Wait()
+}
+
+func goodDeferBeforeErrgroupWait(ctx context.Context, client *spanner.Client) error {
+	txn := client.ReadOnlyTransaction()
+	defer txn.Close()
+	g, ctx := errgroup.WithContext(ctx)
+	for range 3 {
+		g.Go(func() error {
+			iter := txn.Query(ctx, spanner.Statement{})
+			defer iter.Stop()
+			return nil
+		})
+	}
+	return g.Wait()
+}
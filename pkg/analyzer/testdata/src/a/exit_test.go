@@ -0,0 +1,68 @@
+package a
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for deferred closes skipped by os.Exit
+
+var testClient *spanner.Client
+
+func TestMain(m *testing.M) {
+	client, err := spanner.NewClient(context.Background(), "db")
+	if err != nil {
+		os.Exit(1)
+	}
+	defer client.Close() // want "deferred Client\\.Close\\(\\) does not run: os\\.Exit\\(\\) at line 22 exits without running deferred calls, move the defer and the code using the resource into a helper returning the exit code, as in os\\.Exit\\(run\\(\\)\\)"
+	testClient = client
+	os.Exit(m.Run())
+}
+
+func badExitAfterDeferredStop(ctx context.Context, txn *spanner.ReadOnlyTransaction, strict bool) {
+	iter := txn.Query(ctx, spanner.Statement{})
+	defer iter.Stop() // want "deferred RowIterator\\.Stop\\(\\) may not run: os\\.Exit\\(\\) at line 29"
+	if _, err := iter.Next(); err != nil && strict {
+		os.Exit(2)
+	}
+}
+
+func badExitInLoop(ctx context.Context, txn *spanner.ReadOnlyTransaction, ids []string) {
+	iter := txn.Query(ctx, spanner.Statement{})
+	defer iter.Stop() // want "deferred RowIterator\\.Stop\\(\\) does not run: os\\.Exit\\(\\) at line 39"
+	for range ids {
+		_, _ = iter.Next()
+	}
+	os.Exit(0)
+}
+
+func goodExitBeforeDefer(ctx context.Context, client *spanner.Client, ok bool) {
+	if !ok {
+		os.Exit(1)
+	}
+	txn := client.ReadOnlyTransaction()
+	defer txn.Close()
+}
+
+func goodExitInHelper(m *testing.M) {
+	os.Exit(runTests(m))
+}
+
+func runTests(m *testing.M) int {
+	client, err := spanner.NewClient(context.Background(), "db")
+	if err != nil {
+		return 1
+	}
+	defer client.Close()
+	testClient = client
+	return m.Run()
+}
+
+func goodNolintExitAfterDefer(client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	defer txn.Close() //nolint:spannerclosecheck
+	os.Exit(0)
+}
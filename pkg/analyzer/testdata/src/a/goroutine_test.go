@@ -0,0 +1,74 @@
+package a
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for resources acquired in goroutines or escaping into them
+
+func goodDeferInGoroutine(ctx context.Context, client *spanner.Client) {
+	go func() {
+		txn := client.ReadOnlyTransaction()
+		defer txn.Close()
+		_ = txn
+	}()
+}
+
+func badNoDeferInGoroutine(ctx context.Context, client *spanner.Client) {
+	go func() {
+		iter := client.Single().Query(ctx, spanner.Statement{}) // want "RowIterator\\.Stop\\(\\) must be deferred in the goroutine"
+		_ = iter
+	}()
+}
+
+func badCloseNotDeferredInGoroutine(ctx context.Context, client *spanner.Client) {
+	go func() {
+		txn := client.ReadOnlyTransaction() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred in the goroutine"
+		_ = txn
+		txn.Close()
+	}()
+}
+
+func goodCapturedByDeferringGoroutine(client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	go func() {
+		defer txn.Close()
+		_ = txn
+	}()
+}
+
+func badCapturedByGoroutineWithoutDefer(client *spanner.Client) {
+	txn := client.ReadOnlyTransaction() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred in the goroutine"
+	go func() {
+		_ = txn
+		txn.Close()
+	}()
+}
+
+func badCapturedByGoroutineNeverClosed(ctx context.Context, client *spanner.Client) {
+	iter := client.Single().Query(ctx, spanner.Statement{}) // want "RowIterator\\.Stop\\(\\) must be deferred in the goroutine"
+	go func() {
+		_ = iter
+	}()
+}
+
+func goodPassedToDeferringGoroutine(client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	go closeWhenDone(txn)
+}
+
+func closeWhenDone(txn *spanner.ReadOnlyTransaction) {
+	defer txn.Close()
+	_ = txn
+}
+
+func badPassedToGoroutineWithoutDefer(client *spanner.Client) {
+	txn := client.ReadOnlyTransaction() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred in the goroutine"
+	go useTxn(txn)
+}
+
+func useTxn(txn *spanner.ReadOnlyTransaction) {
+	_ = txn
+}
@@ -0,0 +1,70 @@
+package a
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for resources acquired in if and switch init statements
+
+func badIfInitDeferInBlockOnly(ctx context.Context, txn *spanner.ReadOnlyTransaction, enabled bool) {
+	if iter := txn.Query(ctx, spanner.Statement{}); enabled {
+		defer iter.Stop() // want "RowIterator\\.Stop\\(\\) must be deferred on every path from the acquisition"
+	}
+}
+
+func goodIfInitDeferInBothBranches(ctx context.Context, txn *spanner.ReadOnlyTransaction, enabled bool) {
+	if iter := txn.Query(ctx, spanner.Statement{}); enabled {
+		defer iter.Stop()
+	} else {
+		defer iter.Stop()
+	}
+}
+
+func badIfInitNoDefer(ctx context.Context, txn *spanner.ReadOnlyTransaction, enabled bool) {
+	if iter := txn.Query(ctx, spanner.Statement{}); enabled { // want "RowIterator\\.Stop\\(\\) must be deferred"
+		_ = iter
+	}
+}
+
+func badIfInitTupleNoDefer(ctx context.Context, client *spanner.Client) error {
+	if txn, err := client.BatchReadOnlyTransaction(ctx, spanner.StrongRead()); err != nil { // want "BatchReadOnlyTransaction\\.Close\\(\\) must be deferred"
+		return err
+	} else {
+		_ = txn
+	}
+	return nil
+}
+
+func goodIfInitTupleDeferInElse(ctx context.Context, client *spanner.Client) error {
+	if txn, err := client.BatchReadOnlyTransaction(ctx, spanner.StrongRead()); err != nil {
+		return err
+	} else {
+		defer txn.Close()
+	}
+	return nil
+}
+
+func goodSwitchInitDeferInCase(ctx context.Context, txn *spanner.ReadOnlyTransaction, mode int) {
+	switch iter := txn.Query(ctx, spanner.Statement{}); mode {
+	case 1:
+		defer iter.Stop()
+	default:
+		defer iter.Stop()
+	}
+}
+
+func badSwitchInitNoDefer(ctx context.Context, client *spanner.Client, mode int) {
+	switch txn := client.ReadOnlyTransaction(); mode { // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred"
+	case 1:
+		txn.Close()
+	}
+}
+
+func badTypeSwitchInitNoDefer(ctx context.Context, txn *spanner.ReadOnlyTransaction, v any) {
+	switch iter := txn.Query(ctx, spanner.Statement{}); v.(type) { // want "RowIterator\\.Stop\\(\\) must be deferred"
+	case string:
+		_ = iter
+	}
+}
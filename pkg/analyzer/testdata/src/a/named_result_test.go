@@ -0,0 +1,53 @@
+package a
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for resources returned through named results
+
+func goodNamedResultTxn(client *spanner.Client) (txn *spanner.ReadOnlyTransaction, err error) {
+	txn = client.ReadOnlyTransaction()
+	return
+}
+
+func goodNamedResultIterStoppedOnError(ctx context.Context, txn *spanner.ReadOnlyTransaction) (iter *spanner.RowIterator, err error) {
+	defer func() {
+		if err != nil {
+			iter.Stop()
+		}
+	}()
+	iter = txn.Query(ctx, spanner.Statement{})
+	return
+}
+
+func goodNamedResultTxnClosedOnError(client *spanner.Client, fail bool) (txn *spanner.ReadOnlyTransaction, err error) {
+	txn = client.ReadOnlyTransaction()
+	defer func() {
+		if err != nil {
+			txn.Close()
+		}
+	}()
+	if fail {
+		return nil, context.Canceled
+	}
+	return
+}
+
+func goodReturnedTxn(client *spanner.Client) *spanner.ReadOnlyTransaction {
+	txn := client.ReadOnlyTransaction()
+	return txn
+}
+
+func badNamedResultCallerNotClosed(client *spanner.Client) {
+	txn, _ := goodNamedResultTxn(client) // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred"
+	_ = txn
+}
+
+func badNamedResultNotReturned(client *spanner.Client) (txn *spanner.ReadOnlyTransaction) {
+	other := client.ReadOnlyTransaction() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred"
+	_ = other
+	return nil
+}
@@ -0,0 +1,41 @@
+package a
+
+import (
+	"context"
+	"sync"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for closes wrapped in sync.Once.Do
+
+func goodDeferredOnceMethodValue(client *spanner.Client) {
+	var closeOnce sync.Once
+	txn := client.ReadOnlyTransaction()
+	defer closeOnce.Do(txn.Close)
+}
+
+func goodDeferredOnceClosure(client *spanner.Client) {
+	var stopOnce sync.Once
+	iter := client.Single().Query(context.Background(), spanner.Statement{})
+	defer stopOnce.Do(func() { iter.Stop() })
+}
+
+func goodOnceInDeferredClosure(client *spanner.Client, closeOnce *sync.Once) {
+	txn := client.ReadOnlyTransaction()
+	defer func() {
+		closeOnce.Do(txn.Close)
+	}()
+}
+
+func badOnceNotDeferred(client *spanner.Client) {
+	var closeOnce sync.Once
+	txn := client.ReadOnlyTransaction() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred"
+	closeOnce.Do(txn.Close)
+}
+
+func badDeferredOnceNotClosing(client *spanner.Client) {
+	var once sync.Once
+	txn := client.ReadOnlyTransaction() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred"
+	defer once.Do(func() { _ = txn })
+}
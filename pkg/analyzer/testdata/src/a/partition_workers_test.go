@@ -0,0 +1,133 @@
+package a
+
+import (
+	"context"
+	"sync"
+
+	"cloud.google.com/go/spanner"
+	"golang.org/x/sync/errgroup"
+)
+
+// Tests for resources closed while goroutines using them may still run
+
+func badDeferCloseBeforeDeferredWait(ctx context.Context, client *spanner.Client, stmt spanner.Statement) error {
+	txn, err := client.BatchReadOnlyTransaction(ctx, spanner.StrongRead())
+	if err != nil {
+		return err
+	}
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer txn.Close() // want "deferred BatchReadOnlyTransaction\\.Close\\(\\) may run before the goroutine started at line 27 is done with the resource: wait for the goroutines, e\\.g\\. with wg\\.Wait\\(\\), before closing it"
+	partitions, err := txn.PartitionQuery(ctx, stmt, spanner.PartitionOptions{})
+	if err != nil {
+		return err
+	}
+	for _, p := range partitions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			iter := txn.Execute(ctx, p)
+			defer iter.Stop()
+		}()
+	}
+	return nil
+}
+
+func badDeferCloseWithoutWait(ctx context.Context, client *spanner.Client, partitions []*spanner.Partition) error {
+	txn, err := client.BatchReadOnlyTransaction(ctx, spanner.StrongRead())
+	if err != nil {
+		return err
+	}
+	defer txn.Close() // want "deferred BatchReadOnlyTransaction\\.Close\\(\\) may run before the goroutine started at line 43"
+	for _, p := range partitions {
+		go func() {
+			iter := txn.Execute(ctx, p)
+			defer iter.Stop()
+		}()
+	}
+	return nil
+}
+
+func badCloseBeforeWait(ctx context.Context, txn *spanner.BatchReadOnlyTransaction, partitions []*spanner.Partition) {
+	var wg sync.WaitGroup
+	for _, p := range partitions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			iter := txn.Execute(ctx, p)
+			defer iter.Stop()
+		}()
+	}
+	txn.Close() // want "BatchReadOnlyTransaction\\.Close\\(\\) may run before the goroutine started at line 55"
+	wg.Wait()
+}
+
+func goodDeferCloseAfterWait(ctx context.Context, client *spanner.Client, partitions []*spanner.Partition) error {
+	txn, err := client.BatchReadOnlyTransaction(ctx, spanner.StrongRead())
+	if err != nil {
+		return err
+	}
+	defer txn.Close()
+	var wg sync.WaitGroup
+	for _, p := range partitions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			iter := txn.Execute(ctx, p)
+			defer iter.Stop()
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+func goodDeferCloseBeforeErrgroupWait(ctx context.Context, client *spanner.Client, partitions []*spanner.Partition) error {
+	txn, err := client.BatchReadOnlyTransaction(ctx, spanner.StrongRead())
+	if err != nil {
+		return err
+	}
+	defer txn.Close()
+	g, ctx := errgroup.WithContext(ctx)
+	for _, p := range partitions {
+		g.Go(func() error {
+			iter := txn.Execute(ctx, p)
+			defer iter.Stop()
+			return nil
+		})
+	}
+	return g.Wait()
+}
+
+func goodDeferredWaitAfterDeferredClose(ctx context.Context, client *spanner.Client, partitions []*spanner.Partition) error {
+	txn, err := client.BatchReadOnlyTransaction(ctx, spanner.StrongRead())
+	if err != nil {
+		return err
+	}
+	defer txn.Close()
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for _, p := range partitions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			iter := txn.Execute(ctx, p)
+			defer iter.Stop()
+		}()
+	}
+	return nil
+}
+
+func goodNolintDeferCloseWithoutWait(ctx context.Context, client *spanner.Client, partitions []*spanner.Partition) error {
+	txn, err := client.BatchReadOnlyTransaction(ctx, spanner.StrongRead())
+	if err != nil {
+		return err
+	}
+	defer txn.Close() //nolint:spannerclosecheck
+	for _, p := range partitions {
+		go func() {
+			iter := txn.Execute(ctx, p)
+			defer iter.Stop()
+		}()
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package a
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for variables reassigned while holding an unreleased resource
+
+func badReassignBeforeStop(ctx context.Context, txn *spanner.ReadOnlyTransaction) {
+	iter := txn.Query(ctx, spanner.Statement{SQL: "SELECT 1"}) // want "RowIterator\\.Stop\\(\\) must be called before iter is reassigned, the previous value leaks"
+	iter = txn.Query(ctx, spanner.Statement{SQL: "SELECT 2"})
+	defer iter.Stop()
+}
+
+func badReassignInLoopStoppedAfter(ctx context.Context, txn *spanner.ReadOnlyTransaction, stmts []spanner.Statement) {
+	var iter *spanner.RowIterator
+	for _, stmt := range stmts {
+		iter = txn.Query(ctx, stmt) // want "RowIterator\\.Stop\\(\\) must be called before iter is reassigned"
+	}
+	defer iter.Stop()
+}
+
+func badReassignCapturedByDeferredClosure(ctx context.Context, txn *spanner.ReadOnlyTransaction, stmts []spanner.Statement) {
+	var iter *spanner.RowIterator
+	defer func() {
+		if iter != nil {
+			iter.Stop()
+		}
+	}()
+	for _, stmt := range stmts {
+		iter = txn.Query(ctx, stmt) // want "RowIterator\\.Stop\\(\\) must be called before iter is reassigned"
+	}
+}
+
+func badReassignTwiceInLoopBody(ctx context.Context, txn *spanner.ReadOnlyTransaction, stmts []spanner.Statement) {
+	for range stmts {
+		iter := txn.Query(ctx, spanner.Statement{SQL: "SELECT 1"}) // want "RowIterator\\.Stop\\(\\) must be called before iter is reassigned"
+		iter = txn.Query(ctx, spanner.Statement{SQL: "SELECT 2"})
+		defer iter.Stop() // want "RowIterator\\.Stop\\(\\) deferred inside a loop only runs when the function returns"
+	}
+}
+
+func badReassignTuple(ctx context.Context, client *spanner.Client) error {
+	txn, err := client.BatchReadOnlyTransaction(ctx, spanner.StrongRead()) // want "BatchReadOnlyTransaction\\.Close\\(\\) must be called before txn is reassigned"
+	if err != nil {
+		return err
+	}
+	txn, err = client.BatchReadOnlyTransaction(ctx, spanner.StrongRead())
+	if err != nil {
+		return err
+	}
+	defer txn.Close()
+	return nil
+}
+
+func goodStopBeforeReassign(ctx context.Context, txn *spanner.ReadOnlyTransaction) {
+	iter := txn.Query(ctx, spanner.Statement{SQL: "SELECT 1"})
+	defer iter.Stop()
+	iter = txn.Query(ctx, spanner.Statement{SQL: "SELECT 2"})
+	defer iter.Stop()
+}
+
+func goodDeclaredInLoop(ctx context.Context, txn *spanner.ReadOnlyTransaction, stmts []spanner.Statement) {
+	for _, stmt := range stmts {
+		func() {
+			iter := txn.Query(ctx, stmt)
+			defer iter.Stop()
+		}()
+	}
+}
+
+// The defer releases each value before the reassignment, but holds it until
+// the function returns
+func badDeferInLoopBeforeReassign(ctx context.Context, txn *spanner.ReadOnlyTransaction, stmts []spanner.Statement) {
+	var iter *spanner.RowIterator
+	for _, stmt := range stmts {
+		iter = txn.Query(ctx, stmt)
+		defer iter.Stop() // want "RowIterator\\.Stop\\(\\) deferred inside a loop only runs when the function returns"
+	}
+	_ = iter
+}
+
+func goodAssignedInExclusiveBranches(ctx context.Context, txn *spanner.ReadOnlyTransaction, byKey bool) {
+	var iter *spanner.RowIterator
+	defer func() {
+		if iter != nil {
+			iter.Stop()
+		}
+	}()
+	if byKey {
+		iter = txn.Read(ctx, "Users", nil, []string{"id"})
+	} else {
+		iter = txn.Query(ctx, spanner.Statement{SQL: "SELECT id FROM Users"})
+	}
+}
+
+func goodAssignedInSwitchCases(ctx context.Context, txn *spanner.ReadOnlyTransaction, mode int) {
+	var iter *spanner.RowIterator
+	defer func() {
+		if iter != nil {
+			iter.Stop()
+		}
+	}()
+	switch mode {
+	case 0:
+		iter = txn.Read(ctx, "Users", nil, []string{"id"})
+	default:
+		iter = txn.Query(ctx, spanner.Statement{SQL: "SELECT id FROM Users"})
+	}
+}
+
+func goodNolintReassign(ctx context.Context, txn *spanner.ReadOnlyTransaction) {
+	iter := txn.Query(ctx, spanner.Statement{SQL: "SELECT 1"}) //nolint:spannerclosecheck
+	iter = txn.Query(ctx, spanner.Statement{SQL: "SELECT 2"})
+	defer iter.Stop()
+}
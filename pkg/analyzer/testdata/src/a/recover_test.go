@@ -0,0 +1,55 @@
+package a
+
+import (
+	"context"
+	"log"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for non-deferred closes in functions that recover from panics
+
+func logPanic() {
+	if r := recover(); r != nil {
+		log.Println(r)
+	}
+}
+
+func badCloseNotDeferredWithRecover(client *spanner.Client) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println(r)
+		}
+	}()
+
+	ctx := context.Background()
+	txn := client.ReadOnlyTransaction() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred: the function recovers from panics, so a non-deferred Close\\(\\) is skipped when one occurs"
+	iter := txn.Query(ctx, spanner.Statement{})
+	defer iter.Stop()
+	txn.Close()
+}
+
+func badStopNotDeferredWithRecoverHelper(client *spanner.Client) {
+	defer logPanic()
+
+	ctx := context.Background()
+	txn := client.ReadOnlyTransaction()
+	defer txn.Close()
+
+	iter := txn.Query(ctx, spanner.Statement{}) // want "RowIterator\\.Stop\\(\\) must be deferred: the function recovers from panics, so a non-deferred Stop\\(\\) is skipped when one occurs"
+	iter.Stop()
+}
+
+func badNoCloseWithRecover(client *spanner.Client) {
+	defer logPanic()
+
+	txn := client.ReadOnlyTransaction() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred$"
+	_ = txn
+}
+
+func goodDeferWithRecover(client *spanner.Client) {
+	defer logPanic()
+
+	txn := client.ReadOnlyTransaction()
+	defer txn.Close()
+}
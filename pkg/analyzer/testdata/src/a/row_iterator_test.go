@@ -49,3 +49,44 @@ func badRowIteratorReadNoDefer(client *spanner.Client) {
 	iter := txn.Read(ctx, "table", nil, []string{"col1", "col2"}) // want "RowIterator\\.Stop\\(\\) must be deferred"
 	_ = iter
 }
+
+func goodRowIteratorDo(ctx context.Context, client *spanner.Client) error {
+	return client.Single().Query(ctx, spanner.Statement{}).Do(func(r *spanner.Row) error {
+		return nil
+	})
+}
+
+func goodRowIteratorDoVariable(ctx context.Context, client *spanner.Client) error {
+	txn := client.ReadOnlyTransaction()
+	defer txn.Close()
+
+	iter := txn.Query(ctx, spanner.Statement{})
+	return iter.Do(func(r *spanner.Row) error {
+		return nil
+	})
+}
+
+func badRowIteratorDoOtherIterator(ctx context.Context, client *spanner.Client) error {
+	txn := client.ReadOnlyTransaction()
+	defer txn.Close()
+
+	iter := txn.Query(ctx, spanner.Statement{}) // want "RowIterator\\.Stop\\(\\) must be deferred"
+	_ = iter
+	return txn.Read(ctx, "table", nil, nil).Do(func(r *spanner.Row) error {
+		return nil
+	})
+}
+
+func goodRowIteratorSelectAll(ctx context.Context, client *spanner.Client) error {
+	var rows []struct{}
+	return spanner.SelectAll(client.Single().Query(ctx, spanner.Statement{}), &rows)
+}
+
+func goodRowIteratorSelectAllVariable(ctx context.Context, client *spanner.Client) error {
+	txn := client.ReadOnlyTransaction()
+	defer txn.Close()
+
+	var rows []struct{}
+	iter := txn.Query(ctx, spanner.Statement{})
+	return spanner.SelectAll(iter, &rows)
+}
@@ -0,0 +1,58 @@
+package a
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for ReadWriteTransactions retained beyond their callback
+
+var lastTxn *spanner.ReadWriteTransaction
+
+type txnHolder struct {
+	txn *spanner.ReadWriteTransaction
+}
+
+func badTxnCapturedVariable(ctx context.Context, client *spanner.Client) {
+	var saved *spanner.ReadWriteTransaction
+	_, _ = client.ReadWriteTransaction(ctx, func(ctx context.Context, txn *spanner.ReadWriteTransaction) error {
+		saved = txn // want "ReadWriteTransaction is stored in captured variable saved, which outlives the callback receiving it"
+		return nil
+	})
+	_ = saved
+}
+
+func badTxnGlobal(ctx context.Context, client *spanner.Client) {
+	_, _ = client.ReadWriteTransaction(ctx, func(ctx context.Context, txn *spanner.ReadWriteTransaction) error {
+		lastTxn = txn // want "ReadWriteTransaction is stored in package-level variable lastTxn"
+		return nil
+	})
+}
+
+func (h *txnHolder) update(ctx context.Context, txn *spanner.ReadWriteTransaction) error {
+	h.txn = txn // want "ReadWriteTransaction is stored in field txn"
+	return nil
+}
+
+func badTxnSent(ctx context.Context, client *spanner.Client, txns chan<- *spanner.ReadWriteTransaction) {
+	_, _ = client.ReadWriteTransaction(ctx, func(ctx context.Context, txn *spanner.ReadWriteTransaction) error {
+		txns <- txn // want "ReadWriteTransaction is stored in a channel"
+		return nil
+	})
+}
+
+func goodTxnScopedHolder(ctx context.Context, client *spanner.Client) {
+	_, _ = client.ReadWriteTransaction(ctx, func(ctx context.Context, txn *spanner.ReadWriteTransaction) error {
+		h := &txnHolder{txn: txn}
+		return h.update(ctx, txn)
+	})
+}
+
+func goodTxnLocalVariable(ctx context.Context, client *spanner.Client) {
+	_, _ = client.ReadWriteTransaction(ctx, func(ctx context.Context, txn *spanner.ReadWriteTransaction) error {
+		current := txn
+		_ = current
+		return nil
+	})
+}
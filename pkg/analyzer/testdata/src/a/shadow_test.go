@@ -0,0 +1,69 @@
+package a
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for variables shadowing a resource variable
+
+func badShadowedInIf(client *spanner.Client, fresh bool) {
+	txn := client.ReadOnlyTransaction() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred, the deferred txn\\.Close\\(\\) at line 15 closes the txn declared at line 14 instead"
+	if fresh {
+		txn := client.ReadOnlyTransaction()
+		defer txn.Close()
+		_ = txn
+	}
+	_ = txn
+}
+
+func badShadowedInDeferredClosure(ctx context.Context, client *spanner.Client, fresh bool) {
+	txn := client.ReadOnlyTransaction() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred, the deferred txn\\.Close\\(\\) at line 25 closes the txn declared at line 24 instead"
+	if fresh {
+		txn := client.ReadOnlyTransaction()
+		defer func() { txn.Close() }()
+	}
+	iter := txn.Query(ctx, spanner.Statement{})
+	defer iter.Stop()
+}
+
+func badShadowedTuple(ctx context.Context, client *spanner.Client, fresh bool) error {
+	txn, err := client.BatchReadOnlyTransaction(ctx, spanner.StrongRead()) // want "BatchReadOnlyTransaction\\.Close\\(\\) must be deferred for txn, the deferred txn\\.Close\\(\\) at line 41 closes the txn declared at line 37 instead"
+	if err != nil {
+		return err
+	}
+	if fresh {
+		txn, err := client.BatchReadOnlyTransaction(ctx, spanner.StrongRead())
+		if err != nil {
+			return err
+		}
+		defer txn.Close()
+	}
+	_ = txn
+	return nil
+}
+
+func badShadowInnerLeaks(client *spanner.Client, fresh bool) {
+	txn := client.ReadOnlyTransaction()
+	defer txn.Close()
+	if fresh {
+		txn := client.ReadOnlyTransaction() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred$"
+		_ = txn
+	}
+}
+
+func goodShadowBothDeferred(client *spanner.Client, fresh bool) {
+	txn := client.ReadOnlyTransaction()
+	defer txn.Close()
+	if fresh {
+		txn := client.ReadOnlyTransaction()
+		defer txn.Close()
+		_ = txn
+	}
+}
+
+func goodClosureParamNamedLikeVariable(client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	defer func(txn *spanner.ReadOnlyTransaction) { txn.Close() }(txn)
+}
@@ -0,0 +1,71 @@
+package a
+
+import (
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for closes inside the loop reading the resource with Next()
+
+func badStopInRowLoop(iter *spanner.RowIterator) error {
+	for {
+		row, err := iter.Next()
+		if err != nil {
+			return err
+		}
+		_ = row
+		iter.Stop() // want "RowIterator\\.Stop\\(\\) is called inside the loop reading it with Next\\(\\) at line 11, which ends the iteration early: the next iteration reads a closed resource, close it after the loop instead"
+	}
+}
+
+func badStopInNestedRowLoop(iters []*spanner.RowIterator) {
+	for _, iter := range iters {
+		for i := 0; i < 3; i++ {
+			if _, err := iter.Next(); err != nil {
+				break
+			}
+			iter.Stop() // want "RowIterator\\.Stop\\(\\) is called inside the loop reading it with Next\\(\\) at line 23"
+		}
+	}
+}
+
+func goodStopBeforeBreak(iter *spanner.RowIterator) {
+	for {
+		row, err := iter.Next()
+		if err != nil {
+			iter.Stop()
+			break
+		}
+		_ = row
+	}
+}
+
+func goodStopBeforeReturnInLoop(iter *spanner.RowIterator, limit int) error {
+	for n := 0; ; n++ {
+		if n == limit {
+			iter.Stop()
+			return nil
+		}
+		if _, err := iter.Next(); err != nil {
+			iter.Stop()
+			return err
+		}
+	}
+}
+
+func goodStopAfterRowLoop(iter *spanner.RowIterator) {
+	for {
+		if _, err := iter.Next(); err != nil {
+			break
+		}
+	}
+	iter.Stop()
+}
+
+func goodNolintStopInRowLoop(iter *spanner.RowIterator) {
+	for {
+		if _, err := iter.Next(); err != nil {
+			return
+		}
+		iter.Stop() //nolint:spannerclosecheck
+	}
+}
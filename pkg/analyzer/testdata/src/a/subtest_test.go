@@ -0,0 +1,78 @@
+package a
+
+import (
+	"context"
+	"testing"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for resources acquired in t.Run subtests
+
+func TestGoodSubtestDefer(t *testing.T) {
+	client := &spanner.Client{}
+	for _, tc := range []struct{ name string }{{"a"}, {"b"}} {
+		t.Run(tc.name, func(t *testing.T) {
+			iter := client.Single().Query(context.Background(), spanner.Statement{})
+			defer iter.Stop()
+		})
+	}
+}
+
+func TestGoodSubtestCleanup(t *testing.T) {
+	client := &spanner.Client{}
+	t.Run("cleanup", func(t *testing.T) {
+		txn := client.ReadOnlyTransaction()
+		t.Cleanup(txn.Close)
+	})
+}
+
+func TestGoodNestedSubtestCleanup(t *testing.T) {
+	client := &spanner.Client{}
+	t.Run("outer", func(t *testing.T) {
+		txn := client.ReadOnlyTransaction()
+		t.Cleanup(func() {
+			txn.Close()
+		})
+		t.Run("inner", func(t *testing.T) {
+			iter := txn.Query(context.Background(), spanner.Statement{})
+			defer iter.Stop()
+		})
+	})
+}
+
+func TestGoodParentCleanupSharedWithSubtests(t *testing.T) {
+	client := &spanner.Client{}
+	txn := client.ReadOnlyTransaction()
+	t.Cleanup(txn.Close)
+	t.Run("query", func(t *testing.T) {
+		iter := txn.Query(context.Background(), spanner.Statement{})
+		t.Cleanup(iter.Stop)
+	})
+}
+
+func TestBadSubtestNoDefer(t *testing.T) {
+	client := &spanner.Client{}
+	t.Run("leak", func(t *testing.T) {
+		iter := client.Single().Query(context.Background(), spanner.Statement{}) // want "RowIterator\\.Stop\\(\\) must be deferred"
+		_ = iter
+	})
+}
+
+func TestBadNestedSubtestNoDefer(t *testing.T) {
+	client := &spanner.Client{}
+	t.Run("outer", func(t *testing.T) {
+		t.Run("inner", func(t *testing.T) {
+			txn := client.ReadOnlyTransaction() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred"
+			txn.Close()
+		})
+	})
+}
+
+func BenchmarkGoodSubBenchmarkCleanup(b *testing.B) {
+	client := &spanner.Client{}
+	b.Run("bench", func(b *testing.B) {
+		txn := client.ReadOnlyTransaction()
+		b.Cleanup(txn.Close)
+	})
+}
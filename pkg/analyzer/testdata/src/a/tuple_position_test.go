@@ -0,0 +1,63 @@
+package a
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for the position and variable name of resources acquired in tuple
+// assignments
+
+func badTupleAssignNamesVariable(ctx context.Context, client *spanner.Client) error {
+	txn, err := client.BatchReadOnlyTransaction(ctx, spanner.StrongRead()) // want "BatchReadOnlyTransaction\\.Close\\(\\) must be deferred for txn"
+	if err != nil {
+		return err
+	}
+	_ = txn
+	return nil
+}
+
+func badTupleAssignReportedAtVariable(ctx context.Context, client *spanner.Client) error {
+	batchTxn, err := // want "BatchReadOnlyTransaction\\.Close\\(\\) must be deferred for batchTxn"
+		client.BatchReadOnlyTransaction(ctx, spanner.StrongRead())
+	if err != nil {
+		return err
+	}
+	_ = batchTxn
+	return nil
+}
+
+func badTupleVarDecl(ctx context.Context, client *spanner.Client) error {
+	var txn, err = client.BatchReadOnlyTransaction(ctx, spanner.StrongRead()) // want "BatchReadOnlyTransaction\\.Close\\(\\) must be deferred for txn"
+	if err != nil {
+		return err
+	}
+	_ = txn
+	return nil
+}
+
+func badTupleReassign(ctx context.Context, client *spanner.Client) error {
+	var txn *spanner.BatchReadOnlyTransaction
+	var err error
+	txn, err = client.BatchReadOnlyTransaction(ctx, spanner.StrongRead()) // want "BatchReadOnlyTransaction\\.Close\\(\\) must be deferred for txn"
+	if err != nil {
+		return err
+	}
+	_ = txn
+	return nil
+}
+
+func badTupleDiscarded(ctx context.Context, client *spanner.Client) error {
+	_, err := client.BatchReadOnlyTransaction(ctx, spanner.StrongRead()) // want "BatchReadOnlyTransaction acquired and discarded: the blank identifier drops the only reference, so Close\\(\\) can never be called$"
+	return err
+}
+
+func goodTupleAssignDefer(ctx context.Context, client *spanner.Client) error {
+	txn, err := client.BatchReadOnlyTransaction(ctx, spanner.StrongRead())
+	if err != nil {
+		return err
+	}
+	defer txn.Close()
+	return nil
+}
@@ -0,0 +1,109 @@
+package a
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for resources used after they are closed
+
+func badQueryAfterClose(ctx context.Context, txn *spanner.ReadOnlyTransaction) {
+	txn.Close()
+	iter := txn.Query(ctx, spanner.Statement{}) // want "ReadOnlyTransaction\\.Query\\(\\) is called after Close\\(\\) at line 12 closed it"
+	defer iter.Stop()
+}
+
+func badNextAfterStop(iter *spanner.RowIterator) error {
+	iter.Stop()
+	_, err := iter.Next() // want "RowIterator\\.Next\\(\\) is called after Stop\\(\\) at line 18 closed it"
+	return err
+}
+
+func badReadAfterCloseInBranch(ctx context.Context, txn *spanner.ReadOnlyTransaction, done bool) {
+	if done {
+		txn.Close()
+	}
+	iter := txn.Read(ctx, "Users", nil, []string{"id"}) // want "ReadOnlyTransaction\\.Read\\(\\) is called after Close\\(\\) at line 25 closed it"
+	defer iter.Stop()
+}
+
+func badNextAfterStopInLoop(iter *spanner.RowIterator) {
+	for i := 0; i < 3; i++ {
+		if _, err := iter.Next(); err != nil {
+			return
+		}
+		iter.Stop() // want "RowIterator\\.Stop\\(\\) is called inside the loop reading it with Next\\(\\) at line 33"
+	}
+}
+
+func goodStopBeforeReturn(iter *spanner.RowIterator, done bool) error {
+	if done {
+		iter.Stop()
+		return nil
+	}
+	_, err := iter.Next()
+	return err
+}
+
+// Each iteration acquires a new iterator, so Next() does not follow the Stop()
+// of the previous one
+func badStopNotDeferredInLoop(ctx context.Context, txn *spanner.ReadOnlyTransaction, stmts []spanner.Statement) {
+	for _, stmt := range stmts {
+		iter := txn.Query(ctx, stmt) // want "RowIterator\\.Stop\\(\\) must be deferred"
+		_, _ = iter.Next()
+		iter.Stop()
+	}
+}
+
+func goodUseBeforeDeferredClose(ctx context.Context, client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	defer txn.Close()
+	iter := txn.Query(ctx, spanner.Statement{})
+	defer iter.Stop()
+}
+
+func goodNolintUseAfterClose(iter *spanner.RowIterator) {
+	iter.Stop()
+	_, _ = iter.Next() //nolint:spannerclosecheck
+}
+
+func badQueryAfterCloseInPreviousIteration(ctx context.Context, txn *spanner.ReadOnlyTransaction, stmts []spanner.Statement) {
+	for _, stmt := range stmts {
+		_ = txn.Query(ctx, stmt).Do(func(*spanner.Row) error { return nil }) // want "ReadOnlyTransaction\\.Query\\(\\) is called after Close\\(\\) at line 74 closed it in a previous iteration of the loop"
+		txn.Close()                                                          // want "ReadOnlyTransaction\\.Close\\(\\) closes the resource again after Close\\(\\) at line 74"
+	}
+}
+
+// The variable keeps the closed transaction for the next iteration, which only
+// acquires a new one when it is nil
+func badReuseAfterCloseAcrossIterations(ctx context.Context, client *spanner.Client, batches [][]spanner.Statement) {
+	var txn *spanner.ReadOnlyTransaction //nolint:spannerclosecheck
+	for i, batch := range batches {
+		if txn == nil {
+			txn = client.ReadOnlyTransaction() //nolint:spannerclosecheck
+		}
+		for _, stmt := range batch {
+			_ = txn.Query(ctx, stmt).Do(func(*spanner.Row) error { return nil }) // want "ReadOnlyTransaction\\.Query\\(\\) is called after Close\\(\\) at line 90 closed it in a previous iteration of the loop"
+		}
+		if i%10 == 9 {
+			txn.Close()
+		}
+	}
+}
+
+func goodResetAfterCloseAcrossIterations(ctx context.Context, client *spanner.Client, batches [][]spanner.Statement) {
+	var txn *spanner.ReadOnlyTransaction //nolint:spannerclosecheck
+	for i, batch := range batches {
+		if txn == nil {
+			txn = client.ReadOnlyTransaction() //nolint:spannerclosecheck
+		}
+		for _, stmt := range batch {
+			_ = txn.Query(ctx, stmt).Do(func(*spanner.Row) error { return nil })
+		}
+		if i%10 == 9 {
+			txn.Close()
+			txn = nil
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package a
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for constructors returning wrappers that own a resource through a field
+
+type cursor struct {
+	iter *spanner.RowIterator
+}
+
+func (c *cursor) Close() {
+	c.iter.Stop()
+}
+
+func goodCursorConstructor(ctx context.Context, txn *spanner.ReadOnlyTransaction) (*cursor, error) {
+	iter := txn.Query(ctx, spanner.Statement{})
+	return &cursor{iter: iter}, nil
+}
+
+type session struct {
+	txn  *spanner.ReadOnlyTransaction
+	name string
+}
+
+func (s session) Stop() error {
+	if s.txn != nil {
+		s.txn.Close()
+	}
+	return nil
+}
+
+func goodSessionByValue(client *spanner.Client) session {
+	txn := client.ReadOnlyTransaction()
+	return session{txn: txn, name: "read"}
+}
+
+func goodSessionVariable(client *spanner.Client) *session {
+	s := &session{name: "read"}
+	s.txn = client.ReadOnlyTransaction()
+	return s
+}
+
+type leakyCursor struct {
+	iter *spanner.RowIterator
+}
+
+func (c *leakyCursor) Next() (*spanner.Row, error) {
+	return c.iter.Next()
+}
+
+func badLeakyCursorConstructor(ctx context.Context, txn *spanner.ReadOnlyTransaction) (*leakyCursor, error) {
+	iter := txn.Query(ctx, spanner.Statement{}) // want "RowIterator\\.Stop\\(\\) must be called by a Close method of leakyCursor, which owns it through field iter"
+	return &leakyCursor{iter: iter}, nil
+}
+
+type pagedCursor struct {
+	iter *spanner.RowIterator
+	done *spanner.RowIterator
+}
+
+func (c *pagedCursor) Close() {
+	c.done.Stop()
+}
+
+func badFieldNotClosedByWrapper(ctx context.Context, txn *spanner.ReadOnlyTransaction) *pagedCursor {
+	iter := txn.Query(ctx, spanner.Statement{}) // want "RowIterator\\.Stop\\(\\) must be called by a Close method of pagedCursor, which owns it through field iter"
+	return &pagedCursor{iter: iter}
+}
+
+func badWrapperNotReturned(ctx context.Context, txn *spanner.ReadOnlyTransaction) {
+	iter := txn.Query(ctx, spanner.Statement{}) // want "RowIterator\\.Stop\\(\\) must be deferred"
+	c := &cursor{iter: iter}
+	_ = c
+}
@@ -0,0 +1,83 @@
+package a
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for wrapper structs embedding Spanner resources
+
+type roTxn struct {
+	*spanner.ReadOnlyTransaction
+	label string
+}
+
+type rowIter struct {
+	*spanner.RowIterator
+}
+
+type nestedTxn struct {
+	roTxn
+}
+
+type holder struct {
+	w *roTxn
+}
+
+func newROTxn(client *spanner.Client) *roTxn {
+	return &roTxn{ReadOnlyTransaction: client.ReadOnlyTransaction()}
+}
+
+func goodWrapperLiteralDefer(client *spanner.Client) {
+	w := &roTxn{ReadOnlyTransaction: client.ReadOnlyTransaction(), label: "read"}
+	defer w.Close()
+}
+
+func badWrapperLiteralNoDefer(client *spanner.Client) {
+	w := &roTxn{ReadOnlyTransaction: client.ReadOnlyTransaction()} // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred"
+	_ = w.label
+}
+
+func goodWrapperConstructorDefer(client *spanner.Client) {
+	w := newROTxn(client)
+	defer w.Close()
+}
+
+func badWrapperConstructorNoDefer(client *spanner.Client) {
+	w := newROTxn(client) // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred"
+	_ = w.label
+}
+
+func goodWrapperIteratorDefer(client *spanner.Client) {
+	ctx := context.Background()
+	txn := client.ReadOnlyTransaction()
+	defer txn.Close()
+
+	it := &rowIter{txn.Query(ctx, spanner.Statement{})}
+	defer it.Stop()
+}
+
+func badWrapperIteratorNoDefer(client *spanner.Client) {
+	ctx := context.Background()
+	txn := client.ReadOnlyTransaction()
+	defer txn.Close()
+
+	it := &rowIter{txn.Query(ctx, spanner.Statement{})} // want "RowIterator\\.Stop\\(\\) must be deferred"
+	_ = it
+}
+
+func goodNestedWrapperDefer(client *spanner.Client) {
+	w := &nestedTxn{roTxn{ReadOnlyTransaction: client.ReadOnlyTransaction()}}
+	defer w.Close()
+}
+
+func goodWrapperNotHoldingResource() {
+	var w roTxn
+	_ = w.label
+}
+
+func goodWrapperField(h *holder) {
+	w := h.w
+	_ = w.label
+}
@@ -0,0 +1,48 @@
+package acquire
+
+import (
+	"context"
+
+	"acquire/repo"
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for project-specific acquisition functions registered via -acquire-func
+
+func newLocalTxn(client *spanner.Client) *spanner.ReadOnlyTransaction {
+	return client.ReadOnlyTransaction()
+}
+
+func goodFactoryDeferred(ctx context.Context, r *repo.Repo) {
+	txn := r.NewReadTxn(ctx)
+	defer txn.Close()
+}
+
+func badFactoryNotClosed(ctx context.Context, r *repo.Repo) {
+	txn := r.NewReadTxn(ctx) // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred"
+	_ = txn
+}
+
+func goodCustomFactoryDeferred(r *repo.Repo) error {
+	txn, err := r.NewTxn()
+	if err != nil {
+		return err
+	}
+	defer txn.Release()
+	return nil
+}
+
+func badCustomFactoryNotClosed(r *repo.Repo) {
+	txn, _ := r.NewTxn() // want "ourdb\\.Txn\\.Release\\(\\) must be deferred"
+	txn.Rows()
+}
+
+func badLocalFactoryNotClosed(client *spanner.Client) {
+	txn := newLocalTxn(client) // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred"
+	_ = txn
+}
+
+func badLookupNotClosed(r *repo.Repo) {
+	txn := r.Lookup() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred"
+	_ = txn
+}
@@ -0,0 +1,32 @@
+package repo
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+	"example.com/ourdb"
+)
+
+// Repo is a project repository layer with factories registered via -acquire-func
+
+type Repo struct {
+	client *spanner.Client
+	db     *ourdb.DB
+}
+
+// NewReadTxn hands the transaction to its caller, who must close it
+func (r *Repo) NewReadTxn(ctx context.Context) *spanner.ReadOnlyTransaction {
+	return r.client.ReadOnlyTransaction()
+}
+
+// NewTxn wraps ourdb.DB.Begin for callers
+func (r *Repo) NewTxn() (*ourdb.Txn, error) {
+	txn := r.db.Begin()
+	return txn, nil
+}
+
+// Lookup is not a registered factory, but returning the transaction still
+// hands it to the callers
+func (r *Repo) Lookup() *spanner.ReadOnlyTransaction {
+	return r.client.ReadOnlyTransaction()
+}
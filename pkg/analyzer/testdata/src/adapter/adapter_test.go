@@ -0,0 +1,37 @@
+package adapter
+
+import (
+	"context"
+
+	"adapter/models"
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for ORM adapters declared as resources with //spannerclosecheck:resource
+
+func goodAdapterDeferred(ctx context.Context, client *spanner.Client) {
+	it := models.FindUsers(ctx, client.Single())
+	defer it.Stop()
+	it.Next()
+}
+
+func badAdapterNotStopped(ctx context.Context, client *spanner.Client) {
+	it := models.FindUsers(ctx, client.Single()) // want "models\\.UserIter\\.Stop\\(\\) must be deferred"
+	it.Next()
+}
+
+func badAdapterStopNotDeferred(ctx context.Context, client *spanner.Client) {
+	it := models.FindUsers(ctx, client.Single()) // want "models\\.UserIter\\.Stop\\(\\) must be deferred"
+	it.Next()
+	it.Stop()
+}
+
+func goodReaderDeferred(client *spanner.Client) {
+	r := models.NewUserReader(client)
+	defer r.Close()
+}
+
+func badReaderNotClosed(client *spanner.Client) {
+	r := models.NewUserReader(client) // want "models\\.UserReader\\.Close\\(\\) must be deferred"
+	_ = r
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+//spannerclosecheck:resource Release // want "Broken has no method Release\\(\\) to close it with"
+type Broken struct{}
+
+// Hand-written code in the declaring package is checked as well
+
+func goodLocalDeferred(ctx context.Context, client *spanner.Client) {
+	it := FindUsers(ctx, client.Single())
+	defer it.Stop()
+	it.Next()
+}
+
+func badLocalNotStopped(ctx context.Context, client *spanner.Client) {
+	it := FindUsers(ctx, client.Single()) // want "models\\.UserIter\\.Stop\\(\\) must be deferred"
+	it.Next()
+}
@@ -0,0 +1,66 @@
+package borrowed
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+	apiv1 "cloud.google.com/go/spanner/apiv1"
+)
+
+// Tests for closes of clients borrowed from the caller
+
+func goodBorrowedClient(ctx context.Context, client *spanner.Client) error {
+	iter := client.Single().Query(ctx, spanner.Statement{})
+	defer iter.Stop()
+	return nil
+}
+
+func goodOwnedClient(ctx context.Context) error {
+	client, err := spanner.NewClient(ctx, "db")
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	return nil
+}
+
+func badCloseBorrowedClient(client *spanner.Client) {
+	client.Close() // want "Client\\.Close\\(\\) closes a client borrowed from the caller"
+}
+
+func badDeferCloseBorrowedClient(ctx context.Context, client *spanner.Client) error {
+	defer client.Close() // want "Client\\.Close\\(\\) closes a client borrowed from the caller"
+	iter := client.Single().Query(ctx, spanner.Statement{})
+	defer iter.Stop()
+	return nil
+}
+
+func badCloseBorrowedClientInClosure(client *spanner.Client) func() {
+	return func() {
+		client.Close() // want "Client\\.Close\\(\\) closes a client borrowed from the caller"
+	}
+}
+
+func badCloseBorrowedGapicClient(client *apiv1.Client) error {
+	return client.Close() // want "apiv1\\.Client\\.Close\\(\\) closes a client borrowed from the caller"
+}
+
+// shutdown takes over the client of its caller
+//
+//spannerclosecheck:closes client
+func shutdown(client *spanner.Client) {
+	client.Close()
+}
+
+type store struct {
+	client *spanner.Client
+}
+
+// Close closes the client the store owns
+func (s *store) Close() {
+	s.client.Close()
+}
+
+func goodNolintBorrowedClient(client *spanner.Client) {
+	client.Close() //nolint:spannerclosecheck
+}
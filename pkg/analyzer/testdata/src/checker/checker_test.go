@@ -0,0 +1,20 @@
+package checker
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// The checker registered by the test reports the resources acquired with
+// the methods given by its -checker-methods flag
+
+func query(ctx context.Context, txn *spanner.ReadOnlyTransaction) {
+	iter := txn.Query(ctx, spanner.Statement{}) // want "RowIterator acquired with Query"
+	defer iter.Stop()
+}
+
+func read(ctx context.Context, txn *spanner.ReadOnlyTransaction) {
+	iter := txn.Read(ctx, "Users", nil, []string{"ID"})
+	defer iter.Stop()
+}
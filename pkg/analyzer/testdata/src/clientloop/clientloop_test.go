@@ -0,0 +1,75 @@
+package clientloop
+
+import (
+	"context"
+
+	"cloud.google.com/go/pubsub"
+	"cloud.google.com/go/spanner"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// Tests for -client-in-loop
+
+func goodClientOutsideLoop(ctx context.Context, databases []string) error {
+	client, err := spanner.NewClient(ctx, databases[0])
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	for range databases {
+		txn := client.Single()
+		_ = txn
+	}
+	return nil
+}
+
+func badClientInLoop(ctx context.Context, databases []string) {
+	for _, db := range databases {
+		client, err := spanner.NewClient(ctx, db) // want "spanner\\.NewClient\\(\\) is called inside a loop"
+		if err != nil {
+			continue
+		}
+		client.Close()
+	}
+}
+
+func badClientInRetryLoop(ctx context.Context, db string) {
+	for {
+		client, err := spanner.NewClientWithConfig(ctx, db, spanner.ClientConfig{}) // want "spanner\\.NewClientWithConfig\\(\\) is called inside a loop"
+		if err == nil {
+			defer client.Close()
+			return
+		}
+	}
+}
+
+type reconciler struct {
+	db string
+}
+
+func (r *reconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	client, err := spanner.NewClient(ctx, r.db) // want "spanner\\.NewClient\\(\\) is called for every reconcile\\.Request"
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	defer client.Close()
+	return reconcile.Result{}, nil
+}
+
+func badPubSubReceiver(ctx context.Context, sub *pubsub.Subscription, db string) error {
+	return sub.Receive(ctx, func(ctx context.Context, m *pubsub.Message) {
+		client, err := spanner.NewClient(ctx, db) // want "spanner\\.NewClient\\(\\) is called for every pubsub\\.Message"
+		if err != nil {
+			return
+		}
+		defer client.Close()
+		m.Ack()
+	})
+}
+
+func goodNolint(ctx context.Context, databases []string) {
+	for _, db := range databases {
+		client, _ := spanner.NewClient(ctx, db) //nolint:spannerclosecheck // one client per database, closed below
+		client.Close()
+	}
+}
@@ -0,0 +1,81 @@
+package closeerr
+
+import (
+	"errors"
+
+	"example.com/ourdb"
+)
+
+func badDeferredCloseNamedResult(txn *ourdb.Txn) (err error) {
+	iter, err := txn.Rows()
+	if err != nil {
+		return err
+	}
+	defer iter.Close() // want "error of deferred ourdb\\.Iter\\.Close\\(\\) is discarded although the function returns an error: join it into the result, as in defer func\\(\\) \\{ err = errors\\.Join\\(err, iter\\.Close\\(\\)\\) \\}\\(\\)"
+	return nil
+}
+
+func badDeferredCloseUnnamedResult(txn *ourdb.Txn) error {
+	iter, err := txn.Rows()
+	if err != nil {
+		return err
+	}
+	defer iter.Close() // want "error of deferred ourdb\\.Iter\\.Close\\(\\) is discarded"
+	return nil
+}
+
+func badDeferredLiteral(txn *ourdb.Txn) (n int, err error) {
+	iter, err := txn.Rows()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		iter.Close() // want "error of deferred ourdb\\.Iter\\.Close\\(\\) is discarded"
+	}()
+	return 0, nil
+}
+
+func goodErrorsJoin(txn *ourdb.Txn) (err error) {
+	iter, err := txn.Rows()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, iter.Close())
+	}()
+	return nil
+}
+
+func goodExplicitDiscard(txn *ourdb.Txn) error {
+	iter, err := txn.Rows()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = iter.Close()
+	}()
+	return nil
+}
+
+func goodNoErrorResult(txn *ourdb.Txn) {
+	iter, err := txn.Rows()
+	if err != nil {
+		return
+	}
+	defer iter.Close()
+}
+
+func goodCloseWithoutError(db *ourdb.DB) error {
+	txn := db.Begin()
+	defer txn.Release()
+	return nil
+}
+
+func goodNolint(txn *ourdb.Txn) error {
+	iter, err := txn.Rows()
+	if err != nil {
+		return err
+	}
+	defer iter.Close() //nolint:spannerclosecheck
+	return nil
+}
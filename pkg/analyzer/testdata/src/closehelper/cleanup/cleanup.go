@@ -0,0 +1,18 @@
+package cleanup
+
+// Closer is implemented by resources closed with Close
+type Closer interface{ Close() }
+
+// CloseAll closes every resource passed to it
+func CloseAll(closers ...Closer) {
+	for _, c := range closers {
+		c.Close()
+	}
+}
+
+// Run calls every cleanup function passed to it
+func Run(funcs ...func()) {
+	for _, f := range funcs {
+		f()
+	}
+}
@@ -0,0 +1,27 @@
+package closehelper
+
+import (
+	"context"
+
+	"closehelper/cleanup"
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for close helpers of other packages registered via -close-helper
+
+func goodConfiguredHelper(client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	batch, _ := client.BatchReadOnlyTransaction(context.Background(), spanner.StrongRead())
+	defer cleanup.CloseAll(txn, batch)
+}
+
+func goodConfiguredMethodValueHelper(ctx context.Context, client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	iter := txn.Query(ctx, spanner.Statement{})
+	defer cleanup.Run(txn.Close, iter.Stop)
+}
+
+func badConfiguredHelperNotDeferred(client *spanner.Client) {
+	txn := client.ReadOnlyTransaction() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred"
+	cleanup.CloseAll(txn)
+}
@@ -0,0 +1,76 @@
+package closemethods
+
+import "context"
+
+// Tests for resources closed by several methods: Conn is registered with
+// -resource closemethods.Conn:CloseWithContext:close=Release:acquire=Dial,
+// Lease and Broken with directives
+
+type Conn struct{}
+
+func Dial() *Conn { return &Conn{} }
+
+func (c *Conn) CloseWithContext(ctx context.Context) error { return nil }
+
+func (c *Conn) Release() {}
+
+func (c *Conn) Ping() {}
+
+//spannerclosecheck:resource Close Release
+type Lease struct{}
+
+func NewLease() *Lease { return &Lease{} }
+
+func (l *Lease) Close() {}
+
+func (l *Lease) Release() {}
+
+//spannerclosecheck:resource Close Release // want "Broken has no method Release\\(\\) to close it with"
+type Broken struct{}
+
+func (b *Broken) Close() {}
+
+func goodCloseWithContext(ctx context.Context) {
+	c := Dial()
+	defer c.CloseWithContext(ctx)
+	c.Ping()
+}
+
+func goodRelease() {
+	c := Dial()
+	defer c.Release()
+	c.Ping()
+}
+
+func badRelease() {
+	c := Dial() // want "closemethods\\.Conn\\.CloseWithContext\\(\\) must be deferred"
+	c.Ping()
+	c.Release()
+}
+
+func goodHelper(ctx context.Context) {
+	c := Dial()
+	shutdown(ctx, c)
+}
+
+func shutdown(ctx context.Context, c *Conn) {
+	defer c.CloseWithContext(ctx)
+	c.Ping()
+}
+
+func goodLeaseRelease() {
+	l := NewLease()
+	defer l.Release()
+}
+
+func badLease() {
+	l := NewLease() // want "closemethods\\.Lease\\.Close\\(\\) must be deferred"
+	l.Release()
+}
+
+func badUseAfterRelease() {
+	c := Dial()
+	defer c.CloseWithContext(context.Background()) // want "deferred closemethods\\.Conn\\.CloseWithContext\\(\\) closes the resource again after Release\\(\\) at line 74"
+	c.Release()
+	c.Ping() // want "closemethods\\.Conn\\.Ping\\(\\) is called after Release\\(\\) at line 74 closed it"
+}
@@ -0,0 +1,64 @@
+package closer
+
+import (
+	"context"
+	"io"
+
+	"closer/render"
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for functions deferring the close of resources passed to them
+
+func useTxn(ctx context.Context, txn *spanner.ReadOnlyTransaction) {
+	defer txn.Close()
+	_ = txn.Query(ctx, spanner.Statement{}).Do(nil)
+}
+
+func closeTxn(txn *spanner.ReadOnlyTransaction) {
+	txn.Close()
+}
+
+func useTxnThroughHelper(txn *spanner.ReadOnlyTransaction) {
+	defer closeTxn(txn)
+}
+
+func goodSamePackageCloser(ctx context.Context, client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	useTxn(ctx, txn)
+}
+
+func goodOtherPackageCloser(ctx context.Context, client *spanner.Client, w io.Writer) error {
+	iter := client.Single().Query(ctx, spanner.Statement{})
+	return render.Rows(w, iter)
+}
+
+func goodTransitiveCloser(ctx context.Context, client *spanner.Client, w io.Writer) error {
+	iter := client.Single().Query(ctx, spanner.Statement{})
+	return render.Report(w, iter)
+}
+
+func goodGuardedCloser(ctx context.Context, client *spanner.Client) {
+	iter := client.Single().Query(ctx, spanner.Statement{})
+	render.Guarded(iter)
+}
+
+func goodMethodCloser(ctx context.Context, client *spanner.Client, p *render.Printer) {
+	iter := client.Single().Query(ctx, spanner.Statement{})
+	p.Print(iter)
+}
+
+func goodHelperChainCloser(client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	useTxnThroughHelper(txn)
+}
+
+func badNonDeferredHelperCloser(client *spanner.Client) {
+	txn := client.ReadOnlyTransaction() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred"
+	closeTxn(txn)
+}
+
+func badGoroutineCloser(ctx context.Context, client *spanner.Client) {
+	iter := client.Single().Query(ctx, spanner.Statement{}) // want "RowIterator\\.Stop\\(\\) must be deferred"
+	render.Peek(iter)
+}
@@ -0,0 +1,45 @@
+package render
+
+import (
+	"io"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Rows writes the rows of iter to w and stops it
+func Rows(w io.Writer, iter *spanner.RowIterator) error {
+	defer iter.Stop()
+	return nil
+}
+
+// Report writes the rows of iter, stopping it through Rows
+func Report(w io.Writer, iter *spanner.RowIterator) error {
+	defer func() {
+		_ = Rows(w, iter)
+	}()
+	return nil
+}
+
+// Guarded stops iter after checking it was created
+func Guarded(iter *spanner.RowIterator) {
+	defer func() {
+		if iter != nil {
+			iter.Stop()
+		}
+	}()
+}
+
+// Printer writes rows
+type Printer struct{}
+
+// Print writes the rows of iter and stops it
+func (p *Printer) Print(iter *spanner.RowIterator) {
+	defer iter.Stop()
+}
+
+// Peek reads the first row of iter without stopping it
+func Peek(iter *spanner.RowIterator) {
+	go func() {
+		defer iter.Stop()
+	}()
+}
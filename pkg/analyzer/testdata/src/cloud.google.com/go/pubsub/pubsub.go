@@ -0,0 +1,16 @@
+package pubsub
+
+import "context"
+
+// Mock types for testing
+type Message struct {
+	Data []byte
+}
+
+func (m *Message) Ack() {}
+
+type Subscription struct{}
+
+func (s *Subscription) Receive(ctx context.Context, f func(context.Context, *Message)) error {
+	return nil
+}
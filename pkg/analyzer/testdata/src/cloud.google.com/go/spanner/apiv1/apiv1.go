@@ -0,0 +1,30 @@
+package spanner
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner/apiv1/spannerpb"
+)
+
+// Mock types for testing
+type Client struct{}
+
+func NewClient(ctx context.Context, opts ...interface{}) (*Client, error) {
+	return &Client{}, nil
+}
+
+func (c *Client) Close() error {
+	return nil
+}
+
+func (c *Client) ExecuteStreamingSql(ctx context.Context, req *spannerpb.ExecuteSqlRequest, opts ...interface{}) (spannerpb.Spanner_ExecuteStreamingSqlClient, error) {
+	return nil, nil
+}
+
+func (c *Client) StreamingRead(ctx context.Context, req *spannerpb.ReadRequest, opts ...interface{}) (spannerpb.Spanner_StreamingReadClient, error) {
+	return nil, nil
+}
+
+func (c *Client) BatchWrite(ctx context.Context, req *spannerpb.BatchWriteRequest, opts ...interface{}) (spannerpb.Spanner_BatchWriteClient, error) {
+	return nil, nil
+}
@@ -0,0 +1,40 @@
+package spannerpb
+
+import "context"
+
+// Mock types for testing
+type ExecuteSqlRequest struct {
+	Session string
+	Sql     string
+}
+
+type ReadRequest struct {
+	Session string
+	Table   string
+}
+
+type BatchWriteRequest struct {
+	Session string
+}
+
+type PartialResultSet struct{}
+
+type BatchWriteResponse struct{}
+
+type Spanner_ExecuteStreamingSqlClient interface {
+	Recv() (*PartialResultSet, error)
+	CloseSend() error
+	Context() context.Context
+}
+
+type Spanner_StreamingReadClient interface {
+	Recv() (*PartialResultSet, error)
+	CloseSend() error
+	Context() context.Context
+}
+
+type Spanner_BatchWriteClient interface {
+	Recv() (*BatchWriteResponse, error)
+	CloseSend() error
+	Context() context.Context
+}
@@ -0,0 +1,30 @@
+package spanner
+
+import "context"
+
+// Mock types for a future major version of the Spanner package
+type Client struct{}
+
+func (c *Client) ReadOnlyTransaction() *ReadOnlyTransaction {
+	return &ReadOnlyTransaction{}
+}
+
+func (c *Client) Single() *ReadOnlyTransaction {
+	return &ReadOnlyTransaction{}
+}
+
+type ReadOnlyTransaction struct{}
+
+func (t *ReadOnlyTransaction) Close() {}
+
+func (t *ReadOnlyTransaction) Query(ctx context.Context, stmt Statement) *RowIterator {
+	return &RowIterator{}
+}
+
+type RowIterator struct{}
+
+func (r *RowIterator) Stop() {}
+
+type Statement struct {
+	SQL string
+}
@@ -0,0 +1,31 @@
+package closers
+
+// Closers runs the close functions added to it
+type Closers struct {
+	funcs []func()
+}
+
+func New() *Closers {
+	return &Closers{}
+}
+
+func (c *Closers) Add(f func()) {
+	c.funcs = append(c.funcs, f)
+}
+
+func (c *Closers) CloseAll() {
+	for _, f := range c.funcs {
+		f()
+	}
+}
+
+// Group closes the resources tracked with it
+type Group struct {
+	resources []any
+}
+
+func (g *Group) Track(r any) {
+	g.resources = append(g.resources, r)
+}
+
+func (g *Group) Release() {}
@@ -0,0 +1,46 @@
+package collector
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+	"collector/closers"
+)
+
+// Tests for resources added to collectors closing them all at once
+
+func goodAddedToDeferredCollector(ctx context.Context, client *spanner.Client) {
+	c := closers.New()
+	defer c.CloseAll()
+	txn := client.ReadOnlyTransaction()
+	c.Add(txn.Close)
+	iter := txn.Query(ctx, spanner.Statement{})
+	c.Add(iter.Stop)
+}
+
+func goodAddedToDeferredCollectorValue(client *spanner.Client) {
+	var c closers.Closers
+	defer c.CloseAll()
+	txn := client.ReadOnlyTransaction()
+	c.Add(txn.Close)
+}
+
+func goodTrackedAsInterface(ctx context.Context, client *spanner.Client) {
+	var g closers.Group
+	defer g.Release()
+	iter := client.Single().Query(ctx, spanner.Statement{})
+	g.Track(iter)
+}
+
+func badCollectorNotDeferred(client *spanner.Client) {
+	c := closers.New()
+	txn := client.ReadOnlyTransaction() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred"
+	c.Add(txn.Close)
+	c.CloseAll()
+}
+
+func badCollectorNeverClosed(ctx context.Context, client *spanner.Client) {
+	var g closers.Group
+	iter := client.Single().Query(ctx, spanner.Statement{}) // want "RowIterator\\.Stop\\(\\) must be deferred"
+	g.Track(iter)
+}
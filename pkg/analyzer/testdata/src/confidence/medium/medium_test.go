@@ -0,0 +1,31 @@
+package medium
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+	"golang.org/x/sync/errgroup"
+)
+
+// Reports below -min-confidence=medium are dropped
+
+func unclosed(client *spanner.Client) {
+	txn := client.ReadOnlyTransaction() // want `ReadOnlyTransaction\.Close\(\) must be deferred$`
+	_ = txn
+}
+
+func conditional(ctx context.Context, txn *spanner.ReadOnlyTransaction, enabled bool) {
+	if iter := txn.Query(ctx, spanner.Statement{}); enabled {
+		defer iter.Stop() // want `must be deferred on every path from the acquisition \[confidence: medium\]$`
+	}
+}
+
+func escaped(client *spanner.Client) error {
+	txn := client.ReadOnlyTransaction()
+	var g errgroup.Group
+	g.Go(func() error {
+		txn.Close()
+		return nil
+	})
+	return g.Wait()
+}
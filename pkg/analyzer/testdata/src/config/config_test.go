@@ -0,0 +1,21 @@
+package config
+
+import (
+	"cloud.google.com/go/spanner"
+
+	"example.com/ourdb"
+)
+
+// The configuration file of the test registers ourdb.Txn and enables
+// -duplicate-defers
+
+func leak(db *ourdb.DB) {
+	txn := db.Begin() // want "ourdb\\.Txn\\.Release\\(\\) must be deferred"
+	_, _ = txn.Rows()
+}
+
+func duplicate(client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	defer txn.Close()
+	defer txn.Close() // want "deferred ReadOnlyTransaction\\.Close\\(\\) duplicates the defer at line 19"
+}
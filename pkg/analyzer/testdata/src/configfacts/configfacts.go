@@ -0,0 +1,19 @@
+package configfacts
+
+import (
+	"configfacts/store"
+	"example.com/ourdb"
+)
+
+// The configuration file of the test registers ourdb.Txn, which the facts of
+// the store package must already know
+
+func leakFromStore(db *ourdb.DB) {
+	txn := store.Begin(db) // want "ourdb\\.Txn\\.Release\\(\\) must be deferred"
+	txn.Release()
+}
+
+func goodFromStore(db *ourdb.DB) {
+	txn := store.Begin(db)
+	defer txn.Release()
+}
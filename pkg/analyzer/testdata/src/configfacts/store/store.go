@@ -0,0 +1,14 @@
+package store
+
+import "example.com/ourdb"
+
+// Releaser is implemented by *ourdb.Txn
+type Releaser interface {
+	Release()
+}
+
+// Begin hands a transaction to its caller, who must release it. Only the
+// configuration file registers ourdb.Txn, for the facts of this package too.
+func Begin(db *ourdb.DB) Releaser {
+	return db.Begin()
+}
@@ -0,0 +1,25 @@
+package consume
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+	"consume/scan"
+)
+
+// Tests for consuming functions registered via -consuming-func
+
+func goodConsumingFunc(ctx context.Context, client *spanner.Client) error {
+	var rows []struct{}
+	return scan.All(client.Single().Query(ctx, spanner.Statement{}), &rows)
+}
+
+func goodConsumingMethod(ctx context.Context, client *spanner.Client, s *scan.Scanner) error {
+	iter := client.Single().Query(ctx, spanner.Statement{})
+	return s.Collect(iter)
+}
+
+func badNonConsumingFunc(ctx context.Context, client *spanner.Client) int {
+	iter := client.Single().Query(ctx, spanner.Statement{}) // want "RowIterator\\.Stop\\(\\) must be deferred"
+	return scan.Count(iter)
+}
@@ -0,0 +1,23 @@
+package scan
+
+import "cloud.google.com/go/spanner"
+
+// All reads every row of iter into dst and stops it
+func All(iter *spanner.RowIterator, dst any) error {
+	defer iter.Stop()
+	return nil
+}
+
+// Scanner collects rows of iterators
+type Scanner struct{}
+
+// Collect reads every row of iter and stops it
+func (s *Scanner) Collect(iter *spanner.RowIterator) error {
+	defer iter.Stop()
+	return nil
+}
+
+// Count returns the number of rows of iter without stopping it
+func Count(iter *spanner.RowIterator) int {
+	return 0
+}
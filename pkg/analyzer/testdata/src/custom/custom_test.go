@@ -0,0 +1,67 @@
+package custom
+
+import (
+	"errors"
+
+	"example.com/ourdb"
+)
+
+// Tests for custom resource types registered via -resource
+
+func goodCustomTxnDefer(db *ourdb.DB) {
+	txn := db.Begin()
+	defer txn.Release()
+}
+
+func badCustomTxnNoDefer(db *ourdb.DB) {
+	txn := db.Begin() // want "ourdb\\.Txn\\.Release\\(\\) must be deferred"
+	txn.Release()
+}
+
+func goodCustomTxnExempt(db *ourdb.DB) {
+	txn := db.OneShotTxn()
+	_ = txn
+}
+
+func goodCustomTxnNotAcquired(db *ourdb.DB) {
+	// Current() is not an acquiring constructor
+	txn := db.Current()
+	_ = txn
+}
+
+func goodCustomIterDefer(db *ourdb.DB) error {
+	txn := db.Begin()
+	defer txn.Release()
+
+	iter, err := txn.Rows()
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+	return nil
+}
+
+func goodCustomIterErrorsJoin(db *ourdb.DB) (err error) {
+	txn := db.Begin()
+	defer txn.Release()
+
+	iter, err := txn.Rows()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, iter.Close())
+	}()
+	return nil
+}
+
+func badCustomIterNoDefer(db *ourdb.DB) error {
+	txn := db.Begin()
+	defer txn.Release()
+
+	iter, err := txn.Rows() // want "ourdb\\.Iter\\.Close\\(\\) must be deferred"
+	if err != nil {
+		return err
+	}
+	return iter.Close()
+}
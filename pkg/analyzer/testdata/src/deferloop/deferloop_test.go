@@ -0,0 +1,61 @@
+package deferloop
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for closes deferred inside the loop acquiring the resource
+
+func badDeferInRangeLoop(ctx context.Context, txn *spanner.ReadOnlyTransaction, stmts []spanner.Statement) {
+	for _, stmt := range stmts {
+		iter := txn.Query(ctx, stmt)
+		defer iter.Stop() // want "RowIterator\\.Stop\\(\\) deferred inside a loop only runs when the function returns, holding the resource of every iteration until then: close it at the end of each iteration or move the loop body into a function"
+
+		_, _ = iter.Next()
+	}
+}
+
+func badDeferInForLoop(client *spanner.Client, n int) {
+	for i := 0; i < n; i++ {
+		txn := client.ReadOnlyTransaction()
+		defer txn.Close() // want "ReadOnlyTransaction\\.Close\\(\\) deferred inside a loop"
+	}
+}
+
+func badDeferInLoopNoFixWithContinue(ctx context.Context, txn *spanner.ReadOnlyTransaction, stmts []spanner.Statement) {
+	for _, stmt := range stmts {
+		iter := txn.Query(ctx, stmt)
+		defer iter.Stop() // want "RowIterator\\.Stop\\(\\) deferred inside a loop"
+		if _, err := iter.Next(); err != nil {
+			continue
+		}
+	}
+}
+
+func goodDeferInFuncPerIteration(ctx context.Context, txn *spanner.ReadOnlyTransaction, stmts []spanner.Statement) {
+	for _, stmt := range stmts {
+		func() {
+			iter := txn.Query(ctx, stmt)
+			defer iter.Stop()
+		}()
+	}
+}
+
+func goodDeferElementsAcquiredBeforeLoop(ctx context.Context, txn *spanner.ReadOnlyTransaction) {
+	iters := []*spanner.RowIterator{
+		txn.Query(ctx, spanner.Statement{SQL: "SELECT 1"}),
+		txn.Query(ctx, spanner.Statement{SQL: "SELECT 2"}),
+	}
+	for _, iter := range iters {
+		defer iter.Stop()
+	}
+}
+
+func goodNolintDeferInLoop(client *spanner.Client, n int) {
+	for i := 0; i < n; i++ {
+		txn := client.ReadOnlyTransaction()
+		defer txn.Close() //nolint:spannerclosecheck
+	}
+}
@@ -0,0 +1,99 @@
+package deferorder
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for the opt-in defer-before-use ordering rule
+
+func goodDeferBeforeUse(client *spanner.Client) {
+	ctx := context.Background()
+	txn := client.ReadOnlyTransaction()
+	defer txn.Close()
+
+	iter := txn.Query(ctx, spanner.Statement{})
+	defer iter.Stop()
+}
+
+func badDeferAfterQuery(client *spanner.Client) {
+	ctx := context.Background()
+	txn := client.ReadOnlyTransaction()
+	iter := txn.Query(ctx, spanner.Statement{})
+	defer iter.Stop()
+	defer txn.Close() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred before the resource is first used"
+}
+
+func badDeferAfterErrCheckAndUse(client *spanner.Client) error {
+	ctx := context.Background()
+	txn, err := client.BatchReadOnlyTransaction(ctx, spanner.StrongRead())
+	if err != nil {
+		return err
+	}
+	useBatch(txn)
+	defer txn.Close() // want "BatchReadOnlyTransaction\\.Close\\(\\) must be deferred before the resource is first used"
+	return nil
+}
+
+func badDeferOnlyOnOneBranch(client *spanner.Client, fast bool) {
+	ctx := context.Background()
+	txn := client.ReadOnlyTransaction()
+	if fast {
+		iter := txn.Query(ctx, spanner.Statement{})
+		defer iter.Stop()
+	}
+	defer txn.Close() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred before the resource is first used"
+}
+
+func goodDeferBeforeUseNolint(client *spanner.Client) {
+	ctx := context.Background()
+	txn := client.ReadOnlyTransaction()
+	iter := txn.Query(ctx, spanner.Statement{})
+	defer iter.Stop()
+	defer txn.Close() //nolint:spannerclosecheck
+}
+
+func badNextBeforeStop(client *spanner.Client) error {
+	ctx := context.Background()
+	iter := client.Single().Query(ctx, spanner.Statement{})
+	row, err := iter.Next()
+	defer iter.Stop() // want "RowIterator\\.Stop\\(\\) must be deferred before the resource is first used"
+	if err != nil {
+		return err
+	}
+	_ = row
+	return nil
+}
+
+func badReadBeforeClose(client *spanner.Client) {
+	ctx := context.Background()
+	txn := client.ReadOnlyTransaction()
+	iter := txn.Read(ctx, "Users", spanner.KeySets(), []string{"ID"})
+	defer iter.Stop()
+	defer txn.Close() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred before the resource is first used"
+}
+
+func badClosureQueryBeforeClose(client *spanner.Client) {
+	ctx := context.Background()
+	txn := client.ReadOnlyTransaction()
+	query := func() {
+		iter := txn.Query(ctx, spanner.Statement{})
+		defer iter.Stop()
+	}
+	query()
+	defer txn.Close() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred before the resource is first used"
+}
+
+func goodClosureQueryAfterClose(client *spanner.Client) {
+	ctx := context.Background()
+	txn := client.ReadOnlyTransaction()
+	query := func() {
+		iter := txn.Query(ctx, spanner.Statement{})
+		defer iter.Stop()
+	}
+	defer txn.Close()
+	query()
+}
+
+func useBatch(txn *spanner.BatchReadOnlyTransaction) {}
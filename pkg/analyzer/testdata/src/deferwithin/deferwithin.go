@@ -0,0 +1,60 @@
+package deferwithin
+
+import (
+	"context"
+	"strings"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for -defer-within=2: the defer must come at most two statements
+// after the acquisition or its error check
+
+func goodDeferNext(client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	defer txn.Close()
+}
+
+func goodDeferSecond(client *spanner.Client) {
+	ctx := context.Background()
+	txn := client.ReadOnlyTransaction()
+	stmt := spanner.Statement{SQL: "SELECT 1"}
+	defer txn.Close()
+	iter := txn.Query(ctx, stmt)
+	defer iter.Stop()
+}
+
+func goodDeferAfterErrCheck(ctx context.Context, client *spanner.Client) error {
+	txn, err := client.BatchReadOnlyTransaction(ctx, spanner.StrongRead())
+	if err != nil {
+		return err
+	}
+	defer txn.Close()
+	return nil
+}
+
+func badDeferFar(client *spanner.Client, name string) string {
+	txn := client.ReadOnlyTransaction()
+	name = strings.TrimSpace(name)
+	name = strings.ToUpper(name)
+	defer txn.Close() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred within 2 statements of the acquisition, found 3"
+	return name
+}
+
+func badDeferFarAfterErrCheck(ctx context.Context, client *spanner.Client, name string) (string, error) {
+	txn, err := client.BatchReadOnlyTransaction(ctx, spanner.StrongRead())
+	if err != nil {
+		return "", err
+	}
+	name = strings.TrimSpace(name)
+	name = strings.ToUpper(name)
+	defer txn.Close() // want "BatchReadOnlyTransaction\\.Close\\(\\) must be deferred within 2 statements of the acquisition, found 3"
+	return name, nil
+}
+
+func goodDeferNolint(client *spanner.Client, name string) {
+	txn := client.ReadOnlyTransaction()
+	name += "?"
+	name += "!"
+	defer txn.Close() //nolint:spannerclosecheck
+}
@@ -0,0 +1,27 @@
+package disabled
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+	"example.com/ourdb"
+)
+
+// Tests for -disable-resource: the test disables RowIterator and
+// example.com/ourdb.Txn, registered with -resource
+
+func iteratorNotChecked(ctx context.Context, client *spanner.Client) {
+	iter := client.Single().Query(ctx, spanner.Statement{})
+	iter.Stop()
+	_ = client.Single().Query(ctx, spanner.Statement{})
+}
+
+func customNotChecked(db *ourdb.DB) {
+	txn := db.Begin()
+	txn.Release()
+}
+
+func transactionChecked(ctx context.Context, client *spanner.Client) {
+	txn := client.ReadOnlyTransaction() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred"
+	txn.Close()
+}
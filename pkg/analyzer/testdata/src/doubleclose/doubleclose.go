@@ -0,0 +1,30 @@
+package doubleclose
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for the severity of double closes: closing twice is a no-op, so the
+// idiom of a deferred close along with an early one is a warning
+
+func earlyStop(ctx context.Context, txn *spanner.ReadOnlyTransaction, done bool) {
+	iter := txn.Query(ctx, spanner.Statement{})
+	defer iter.Stop() // want `^deferred RowIterator\.Stop\(\) closes the resource again after Stop\(\) at line 16$`
+	if done {
+		iter.Stop()
+		return
+	}
+	_, _ = iter.Next()
+}
+
+func earlyClose(client *spanner.Client, done bool) {
+	txn := client.ReadOnlyTransaction()
+	defer txn.Close() // want `^deferred ReadOnlyTransaction\.Close\(\) closes the resource again after Close\(\) at line 26$`
+	if done {
+		txn.Close()
+		return
+	}
+	_ = txn.Query(context.Background(), spanner.Statement{}).Do(func(r *spanner.Row) error { return nil })
+}
@@ -0,0 +1,41 @@
+package duplicatedefer
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+func badDeferredTwice(client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	defer txn.Close()
+	defer txn.Close() // want "deferred ReadOnlyTransaction\\.Close\\(\\) duplicates the defer at line 11: the resource is closed twice on return"
+}
+
+func badDeferredAgainInBranch(ctx context.Context, txn *spanner.ReadOnlyTransaction, verbose bool) {
+	iter := txn.Query(ctx, spanner.Statement{})
+	defer iter.Stop()
+	if verbose {
+		defer iter.Stop() // want "deferred RowIterator\\.Stop\\(\\) duplicates the defer at line 17"
+	}
+}
+
+func goodDeferredInExclusiveBranches(client *spanner.Client, fresh bool) {
+	txn := client.ReadOnlyTransaction()
+	if fresh {
+		defer txn.Close()
+	} else {
+		defer txn.Close()
+	}
+}
+
+func goodDeferredOnce(client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	defer txn.Close()
+}
+
+func goodNolint(client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	defer txn.Close()
+	defer txn.Close() //nolint:spannerclosecheck
+}
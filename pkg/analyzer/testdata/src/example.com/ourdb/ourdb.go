@@ -0,0 +1,30 @@
+package ourdb
+
+// Mock in-house wrapper types holding Spanner resources
+type DB struct{}
+
+type Txn struct{}
+
+func (db *DB) Begin() *Txn {
+	return &Txn{}
+}
+
+func (db *DB) OneShotTxn() *Txn {
+	return &Txn{}
+}
+
+func (db *DB) Current() *Txn {
+	return &Txn{}
+}
+
+func (t *Txn) Release() {}
+
+func (t *Txn) Rows() (*Iter, error) {
+	return &Iter{}, nil
+}
+
+type Iter struct{}
+
+func (i *Iter) Close() error {
+	return nil
+}
@@ -0,0 +1,15 @@
+package exclude
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for -exclude: the test skips exclude/*_migration.go, files matching
+// re:experimental and packages under third_party
+
+func leak(ctx context.Context, client *spanner.Client) {
+	txn := client.ReadOnlyTransaction() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred"
+	txn.Close()
+}
@@ -0,0 +1,12 @@
+package exclude
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+func experiment(ctx context.Context, client *spanner.Client) {
+	iter := client.Single().Query(ctx, spanner.Statement{})
+	iter.Stop()
+}
@@ -0,0 +1,12 @@
+package vendorlib
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+func Read(ctx context.Context, client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	txn.Close()
+}
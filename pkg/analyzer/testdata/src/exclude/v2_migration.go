@@ -0,0 +1,12 @@
+package exclude
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+func migrate(ctx context.Context, client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	txn.Close()
+}
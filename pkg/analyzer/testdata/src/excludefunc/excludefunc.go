@@ -0,0 +1,39 @@
+package excludefunc
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for -exclude-func: the test skips .*\.mock.* and .*Fixture.*
+
+type mockStore struct {
+	client *spanner.Client
+}
+
+func (m *mockStore) Get(ctx context.Context) {
+	txn := m.client.ReadOnlyTransaction()
+	txn.Close()
+}
+
+func NewFixture(ctx context.Context, client *spanner.Client) {
+	go func() {
+		iter := client.Single().Query(ctx, spanner.Statement{})
+		iter.Stop()
+	}()
+}
+
+type store struct {
+	client *spanner.Client
+}
+
+func (s *store) Get(ctx context.Context) {
+	txn := s.client.ReadOnlyTransaction() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred"
+	txn.Close()
+}
+
+func newStore(ctx context.Context, client *spanner.Client) {
+	txn := client.ReadOnlyTransaction() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred"
+	txn.Close()
+}
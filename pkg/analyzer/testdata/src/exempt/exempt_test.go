@@ -0,0 +1,64 @@
+package exempt
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for additional exempt constructors registered via -exempt-constructor
+
+type repo struct {
+	client *spanner.Client
+}
+
+// OneShotTxn has the same semantics as Client.Single()
+func (r *repo) OneShotTxn() *spanner.ReadOnlyTransaction {
+	return r.client.Single()
+}
+
+func (r *repo) Txn() *spanner.ReadOnlyTransaction {
+	return r.client.Single()
+}
+
+func newOneShot(client *spanner.Client) *spanner.ReadOnlyTransaction {
+	return client.Single()
+}
+
+func goodExemptMethod(r *repo) {
+	ctx := context.Background()
+	iter := r.OneShotTxn().Query(ctx, spanner.Statement{})
+	defer iter.Stop()
+}
+
+func goodExemptQualifiedFunc(client *spanner.Client) {
+	txn := newOneShot(client)
+	_ = txn
+}
+
+func badNotExempt(r *repo) {
+	txn := r.Txn() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred"
+	_ = txn
+}
+
+// CachedTxn is exempt for ReadOnlyTransaction only, via
+// ReadOnlyTransaction=CachedTxn
+func (r *repo) CachedTxn() *spanner.ReadOnlyTransaction {
+	return r.client.Single()
+}
+
+// cachedQuery is listed for ReadOnlyTransaction, so the RowIterator it
+// returns is still checked
+func cachedQuery(client *spanner.Client) *spanner.RowIterator {
+	return client.Single().Query(context.Background(), spanner.Statement{})
+}
+
+func goodExemptForType(r *repo) {
+	txn := r.CachedTxn()
+	_ = txn
+}
+
+func badExemptForOtherType(client *spanner.Client) {
+	iter := cachedQuery(client) // want "RowIterator\\.Stop\\(\\) must be deferred"
+	_ = iter
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for -exempt-func=main,TestMain,exemptfunc.startDaemon: resources of
+// these functions live as long as the process
+
+var daemonTxn *spanner.ReadOnlyTransaction
+
+func main() {
+	ctx := context.Background()
+	client, _ := spanner.NewClient(ctx, "db")
+	txn := client.ReadOnlyTransaction()
+	serve(ctx, txn)
+}
+
+func startDaemon(client *spanner.Client) {
+	go func() {
+		txn := client.ReadOnlyTransaction()
+		daemonTxn = txn
+	}()
+}
+
+func serve(ctx context.Context, txn *spanner.ReadOnlyTransaction) {
+	iter := txn.Query(ctx, spanner.Statement{}) // want "RowIterator\\.Stop\\(\\) must be deferred"
+	iter.Stop()
+}
+
+func (s *server) startDaemon(client *spanner.Client) {
+	txn := client.ReadOnlyTransaction() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred"
+	txn.Close()
+}
+
+type server struct{}
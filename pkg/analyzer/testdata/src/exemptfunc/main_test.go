@@ -0,0 +1,21 @@
+package main
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"cloud.google.com/go/spanner"
+)
+
+var testClient *spanner.Client
+
+func TestMain(m *testing.M) {
+	client, err := spanner.NewClient(context.Background(), "db")
+	if err != nil {
+		os.Exit(1)
+	}
+	defer client.Close()
+	testClient = client
+	os.Exit(m.Run())
+}
@@ -0,0 +1,4 @@
+//spannerclosecheck:config exclude=legacy // want "exclude=legacy: not an option that can be set per file"
+//spannerclosecheck:config defer-within // want "defer-within: missing value, want defer-within=value"
+//spannerclosecheck:config single-close=never // want `single-close=never: invalid severity "never"`
+package fileconfig
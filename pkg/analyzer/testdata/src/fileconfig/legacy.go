@@ -0,0 +1,24 @@
+// Legacy queries, closed on every path but not with defer yet
+
+//spannerclosecheck:config lenient
+//spannerclosecheck:config single-close=off
+package fileconfig
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for //spannerclosecheck:config: the options of the directive apply
+// to the functions of this file only
+
+func legacyStop(ctx context.Context, client *spanner.Client) {
+	iter := client.Single().Query(ctx, spanner.Statement{})
+	iter.Stop()
+}
+
+func legacySingleClose(client *spanner.Client) {
+	txn := client.Single()
+	txn.Close()
+}
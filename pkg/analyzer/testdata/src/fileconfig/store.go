@@ -0,0 +1,12 @@
+package fileconfig
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+func stop(ctx context.Context, client *spanner.Client) {
+	iter := client.Single().Query(ctx, spanner.Statement{}) // want "RowIterator\\.Stop\\(\\) must be deferred"
+	iter.Stop()
+}
@@ -0,0 +1,76 @@
+package fixes
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for suggested fixes inserting or moving deferred closes
+
+func badMissingDefer(client *spanner.Client) {
+	txn := client.ReadOnlyTransaction() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred"
+	_ = txn
+}
+
+func badCloseNotDeferred(client *spanner.Client) {
+	ctx := context.Background()
+	txn := client.ReadOnlyTransaction()
+	defer txn.Close()
+
+	iter := txn.Query(ctx, spanner.Statement{}) // want "RowIterator\\.Stop\\(\\) must be deferred"
+	_ = iter
+	iter.Stop()
+}
+
+func badTupleAfterErrCheck(client *spanner.Client) error {
+	ctx := context.Background()
+	txn, err := client.BatchReadOnlyTransaction(ctx, spanner.StrongRead()) // want "BatchReadOnlyTransaction\\.Close\\(\\) must be deferred"
+	if err != nil {
+		return err
+	}
+	_ = txn
+	return nil
+}
+
+func badNoFixWithoutVariable(client *spanner.Client) {
+	_ = client.ReadOnlyTransaction() // want "ReadOnlyTransaction acquired and discarded"
+}
+
+func badNoFixInIfInit(ctx context.Context, txn *spanner.ReadOnlyTransaction, enabled bool) {
+	if iter := txn.Query(ctx, spanner.Statement{}); enabled { // want "RowIterator\\.Stop\\(\\) must be deferred"
+		_ = iter
+	}
+}
+
+func badDeferBeforeErrCheck(client *spanner.Client) error {
+	ctx := context.Background()
+	txn, err := client.BatchReadOnlyTransaction(ctx, spanner.StrongRead())
+	defer txn.Close() // want "BatchReadOnlyTransaction\\.Close\\(\\) must be deferred after the error check, the resource may be nil when an error is returned"
+	if err != nil {
+		return err
+	}
+	_ = txn
+	return nil
+}
+
+func badDeferBeforeNamedErrCheck(client *spanner.Client) (err error) {
+	ctx := context.Background()
+	txn, err := client.BatchReadOnlyTransaction(ctx, spanner.StrongRead())
+	defer txn.Close() // want "BatchReadOnlyTransaction\\.Close\\(\\) must be deferred after the error check"
+	if err != nil {
+		return err
+	}
+	defer func() { _ = err }()
+	return nil
+}
+
+func goodDeferAfterErrCheck(client *spanner.Client) error {
+	ctx := context.Background()
+	txn, err := client.BatchReadOnlyTransaction(ctx, spanner.StrongRead())
+	if err != nil {
+		return err
+	}
+	defer txn.Close()
+	return nil
+}
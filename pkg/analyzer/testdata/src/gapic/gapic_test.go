@@ -0,0 +1,107 @@
+package gapic
+
+import (
+	"context"
+	"io"
+
+	apiv1 "cloud.google.com/go/spanner/apiv1"
+	"cloud.google.com/go/spanner/apiv1/spannerpb"
+)
+
+// Tests for the low-level apiv1 gapic client
+
+func goodGapicClientDefer(ctx context.Context) error {
+	client, err := apiv1.NewClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	return nil
+}
+
+func badGapicClientNoDefer(ctx context.Context) error {
+	client, err := apiv1.NewClient(ctx) // want "apiv1\\.Client\\.Close\\(\\) must be deferred"
+	if err != nil {
+		return err
+	}
+	_ = client
+	return nil
+}
+
+func badGapicClientCloseNotDeferred(ctx context.Context) error {
+	client, err := apiv1.NewClient(ctx) // want "apiv1\\.Client\\.Close\\(\\) must be deferred"
+	if err != nil {
+		return err
+	}
+	return client.Close()
+}
+
+func goodStreamDrained(ctx context.Context, client *apiv1.Client) error {
+	stream, err := client.ExecuteStreamingSql(ctx, &spannerpb.ExecuteSqlRequest{})
+	if err != nil {
+		return err
+	}
+	for {
+		_, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func goodStreamContextCancelled(ctx context.Context, client *apiv1.Client) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := client.StreamingRead(ctx, &spannerpb.ReadRequest{})
+	if err != nil {
+		return err
+	}
+	_, err = stream.Recv()
+	return err
+}
+
+func goodStreamTimeout(ctx context.Context, client *apiv1.Client) error {
+	ctx, cancel := context.WithTimeout(ctx, 0)
+	defer cancel()
+
+	stream, err := client.BatchWrite(ctx, &spannerpb.BatchWriteRequest{})
+	if err != nil {
+		return err
+	}
+	_, err = stream.Recv()
+	return err
+}
+
+func badStreamSingleRecv(ctx context.Context, client *apiv1.Client) error {
+	stream, err := client.ExecuteStreamingSql(ctx, &spannerpb.ExecuteSqlRequest{}) // want "apiv1\\.Client\\.ExecuteStreamingSql\\(\\) stream must be drained or its context cancelled with defer"
+	if err != nil {
+		return err
+	}
+	_, err = stream.Recv()
+	return err
+}
+
+func badStreamCancelNotDeferred(ctx context.Context, client *apiv1.Client) error {
+	ctx, cancel := context.WithCancel(ctx)
+	stream, err := client.StreamingRead(ctx, &spannerpb.ReadRequest{}) // want "apiv1\\.Client\\.StreamingRead\\(\\) stream must be drained or its context cancelled with defer"
+	if err != nil {
+		cancel()
+		return err
+	}
+	_, err = stream.Recv()
+	cancel()
+	return err
+}
+
+func goodStreamNolint(ctx context.Context, client *apiv1.Client) error {
+	stream, err := client.ExecuteStreamingSql(ctx, &spannerpb.ExecuteSqlRequest{}) //nolint:spannerclosecheck
+	if err != nil {
+		return err
+	}
+	_, err = stream.Recv()
+	return err
+}
@@ -0,0 +1,15 @@
+package gencode
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Generated without the standard comment, skipped with
+// -generated-pattern=*.sql.go only
+
+func readQuery(ctx context.Context, client *spanner.Client) {
+	txn := client.ReadOnlyTransaction() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred"
+	txn.Close()
+}
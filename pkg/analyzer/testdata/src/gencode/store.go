@@ -0,0 +1,15 @@
+package gencode
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for generated files detected by their "Code generated" comment
+// rather than by their name
+
+func read(ctx context.Context, client *spanner.Client) {
+	txn := client.ReadOnlyTransaction() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred"
+	txn.Close()
+}
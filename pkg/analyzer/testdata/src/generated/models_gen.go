@@ -0,0 +1,20 @@
+package generated
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for -skip-generated=false: files named like generated code are
+// checked, but for their file-level nolint directives
+
+func badGenerated(ctx context.Context, client *spanner.Client) {
+	txn := client.ReadOnlyTransaction() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred"
+	txn.Close()
+}
+
+func goodGenerated(ctx context.Context, client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	defer txn.Close()
+}
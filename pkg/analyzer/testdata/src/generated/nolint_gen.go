@@ -0,0 +1,13 @@
+//nolint:spannerclosecheck
+package generated
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+func excludedGenerated(ctx context.Context, client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	txn.Close()
+}
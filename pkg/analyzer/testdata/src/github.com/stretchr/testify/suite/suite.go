@@ -0,0 +1,14 @@
+package suite
+
+import "testing"
+
+// Mock types for testing
+type Suite struct {
+	t *testing.T
+}
+
+func (s *Suite) T() *testing.T {
+	return s.t
+}
+
+func Run(t *testing.T, suite interface{}) {}
@@ -0,0 +1,14 @@
+package spanner
+
+import "context"
+
+// Mock types for an internal mirror of the Spanner GAPIC client
+type Client struct{}
+
+func NewClient(ctx context.Context, opts ...interface{}) (*Client, error) {
+	return &Client{}, nil
+}
+
+func (c *Client) Close() error {
+	return nil
+}
@@ -0,0 +1,21 @@
+package fx
+
+import "context"
+
+// Mock types for testing
+type Hook struct {
+	OnStart func(context.Context) error
+	OnStop  func(context.Context) error
+}
+
+type Lifecycle interface {
+	Append(Hook)
+}
+
+type HookFunc interface {
+	~func() | ~func() error | ~func(context.Context) | ~func(context.Context) error
+}
+
+func StopHook[T HookFunc](stop T) Hook {
+	return Hook{}
+}
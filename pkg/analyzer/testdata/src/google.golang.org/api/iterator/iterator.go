@@ -0,0 +1,7 @@
+package iterator
+
+import "errors"
+
+// Done is returned by an iterator's Next method when the iteration is
+// complete
+var Done = errors.New("no more items in iterator")
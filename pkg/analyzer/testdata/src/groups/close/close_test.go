@@ -0,0 +1,28 @@
+package close
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Only the findings of the close group are reported
+
+func leak(client *spanner.Client) {
+	txn := client.ReadOnlyTransaction() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred"
+	_ = txn
+}
+
+func useAfterClose(ctx context.Context, txn *spanner.ReadOnlyTransaction) {
+	txn.Close()
+	_ = txn.Query(ctx, spanner.Statement{}).Do(func(*spanner.Row) error { return nil })
+}
+
+func borrowed(client *spanner.Client) {
+	defer client.Close()
+}
+
+func redundant(client *spanner.Client) {
+	txn := client.Single()
+	defer txn.Close()
+}
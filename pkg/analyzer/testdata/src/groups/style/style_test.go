@@ -0,0 +1,28 @@
+package style
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Only the findings of the style group are reported
+
+func leak(client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	_ = txn
+}
+
+func useAfterClose(ctx context.Context, txn *spanner.ReadOnlyTransaction) {
+	txn.Close()
+	_ = txn.Query(ctx, spanner.Statement{}).Do(func(*spanner.Row) error { return nil })
+}
+
+func borrowed(client *spanner.Client) {
+	defer client.Close()
+}
+
+func redundant(client *spanner.Client) {
+	txn := client.Single()
+	defer txn.Close() // want "ReadOnlyTransaction\\.Close\\(\\) is redundant"
+}
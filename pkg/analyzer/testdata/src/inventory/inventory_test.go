@@ -0,0 +1,38 @@
+package inventory
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// The inventory analyzer of the test reports the resources of the result of
+// spannerclosecheck with their close sites
+
+func deferred(ctx context.Context, client *spanner.Client) {
+	txn := client.ReadOnlyTransaction() // want "ReadOnlyTransaction closed at deferred:14"
+	defer txn.Close()
+	iter := txn.Query(ctx, spanner.Statement{}) // want "RowIterator closed at deferred:16"
+	defer iter.Stop()
+}
+
+func explicit(ctx context.Context, client *spanner.Client) {
+	txn := client.ReadOnlyTransaction() // want "ReadOnlyTransaction closed at 21 deferred:22"
+	txn.Close()
+	defer txn.Close()
+}
+
+func literal(client *spanner.Client) {
+	txn := client.ReadOnlyTransaction() // want "ReadOnlyTransaction closed at deferred:27"
+	defer func() {
+		txn.Close()
+	}()
+}
+
+func returned(client *spanner.Client) *spanner.ReadOnlyTransaction {
+	return client.ReadOnlyTransaction() // want "ReadOnlyTransaction closed at returned"
+}
+
+func single(ctx context.Context, client *spanner.Client) {
+	_ = client.Single().Query(ctx, spanner.Statement{}).Do(func(*spanner.Row) error { return nil }) // want "RowIterator closed at"
+}
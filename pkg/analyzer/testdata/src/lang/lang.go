@@ -0,0 +1,43 @@
+package lang
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for -lang ja: reports are in Japanese
+
+func unclosed(ctx context.Context, client *spanner.Client) {
+	txn := client.ReadOnlyTransaction() // want `^ReadOnlyTransaction\.Close\(\) を defer で呼び出す必要があります$`
+	_ = txn
+}
+
+func discarded(ctx context.Context, client *spanner.Client) {
+	_ = client.Single().Query(ctx, spanner.Statement{}) // want `^RowIterator が取得後に破棄されています：ブランク識別子により唯一の参照が失われるため、Stop\(\) を呼び出せません$`
+}
+
+func inGoroutine(ctx context.Context, client *spanner.Client) {
+	go func() {
+		txn := client.ReadOnlyTransaction() // want `^ReadOnlyTransaction\.Close\(\) を defer で呼び出す必要があります（goroutine 内）$`
+		_ = txn
+	}()
+}
+
+func closedTwice(ctx context.Context, client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	defer txn.Close() // want `^defer された ReadOnlyTransaction\.Close\(\) は 30 行目の Close\(\) の後で再びリソースを閉じています$`
+	txn.Close()
+}
+
+func usedAfterClose(ctx context.Context, client *spanner.Client) {
+	txn := client.ReadOnlyTransaction() // want `^ReadOnlyTransaction\.Close\(\) を defer で呼び出す必要があります$`
+	txn.Close()
+	iter := txn.Query(ctx, spanner.Statement{}) // want `^ReadOnlyTransaction\.Query\(\) が、35 行目の Close\(\) で閉じた後に呼ばれています$`
+	defer iter.Stop()
+}
+
+func deferred(ctx context.Context, client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	defer txn.Close()
+}
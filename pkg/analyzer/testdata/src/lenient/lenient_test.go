@@ -0,0 +1,94 @@
+package lenient
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for -lenient: non-deferred closes that run on every path are accepted
+
+var errEmpty = errors.New("empty")
+
+func goodStopOnEveryReturn(ctx context.Context, client *spanner.Client, skip bool) error {
+	iter := client.Single().Query(ctx, spanner.Statement{})
+	if skip {
+		iter.Stop()
+		return nil
+	}
+	iter.Stop()
+	return nil
+}
+
+func goodStopBeforeBranches(ctx context.Context, client *spanner.Client, skip bool) error {
+	iter := client.Single().Query(ctx, spanner.Statement{})
+	iter.Stop()
+	if skip {
+		return errEmpty
+	}
+	return nil
+}
+
+func goodErrorBranch(ctx context.Context, client *spanner.Client) error {
+	txn, err := client.BatchReadOnlyTransaction(ctx, spanner.StrongRead())
+	if err != nil {
+		return err
+	}
+	txn.Close()
+	return nil
+}
+
+func goodPanicPath(ctx context.Context, client *spanner.Client, ok bool) {
+	txn := client.ReadOnlyTransaction()
+	if !ok {
+		panic("unexpected")
+	}
+	txn.Close()
+}
+
+func closeTxn(txn *spanner.ReadOnlyTransaction) {
+	txn.Close()
+}
+
+func goodHelperOnEveryPath(client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	closeTxn(txn)
+}
+
+func badEarlyReturn(ctx context.Context, client *spanner.Client, skip bool) error {
+	iter := client.Single().Query(ctx, spanner.Statement{}) // want "RowIterator\\.Stop\\(\\) must be deferred"
+	if skip {
+		return errEmpty
+	}
+	iter.Stop()
+	return nil
+}
+
+func badConditionalClose(client *spanner.Client, done bool) {
+	txn := client.ReadOnlyTransaction() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred"
+	if done {
+		txn.Close()
+	}
+}
+
+func badLoop(ctx context.Context, client *spanner.Client, n int) {
+	for i := 0; i < n; i++ {
+		iter := client.Single().Query(ctx, spanner.Statement{}) // want "RowIterator\\.Stop\\(\\) must be deferred"
+		if i%2 == 0 {
+			continue
+		}
+		iter.Stop()
+	}
+}
+
+func badNeverClosed(client *spanner.Client) {
+	txn := client.ReadOnlyTransaction() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred"
+	_ = txn
+}
+
+func goodOnceOnEveryPath(client *spanner.Client, closeOnce *sync.Once) {
+	txn := client.ReadOnlyTransaction()
+	closeOnce.Do(txn.Close)
+}
@@ -0,0 +1,113 @@
+package lifecycle
+
+import (
+	"context"
+	"sync"
+
+	apiv1 "cloud.google.com/go/spanner/apiv1"
+	"go.uber.org/fx"
+)
+
+// Tests for closes registered with a lifecycle manager instead of deferred.
+// Clients returned to the caller are its to close, so none is returned here.
+
+func goodFxOnStop(lc fx.Lifecycle) error {
+	client, err := apiv1.NewClient(context.Background())
+	if err != nil {
+		return err
+	}
+	lc.Append(fx.Hook{
+		OnStop: func(ctx context.Context) error {
+			return client.Close()
+		},
+	})
+	return nil
+}
+
+func goodFxStopHook(lc fx.Lifecycle) error {
+	client, err := apiv1.NewClient(context.Background())
+	if err != nil {
+		return err
+	}
+	lc.Append(fx.StopHook(client.Close))
+	return nil
+}
+
+func goodFxHookVariable(lc fx.Lifecycle) error {
+	client, err := apiv1.NewClient(context.Background())
+	if err != nil {
+		return err
+	}
+	hook := fx.Hook{OnStop: func(context.Context) error { return client.Close() }}
+	lc.Append(hook)
+	return nil
+}
+
+func badFxOnStart(lc fx.Lifecycle) error {
+	client, err := apiv1.NewClient(context.Background()) // want "apiv1\\.Client\\.Close\\(\\) must be deferred"
+	if err != nil {
+		return err
+	}
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			_ = client
+			return nil
+		},
+	})
+	return nil
+}
+
+func badFxHookWithoutClose(lc fx.Lifecycle) error {
+	client, err := apiv1.NewClient(context.Background()) // want "apiv1\\.Client\\.Close\\(\\) must be deferred"
+	if err != nil {
+		return err
+	}
+	lc.Append(fx.Hook{
+		OnStop: func(ctx context.Context) error {
+			_ = client
+			return nil
+		},
+	})
+	return nil
+}
+
+// shutdown is an in-house lifecycle manager, registered with -lifecycle-hook
+type shutdown struct {
+	hooks []func() error
+}
+
+func (s *shutdown) Register(hooks ...func() error) {
+	s.hooks = append(s.hooks, hooks...)
+}
+
+func onExit(hook func()) {}
+
+func goodCustomRegister(s *shutdown) {
+	client, _ := apiv1.NewClient(context.Background())
+	s.Register(client.Close)
+}
+
+func goodCustomFunc() {
+	client, _ := apiv1.NewClient(context.Background())
+	onExit(func() { client.Close() })
+}
+
+func badNotRegistered() {
+	client, _ := apiv1.NewClient(context.Background()) // want "apiv1\\.Client\\.Close\\(\\) must be deferred"
+	hook := func() { client.Close() }
+	_ = hook
+}
+
+func goodFxOnStopOnce(lc fx.Lifecycle, closeOnce *sync.Once) error {
+	client, err := apiv1.NewClient(context.Background())
+	if err != nil {
+		return err
+	}
+	lc.Append(fx.Hook{
+		OnStop: func(ctx context.Context) error {
+			closeOnce.Do(func() { client.Close() })
+			return nil
+		},
+	})
+	return nil
+}
@@ -0,0 +1,13 @@
+package linedirective
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+//line excluded.tmpl:1
+func excludedLeak(ctx context.Context, client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	_ = txn
+}
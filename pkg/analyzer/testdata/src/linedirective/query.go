@@ -0,0 +1,16 @@
+package linedirective
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Lines mentioned by messages are those of the template too
+
+//line query.tmpl:30
+func stopTwice(ctx context.Context, client *spanner.Client) {
+	iter := client.Single().Query(ctx, spanner.Statement{})
+	defer iter.Stop() // want "deferred RowIterator\\.Stop\\(\\) closes the resource again after Stop\\(\\) at line 33"
+	iter.Stop()
+}
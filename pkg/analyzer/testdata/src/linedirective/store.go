@@ -0,0 +1,22 @@
+package linedirective
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for //line directives: reports point at the template the code was
+// generated from, while -exclude and -skip-tests also match the Go files.
+// The test sets -exclude=excluded.go and -skip-tests.
+
+//line store.tmpl:10
+func leak(ctx context.Context, client *spanner.Client) {
+	txn := client.ReadOnlyTransaction() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred"
+	_ = txn
+}
+
+func deferred(ctx context.Context, client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	defer txn.Close()
+}
@@ -0,0 +1,13 @@
+package linedirective
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+//line store_fixture.tmpl:1
+func testLeak(ctx context.Context, client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	_ = txn
+}
@@ -0,0 +1,12 @@
+package loopvar
+
+import "cloud.google.com/go/spanner"
+
+// Tests for deferred closures capturing loop variables with the current loop
+// semantics, declaring a new variable for each iteration
+
+func goodDeferredClosureRangeVar(iters []*spanner.RowIterator) {
+	for _, iter := range iters {
+		defer func() { iter.Stop() }()
+	}
+}
@@ -0,0 +1,72 @@
+//go:build go1.21
+
+package loopvar
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for deferred closures capturing loop variables with the loop
+// semantics of Go 1.21, sharing one variable across iterations
+
+func badDeferredClosureRangeVar(ctx context.Context, txn *spanner.ReadOnlyTransaction, stmts []spanner.Statement) {
+	var iters []*spanner.RowIterator
+	for _, stmt := range stmts {
+		iters = append(iters, txn.Query(ctx, stmt))
+	}
+	for _, iter := range iters {
+		defer func() { iter.Stop() }() // want "RowIterator\\.Stop\\(\\) in a deferred closure only closes the last value of loop variable iter before Go 1\\.22 \\(file version go1\\.21\\), defer iter\\.Stop\\(\\) directly"
+	}
+}
+
+func badDeferredClosureNilGuard(txns []*spanner.ReadOnlyTransaction) {
+	for _, txn := range txns {
+		defer func() {
+			if txn != nil {
+				txn.Close() // want "ReadOnlyTransaction\\.Close\\(\\) in a deferred closure only closes the last value of loop variable txn"
+			}
+		}()
+	}
+}
+
+func badDeferredClosureForVar(ctx context.Context, txn *spanner.ReadOnlyTransaction, n int) {
+	for i := 0; i < n; i++ {
+		iter := txn.Query(ctx, spanner.Statement{})
+		defer iter.Stop() // want "RowIterator\\.Stop\\(\\) deferred inside a loop only runs when the function returns"
+		for row, err := iter.Next(); err == nil; row, err = iter.Next() {
+			_ = row
+		}
+	}
+	for iter, i := txn.Query(ctx, spanner.Statement{}), 0; i < n; i++ {
+		defer func() { iter.Stop() }() // want "RowIterator\\.Stop\\(\\) in a deferred closure only closes the last value of loop variable iter" "RowIterator\\.Stop\\(\\) must be deferred on every path from the acquisition"
+	}
+}
+
+func goodDeferDirectly(iters []*spanner.RowIterator) {
+	for _, iter := range iters {
+		defer iter.Stop()
+	}
+}
+
+func goodDeferredClosureArgument(iters []*spanner.RowIterator) {
+	for _, iter := range iters {
+		defer func(iter *spanner.RowIterator) { iter.Stop() }(iter)
+	}
+}
+
+// Variables declared in the body are not shared by iterations, but the defer
+// holds them until the function returns
+func badDeferredClosureBodyVar(ctx context.Context, txn *spanner.ReadOnlyTransaction, stmts []spanner.Statement) {
+	for _, stmt := range stmts {
+		iter := txn.Query(ctx, stmt)
+		defer func() { iter.Stop() }() // want "RowIterator\\.Stop\\(\\) deferred inside a loop only runs when the function returns"
+	}
+}
+
+func goodDeferredClosureNolint(iters []*spanner.RowIterator) {
+	for _, iter := range iters {
+		defer func() { iter.Stop() }() //nolint:spannerclosecheck
+	}
+}
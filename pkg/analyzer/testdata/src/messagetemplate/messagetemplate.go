@@ -0,0 +1,29 @@
+package messagetemplate
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for -message-template: the test sets
+// "{{.Message}} (see RUNBOOK-12#{{.Category}})" and not-deferred=warning
+
+func unclosed(ctx context.Context, client *spanner.Client) {
+	txn := client.ReadOnlyTransaction() // want `^ReadOnlyTransaction\.Close\(\) must be deferred \(see RUNBOOK-12#unclosed\)$`
+	_ = txn
+}
+
+func notDeferred(ctx context.Context, client *spanner.Client) {
+	txn := client.ReadOnlyTransaction() // want `^ReadOnlyTransaction\.Close\(\) must be deferred.* \(see RUNBOOK-12#not-deferred\)$`
+	txn.Close()
+}
+
+func discarded(ctx context.Context, client *spanner.Client) {
+	_ = client.Single().Query(ctx, spanner.Statement{}) // want `^RowIterator acquired and discarded.* \(see RUNBOOK-12#discarded\)$`
+}
+
+func deferred(ctx context.Context, client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	defer txn.Close()
+}
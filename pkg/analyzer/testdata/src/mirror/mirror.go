@@ -0,0 +1,42 @@
+package mirror
+
+import (
+	"context"
+
+	"go.corp.example/third_party/spanner"
+	apiv1 "go.corp.example/third_party/spanner/apiv1"
+)
+
+// Tests for -spanner-path: the test registers go.corp.example/third_party/spanner
+// as a mirror of the Spanner package
+
+func goodMirrorDefer(client *spanner.Client) {
+	ctx := context.Background()
+	txn := client.ReadOnlyTransaction()
+	defer txn.Close()
+
+	iter := txn.Query(ctx, spanner.Statement{})
+	defer iter.Stop()
+}
+
+func badMirrorNoDefer(client *spanner.Client) {
+	ctx := context.Background()
+	txn := client.ReadOnlyTransaction() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred"
+
+	iter := txn.Query(ctx, spanner.Statement{}) // want "RowIterator\\.Stop\\(\\) must be deferred"
+	_ = iter
+}
+
+func goodMirrorSingle(client *spanner.Client) {
+	ctx := context.Background()
+	iter := client.Single().Query(ctx, spanner.Statement{})
+	defer iter.Stop()
+}
+
+func badMirrorGAPIC(ctx context.Context) {
+	client, err := apiv1.NewClient(ctx) // want "apiv1\\.Client\\.Close\\(\\) must be deferred"
+	if err != nil {
+		return
+	}
+	_ = client
+}
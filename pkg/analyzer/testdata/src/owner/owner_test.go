@@ -0,0 +1,83 @@
+package owner
+
+import (
+	"context"
+	"sync"
+
+	"cloud.google.com/go/spanner"
+	"golang.org/x/sync/errgroup"
+)
+
+func badStopFromWatcher(ctx context.Context, txn *spanner.ReadOnlyTransaction) {
+	iter := txn.Query(ctx, spanner.Statement{}) // want "RowIterator\\.Stop\\(\\) must be deferred in the goroutine"
+	go func() {
+		<-ctx.Done()
+		iter.Stop() // want "RowIterator\\.Stop\\(\\) runs in a different goroutine than the one acquiring the resource at line 12"
+	}()
+	for {
+		if _, err := iter.Next(); err != nil {
+			return
+		}
+	}
+}
+
+func badStopStatement(ctx context.Context, txn *spanner.ReadOnlyTransaction) {
+	iter := txn.Query(ctx, spanner.Statement{}) // want "RowIterator\\.Stop\\(\\) must be deferred in the goroutine"
+	_, _ = iter.Next()
+	go iter.Stop() // want "RowIterator\\.Stop\\(\\) runs in a different goroutine than the one acquiring the resource at line 25"
+}
+
+func badHandoffToGroup(ctx context.Context, client *spanner.Client) error {
+	txn := client.ReadOnlyTransaction()
+	var g errgroup.Group
+	g.Go(func() error {
+		defer txn.Close() // want "ReadOnlyTransaction\\.Close\\(\\) runs in a different goroutine than the one acquiring the resource at line 31"
+		return txn.Query(ctx, spanner.Statement{}).Do(func(*spanner.Row) error { return nil })
+	})
+	return g.Wait()
+}
+
+func badCloseOfGoroutineResult(ctx context.Context, client *spanner.Client) {
+	var txn *spanner.ReadOnlyTransaction
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		txn = client.ReadOnlyTransaction() //nolint:spannerclosecheck
+	}()
+	wg.Wait()
+	txn.Close() // want "ReadOnlyTransaction\\.Close\\(\\) runs in a different goroutine than the one acquiring the resource at line 46"
+}
+
+func goodAcquiredInGoroutine(ctx context.Context, client *spanner.Client) {
+	go func() {
+		txn := client.ReadOnlyTransaction()
+		defer txn.Close()
+		func() {
+			defer txn.Close()
+		}()
+	}()
+}
+
+func goodSharedThenClosedByOwner(ctx context.Context, client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	defer txn.Close()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_ = txn.Query(ctx, spanner.Statement{}).Do(func(*spanner.Row) error { return nil })
+	}()
+	wg.Wait()
+}
+
+func goodParameter(iter *spanner.RowIterator) {
+	defer iter.Stop()
+}
+
+func goodNolint(ctx context.Context, client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	go func() {
+		defer txn.Close() //nolint:spannerclosecheck
+	}()
+}
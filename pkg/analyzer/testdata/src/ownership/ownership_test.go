@@ -0,0 +1,66 @@
+package ownership
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+	"ownership/repo"
+)
+
+// Tests for ownership directives
+
+type handler struct {
+	txn  *spanner.ReadOnlyTransaction //spannerclosecheck:owns
+	iter *spanner.RowIterator
+}
+
+func (h *handler) Close() {
+	h.txn.Close()
+}
+
+// release hands iter to a background worker stopping it
+//
+//spannerclosecheck:closes iter
+func release(iter *spanner.RowIterator) {
+	go iter.Stop()
+}
+
+//spannerclosecheck:closes txn iterator // want "releaseAll has no parameter iterator to close"
+func releaseAll(txn *spanner.ReadOnlyTransaction, iter *spanner.RowIterator) {
+	go txn.Close()
+}
+
+func goodOwningField(client *spanner.Client) *handler {
+	return &handler{txn: client.ReadOnlyTransaction()}
+}
+
+func goodOwningFieldAssigned(client *spanner.Client, h *handler) {
+	txn := client.ReadOnlyTransaction()
+	h.txn = txn
+}
+
+func badNotOwningField(ctx context.Context, client *spanner.Client, h *handler) {
+	h.iter = client.Single().Query(ctx, spanner.Statement{}) // want "RowIterator\\.Stop\\(\\) must be deferred"
+}
+
+func goodOwningFieldOtherPackage(client *spanner.Client) *repo.Repository {
+	r := &repo.Repository{}
+	r.Txn = client.ReadOnlyTransaction()
+	return r
+}
+
+func goodClosesDirective(ctx context.Context, client *spanner.Client) {
+	iter := client.Single().Query(ctx, spanner.Statement{})
+	release(iter)
+}
+
+func goodClosesDirectiveOtherPackage(client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	repo.Finish(txn)
+}
+
+func badClosesDirectiveOtherParam(ctx context.Context, client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	iter := txn.Query(ctx, spanner.Statement{}) // want "RowIterator\\.Stop\\(\\) must be deferred"
+	releaseAll(txn, iter)
+}
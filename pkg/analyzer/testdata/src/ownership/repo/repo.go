@@ -0,0 +1,28 @@
+package repo
+
+import "cloud.google.com/go/spanner"
+
+// Repository owns the transaction it reads with
+type Repository struct {
+	//spannerclosecheck:owns
+	Txn *spanner.ReadOnlyTransaction
+
+	iter *spanner.RowIterator
+}
+
+// SetIter stores iter without taking ownership
+func (r *Repository) SetIter(iter *spanner.RowIterator) {
+	r.iter = iter
+}
+
+// Close closes the transaction of the repository
+func (r *Repository) Close() {
+	r.Txn.Close()
+}
+
+// Finish takes ownership of txn and closes it once pending reads complete
+//
+//spannerclosecheck:closes txn
+func Finish(txn *spanner.ReadOnlyTransaction) {
+	go txn.Close()
+}
@@ -0,0 +1,66 @@
+package perrequest
+
+import (
+	"context"
+	"net/http"
+
+	"cloud.google.com/go/spanner"
+	apiv1 "cloud.google.com/go/spanner/apiv1"
+)
+
+// Tests for -client-per-request
+
+const database = "projects/p/instances/i/databases/d"
+
+type server struct {
+	client *spanner.Client
+}
+
+func goodSharedClient(s *server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		txn := s.client.Single()
+		_ = txn
+	}
+}
+
+func goodProcessScope(ctx context.Context) (*server, error) {
+	client, err := spanner.NewClient(ctx, database)
+	if err != nil {
+		return nil, err
+	}
+	return &server{client: client}, nil
+}
+
+func badHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	client, err := spanner.NewClient(r.Context(), database) // want "spanner\\.NewClient\\(\\) is called for every HTTP request"
+	if err != nil {
+		return
+	}
+	defer client.Close()
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	client, _ := spanner.NewClientWithConfig(r.Context(), database, spanner.ClientConfig{}) // want "spanner\\.NewClientWithConfig\\(\\) is called for every HTTP request"
+	defer client.Close()
+}
+
+func badHandlerLiteral(mux *http.ServeMux) {
+	mux.HandleFunc("/users", func(w http.ResponseWriter, r *http.Request) {
+		client, _ := apiv1.NewClient(r.Context()) // want "apiv1\\.NewClient\\(\\) is called for every HTTP request"
+		defer client.Close()
+	})
+}
+
+func badNestedInHandler(w http.ResponseWriter, r *http.Request) {
+	open := func() *spanner.Client {
+		client, _ := spanner.NewClient(r.Context(), database) // want "spanner\\.NewClient\\(\\) is called for every HTTP request"
+		return client
+	}
+	client := open()
+	defer client.Close()
+}
+
+func goodNolint(w http.ResponseWriter, r *http.Request) {
+	client, _ := spanner.NewClient(r.Context(), database) //nolint:spannerclosecheck // admin endpoint, called once
+	defer client.Close()
+}
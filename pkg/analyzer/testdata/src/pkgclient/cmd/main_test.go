@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for -package-clients closed in main
+
+var (
+	mainClient    *spanner.Client
+	closureClient *spanner.Client
+	leakedClient  *spanner.Client
+)
+
+func main() {
+	ctx := context.Background()
+	var err error
+	if mainClient, err = spanner.NewClient(ctx, "db"); err != nil {
+		log.Fatal(err)
+	}
+	defer mainClient.Close()
+
+	if closureClient, err = spanner.NewClient(ctx, "db"); err != nil {
+		log.Fatal(err)
+	}
+	defer func() {
+		closureClient.Close()
+	}()
+
+	if leakedClient, err = spanner.NewClient(ctx, "db"); err != nil { // want "spanner\\.NewClient\\(\\) is assigned to package-level variable leakedClient"
+		log.Fatal(err)
+	}
+	run()
+	leakedClient.Close()
+}
+
+func run() {}
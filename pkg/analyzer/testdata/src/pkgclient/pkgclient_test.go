@@ -0,0 +1,63 @@
+package pkgclient
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+	"go.uber.org/fx"
+)
+
+// Tests for -package-clients
+
+var (
+	initClient     *spanner.Client
+	declClient, _  = spanner.NewClient(context.Background(), "db") // want "spanner\\.NewClient\\(\\) is assigned to package-level variable declClient, which is not closed on shutdown: defer declClient\\.Close\\(\\) in main, close it in a lifecycle hook or in a function declared with //spannerclosecheck:shutdown"
+	hookClient     *spanner.Client
+	shutdownClient *spanner.Client
+	helperClient   *spanner.Client
+	nolintClient   *spanner.Client
+)
+
+func init() {
+	var err error
+	initClient, err = spanner.NewClient(context.Background(), "db") // want "spanner\\.NewClient\\(\\) is assigned to package-level variable initClient"
+	if err != nil {
+		panic(err)
+	}
+}
+
+func setupHook(ctx context.Context, lc fx.Lifecycle) (err error) {
+	hookClient, err = spanner.NewClient(ctx, "db")
+	lc.Append(fx.Hook{OnStop: func(context.Context) error {
+		hookClient.Close()
+		return nil
+	}})
+	return err
+}
+
+func setupShutdown(ctx context.Context) (err error) {
+	shutdownClient, err = spanner.NewClient(ctx, "db")
+	return err
+}
+
+// Shutdown releases the clients of the package
+//
+//spannerclosecheck:shutdown
+func Shutdown() {
+	shutdownClient.Close()
+}
+
+// Closing in a function that is not a recognized shutdown path is not enough
+func setupHelper(ctx context.Context) (err error) {
+	helperClient, err = spanner.NewClient(ctx, "db") // want "spanner\\.NewClient\\(\\) is assigned to package-level variable helperClient"
+	return err
+}
+
+func closeHelper() {
+	helperClient.Close()
+}
+
+func setupNolint(ctx context.Context) (err error) {
+	nolintClient, err = spanner.NewClient(ctx, "db") //nolint:spannerclosecheck
+	return err
+}
@@ -0,0 +1,74 @@
+package returns
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+	"returns/store"
+)
+
+// Tests for resources returned by functions of other packages
+
+func goodInterfaceResultStopped(ctx context.Context, client *spanner.Client) {
+	rows := store.Query(ctx, client)
+	defer rows.Stop()
+}
+
+func goodInterfaceResultConsumed(ctx context.Context, client *spanner.Client) error {
+	return store.Query(ctx, client).Do(func(r *spanner.Row) error {
+		return nil
+	})
+}
+
+func badInterfaceResultNotStopped(ctx context.Context, client *spanner.Client) {
+	rows := store.Query(ctx, client) // want "RowIterator\\.Stop\\(\\) must be deferred"
+	_ = rows
+}
+
+func goodTupleResultStopped(ctx context.Context, client *spanner.Client) error {
+	iter, _, err := store.QueryAll(ctx, client)
+	if err != nil {
+		return err
+	}
+	defer iter.Stop()
+	return nil
+}
+
+func badTupleResultDiscarded(ctx context.Context, client *spanner.Client) (int, error) {
+	_, n, err := store.QueryWithCount(ctx, client) // want "RowIterator acquired and discarded"
+	return n, err
+}
+
+func badForwardedTupleResultDiscarded(ctx context.Context, client *spanner.Client) error {
+	_, _, err := store.QueryAll(ctx, client) // want "RowIterator acquired and discarded"
+	return err
+}
+
+func goodBorrowedResult(c *store.Cache) {
+	rows := c.Rows()
+	_ = rows
+}
+
+func goodNoResourceResult() (int, error) {
+	_, err := store.Count(nil)
+	return 0, err
+}
+
+func badNamedResultDiscarded(ctx context.Context, client *spanner.Client) (int, error) {
+	_, n, err := store.QueryNamed(ctx, client) // want "RowIterator acquired and discarded"
+	return n, err
+}
+
+func goodNamedResultClosed(client *spanner.Client) error {
+	txn, err := store.BeginNamed(client)
+	if err != nil {
+		return err
+	}
+	defer txn.Close()
+	return nil
+}
+
+func badNamedResultDiscardedTxn(client *spanner.Client) error {
+	_, err := store.BeginNamed(client) // want "ReadOnlyTransaction acquired and discarded"
+	return err
+}
@@ -0,0 +1,61 @@
+package store
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Rows is implemented by *spanner.RowIterator
+type Rows interface {
+	Do(f func(r *spanner.Row) error) error
+	Stop()
+}
+
+// Query returns an iterator its caller must stop
+func Query(ctx context.Context, client *spanner.Client) Rows {
+	return client.Single().Query(ctx, spanner.Statement{})
+}
+
+// QueryWithCount returns an iterator its caller must stop, along with a count
+func QueryWithCount(ctx context.Context, client *spanner.Client) (*spanner.RowIterator, int, error) {
+	iter := client.Single().Query(ctx, spanner.Statement{})
+	return iter, 0, nil
+}
+
+// QueryAll forwards the results of QueryWithCount
+func QueryAll(ctx context.Context, client *spanner.Client) (*spanner.RowIterator, int, error) {
+	return QueryWithCount(ctx, client)
+}
+
+// Cache holds an iterator it owns
+type Cache struct {
+	rows Rows
+}
+
+// Rows returns the iterator of the cache, which stays owned by the cache
+func (c *Cache) Rows() Rows {
+	return c.rows
+}
+
+// Count returns the number of rows of iter
+func Count(iter *spanner.RowIterator) (int, error) {
+	return 0, nil
+}
+
+// QueryNamed returns the iterator through a named result, stopping it on errors
+func QueryNamed(ctx context.Context, client *spanner.Client) (iter *spanner.RowIterator, n int, err error) {
+	defer func() {
+		if err != nil {
+			iter.Stop()
+		}
+	}()
+	iter = client.Single().Query(ctx, spanner.Statement{})
+	return
+}
+
+// BeginNamed returns a transaction through a named result
+func BeginNamed(client *spanner.Client) (txn *spanner.ReadOnlyTransaction, err error) {
+	txn = client.ReadOnlyTransaction()
+	return
+}
@@ -0,0 +1,65 @@
+package sessionpool
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/spanner"
+)
+
+const maxSessions = 50
+
+func badMinOpenedGreaterThanMaxOpened(ctx context.Context) (*spanner.Client, error) {
+	return spanner.NewClientWithConfig(ctx, "db", spanner.ClientConfig{
+		SessionPoolConfig: spanner.SessionPoolConfig{
+			MaxOpened: maxSessions,
+			MinOpened: 100, // want "SessionPoolConfig\\.MinOpened 100 is greater than MaxOpened 50: NewClientWithConfig fails"
+		},
+	})
+}
+
+func badNegativeHealthChecks() spanner.SessionPoolConfig {
+	return spanner.SessionPoolConfig{
+		HealthCheckWorkers:  -1,               // want "SessionPoolConfig\\.HealthCheckWorkers is negative"
+		HealthCheckInterval: -5 * time.Minute, // want "SessionPoolConfig\\.HealthCheckInterval is negative"
+	}
+}
+
+func badWriteSessions() spanner.SessionPoolConfig {
+	return spanner.SessionPoolConfig{WriteSessions: 1.5} // want "SessionPoolConfig\\.WriteSessions 1\\.5 is outside \\[0, 1\\]"
+}
+
+func badZeroMaxIdleUnderLoad() spanner.SessionPoolConfig {
+	return spanner.SessionPoolConfig{
+		MaxOpened: 400,
+		MaxIdle:   0, // want "SessionPoolConfig\\.MaxIdle is 0 with MaxOpened 400: sessions beyond MinOpened are deleted as soon as they are idle"
+	}
+}
+
+func goodConfig() spanner.SessionPoolConfig {
+	return spanner.SessionPoolConfig{
+		MaxOpened:     400,
+		MinOpened:     100,
+		MaxIdle:       50,
+		WriteSessions: 0.2,
+	}
+}
+
+func goodUnlimitedMaxOpened() spanner.SessionPoolConfig {
+	return spanner.SessionPoolConfig{MinOpened: 100}
+}
+
+func goodSmallPoolWithoutIdle() spanner.SessionPoolConfig {
+	return spanner.SessionPoolConfig{MaxOpened: 10, MaxIdle: 0}
+}
+
+func goodNonConstant(n uint64) spanner.SessionPoolConfig {
+	return spanner.SessionPoolConfig{MaxOpened: n, MinOpened: 100}
+}
+
+func goodNolint() spanner.SessionPoolConfig {
+	return spanner.SessionPoolConfig{
+		MaxOpened: 10,
+		MinOpened: 20, //nolint:spannerclosecheck
+	}
+}
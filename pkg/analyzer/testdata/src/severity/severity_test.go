@@ -0,0 +1,24 @@
+package severity
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for -severity: the test sets not-deferred=warning, RowIterator=warning
+// and discarded=error
+
+func notDeferred(ctx context.Context, client *spanner.Client) {
+	txn := client.ReadOnlyTransaction() // want "^ReadOnlyTransaction\\.Close\\(\\) must be deferred"
+	txn.Close()
+}
+
+func iteratorUnclosed(ctx context.Context, client *spanner.Client) {
+	iter := client.Single().Query(ctx, spanner.Statement{}) // want "^RowIterator\\.Stop\\(\\) must be"
+	_ = iter
+}
+
+func discardedIterator(ctx context.Context, client *spanner.Client) {
+	_ = client.Single().Query(ctx, spanner.Statement{}) // want "^RowIterator acquired and discarded"
+}
@@ -0,0 +1,9 @@
+package reconcile
+
+// Mock types for testing
+type Request struct {
+	Name      string
+	Namespace string
+}
+
+type Result struct{}
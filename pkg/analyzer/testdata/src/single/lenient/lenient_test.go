@@ -0,0 +1,33 @@
+package lenient
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for the Client.Single() suggestion with -lenient, accepting explicit closes
+
+func badSingleQueryExplicitClose(ctx context.Context, client *spanner.Client) error {
+	txn := client.ReadOnlyTransaction() // want "ReadOnlyTransaction is used for a single statement, use Client\\.Single\\(\\) instead"
+	iter := txn.Query(ctx, spanner.Statement{})
+	defer iter.Stop()
+	_, err := iter.Next()
+	txn.Close()
+	return err
+}
+
+func goodCloseBeforeStatement(ctx context.Context, client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	txn.Close()
+	iter := txn.Query(ctx, spanner.Statement{}) // want "ReadOnlyTransaction\\.Query\\(\\) is called after Close\\(\\) at line 22 closed it"
+	defer iter.Stop()
+}
+
+func goodClosedTwice(ctx context.Context, client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	defer txn.Close() // want "deferred ReadOnlyTransaction\\.Close\\(\\) closes the resource again after Close\\(\\) at line 32"
+	iter := txn.Query(ctx, spanner.Statement{})
+	defer iter.Stop()
+	txn.Close()
+}
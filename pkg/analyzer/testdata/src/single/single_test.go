@@ -0,0 +1,77 @@
+package single
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for the opt-in Client.Single() suggestion
+
+func badSingleQuery(client *spanner.Client) {
+	ctx := context.Background()
+	txn := client.ReadOnlyTransaction() // want "ReadOnlyTransaction is used for a single statement, use Client\\.Single\\(\\) instead"
+	defer txn.Close()
+
+	iter := txn.Query(ctx, spanner.Statement{})
+	defer iter.Stop()
+}
+
+func badSingleRead(client *spanner.Client) {
+	ctx := context.Background()
+	txn := client.ReadOnlyTransaction() // want "ReadOnlyTransaction is used for a single statement, use Client\\.Single\\(\\) instead"
+	defer txn.Close()
+
+	iter := txn.Read(ctx, "table", spanner.KeySets(), []string{"col"})
+	defer iter.Stop()
+}
+
+func goodMultipleStatements(client *spanner.Client) {
+	ctx := context.Background()
+	txn := client.ReadOnlyTransaction()
+	defer txn.Close()
+
+	iter1 := txn.Query(ctx, spanner.Statement{})
+	defer iter1.Stop()
+
+	iter2 := txn.Query(ctx, spanner.Statement{})
+	defer iter2.Stop()
+}
+
+func goodPassedToHelper(client *spanner.Client) {
+	ctx := context.Background()
+	txn := client.ReadOnlyTransaction()
+	defer txn.Close()
+
+	iter := txn.Query(ctx, spanner.Statement{})
+	defer iter.Stop()
+	useTxn(txn)
+}
+
+func goodAlreadySingle(client *spanner.Client) {
+	ctx := context.Background()
+	iter := client.Single().Query(ctx, spanner.Statement{})
+	defer iter.Stop()
+}
+
+func goodSingleNolint(client *spanner.Client) {
+	ctx := context.Background()
+	txn := client.ReadOnlyTransaction() //nolint:spannerclosecheck
+	defer txn.Close()
+
+	iter := txn.Query(ctx, spanner.Statement{})
+	defer iter.Stop()
+}
+
+func goodStatementInLoop(client *spanner.Client, stmts []spanner.Statement) {
+	ctx := context.Background()
+	txn := client.ReadOnlyTransaction()
+	defer txn.Close()
+
+	// The transaction runs a statement on every iteration
+	for _, stmt := range stmts {
+		_ = txn.Query(ctx, stmt).Do(func(r *spanner.Row) error { return nil })
+	}
+}
+
+func useTxn(txn *spanner.ReadOnlyTransaction) {}
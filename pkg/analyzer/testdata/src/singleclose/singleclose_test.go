@@ -0,0 +1,42 @@
+package singleclose
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for closes of transactions releasing themselves
+
+func badDeferCloseSingle(ctx context.Context, client *spanner.Client) {
+	txn := client.Single()
+	defer txn.Close() // want "ReadOnlyTransaction\\.Close\\(\\) is redundant: the ReadOnlyTransaction from Client\\.Single\\(\\) releases itself"
+
+	iter := txn.Query(ctx, spanner.Statement{})
+	defer iter.Stop()
+}
+
+func badCloseSingle(ctx context.Context, client *spanner.Client) error {
+	txn := client.Single()
+	err := txn.Query(ctx, spanner.Statement{}).Do(func(*spanner.Row) error { return nil })
+	txn.Close() // want "ReadOnlyTransaction\\.Close\\(\\) is redundant"
+	return err
+}
+
+func goodSingleWithoutClose(ctx context.Context, client *spanner.Client) {
+	iter := client.Single().Query(ctx, spanner.Statement{})
+	defer iter.Stop()
+}
+
+func goodCloseReadOnlyTransaction(ctx context.Context, client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	defer txn.Close()
+
+	iter := txn.Query(ctx, spanner.Statement{})
+	defer iter.Stop()
+}
+
+func goodNolintCloseSingle(client *spanner.Client) {
+	txn := client.Single()
+	defer txn.Close() //nolint:spannerclosecheck
+}
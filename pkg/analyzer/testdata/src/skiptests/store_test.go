@@ -0,0 +1,12 @@
+package skiptests
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+func readInTest(ctx context.Context, client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	txn.Close()
+}
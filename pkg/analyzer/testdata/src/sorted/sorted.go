@@ -0,0 +1,26 @@
+package sorted
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for the order of the reports: checks visit the SSA of functions and
+// closures, and run one after the other, while reports follow the source
+
+func closureFirst(ctx context.Context, client *spanner.Client) {
+	func() {
+		txn := client.ReadOnlyTransaction() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred"
+		txn.Close()
+	}()
+	iter := client.Single().Query(ctx, spanner.Statement{}) // want "RowIterator\\.Stop\\(\\) must be deferred"
+	iter.Stop()
+}
+
+func discardedFirst(ctx context.Context, client *spanner.Client) {
+	_ = client.Single().Query(ctx, spanner.Statement{}) // want "RowIterator acquired and discarded"
+	txn := client.ReadOnlyTransaction()                 // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred"
+	txn.Close()
+	txn.Close() // want "closes the resource again"
+}
@@ -0,0 +1,68 @@
+package streamcancel
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"cloud.google.com/go/spanner"
+)
+
+func badCancelNotDeferred(ctx context.Context, txn *spanner.ReadOnlyTransaction) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute) // want "cancel of context\\.WithTimeout\\(\\) is not deferred: the context is used by the streaming read ReadOnlyTransaction\\.Query\\(\\) at line 13"
+	iter := txn.Query(ctx, spanner.Statement{})
+	defer iter.Stop()
+	if _, err := iter.Next(); err != nil {
+		return err
+	}
+	cancel()
+	return nil
+}
+
+func badCancelNeverCalled(ctx context.Context, txn *spanner.ReadOnlyTransaction) {
+	ctx, cancel := context.WithCancel(ctx) // want "cancel of context\\.WithCancel\\(\\) is not deferred: the context is used by the streaming read ReadOnlyTransaction\\.Read\\(\\) at line 24"
+	_ = txn.Read(ctx, "Users", nil, []string{"id"}).Do(func(*spanner.Row) error {
+		return errors.New("stop")
+	})
+	if ctx.Err() != nil {
+		cancel()
+	}
+}
+
+func goodCancelDeferred(ctx context.Context, txn *spanner.ReadOnlyTransaction) {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	iter := txn.Query(ctx, spanner.Statement{})
+	defer iter.Stop()
+}
+
+func goodCancelDeferredInClosure(ctx context.Context, txn *spanner.ReadOnlyTransaction) {
+	ctx, cancel := context.WithDeadline(ctx, time.Now().Add(time.Minute))
+	defer func() {
+		cancel()
+	}()
+	iter := txn.Query(ctx, spanner.Statement{})
+	defer iter.Stop()
+}
+
+func goodCancelReturned(ctx context.Context, txn *spanner.ReadOnlyTransaction) (*spanner.RowIterator, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	return txn.Query(ctx, spanner.Statement{}), cancel //nolint:spannerclosecheck
+}
+
+func goodNotStreaming(ctx context.Context, client *spanner.Client) {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	txn, err := client.BatchReadOnlyTransaction(ctx, spanner.StrongRead())
+	cancel()
+	if err != nil {
+		return
+	}
+	defer txn.Close()
+}
+
+func goodNolint(ctx context.Context, txn *spanner.ReadOnlyTransaction) {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute) //nolint:spannerclosecheck
+	iter := txn.Query(ctx, spanner.Statement{})
+	defer iter.Stop()
+	cancel()
+}
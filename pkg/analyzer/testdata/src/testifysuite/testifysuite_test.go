@@ -0,0 +1,75 @@
+package testifysuite
+
+import (
+	"context"
+	"testing"
+
+	"cloud.google.com/go/spanner"
+	"github.com/stretchr/testify/suite"
+)
+
+// Tests for resources acquired in testify suite setup and closed in teardown
+
+type txnSuite struct {
+	suite.Suite
+	client *spanner.Client
+	txn    *spanner.ReadOnlyTransaction
+	iter   *spanner.RowIterator
+}
+
+func TestTxnSuite(t *testing.T) {
+	suite.Run(t, &txnSuite{client: &spanner.Client{}})
+}
+
+func (s *txnSuite) SetupTest() {
+	s.txn = s.client.ReadOnlyTransaction()
+	s.iter = s.txn.Query(context.Background(), spanner.Statement{}) // want "RowIterator\\.Stop\\(\\) must be deferred"
+}
+
+func (s *txnSuite) TearDownTest() {
+	if s.txn != nil {
+		s.txn.Close()
+	}
+}
+
+type batchSuite struct {
+	suite.Suite
+	client *spanner.Client
+	txn    *spanner.BatchReadOnlyTransaction
+}
+
+func (s *batchSuite) SetupSuite() {
+	txn, err := s.client.BatchReadOnlyTransaction(context.Background(), spanner.StrongRead())
+	if err != nil {
+		s.T().Fatal(err)
+	}
+	s.txn = txn
+}
+
+func (s *batchSuite) TearDownSuite() {
+	s.txn.Close()
+}
+
+// notSuite has suite method names but is not a testify suite
+type notSuite struct {
+	client *spanner.Client
+	txn    *spanner.ReadOnlyTransaction
+}
+
+func (s *notSuite) SetupTest() {
+	s.txn = s.client.ReadOnlyTransaction() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred"
+}
+
+func (s *notSuite) TearDownTest() {
+	s.txn.Close()
+}
+
+type noTeardownSuite struct {
+	suite.Suite
+	client *spanner.Client
+	txn    *spanner.ReadOnlyTransaction
+}
+
+func (s *noTeardownSuite) SetupTest() {
+	s.txn = s.client.ReadOnlyTransaction() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred"
+}
@@ -0,0 +1,14 @@
+package prod
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// A package without tests is skipped entirely
+
+func read(ctx context.Context, client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	txn.Close()
+}
@@ -0,0 +1,14 @@
+package testsonly
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for -tests-only: only the reports of store_test.go are kept
+
+func read(ctx context.Context, client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	txn.Close()
+}
@@ -0,0 +1,15 @@
+package testsonly
+
+import (
+	"context"
+	"testing"
+
+	"cloud.google.com/go/spanner"
+)
+
+func TestRead(t *testing.T) {
+	ctx := context.Background()
+	var client *spanner.Client
+	iter := client.Single().Query(ctx, spanner.Statement{}) // want "RowIterator\\.Stop\\(\\) must be deferred"
+	iter.Stop()
+}
@@ -0,0 +1,26 @@
+package vendored
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Tests for a vendored copy of the Spanner package
+
+func goodVendoredDefer(client *spanner.Client) {
+	ctx := context.Background()
+	txn := client.ReadOnlyTransaction()
+	defer txn.Close()
+
+	iter := txn.Query(ctx, spanner.Statement{})
+	defer iter.Stop()
+}
+
+func badVendoredNoDefer(client *spanner.Client) {
+	ctx := context.Background()
+	txn := client.ReadOnlyTransaction() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred"
+
+	iter := txn.Query(ctx, spanner.Statement{}) // want "RowIterator\\.Stop\\(\\) must be deferred"
+	_ = iter
+}
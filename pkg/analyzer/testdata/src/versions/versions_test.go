@@ -0,0 +1,32 @@
+package versions
+
+import (
+	"context"
+
+	spanner "cloud.google.com/go/spanner/v2"
+)
+
+// Tests for a major version of the Spanner package
+
+func goodMajorVersionDefer(client *spanner.Client) {
+	ctx := context.Background()
+	txn := client.ReadOnlyTransaction()
+	defer txn.Close()
+
+	iter := txn.Query(ctx, spanner.Statement{})
+	defer iter.Stop()
+}
+
+func badMajorVersionNoDefer(client *spanner.Client) {
+	ctx := context.Background()
+	txn := client.ReadOnlyTransaction() // want "ReadOnlyTransaction\\.Close\\(\\) must be deferred"
+
+	iter := txn.Query(ctx, spanner.Statement{}) // want "RowIterator\\.Stop\\(\\) must be deferred"
+	_ = iter
+}
+
+func goodMajorVersionSingle(client *spanner.Client) {
+	ctx := context.Background()
+	iter := client.Single().Query(ctx, spanner.Statement{})
+	defer iter.Stop()
+}
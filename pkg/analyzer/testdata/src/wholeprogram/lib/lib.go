@@ -0,0 +1,38 @@
+package lib
+
+import (
+	"cloud.google.com/go/spanner"
+
+	"example.com/ourdb"
+)
+
+// Open returns a transaction assigned in a callback, which the facts of
+// returned resources do not follow
+func Open(db *ourdb.DB) *ourdb.Txn {
+	var txn *ourdb.Txn
+	run(func() {
+		txn = db.Begin()
+	})
+	return txn
+}
+
+func run(f func()) {
+	f()
+}
+
+// Drain stops iter with a deferred method value, which the facts of closed
+// parameters do not follow
+func Drain(iter *spanner.RowIterator) {
+	stop := iter.Stop
+	defer stop()
+	for {
+		if _, err := iter.Next(); err != nil {
+			return
+		}
+	}
+}
+
+// DrainAll stops iter through Drain
+func DrainAll(iter *spanner.RowIterator) {
+	Drain(iter)
+}
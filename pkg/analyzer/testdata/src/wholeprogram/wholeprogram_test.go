@@ -0,0 +1,31 @@
+package wholeprogram
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+
+	"example.com/ourdb"
+	"wholeprogram/lib"
+)
+
+func leak(db *ourdb.DB) {
+	txn := lib.Open(db) // want "ourdb\\.Txn\\.Release\\(\\) must be deferred"
+	_, _ = txn.Rows()
+}
+
+func released(db *ourdb.DB) {
+	txn := lib.Open(db)
+	defer txn.Release()
+	_, _ = txn.Rows()
+}
+
+func drained(ctx context.Context, txn *spanner.ReadOnlyTransaction) {
+	iter := txn.Query(ctx, spanner.Statement{})
+	lib.Drain(iter)
+}
+
+func drainedAll(ctx context.Context, txn *spanner.ReadOnlyTransaction) {
+	iter := txn.Query(ctx, spanner.Statement{})
+	lib.DrainAll(iter)
+}
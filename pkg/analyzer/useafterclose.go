@@ -0,0 +1,239 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/types"
+	"slices"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ssa"
+)
+
+// useAfterCloseMessage reports a method called on a resource after a
+// non-deferred close of it has run
+const useAfterCloseMessage = "%s.%s() is called after %s() at line %d closed it"
+
+// checkUseAfterClose reports methods called on a resource after its close
+// method has executed on some path leading to the call:
+//
+//	txn.Close()
+//	iter := txn.Query(ctx, stmt) // queries a closed transaction
+//
+// Deferred closes run when the function returns and are not considered.
+// A path through the acquisition of the value again, as in the next iteration
+// of a loop, reaches a new resource.
+func checkUseAfterClose(pass *analysis.Pass, fn *ssa.Function, spannerTypes map[*types.Named]*ResourceType) {
+	if fn == nil || isGeneratedFile(pass, fn.Pos()) {
+		return
+	}
+
+	for _, block := range fn.Blocks {
+		for _, instr := range block.Instrs {
+			closeCall, ok := instr.(*ssa.Call)
+			if !ok {
+				continue
+			}
+			val := methodReceiver(closeCall.Common())
+			if val == nil {
+				continue
+			}
+			rt := getSpannerType(val.Type(), spannerTypes)
+			if rt == nil || !isCloseCall(closeCall.Common(), val, rt) {
+				continue
+			}
+			reportUsesAfterClose(pass, val, rt, closeCall)
+		}
+	}
+}
+
+// reportUsesAfterClose reports the method calls on val reachable from
+// closeCall, including calls on the values val flows into through the phi
+// nodes of a loop
+func reportUsesAfterClose(pass *analysis.Pass, val ssa.Value, rt *ResourceType, closeCall *ssa.Call) {
+	// Closes inside the loop reading the resource are reported by checkCloseInNextLoop
+	if nextCallInLoop(pass, val, closeCall) != nil {
+		return
+	}
+	closeLine := pass.Fset.Position(closeCall.Pos()).Line
+	aliases := closedAliases(closeCall, val)
+	for _, alias := range aliasValues(val, aliases) {
+		for _, ref := range *alias.Referrers() {
+			use, ok := ref.(ssa.CallInstruction)
+			if !ok || use == closeCall || use.Parent() != closeCall.Parent() {
+				continue
+			}
+			if _, isDefer := use.(*ssa.Defer); isDefer {
+				continue
+			}
+			method := methodName(use.Common(), alias)
+			if method == "" || rt.isCloseMethod(method) {
+				continue
+			}
+			sameBlockAfter := alias == val && use.Block() == closeCall.Block() && dominates(closeCall, use)
+			if !sameBlockAfter && !aliases[use.Block()][alias] {
+				continue
+			}
+			message := fmt.Sprintf(useAfterCloseMessage, rt.QualifiedName(), method, rt.closeMethodOf(closeCall.Common(), val), closeLine)
+			if inPreviousIteration(pass, closeCall, use) {
+				message += previousIterationMessage
+			}
+			pass.Report(analysis.Diagnostic{
+				Pos:      use.Pos(),
+				Category: categoryUseAfterClose,
+				Message:  message,
+				Related: []analysis.RelatedInformation{{
+					Pos:     closeCall.Pos(),
+					Message: "closed here",
+				}},
+			})
+		}
+	}
+}
+
+// previousIterationMessage completes useAfterCloseMessage for uses preceding
+// the close in the loop enclosing both, which run again in the next iteration
+const previousIterationMessage = " in a previous iteration of the loop"
+
+// closedAliases returns, for every block reachable from closeCall, the values
+// holding the resource val closed by closeCall when the block is entered.
+// They include the phi nodes val flows into, as when a variable is carried to
+// the next iteration of a loop, and exclude values defined again on the way,
+// as when the next iteration acquires a new resource.
+func closedAliases(closeCall ssa.Instruction, val ssa.Value) map[*ssa.BasicBlock]map[ssa.Value]bool {
+	aliases := make(map[*ssa.BasicBlock]map[ssa.Value]bool)
+
+	// enter adds the aliases in holding flowing from pred into b, and reports
+	// whether any was new
+	enter := func(pred, b *ssa.BasicBlock, holding map[ssa.Value]bool) bool {
+		if aliases[b] == nil {
+			aliases[b] = make(map[ssa.Value]bool)
+		}
+		added := false
+		add := func(v ssa.Value) {
+			if !aliases[b][v] {
+				aliases[b][v] = true
+				added = true
+			}
+		}
+		edge := slices.Index(b.Preds, pred)
+		for v := range holding {
+			if instr, ok := v.(ssa.Instruction); !ok || instr.Block() != b {
+				add(v)
+			}
+		}
+		for _, instr := range b.Instrs {
+			phi, ok := instr.(*ssa.Phi)
+			if !ok {
+				break
+			}
+			if edge >= 0 && edge < len(phi.Edges) && holding[phi.Edges[edge]] {
+				add(phi)
+			}
+		}
+		return added
+	}
+
+	start := closeCall.Block()
+	var stack []*ssa.BasicBlock
+	for _, succ := range start.Succs {
+		if enter(start, succ, map[ssa.Value]bool{val: true}) {
+			stack = append(stack, succ)
+		}
+	}
+	for len(stack) > 0 {
+		b := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, succ := range b.Succs {
+			if enter(b, succ, aliases[b]) {
+				stack = append(stack, succ)
+			}
+		}
+	}
+	return aliases
+}
+
+// aliasValues returns val and the distinct values of aliases, in source order
+func aliasValues(val ssa.Value, aliases map[*ssa.BasicBlock]map[ssa.Value]bool) []ssa.Value {
+	seen := map[ssa.Value]bool{val: true}
+	values := []ssa.Value{val}
+	for _, holding := range aliases {
+		for v := range holding {
+			if !seen[v] {
+				seen[v] = true
+				values = append(values, v)
+			}
+		}
+	}
+	slices.SortFunc(values, func(a, b ssa.Value) int {
+		if a.Pos() != b.Pos() {
+			return int(a.Pos() - b.Pos())
+		}
+		return strings.Compare(a.Name(), b.Name())
+	})
+	return values
+}
+
+// inPreviousIteration checks if use precedes closeCall within the innermost
+// loop enclosing both, so that it only follows the close in the next iteration
+func inPreviousIteration(pass *analysis.Pass, closeCall *ssa.Call, use ssa.Instruction) bool {
+	body := funcBody(closeCall.Parent())
+	if body == nil || use.Pos() >= closeCall.Pos() {
+		return false
+	}
+	loop := enclosingLoop(pass, closeCall.Pos(), body)
+	return loop != nil && use.Pos() >= loop.Pos() && use.Pos() < loop.End()
+}
+
+// methodReceiver returns the receiver of the method called by common, or nil
+// if common does not call a method
+func methodReceiver(common *ssa.CallCommon) ssa.Value {
+	if common.IsInvoke() {
+		return common.Value
+	}
+	callee := common.StaticCallee()
+	if callee == nil || callee.Signature.Recv() == nil || len(common.Args) == 0 {
+		return nil
+	}
+	return common.Args[0]
+}
+
+// methodName returns the name of the method common calls on val, or "" if it
+// does not call a method on val
+func methodName(common *ssa.CallCommon, val ssa.Value) string {
+	if methodReceiver(common) != val {
+		return ""
+	}
+	if common.IsInvoke() {
+		return common.Method.Name()
+	}
+	return common.StaticCallee().Name()
+}
+
+// reachableAfter checks if use can run after from on a path that does not
+// acquire val again. An instruction runs after itself only in a loop.
+func reachableAfter(from, use ssa.Instruction, val ssa.Value) bool {
+	if from != use && from.Block() == use.Block() && dominates(from, use) {
+		return true
+	}
+
+	var def *ssa.BasicBlock
+	if instr, ok := val.(ssa.Instruction); ok {
+		def = instr.Block()
+	}
+	seen := make(map[*ssa.BasicBlock]bool)
+	stack := append([]*ssa.BasicBlock(nil), from.Block().Succs...)
+	for len(stack) > 0 {
+		b := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[b] || b == def {
+			continue
+		}
+		seen[b] = true
+		if b == use.Block() {
+			return true
+		}
+		stack = append(stack, b.Succs...)
+	}
+	return false
+}
@@ -0,0 +1,248 @@
+package analyzer
+
+import (
+	"errors"
+	"go/build"
+	"go/types"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+// ProgramPackages, when set, are the packages the driver loaded, along with
+// the syntax of their dependencies, whose SSA Options.WholeProgram builds
+// once, so that the dependencies are those of the driver, with its build
+// flags, environment and working directory. The spannerclosecheck command
+// sets it when it loads packages itself; drivers that do not, such as
+// golangci-lint or go vet, reject Options.WholeProgram.
+var ProgramPackages []*packages.Package
+
+// errWholeProgram is the error of Options.WholeProgram under drivers that do
+// not set ProgramPackages
+var errWholeProgram = errors.New("whole-program: only the spannerclosecheck command loads the dependencies of packages from source, give it -whole-program on its command line")
+
+// checkWholeProgram returns errWholeProgram if opts selects whole-program
+// mode the driver does not support
+func checkWholeProgram(opts *Options) error {
+	if opts.WholeProgram && ProgramPackages == nil {
+		return errWholeProgram
+	}
+	return nil
+}
+
+// wholeProgram holds the SSA of ProgramPackages, shared by every package
+// analyzed by the process. The functions of the packages analyzers see
+// through export data have no bodies, so their counterparts are looked up
+// here by name.
+var wholeProgram = struct {
+	sync.Mutex
+	built bool
+	// funcs are the package-level functions and methods with a body, by
+	// their ssa.Function.String()
+	funcs map[string]*ssa.Function
+}{funcs: make(map[string]*ssa.Function)}
+
+// loadWholeProgram builds the SSA of ProgramPackages and of their
+// dependencies, unless an earlier package built it
+func loadWholeProgram() {
+	wholeProgram.Lock()
+	defer wholeProgram.Unlock()
+	if wholeProgram.built {
+		return
+	}
+	wholeProgram.built = true
+
+	// Packages with errors have no SSA to build, and the standard library
+	// acquires no resources
+	prog, _ := ssautil.AllPackages(ProgramPackages, ssa.InstantiateGenerics)
+	packages.Visit(ProgramPackages, nil, func(p *packages.Package) {
+		pkg := prog.Package(p.Types)
+		if pkg == nil || len(p.Errors) > 0 || p.IllTyped || isStandardPackage(p) {
+			return
+		}
+		pkg.Build()
+		for _, member := range pkg.Members {
+			switch member := member.(type) {
+			case *ssa.Function:
+				addProgramFunc(member)
+			case *ssa.Type:
+				for _, t := range []types.Type{member.Type(), types.NewPointer(member.Type())} {
+					mset := prog.MethodSets.MethodSet(t)
+					for i := range mset.Len() {
+						if fn := prog.MethodValue(mset.At(i)); fn != nil && fn.Pkg == pkg {
+							addProgramFunc(fn)
+						}
+					}
+				}
+			}
+		}
+	})
+}
+
+// isStandardPackage checks if p is a package of the standard library
+func isStandardPackage(p *packages.Package) bool {
+	return len(p.GoFiles) > 0 && strings.HasPrefix(p.GoFiles[0], filepath.Join(build.Default.GOROOT, "src")+string(filepath.Separator))
+}
+
+// addProgramFunc indexes fn if it has a body
+func addProgramFunc(fn *ssa.Function) {
+	if len(fn.Blocks) > 0 && fn.Synthetic == "" {
+		wholeProgram.funcs[fn.String()] = fn
+	}
+}
+
+// programBody returns fn if it has a body, or its counterpart loaded in
+// whole-program mode, or nil
+func programBody(fn *ssa.Function) *ssa.Function {
+	if fn == nil {
+		return nil
+	}
+	if origin := fn.Origin(); origin != nil {
+		fn = origin
+	}
+	if len(fn.Blocks) > 0 {
+		return fn
+	}
+	wholeProgram.Lock()
+	defer wholeProgram.Unlock()
+	return wholeProgram.funcs[fn.String()]
+}
+
+// isReturnedByProgramCallee checks if val is the result of a call to a
+// function of another package that returns a resource it acquired, following
+// its body and the functions it calls in whole-program mode:
+//
+//	func Open(db *ourdb.DB) *ourdb.Txn {
+//		var txn *ourdb.Txn
+//		run(func() { txn = db.Begin() })
+//		return txn
+//	}
+func isReturnedByProgramCallee(val ssa.Value, rt *ResourceType, opts *Options) bool {
+	index := 0
+	if extract, ok := val.(*ssa.Extract); ok {
+		val, index = extract.Tuple, extract.Index
+	}
+	call, ok := val.(*ssa.Call)
+	if !ok || call.Common().StaticCallee() == nil || len(call.Common().StaticCallee().Blocks) > 0 {
+		return false
+	}
+	return returnsAcquired(programBody(call.Common().StaticCallee()), index, rt, opts, 1)
+}
+
+// returnsAcquired checks if fn returns at result index a resource it acquired
+func returnsAcquired(fn *ssa.Function, index int, rt *ResourceType, opts *Options, depth int) bool {
+	if fn == nil || depth > maxHelperDepth {
+		return false
+	}
+	for _, block := range fn.Blocks {
+		for _, instr := range block.Instrs {
+			if ret, ok := instr.(*ssa.Return); ok && index < len(ret.Results) &&
+				isAcquiredIn(ret.Results[index], rt, opts, depth, make(map[ssa.Value]bool)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isAcquiredIn checks if val, a value returned by a function of the program,
+// holds a resource acquired by it: the result of an acquiring call, possibly
+// stored into a variable captured by function literals
+func isAcquiredIn(val ssa.Value, rt *ResourceType, opts *Options, depth int, seen map[ssa.Value]bool) bool {
+	if seen[val] {
+		return false
+	}
+	seen[val] = true
+
+	switch v := val.(type) {
+	case *ssa.Extract:
+		if call, ok := v.Tuple.(*ssa.Call); ok {
+			return isAcquiringCall(call, v.Index, v, rt, opts, depth)
+		}
+	case *ssa.Call:
+		return isAcquiringCall(v, 0, v, rt, opts, depth)
+	case *ssa.Phi:
+		for _, edge := range v.Edges {
+			if isAcquiredIn(edge, rt, opts, depth, seen) {
+				return true
+			}
+		}
+	case *ssa.MakeInterface:
+		return isAcquiredIn(v.X, rt, opts, depth, seen)
+	case *ssa.ChangeType:
+		return isAcquiredIn(v.X, rt, opts, depth, seen)
+	case *ssa.UnOp:
+		// Variables, possibly assigned in function literals
+		for _, stored := range acquisitions(v) {
+			if stored != v && isAcquiredIn(stored, rt, opts, depth, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isAcquiringCall checks if the result at index of call, held by val, is an
+// acquisition, or is returned acquired by the function called
+func isAcquiringCall(call *ssa.Call, index int, val ssa.Value, rt *ResourceType, opts *Options, depth int) bool {
+	if isFromExemptConstructor(val, rt, opts) {
+		return false
+	}
+	if producedBy(val, rt.Constructors) || producedBy(val, opts.AcquireFuncs) {
+		return true
+	}
+	return returnsAcquired(programBody(call.Common().StaticCallee()), index, rt, opts, depth+1)
+}
+
+// isClosedByProgramCallee checks if val is passed to a function of another
+// package that defers closing it, following its body and the functions it
+// calls in whole-program mode, as in:
+//
+//	func Drain(iter *spanner.RowIterator) {
+//		stop := iter.Stop
+//		defer stop()
+//		...
+//	}
+func isClosedByProgramCallee(val ssa.Value, rt *ResourceType) bool {
+	if val.Referrers() == nil {
+		return false
+	}
+	for _, ref := range *val.Referrers() {
+		call, ok := ref.(*ssa.Call)
+		if !ok || call.Common().StaticCallee() == nil || len(call.Common().StaticCallee().Blocks) > 0 {
+			continue
+		}
+		if passedToDeferringClose(call.Common(), val, rt, 1) {
+			return true
+		}
+	}
+	return false
+}
+
+// passedToDeferringClose checks if common passes val to a function of the
+// program deferring closing it, itself or through the functions it calls
+func passedToDeferringClose(common *ssa.CallCommon, val ssa.Value, rt *ResourceType, depth int) bool {
+	callee := programBody(common.StaticCallee())
+	if callee == nil || depth > maxHelperDepth {
+		return false
+	}
+	for i, arg := range common.Args {
+		if arg != val || i >= len(callee.Params) {
+			continue
+		}
+		param := callee.Params[i]
+		if findDeferredClose(param, rt) != nil {
+			return true
+		}
+		for _, ref := range *param.Referrers() {
+			if call, ok := ref.(*ssa.Call); ok && passedToDeferringClose(call.Common(), param, rt, depth+1) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,282 @@
+package analyzer
+
+import (
+	"go/token"
+	"go/types"
+	"slices"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// maxEmbedDepth limits how deep embedded fields are followed
+const maxEmbedDepth = 3
+
+// embeddedResourceType returns the resource type embedded in a wrapper struct,
+// e.g. type txn struct { *spanner.ReadOnlyTransaction }
+// Pointers must already be stripped from t.
+func embeddedResourceType(t types.Type, spannerTypes map[*types.Named]*ResourceType, depth int) *ResourceType {
+	if depth > maxEmbedDepth {
+		return nil
+	}
+	st, ok := t.Underlying().(*types.Struct)
+	if !ok {
+		return nil
+	}
+
+	for i := 0; i < st.NumFields(); i++ {
+		field := st.Field(i)
+		if !field.Embedded() {
+			continue
+		}
+		if named, ok := derefType(field.Type()).(*types.Named); ok {
+			if rt, ok := spannerTypes[named]; ok {
+				return rt
+			}
+		}
+		if rt := embeddedResourceType(derefType(field.Type()), spannerTypes, depth+1); rt != nil {
+			return rt
+		}
+	}
+
+	return nil
+}
+
+// derefType strips a pointer from t
+func derefType(t types.Type) types.Type {
+	if ptr, ok := t.(*types.Pointer); ok {
+		return ptr.Elem()
+	}
+	return t
+}
+
+// isResourceType checks if t is the resource type rt itself, not a wrapper
+func isResourceType(t types.Type, rt *ResourceType) bool {
+	named, ok := derefType(t).(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return false
+	}
+	return named.Obj().Name() == rt.Name && matchesPkgPath(named.Obj().Pkg().Path(), rt.PkgPath)
+}
+
+// isEmbeddedResourceField checks if fa addresses an embedded field holding rt
+// or a wrapper of rt
+func isEmbeddedResourceField(fa *ssa.FieldAddr, rt *ResourceType) bool {
+	st, ok := derefType(fa.X.Type()).Underlying().(*types.Struct)
+	if !ok || fa.Field >= st.NumFields() {
+		return false
+	}
+	field := st.Field(fa.Field)
+	return field.Embedded() && embedsResource(field.Type(), rt, 0)
+}
+
+// embedsResource checks if t is rt, or a struct embedding rt
+func embedsResource(t types.Type, rt *ResourceType, depth int) bool {
+	if isResourceType(t, rt) {
+		return true
+	}
+	if depth > maxEmbedDepth {
+		return false
+	}
+	st, ok := derefType(t).Underlying().(*types.Struct)
+	if !ok {
+		return false
+	}
+	for i := 0; i < st.NumFields(); i++ {
+		if st.Field(i).Embedded() && embedsResource(st.Field(i).Type(), rt, depth+1) {
+			return true
+		}
+	}
+	return false
+}
+
+// findDeferredCloseThroughField follows an embedded resource field to a deferred
+// close of the promoted method, e.g. defer w.Close() on a wrapper w
+func findDeferredCloseThroughField(fa *ssa.FieldAddr, rt *ResourceType) *ssa.Defer {
+	if fa.Referrers() == nil {
+		return nil
+	}
+
+	for _, ref := range *fa.Referrers() {
+		switch ref := ref.(type) {
+		case *ssa.UnOp:
+			if ref.Op != token.MUL {
+				continue
+			}
+			if d := findDeferredClose(ref, rt); d != nil {
+				return d
+			}
+		case *ssa.FieldAddr:
+			if ref.X == fa && isEmbeddedResourceField(ref, rt) {
+				if d := findDeferredCloseThroughField(ref, rt); d != nil {
+					return d
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// isStoredInWrapper checks if val is stored into the embedded field of a
+// wrapper created in the same function, which then owns the resource
+func isStoredInWrapper(val ssa.Value, rt *ResourceType) bool {
+	if val.Referrers() == nil {
+		return false
+	}
+
+	for _, ref := range *val.Referrers() {
+		store, ok := ref.(*ssa.Store)
+		if !ok || store.Val != val {
+			continue
+		}
+		fa, ok := store.Addr.(*ssa.FieldAddr)
+		if !ok || !isEmbeddedResourceField(fa, rt) {
+			continue
+		}
+		if _, ok := wrapperRoot(fa).(*ssa.Alloc); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// wrapperRoot follows nested embedded fields, e.g. &w.inner.ReadOnlyTransaction,
+// back to the outermost wrapper value
+func wrapperRoot(fa *ssa.FieldAddr) ssa.Value {
+	root := fa.X
+	for {
+		inner, ok := root.(*ssa.FieldAddr)
+		if !ok {
+			return root
+		}
+		root = inner.X
+	}
+}
+
+// isWrapperAcquisition checks if a wrapper allocation holds a resource,
+// i.e. a resource is stored into its embedded field
+func isWrapperAcquisition(addr ssa.Value, rt *ResourceType) bool {
+	if addr.Referrers() == nil {
+		return false
+	}
+
+	for _, ref := range *addr.Referrers() {
+		switch ref := ref.(type) {
+		case *ssa.Store:
+			if ref.Addr == addr && isResourceType(ref.Val.Type(), rt) {
+				return true
+			}
+		case *ssa.FieldAddr:
+			if ref.X == addr && isEmbeddedResourceField(ref, rt) && isWrapperAcquisition(ref, rt) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// wrapperFieldMessage reports a resource returned in a field of a wrapper
+// struct whose methods never close it
+const wrapperFieldMessage = "%s.%s() must be called by a Close method of %s, which owns it through field %s"
+
+// returnedWrapperField returns the wrapper struct val is stored into and the
+// index of its field when fn returns the wrapper, as in
+// return &cursor{iter: iter}, nil. The wrapper owns the resource from then on.
+// Embedded fields are left to isStoredInWrapper.
+func returnedWrapperField(fn *ssa.Function, val ssa.Value, rt *ResourceType) (*types.Named, int, bool) {
+	if val.Referrers() == nil {
+		return nil, 0, false
+	}
+
+	for _, ref := range *val.Referrers() {
+		store, ok := ref.(*ssa.Store)
+		if !ok || store.Val != val {
+			continue
+		}
+		fa, ok := store.Addr.(*ssa.FieldAddr)
+		if !ok || isEmbeddedResourceField(fa, rt) {
+			continue
+		}
+		alloc, ok := fa.X.(*ssa.Alloc)
+		if !ok || !isWrapperReturned(fn, alloc) {
+			continue
+		}
+		if named, ok := derefType(alloc.Type()).(*types.Named); ok {
+			return named, fa.Field, true
+		}
+	}
+
+	return nil, 0, false
+}
+
+// isWrapperReturned checks if fn returns the struct allocated by alloc, as a
+// pointer or by value
+func isWrapperReturned(fn *ssa.Function, alloc *ssa.Alloc) bool {
+	if isReturnedFromFunction(fn, alloc) {
+		return true
+	}
+	for _, ref := range *alloc.Referrers() {
+		if load, ok := ref.(*ssa.UnOp); ok && load.Op == token.MUL && isReturnedFromFunction(fn, load) {
+			return true
+		}
+	}
+	return false
+}
+
+// wrapperClosesField checks if a close-like method of wrapper, one without
+// parameters returning nothing or an error, closes the field at index.
+// Methods declared in other packages, whose bodies are not built, are trusted.
+func wrapperClosesField(prog *ssa.Program, wrapper *types.Named, index int, rt *ResourceType) bool {
+	for i := range wrapper.NumMethods() {
+		method := wrapper.Method(i)
+		sig := method.Type().(*types.Signature)
+		if sig.Params().Len() != 0 || sig.Results().Len() > 1 {
+			continue
+		}
+		if sig.Results().Len() == 1 && !types.Identical(sig.Results().At(0).Type(), types.Universe.Lookup("error").Type()) {
+			continue
+		}
+		fn := prog.FuncValue(method)
+		if fn == nil {
+			continue
+		}
+		if fn.Blocks == nil || len(fn.Params) > 0 && closesField(fn, fn.Params[0], index, rt) {
+			return true
+		}
+	}
+	return false
+}
+
+// closesField checks if fn closes the field at index of its receiver recv
+func closesField(fn *ssa.Function, recv ssa.Value, index int, rt *ResourceType) bool {
+	// Value receivers whose fields are selected are spilled to a local cell
+	recvs := []ssa.Value{recv}
+	for _, ref := range *recv.Referrers() {
+		if store, ok := ref.(*ssa.Store); ok && store.Val == recv {
+			recvs = append(recvs, store.Addr)
+		}
+	}
+
+	for _, block := range fn.Blocks {
+		for _, instr := range block.Instrs {
+			switch instr := instr.(type) {
+			case *ssa.FieldAddr:
+				if !slices.Contains(recvs, instr.X) || instr.Field != index || instr.Referrers() == nil {
+					continue
+				}
+				for _, ref := range *instr.Referrers() {
+					if load, ok := ref.(*ssa.UnOp); ok && load.Op == token.MUL && closesValue(load, rt, 0) {
+						return true
+					}
+				}
+			case *ssa.Field:
+				if slices.Contains(recvs, instr.X) && instr.Field == index && closesValue(instr, rt, 0) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+// Package conformance is a test harness for custom resource types registered
+// with the spannerclosecheck -resource flag.
+//
+// Plugin authors call Run from a test with their resource descriptor. Run
+// generates stub packages declaring the resource type, its close method and
+// constructors, together with a fixture package of good and bad cases, and
+// checks that the analyzer reports exactly the bad cases in each of Modes:
+//
+//	func TestOurDBConformance(t *testing.T) {
+//		conformance.Run(t, "github.com/acme/ourdb.Txn:Release:acquire=Begin:exempt=OneShotTxn")
+//	}
+//
+// Run drives the analyzer with analysistest, so it does not cover drivers
+// such as go vet, golangci-lint or the spannerclosecheck command, nor
+// -whole-program, which only the command supports. Fixtures are test
+// files, so -skip-tests, which would drop their reports, is not a mode
+// either.
+//
+// WriteFixtures writes the same fixtures to a directory for inspection.
+package conformance
+
+import (
+	"testing"
+
+	"github.com/ZZTmercari/spannerclosecheck/pkg/analyzer"
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+// Mode is a configuration of the analyzer the fixtures are checked under
+type Mode struct {
+	Name string
+	// Flags are set on the analyzer in addition to -resource
+	Flags map[string]string
+}
+
+// Modes are the analyzer configurations checked by Run
+var Modes = []Mode{
+	{Name: "default"},
+	{Name: "defer-before-use", Flags: map[string]string{"defer-before-use": "true"}},
+	{Name: "suggest-single", Flags: map[string]string{"suggest-single": "true"}},
+	{Name: "lenient", Flags: map[string]string{"lenient": "true"}},
+	{Name: "defer-within", Flags: map[string]string{"defer-within": "1"}},
+	{Name: "tests-only", Flags: map[string]string{"tests-only": "true"}},
+}
+
+// Run checks the resource descriptor spec against generated fixtures in
+// every mode, failing t if the analyzer misses a bad case or reports a good one
+func Run(t *testing.T, spec string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := WriteFixtures(dir, spec); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, mode := range Modes {
+		t.Run(mode.Name, func(t *testing.T) {
+			a := analyzer.NewAnalyzer(&analyzer.Options{})
+			flags := map[string]string{"resource": spec, "lifecycle-hook": fixtureHook}
+			for name, value := range mode.Flags {
+				flags[name] = value
+			}
+			for name, value := range flags {
+				if err := a.Flags.Set(name, value); err != nil {
+					t.Fatalf("flag -%s=%s: %v", name, value, err)
+				}
+			}
+			analysistest.Run(t, dir, a, FixturePackage)
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package conformance_test
+
+import (
+	"testing"
+
+	"github.com/ZZTmercari/spannerclosecheck/pkg/conformance"
+)
+
+func TestRun(t *testing.T) {
+	for _, spec := range []string{
+		"example.com/ourdb.Iter:Close:exempt=(example.com/ourdb.DB).OneShot,NewOneShot",
+		"example.com/ourdb.Txn:Release:acquire=Begin:exempt=OneShotTxn",
+		"example.com/ourdb/v2.Txn:Release:acquire=(*example.com/ourdb/v2.DB).Begin,example.com/ourdb/factory.NewTxn:exempt=(example.com/ourdb/v2.DB).Single",
+	} {
+		t.Run(spec, func(t *testing.T) {
+			conformance.Run(t, spec)
+		})
+	}
+}
+
+func TestWriteFixturesInvalid(t *testing.T) {
+	if err := conformance.WriteFixtures(t.TempDir(), "example.com/ourdb.Txn"); err == nil {
+		t.Error("expected error for a descriptor without close method")
+	}
+}
@@ -0,0 +1,243 @@
+package conformance
+
+import (
+	"fmt"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ZZTmercari/spannerclosecheck/pkg/analyzer"
+)
+
+// FixturePackage is the import path of the generated test package
+const FixturePackage = "conformancefixture"
+
+// fixtureHook is the lifecycle registration function declared by the
+// fixture package, registered with -lifecycle-hook in every mode
+const fixtureHook = FixturePackage + ".onShutdown"
+
+// WriteFixtures writes GOPATH-style fixtures for the resource descriptor spec
+// into dir: stub packages declaring the resource type, its close method and
+// constructors, and the FixturePackage with good and bad cases annotated
+// with analysistest "want" comments.
+func WriteFixtures(dir, spec string) error {
+	rt, err := analyzer.ParseResourceSpec(spec)
+	if err != nil {
+		return err
+	}
+
+	f := newFixture(rt)
+	for _, name := range rt.Constructors {
+		f.acquire = append(f.acquire, f.declareFunc(name))
+	}
+	for _, name := range rt.ExemptConstructors {
+		f.exempt = append(f.exempt, f.declareFunc(name))
+	}
+	if len(f.acquire) == 0 {
+		// Every value of the type is an acquisition
+		f.acquire = append(f.acquire, "&"+f.qualify(rt.PkgPath, rt.Name)+"{}")
+	}
+
+	for _, stub := range f.stubs {
+		if err := writeFile(filepath.Join(dir, "src", stub.path, "stub.go"), stub.source()); err != nil {
+			return err
+		}
+	}
+	return writeFile(filepath.Join(dir, "src", FixturePackage, FixturePackage+"_test.go"), f.source())
+}
+
+func writeFile(name, content string) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(name, []byte(content), 0o644)
+}
+
+// fixture collects the declarations needed to exercise a resource type
+type fixture struct {
+	rt    analyzer.ResourceType
+	stubs map[string]*stub
+
+	// acquire and exempt are call expressions producing the resource
+	acquire []string
+	exempt  []string
+}
+
+// stub is a generated package declaring resource types and constructors
+type stub struct {
+	path    string
+	name    string
+	alias   string
+	types   map[string]bool
+	imports map[string]string
+	decls   []string
+}
+
+func newFixture(rt analyzer.ResourceType) *fixture {
+	f := &fixture{rt: rt, stubs: make(map[string]*stub)}
+	s := f.stub(rt.PkgPath)
+	s.declareType(rt.Name)
+	s.decls = append(s.decls, fmt.Sprintf("func (*%s) %s() {}", rt.Name, rt.CloseMethod))
+	return f
+}
+
+// stub returns the stub package for pkgPath, creating it on first use
+func (f *fixture) stub(pkgPath string) *stub {
+	if s, ok := f.stubs[pkgPath]; ok {
+		return s
+	}
+	s := &stub{
+		path:    pkgPath,
+		name:    packageName(pkgPath),
+		alias:   fmt.Sprintf("p%d", len(f.stubs)),
+		types:   make(map[string]bool),
+		imports: make(map[string]string),
+	}
+	f.stubs[pkgPath] = s
+	return s
+}
+
+// qualify returns the expression referring to name declared in pkgPath
+func (f *fixture) qualify(pkgPath, name string) string {
+	return f.stub(pkgPath).alias + "." + name
+}
+
+// declareFunc declares the constructor name, either bare, e.g. Begin, or
+// fully qualified, e.g. (*example.com/ourdb.DB).Begin, and returns an
+// expression calling it
+func (f *fixture) declareFunc(name string) string {
+	pkgPath, recv, fn := splitFuncName(name)
+
+	if pkgPath == "" {
+		pkgPath = f.rt.PkgPath
+	}
+	s := f.stub(pkgPath)
+
+	result := "*" + f.rt.Name
+	if s.path != f.rt.PkgPath {
+		rs := f.stub(f.rt.PkgPath)
+		s.imports[rs.path] = rs.alias
+		result = "*" + rs.alias + "." + f.rt.Name
+	}
+
+	if recv == "" {
+		s.decls = append(s.decls, fmt.Sprintf("func %s() %s { return nil }", fn, result))
+		return s.alias + "." + fn + "()"
+	}
+	recvType := recv
+	recv = strings.TrimPrefix(recv, "*")
+	s.declareType(recv)
+	s.decls = append(s.decls, fmt.Sprintf("func (%s) %s() %s { return nil }", recvType, fn, result))
+	return "(&" + s.alias + "." + recv + "{})." + fn + "()"
+}
+
+// splitFuncName splits a fully qualified function or method name into its
+// package path, receiver type, prefixed with * for pointer receivers, and
+// name. Bare names have no package path.
+func splitFuncName(name string) (pkgPath, recv, fn string) {
+	if strings.HasPrefix(name, "(") {
+		end := strings.Index(name, ").")
+		if end < 0 {
+			return "", "", name
+		}
+		qualified, star := strings.CutPrefix(name[1:end], "*")
+		dot := strings.LastIndex(qualified, ".")
+		recv = qualified[dot+1:]
+		if star {
+			recv = "*" + recv
+		}
+		return qualified[:dot], recv, name[end+2:]
+	}
+	slash := strings.LastIndex(name, "/")
+	dot := strings.LastIndex(name, ".")
+	if dot <= slash {
+		return "", "", name
+	}
+	return name[:dot], "", name[dot+1:]
+}
+
+// packageName derives a valid package name from pkgPath, skipping major
+// version suffixes such as /v2
+func packageName(pkgPath string) string {
+	elems := strings.Split(pkgPath, "/")
+	name := elems[len(elems)-1]
+	if len(elems) > 1 && regexp.MustCompile(`^v[2-9][0-9]*$`).MatchString(name) {
+		name = elems[len(elems)-2]
+	}
+	name = regexp.MustCompile(`[^A-Za-z0-9_]`).ReplaceAllString(name, "_")
+	if name == "" || token.IsKeyword(name) || (name[0] >= '0' && name[0] <= '9') {
+		name = "pkg_" + name
+	}
+	return name
+}
+
+func (s *stub) declareType(name string) {
+	if !s.types[name] {
+		s.types[name] = true
+		s.decls = append(s.decls, fmt.Sprintf("type %s struct{}", name))
+	}
+}
+
+func (s *stub) source() string {
+	var b strings.Builder
+	b.WriteString("package " + s.name + "\n")
+	writeImports(&b, s.imports)
+	for _, decl := range s.decls {
+		b.WriteString("\n" + decl + "\n")
+	}
+	return b.String()
+}
+
+// writeImports writes an import declaration for imports, mapping import
+// paths to their aliases
+func writeImports(b *strings.Builder, imports map[string]string) {
+	if len(imports) == 0 {
+		return
+	}
+	paths := make([]string, 0, len(imports))
+	for p := range imports {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	b.WriteString("\nimport (\n")
+	for _, p := range paths {
+		fmt.Fprintf(b, "\t%s %q\n", imports[p], p)
+	}
+	b.WriteString(")\n")
+}
+
+// source returns the fixture package, with one good or bad case per function
+func (f *fixture) source() string {
+	var b strings.Builder
+	b.WriteString("package " + FixturePackage + "\n")
+	imports := make(map[string]string, len(f.stubs))
+	for p, s := range f.stubs {
+		imports[p] = s.alias
+	}
+	writeImports(&b, imports)
+
+	want := " // want " + strconv.Quote(regexp.QuoteMeta(f.rt.CloseMessage()))
+	closeCall := "x." + f.rt.CloseMethod + "()"
+	b.WriteString("\nfunc onShutdown(hook func()) {}\n")
+
+	for i, acquire := range f.acquire {
+		fmt.Fprintf(&b, "\nfunc goodDeferred%d() {\n\tx := %s\n\tdefer %s\n}\n", i, acquire, closeCall)
+		fmt.Fprintf(&b, "\nfunc goodDeferredClosure%d() {\n\tx := %s\n\tdefer func() {\n\t\t%s\n\t}()\n}\n", i, acquire, closeCall)
+		fmt.Fprintf(&b, "\nfunc goodLifecycleHook%d() {\n\tx := %s\n\tonShutdown(func() { %s })\n}\n", i, acquire, closeCall)
+		fmt.Fprintf(&b, "\nfunc goodNolint%d() {\n\tx := %s //nolint:spannerclosecheck\n\t_ = x\n}\n", i, acquire)
+		fmt.Fprintf(&b, "\nfunc badMissingClose%d() {\n\tx := %s%s\n\t_ = x\n}\n", i, acquire, want)
+		// The early return keeps the close from running on every path, which
+		// lenient mode would accept
+		fmt.Fprintf(&b, "\nfunc badNotDeferred%d(skip bool) {\n\tx := %s%s\n\tif skip {\n\t\treturn\n\t}\n\t%s\n}\n", i, acquire, want, closeCall)
+	}
+	for i, exempt := range f.exempt {
+		fmt.Fprintf(&b, "\nfunc goodExempt%d() {\n\tx := %s\n\t_ = x\n}\n", i, exempt)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"go/token"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ZZTmercari/spannerclosecheck/pkg/analyzer"
+)
+
+// SARIF 2.1.0 log, limited to the properties spannerclosecheck fills, see
+// https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+type (
+	sarifLog struct {
+		Schema  string     `json:"$schema"`
+		Version string     `json:"version"`
+		Runs    []sarifRun `json:"runs"`
+	}
+	sarifRun struct {
+		Tool               sarifTool                   `json:"tool"`
+		OriginalURIBaseIDs map[string]sarifArtifactURI `json:"originalUriBaseIds,omitempty"`
+		Results            []sarifResult               `json:"results"`
+	}
+	sarifTool struct {
+		Driver sarifDriver `json:"driver"`
+	}
+	sarifDriver struct {
+		Name           string      `json:"name"`
+		Version        string      `json:"version"`
+		InformationURI string      `json:"informationUri"`
+		Rules          []sarifRule `json:"rules"`
+	}
+	sarifRule struct {
+		ID                   string             `json:"id"`
+		ShortDescription     sarifMessage       `json:"shortDescription"`
+		HelpURI              string             `json:"helpUri"`
+		DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+		Properties           sarifProperties    `json:"properties"`
+	}
+	sarifConfiguration struct {
+		Level string `json:"level"`
+	}
+	sarifProperties struct {
+		Confidence string `json:"confidence"`
+	}
+	sarifResult struct {
+		RuleID           string          `json:"ruleId"`
+		RuleIndex        int             `json:"ruleIndex"`
+		Level            string          `json:"level"`
+		Message          sarifMessage    `json:"message"`
+		Locations        []sarifLocation `json:"locations"`
+		RelatedLocations []sarifLocation `json:"relatedLocations,omitempty"`
+		Fixes            []sarifFix      `json:"fixes,omitempty"`
+	}
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+	sarifLocation struct {
+		ID               *int                  `json:"id,omitempty"`
+		PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+		Message          *sarifMessage         `json:"message,omitempty"`
+	}
+	sarifPhysicalLocation struct {
+		ArtifactLocation sarifArtifactURI `json:"artifactLocation"`
+		Region           sarifRegion      `json:"region"`
+	}
+	sarifArtifactURI struct {
+		URI       string `json:"uri"`
+		URIBaseID string `json:"uriBaseId,omitempty"`
+	}
+	sarifRegion struct {
+		StartLine   int  `json:"startLine,omitempty"`
+		StartColumn int  `json:"startColumn,omitempty"`
+		EndLine     int  `json:"endLine,omitempty"`
+		EndColumn   int  `json:"endColumn,omitempty"`
+		ByteOffset  *int `json:"byteOffset,omitempty"`
+		ByteLength  *int `json:"byteLength,omitempty"`
+	}
+	sarifFix struct {
+		Description     sarifMessage          `json:"description"`
+		ArtifactChanges []sarifArtifactChange `json:"artifactChanges"`
+	}
+	sarifArtifactChange struct {
+		ArtifactLocation sarifArtifactURI   `json:"artifactLocation"`
+		Replacements     []sarifReplacement `json:"replacements"`
+	}
+	sarifReplacement struct {
+		DeletedRegion   sarifRegion  `json:"deletedRegion"`
+		InsertedContent sarifMessage `json:"insertedContent"`
+	}
+)
+
+const (
+	sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+	// sarifSrcRoot is the base of the URIs of the files in the working
+	// directory, so that code scanning resolves them in the repository
+	sarifSrcRoot = "%SRCROOT%"
+	// sarifHelpURI documents the categories, which are the rules
+	sarifHelpURI = "https://github.com/ZZTmercari/spannerclosecheck/blob/main/USAGE.md#diagnostic-categories"
+)
+
+// writeSARIF writes diags to w as a SARIF log, with a rule per category
+func writeSARIF(w io.Writer, diags []diagnostic) error {
+	wd, _ := os.Getwd()
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "spannerclosecheck",
+			Version:        Version,
+			InformationURI: "https://github.com/ZZTmercari/spannerclosecheck",
+		}},
+		Results: []sarifResult{},
+	}
+	if wd != "" {
+		run.OriginalURIBaseIDs = map[string]sarifArtifactURI{sarifSrcRoot: {URI: fileURI(wd) + "/"}}
+	}
+
+	rules := make(map[string]int)
+	addRule := func(id, description string, confidence analyzer.Confidence, severity analyzer.Severity) {
+		rules[id] = len(run.Tool.Driver.Rules)
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+			ID:                   id,
+			ShortDescription:     sarifMessage{Text: description},
+			HelpURI:              sarifHelpURI,
+			DefaultConfiguration: sarifConfiguration{Level: sarifLevel(severity)},
+			Properties:           sarifProperties{Confidence: confidence.String()},
+		})
+	}
+	for _, c := range analyzer.Categories() {
+		addRule(c.Name, c.Description, c.Confidence, c.Severity)
+	}
+
+	for _, d := range diags {
+		// Registered checkers may report other categories, or none
+		id := cmp.Or(d.Category, d.analyzer)
+		if _, ok := rules[id]; !ok {
+			addRule(id, id, analyzer.ConfidenceOf(d.Diagnostic), analyzer.SeverityError)
+		}
+		result := sarifResult{
+			RuleID:    id,
+			RuleIndex: rules[id],
+			Level:     sarifLevel(d.severity),
+			Message:   sarifMessage{Text: d.Message},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysical(d.fset, d.Pos, d.End, wd)}},
+		}
+		for i, r := range d.Related {
+			n := i + 1
+			result.RelatedLocations = append(result.RelatedLocations, sarifLocation{
+				ID:               &n,
+				PhysicalLocation: sarifPhysical(d.fset, r.Pos, r.End, wd),
+				Message:          &sarifMessage{Text: r.Message},
+			})
+		}
+		for _, fix := range d.SuggestedFixes {
+			sf := sarifFix{Description: sarifMessage{Text: fix.Message}}
+			changes := make(map[string]int)
+			for _, edit := range fix.TextEdits {
+				start, end := d.fset.PositionFor(edit.Pos, false), d.fset.PositionFor(edit.End, false)
+				if !end.IsValid() {
+					end = start
+				}
+				artifact := sarifArtifact(start.Filename, wd)
+				i, ok := changes[artifact.URI]
+				if !ok {
+					i = len(sf.ArtifactChanges)
+					changes[artifact.URI] = i
+					sf.ArtifactChanges = append(sf.ArtifactChanges, sarifArtifactChange{ArtifactLocation: artifact})
+				}
+				offset, length := start.Offset, end.Offset-start.Offset
+				sf.ArtifactChanges[i].Replacements = append(sf.ArtifactChanges[i].Replacements, sarifReplacement{
+					DeletedRegion:   sarifRegion{ByteOffset: &offset, ByteLength: &length},
+					InsertedContent: sarifMessage{Text: string(edit.NewText)},
+				})
+			}
+			result.Fixes = append(result.Fixes, sf)
+		}
+		run.Results = append(run.Results, result)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{Schema: sarifSchema, Version: "2.1.0", Runs: []sarifRun{run}})
+}
+
+// sarifLevel returns the SARIF level of the results of severity
+func sarifLevel(severity analyzer.Severity) string {
+	switch severity {
+	case analyzer.SeverityInfo:
+		return "note"
+	case analyzer.SeverityWarning:
+		return "warning"
+	}
+	return "error"
+}
+
+// sarifPhysical returns the location of pos and end, reported in the files
+// of //line directives like the text output
+func sarifPhysical(fset *token.FileSet, pos, end token.Pos, wd string) sarifPhysicalLocation {
+	start := fset.Position(pos)
+	region := sarifRegion{StartLine: start.Line, StartColumn: start.Column}
+	if e := fset.Position(end); e.IsValid() && e.Filename == start.Filename {
+		region.EndLine, region.EndColumn = e.Line, e.Column
+	}
+	return sarifPhysicalLocation{ArtifactLocation: sarifArtifact(start.Filename, wd), Region: region}
+}
+
+// sarifArtifact returns the location of filename, relative to wd, the
+// working directory, when it is inside it
+func sarifArtifact(filename, wd string) sarifArtifactURI {
+	if wd != "" {
+		if rel, err := filepath.Rel(wd, filename); err == nil && filepath.IsLocal(rel) {
+			return sarifArtifactURI{URI: (&url.URL{Path: filepath.ToSlash(rel)}).EscapedPath(), URIBaseID: sarifSrcRoot}
+		}
+	}
+	return sarifArtifactURI{URI: fileURI(filename)}
+}
+
+// fileURI returns the file URI of path, an absolute path
+func fileURI(path string) string {
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		// Windows volumes, as in file:///C:/src
+		path = "/" + path
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
@@ -0,0 +1,8 @@
+package broken
+
+import "cloud.google.com/go/spanner"
+
+func query(client *spanner.Client) int {
+	txn := client.ReadOnlyTransaction()
+	return txn
+}
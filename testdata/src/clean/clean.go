@@ -0,0 +1,16 @@
+package clean
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+func query(ctx context.Context, client *spanner.Client) error {
+	txn := client.ReadOnlyTransaction()
+	defer txn.Close()
+
+	iter := txn.Query(ctx, spanner.Statement{})
+	defer iter.Stop()
+	return iter.Do(func(*spanner.Row) error { return nil })
+}
@@ -0,0 +1,23 @@
+package output
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+func unclosed(ctx context.Context, client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	iter := txn.Query(ctx, spanner.Statement{})
+	defer iter.Stop()
+}
+
+func notDeferred(ctx context.Context, client *spanner.Client) error {
+	txn := client.ReadOnlyTransaction()
+	defer txn.Close()
+
+	iter := txn.Query(ctx, spanner.Statement{})
+	_, err := iter.Next()
+	iter.Stop()
+	return err
+}
@@ -0,0 +1,13 @@
+package suppressed
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+func excluded(ctx context.Context, client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	iter := txn.Query(ctx, spanner.Statement{})
+	defer iter.Stop()
+}
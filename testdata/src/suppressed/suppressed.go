@@ -0,0 +1,19 @@
+package suppressed
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+func unclosed(ctx context.Context, client *spanner.Client) {
+	txn := client.ReadOnlyTransaction()
+	iter := txn.Query(ctx, spanner.Statement{})
+	defer iter.Stop()
+}
+
+func silenced(ctx context.Context, client *spanner.Client) {
+	txn := client.ReadOnlyTransaction() //nolint:spannerclosecheck // closed by the caller's pool
+	iter := txn.Query(ctx, spanner.Statement{})
+	defer iter.Stop()
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"io"
+	"slices"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/checker"
+)
+
+// vetDiagnostic is a diagnostic of go vet -json
+type vetDiagnostic struct {
+	Category       string       `json:"category,omitempty"`
+	Posn           string       `json:"posn"`
+	Message        string       `json:"message"`
+	SuggestedFixes []vetFix     `json:"suggested_fixes,omitempty"`
+	Related        []vetRelated `json:"related,omitempty"`
+}
+
+type vetFix struct {
+	Message string     `json:"message"`
+	Edits   []jsonEdit `json:"edits"`
+}
+
+type vetRelated struct {
+	Posn    string `json:"posn"`
+	Message string `json:"message"`
+}
+
+// vetError is the result of an analysis failing on a package
+type vetError struct {
+	Err string `json:"error"`
+}
+
+// writeVet writes the results of the root actions of graph to w as go vet
+// -json does: for each package go vet analyzes, a "# importpath" line and a
+// JSON object keyed by the import path and then by the analyzer, empty if
+// the analysis succeeded without diagnostics
+func writeVet(w io.Writer, graph *checker.Graph) error {
+	roots := slices.Clone(graph.Roots)
+	slices.SortStableFunc(roots, func(a, b *checker.Action) int {
+		return cmp.Compare(a.Package.ID, b.Package.ID)
+	})
+	// go vet analyzes the test variant of packages with tests instead of the
+	// package, and skips the generated test main packages
+	tested := make(map[string]bool)
+	for _, act := range roots {
+		if strings.HasPrefix(act.Package.ID, act.Package.PkgPath+" [") {
+			tested[act.Package.PkgPath] = true
+		}
+	}
+
+	for _, act := range roots {
+		pkg := act.Package
+		if pkg.ID == pkg.PkgPath && tested[pkg.PkgPath] || pkg.Name == "main" && strings.HasSuffix(pkg.ID, ".test") {
+			continue
+		}
+		tree := make(map[string]map[string]any)
+		if v := vetResult(pkg.Fset, act); v != nil {
+			tree[pkg.PkgPath] = map[string]any{act.Analyzer.Name: v}
+		}
+		data, err := json.MarshalIndent(tree, "", "\t")
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "# %s\n%s\n", pkg.PkgPath, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// vetResult returns the result of act in go vet -json, or nil if it
+// succeeded without diagnostics
+func vetResult(fset *token.FileSet, act *checker.Action) any {
+	if act.Err != nil {
+		return vetError{act.Err.Error()}
+	}
+	if len(act.Diagnostics) == 0 {
+		return nil
+	}
+	diags := make([]vetDiagnostic, 0, len(act.Diagnostics))
+	for _, d := range act.Diagnostics {
+		diags = append(diags, vetDiagnosticOf(fset, d))
+	}
+	return diags
+}
+
+func vetDiagnosticOf(fset *token.FileSet, d analysis.Diagnostic) vetDiagnostic {
+	vd := vetDiagnostic{Category: d.Category, Posn: fset.Position(d.Pos).String(), Message: d.Message}
+	for _, fix := range d.SuggestedFixes {
+		vf := vetFix{Message: fix.Message}
+		for _, edit := range fix.TextEdits {
+			vf.Edits = append(vf.Edits, jsonTextEdit(fset, edit.Pos, edit.End, string(edit.NewText)))
+		}
+		vd.SuggestedFixes = append(vd.SuggestedFixes, vf)
+	}
+	for _, r := range d.Related {
+		vd.Related = append(vd.Related, vetRelated{Posn: fset.Position(r.Pos).String(), Message: r.Message})
+	}
+	return vd
+}